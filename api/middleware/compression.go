@@ -0,0 +1,276 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"github.com/andybalholm/brotli"
+	"github.com/gorilla/mux"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"noteapp/api"
+	"noteapp/pkg/logging"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The content codings of the compressed responses.
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+// compressibleTypes are the media types, or their prefixes ending
+// with a slash, of the responses which are compressed.
+var compressibleTypes = []string{
+	"text/",
+	"application/json",
+	"application/problem+json",
+	"application/x-ndjson",
+	"application/msgpack",
+	"application/x-protobuf",
+	"application/xml",
+	"application/javascript",
+}
+
+var (
+	gzipWriters   = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+	brotliWriters = sync.Pool{New: func() interface{} { return brotli.NewWriter(nil) }}
+)
+
+// CompressionConfig contains the responses compressed by the
+// compression middleware.
+type CompressionConfig struct {
+	// MinSize is the smallest body compressed, the smaller ones are
+	// not worth the overhead of the compression. The flushed bodies
+	// are compressed whatever their size.
+	MinSize int
+}
+
+// NewCompressionMiddleware returns a compression middleware with its name.
+func NewCompressionMiddleware(conf CompressionConfig) api.NamedMiddleware {
+	return api.NewNamedMiddleware("Compression", Compression(conf))
+}
+
+// Compression returns an http handler middleware which compresses the
+// responses with brotli or gzip, the one preferred by the Accept-Encoding
+// header of the request, or brotli when the client accepts both the same.
+// Only the compressible media types are compressed, and the responses
+// which already have a content coding are left as they are.
+func Compression(conf CompressionConfig) mux.MiddlewareFunc {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: conf.MinSize}
+			defer func() {
+				if err := cw.Close(); err != nil {
+					logging.Logger(r.Context()).Error("middleware/compression: ", err)
+				}
+			}()
+			h.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding returns the supported content coding most preferred
+// by the accept encoding header, or an empty string when there is none.
+func negotiateEncoding(acceptEncoding string) string {
+	q := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		weight := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				v, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					v = 0
+				}
+				weight = v
+			}
+		}
+		q[coding] = weight
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range []string{encodingBrotli, encodingGzip} {
+		weight, ok := q[coding]
+		if !ok {
+			weight = q["*"]
+		}
+		if weight > bestQ {
+			best, bestQ = coding, weight
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the beginning of the body until it reaches
+// the min size, then decides whether the response is compressed.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	status  int
+	buf     []byte
+	decided bool
+	w       io.WriteCloser
+}
+
+// WriteHeader keeps the status code until the body is decided.
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.status == 0 {
+		cw.status = code
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.decided {
+		if cw.w != nil {
+			return cw.w.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.minSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush sends the compressed body so far to the client.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if err := cw.decide(true); err != nil {
+			return
+		}
+	}
+
+	if f, ok := cw.w.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements the http.Hijacker when the underlying
+// response writer does.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("middleware: response writer doesn't implement http.Hijacker")
+	}
+	return h.Hijack()
+}
+
+// Close writes the buffered body of the responses smaller than the
+// min size uncompressed, and ends the compressed ones.
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if cw.status == 0 && len(cw.buf) == 0 {
+			return nil
+		}
+		if err := cw.decide(false); err != nil {
+			return err
+		}
+	}
+
+	if cw.w == nil {
+		return nil
+	}
+
+	err := cw.w.Close()
+	switch w := cw.w.(type) {
+	case *gzip.Writer:
+		gzipWriters.Put(w)
+	case *brotli.Writer:
+		brotliWriters.Put(w)
+	}
+	return err
+}
+
+// decide writes the header, compressing the body when compress is set
+// and the response is compressible, then writes the buffered body.
+func (cw *compressWriter) decide(compress bool) error {
+	cw.decided = true
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	header := cw.Header()
+	if header.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	if compress && cw.compressible() {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+
+		switch cw.encoding {
+		case encodingBrotli:
+			w := brotliWriters.Get().(*brotli.Writer)
+			w.Reset(cw.ResponseWriter)
+			cw.w = w
+		default:
+			w := gzipWriters.Get().(*gzip.Writer)
+			w.Reset(cw.ResponseWriter)
+			cw.w = w
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if cw.w != nil {
+		_, err = cw.w.Write(cw.buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+	return err
+}
+
+// compressible reports whether the response can be compressed.
+func (cw *compressWriter) compressible() bool {
+	if cw.status < http.StatusOK || cw.status == http.StatusNoContent || cw.status == http.StatusNotModified {
+		return false
+	}
+
+	header := cw.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	// The event streams are flushed event by event, the
+	// compression would only add latency.
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || mediaType == "text/event-stream" {
+		return false
+	}
+
+	for _, t := range compressibleTypes {
+		if mediaType == t || strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
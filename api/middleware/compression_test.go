@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	body := strings.Repeat(`{"title":"note"}`, 100)
+
+	serve := func(acceptEncoding, contentType, body string, flush bool) *httptest.ResponseRecorder {
+		h := Compression(CompressionConfig{MinSize: 1024})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if contentType != "" {
+				w.Header().Set("Content-Type", contentType)
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = io.WriteString(w, body)
+			if flush {
+				w.(http.Flusher).Flush()
+			}
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/notes", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Gzip", func(t *testing.T) {
+		rec := serve("gzip", "application/json", body, false)
+		require.Equal(t, http.StatusCreated, rec.Code)
+		require.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+		require.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+
+		r, err := gzip.NewReader(rec.Body)
+		require.NoError(t, err)
+		got, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, body, string(got))
+	})
+
+	t.Run("Brotli is preferred", func(t *testing.T) {
+		rec := serve("gzip, deflate, br", "application/json", body, false)
+		require.Equal(t, "br", rec.Header().Get("Content-Encoding"))
+
+		got, err := io.ReadAll(brotli.NewReader(rec.Body))
+		require.NoError(t, err)
+		require.Equal(t, body, string(got))
+	})
+
+	t.Run("Small body", func(t *testing.T) {
+		rec := serve("gzip", "application/json", `{"title":"note"}`, false)
+		require.Equal(t, http.StatusCreated, rec.Code)
+		require.Empty(t, rec.Header().Get("Content-Encoding"))
+		require.Equal(t, `{"title":"note"}`, rec.Body.String())
+	})
+
+	t.Run("Flushed small body", func(t *testing.T) {
+		rec := serve("gzip", "application/x-ndjson", `{"title":"note"}`, true)
+		require.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+		require.True(t, rec.Flushed)
+	})
+
+	t.Run("Incompressible media type", func(t *testing.T) {
+		rec := serve("gzip", "text/event-stream", body, false)
+		require.Empty(t, rec.Header().Get("Content-Encoding"))
+		require.Equal(t, body, rec.Body.String())
+	})
+
+	t.Run("Detected media type", func(t *testing.T) {
+		rec := serve("gzip", "", body, false)
+		require.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+		require.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("No accepted encoding", func(t *testing.T) {
+		rec := serve("deflate, gzip;q=0", "application/json", body, false)
+		require.Empty(t, rec.Header().Get("Content-Encoding"))
+		require.Equal(t, body, rec.Body.String())
+	})
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", encodingGzip},
+		{"br;q=0.5, gzip", encodingGzip},
+		{"gzip, br", encodingBrotli},
+		{"*", encodingBrotli},
+		{"*;q=0.1, br;q=0", encodingGzip},
+		{"GZIP;q=0.8", encodingGzip},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, negotiateEncoding(tt.acceptEncoding), tt.acceptEncoding)
+	}
+}
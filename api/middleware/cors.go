@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+	"noteapp/api"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrWildcardCredentials is an error when the "*" origin is allowed
+// with the credentials, which would let any site make the requests
+// of the users of the API.
+var ErrWildcardCredentials = errors.New(`middleware: the "*" origin can't be allowed with the credentials`)
+
+// CORSConfig contains the cross-origin requests allowed by the CORS
+// middleware.
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to call the API, e.g.
+	// "https://app.example.com". The "*" origin allows all of them
+	// but the requests with credentials.
+	AllowedOrigins []string
+	// AllowedMethods are the methods of the cross-origin requests.
+	AllowedMethods []string
+	// AllowedHeaders are the request headers of the
+	// cross-origin requests.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers the
+	// browsers expose to the callers.
+	ExposedHeaders []string
+	// AllowCredentials allows the requests with the cookies or the
+	// authorization headers. The allowed origins must be listed then.
+	AllowCredentials bool
+	// MaxAge is how long the browsers cache the preflight responses.
+	MaxAge time.Duration
+}
+
+// NewCORSMiddleware returns a CORS middleware with its name.
+func NewCORSMiddleware(conf CORSConfig) (api.NamedMiddleware, error) {
+	cors, err := CORS(conf)
+	if err != nil {
+		return api.NamedMiddleware{}, err
+	}
+	return api.NewNamedMiddleware("CORS", cors), nil
+}
+
+// CORS returns an http handler middleware which allows the cross-origin
+// requests of conf. It answers the preflight requests itself, so it
+// must come before the authentication, and adds the allowed origin to
+// the responses of the other requests. The requests of the origins
+// that are not allowed are served without the CORS headers, so the
+// browsers block their responses. It returns ErrWildcardCredentials
+// when all the origins are allowed with the credentials.
+func CORS(conf CORSConfig) (mux.MiddlewareFunc, error) {
+	allowAll := false
+	origins := make(map[string]bool, len(conf.AllowedOrigins))
+	for _, o := range conf.AllowedOrigins {
+		if o == "*" {
+			allowAll = true
+		}
+		origins[strings.ToLower(o)] = true
+	}
+
+	if allowAll && conf.AllowCredentials {
+		return nil, ErrWildcardCredentials
+	}
+
+	methods := make(map[string]bool, len(conf.AllowedMethods))
+	for _, m := range conf.AllowedMethods {
+		methods[strings.ToUpper(m)] = true
+	}
+
+	allowedMethods := strings.Join(conf.AllowedMethods, ", ")
+	allowedHeaders := strings.Join(conf.AllowedHeaders, ", ")
+	exposedHeaders := strings.Join(conf.ExposedHeaders, ", ")
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			header := w.Header()
+			header.Add("Vary", "Origin")
+
+			if origin == "" || !(allowAll || origins[strings.ToLower(origin)]) {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			if allowAll {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+			if conf.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			requestMethod := r.Header.Get("Access-Control-Request-Method")
+			if r.Method != http.MethodOptions || requestMethod == "" {
+				if exposedHeaders != "" {
+					header.Set("Access-Control-Expose-Headers", exposedHeaders)
+				}
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			// The preflight request of a method which is not allowed
+			// is answered without the allowed methods.
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			if methods[strings.ToUpper(requestMethod)] {
+				header.Set("Access-Control-Allow-Methods", allowedMethods)
+				if allowedHeaders != "" {
+					header.Set("Access-Control-Allow-Headers", allowedHeaders)
+				}
+				if conf.MaxAge > 0 {
+					header.Set("Access-Control-Max-Age", strconv.Itoa(int(conf.MaxAge.Seconds())))
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}, nil
+}
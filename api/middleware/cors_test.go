@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	conf := CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders: []string{"Content-Type", "X-API-Key"},
+		ExposedHeaders: []string{RequestIDHeader},
+		MaxAge:         10 * time.Minute,
+	}
+
+	serve := func(conf CORSConfig, method, origin, requestMethod string) *httptest.ResponseRecorder {
+		cors, err := CORS(conf)
+		require.NoError(t, err)
+		h := cors(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		}))
+
+		req := httptest.NewRequest(method, "/v1/notes", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if requestMethod != "" {
+			req.Header.Set("Access-Control-Request-Method", requestMethod)
+		}
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Same-origin request", func(t *testing.T) {
+		rec := serve(conf, http.MethodGet, "", "")
+		require.Equal(t, http.StatusTeapot, rec.Code)
+		require.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+		require.Equal(t, "Origin", rec.Header().Get("Vary"))
+	})
+
+	t.Run("Allowed origin", func(t *testing.T) {
+		rec := serve(conf, http.MethodGet, "https://app.example.com", "")
+		require.Equal(t, http.StatusTeapot, rec.Code)
+		require.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+		require.Equal(t, RequestIDHeader, rec.Header().Get("Access-Control-Expose-Headers"))
+		require.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("Origin not allowed", func(t *testing.T) {
+		rec := serve(conf, http.MethodGet, "https://evil.example.com", "")
+		require.Equal(t, http.StatusTeapot, rec.Code)
+		require.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Preflight request", func(t *testing.T) {
+		rec := serve(conf, http.MethodOptions, "https://app.example.com", http.MethodPost)
+		require.Equal(t, http.StatusNoContent, rec.Code)
+		require.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+		require.Equal(t, "GET, POST", rec.Header().Get("Access-Control-Allow-Methods"))
+		require.Equal(t, "Content-Type, X-API-Key", rec.Header().Get("Access-Control-Allow-Headers"))
+		require.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+		require.Equal(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, rec.Header().Values("Vary"))
+	})
+
+	t.Run("Preflight request of a method not allowed", func(t *testing.T) {
+		rec := serve(conf, http.MethodOptions, "https://app.example.com", http.MethodDelete)
+		require.Equal(t, http.StatusNoContent, rec.Code)
+		require.Empty(t, rec.Header().Get("Access-Control-Allow-Methods"))
+	})
+
+	t.Run("OPTIONS request which is not a preflight", func(t *testing.T) {
+		rec := serve(conf, http.MethodOptions, "https://app.example.com", "")
+		require.Equal(t, http.StatusTeapot, rec.Code)
+	})
+
+	t.Run("All the origins", func(t *testing.T) {
+		all := conf
+		all.AllowedOrigins = []string{"*"}
+		rec := serve(all, http.MethodGet, "https://other.example.com", "")
+		require.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Allowed origin with credentials", func(t *testing.T) {
+		credentials := conf
+		credentials.AllowCredentials = true
+		rec := serve(credentials, http.MethodGet, "https://app.example.com", "")
+		require.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+		require.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("All the origins with credentials should be rejected", func(t *testing.T) {
+		all := conf
+		all.AllowedOrigins = []string{"https://app.example.com", "*"}
+		all.AllowCredentials = true
+		_, err := CORS(all)
+		require.ErrorIs(t, err, ErrWildcardCredentials)
+	})
+}
@@ -1,19 +1,50 @@
 package middleware
 
 import (
-	"github.com/gorilla/handlers"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 	"net/http"
 	"noteapp/api"
-	"os"
+	"noteapp/pkg/logging"
+	"time"
 )
 
 // NewLoggingMiddleware returns a logging middleware with its name.
-func NewLoggingMiddleware() api.NamedMiddleware {
-	return api.NewNamedMiddleware("Logging", Logging)
+func NewLoggingMiddleware(logger logrus.FieldLogger) api.NamedMiddleware {
+	return api.NewNamedMiddleware("Logging", Logging(logger))
 }
 
-// Logging is an http handler middleware which responsible
-// for logging the request details.
-func Logging(h http.Handler) http.Handler {
-	return handlers.LoggingHandler(os.Stdout, h)
+// Logging returns an http handler middleware which keeps a logger with
+// the request id, the route path template and the trace id, if any, in
+// the request context, then logs the request with the fields added to
+// that logger while it was handled. It must come after the request id
+// and the tracing middlewares.
+func Logging(logger logrus.FieldLogger) mux.MiddlewareFunc {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			begin := time.Now()
+
+			fields := logrus.Fields{
+				"request_id": RequestIDFromContext(r.Context()),
+				"route":      routeTemplate(r),
+				"method":     r.Method,
+			}
+			if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+				fields["trace_id"] = sc.TraceID().String()
+			}
+
+			ctx := logging.NewContext(r.Context(), logger.WithFields(fields))
+			rec := &statusRecorder{ResponseWriter: w}
+			h.ServeHTTP(rec, r.WithContext(ctx))
+
+			logging.Logger(ctx).WithFields(logrus.Fields{
+				"path":        r.URL.Path,
+				"status":      rec.Status(),
+				"bytes":       rec.size,
+				"took":        time.Since(begin).String(),
+				"remote_addr": r.RemoteAddr,
+			}).Info("http request")
+		})
+	}
 }
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"noteapp/pkg/logging"
+	"testing"
+)
+
+func TestLogging(t *testing.T) {
+	logger, hook := logtest.NewNullLogger()
+
+	router := mux.NewRouter()
+	router.Path("/note/{id}").Methods(http.MethodGet).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.AddFields(r.Context(), logrus.Fields{"note_id": mux.Vars(r)["id"]})
+		logging.Logger(r.Context()).Warn("handling")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("not found"))
+	})
+	router.Use(RequestID, Logging(logger))
+
+	req := httptest.NewRequest(http.MethodGet, "/note/1", nil)
+	req.Header.Set(RequestIDHeader, "request-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := hook.AllEntries()
+	require.Len(t, entries, 2)
+
+	for _, e := range entries {
+		require.Equal(t, "request-1", e.Data["request_id"])
+		require.Equal(t, "/note/{id}", e.Data["route"])
+		require.Equal(t, "1", e.Data["note_id"])
+	}
+
+	access := entries[1]
+	require.Equal(t, "http request", access.Message)
+	require.Equal(t, http.StatusNotFound, access.Data["status"])
+	require.Equal(t, len("not found"), access.Data["bytes"])
+	require.Equal(t, "/note/1", access.Data["path"])
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"bufio"
+	"errors"
+	"github.com/go-kit/kit/metrics"
+	"github.com/gorilla/mux"
+	"net"
+	"net/http"
+	"noteapp/api"
+	"strconv"
+	"time"
+)
+
+// unmatchedRoute is the route label of the requests
+// which don't match any route.
+const unmatchedRoute = "unmatched"
+
+// NewMetricsMiddleware returns a metrics middleware with its name.
+func NewMetricsMiddleware(requestCount metrics.Counter, requestLatency metrics.Histogram) api.NamedMiddleware {
+	return api.NewNamedMiddleware("Metrics", Metrics(requestCount, requestLatency))
+}
+
+// Metrics returns an http handler middleware which counts the requests
+// and observes their latency in seconds. The counter is labeled with the
+// "method", the "route" path template and the status "code" of the
+// response, the histogram with the "method" and the "route".
+func Metrics(requestCount metrics.Counter, requestLatency metrics.Histogram) mux.MiddlewareFunc {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			begin := time.Now()
+			rec := &statusRecorder{ResponseWriter: w}
+			h.ServeHTTP(rec, r)
+
+			route := routeTemplate(r)
+			requestCount.With("method", r.Method, "route", route, "code", strconv.Itoa(rec.Status())).Add(1)
+			requestLatency.With("method", r.Method, "route", route).Observe(time.Since(begin).Seconds())
+		})
+	}
+}
+
+// routeTemplate returns the path template of the route matched r
+// instead of its path, so that the label values stay bounded.
+func routeTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return unmatchedRoute
+	}
+
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return unmatchedRoute
+	}
+	return tpl
+}
+
+// statusRecorder is an http.ResponseWriter which keeps the status
+// code and the size of the response. It passes the flushes and the hijacks to
+// the underlying writer for the event streams and the websockets.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.size += n
+	return n, err
+}
+
+// Status returns the status code of the response. It's 200 when
+// the handler didn't write the header explicitly.
+func (rec *statusRecorder) Status() int {
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+	return rec.status
+}
+
+func (rec *statusRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("middleware: response writer doesn't support hijacking")
+	}
+	if rec.status == 0 {
+		rec.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	count := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "count"}, []string{"method", "route", "code"})
+	latency := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "latency"}, []string{"method", "route"})
+
+	router := mux.NewRouter()
+	router.Path("/note/{id}").Methods(http.MethodGet).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("note"))
+	})
+	router.Use(Metrics(kitprometheus.NewCounter(count), kitprometheus.NewHistogram(latency)))
+
+	for _, path := range []string{"/note/1", "/note/2", "/note/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	require.Equal(t, 2.0, testutil.ToFloat64(count.WithLabelValues(http.MethodGet, "/note/{id}", "200")))
+	require.Equal(t, 1.0, testutil.ToFloat64(count.WithLabelValues(http.MethodGet, "/note/{id}", "404")))
+	require.Equal(t, 1, testutil.CollectAndCount(latency))
+}
+
+func TestStatusRecorder(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := &statusRecorder{ResponseWriter: rec}
+	require.Equal(t, http.StatusOK, w.Status())
+
+	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusInternalServerError)
+	w.Flush()
+	require.Equal(t, http.StatusCreated, w.Status())
+	require.True(t, rec.Flushed)
+
+	_, _, err := w.Hijack()
+	require.Error(t, err)
+}
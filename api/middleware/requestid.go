@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"net/http"
+	"noteapp/api"
+)
+
+// RequestIDHeader is the http header carrying the request id.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the longest request id accepted from
+// the clients, longer ones are replaced with a new id.
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// NewRequestIDMiddleware returns a request id middleware with its name.
+func NewRequestIDMiddleware() api.NamedMiddleware {
+	return api.NewNamedMiddleware("RequestID", RequestID)
+}
+
+// RequestID is an http handler middleware which takes the request id
+// from the X-Request-ID header, or generates one when the client didn't
+// send it, then keeps it in the request context and echoes it back in
+// the response header.
+func RequestID(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = uuid.New().String()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		h.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), id)))
+	})
+}
+
+// WithRequestID returns a copy of ctx with the request id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request id kept in ctx or
+// an empty string when there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
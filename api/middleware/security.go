@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"noteapp/api"
+	"time"
+)
+
+// SecurityHeadersConfig contains the security headers of the responses.
+type SecurityHeadersConfig struct {
+	// HSTSMaxAge is how long the browsers only connect to the server
+	// over HTTPS. The Strict-Transport-Security header is not sent
+	// when it is zero.
+	HSTSMaxAge time.Duration
+	// HSTSIncludeSubdomains applies the HSTS to the subdomains too.
+	HSTSIncludeSubdomains bool
+	// FrameOptions is the X-Frame-Options header, e.g. "DENY". The
+	// header is not sent when it is empty.
+	FrameOptions string
+	// ContentSecurityPolicy is the Content-Security-Policy header. The
+	// header is not sent when it is empty.
+	ContentSecurityPolicy string
+}
+
+// NewSecurityHeadersMiddleware returns a security headers middleware
+// with its name.
+func NewSecurityHeadersMiddleware(conf SecurityHeadersConfig) api.NamedMiddleware {
+	return api.NewNamedMiddleware("SecurityHeaders", SecurityHeaders(conf))
+}
+
+// SecurityHeaders returns an http handler middleware which adds the
+// security headers of conf to the responses, along with the
+// X-Content-Type-Options header turning off the content sniffing.
+func SecurityHeaders(conf SecurityHeadersConfig) mux.MiddlewareFunc {
+	var hsts string
+	if conf.HSTSMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d", int(conf.HSTSMaxAge.Seconds()))
+		if conf.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			header.Set("X-Content-Type-Options", "nosniff")
+			if hsts != "" {
+				header.Set("Strict-Transport-Security", hsts)
+			}
+			if conf.FrameOptions != "" {
+				header.Set("X-Frame-Options", conf.FrameOptions)
+			}
+			if conf.ContentSecurityPolicy != "" {
+				header.Set("Content-Security-Policy", conf.ContentSecurityPolicy)
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
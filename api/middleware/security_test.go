@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	serve := func(conf SecurityHeadersConfig) http.Header {
+		rec := httptest.NewRecorder()
+		SecurityHeaders(conf)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).
+			ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Header()
+	}
+
+	header := serve(SecurityHeadersConfig{
+		HSTSMaxAge:            365 * 24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+		FrameOptions:          "DENY",
+		ContentSecurityPolicy: "default-src 'none'",
+	})
+	require.Equal(t, "nosniff", header.Get("X-Content-Type-Options"))
+	require.Equal(t, "max-age=31536000; includeSubDomains", header.Get("Strict-Transport-Security"))
+	require.Equal(t, "DENY", header.Get("X-Frame-Options"))
+	require.Equal(t, "default-src 'none'", header.Get("Content-Security-Policy"))
+
+	header = serve(SecurityHeadersConfig{})
+	require.Equal(t, "nosniff", header.Get("X-Content-Type-Options"))
+	require.Empty(t, header.Get("Strict-Transport-Security"))
+	require.Empty(t, header.Get("X-Frame-Options"))
+	require.Empty(t, header.Get("Content-Security-Policy"))
+}
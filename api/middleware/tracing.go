@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"noteapp/api"
+)
+
+// requestIDAttribute is the span attribute of the request id.
+const requestIDAttribute = attribute.Key("http.request_id")
+
+// NewTracingMiddleware returns a tracing middleware with its name.
+func NewTracingMiddleware(tracer trace.Tracer, propagator propagation.TextMapPropagator) api.NamedMiddleware {
+	return api.NewNamedMiddleware("Tracing", Tracing(tracer, propagator))
+}
+
+// Tracing returns an http handler middleware which starts a server
+// span named after the route path template for each request. The span
+// continues the trace of the client when the propagator finds its
+// context in the request headers, e.g. the W3C "traceparent" header.
+func Tracing(tracer trace.Tracer, propagator propagation.TextMapPropagator) mux.MiddlewareFunc {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			route := routeTemplate(r)
+			attrs := semconv.HTTPServerAttributesFromHTTPRequest("", route, r)
+			if id := RequestIDFromContext(ctx); id != "" {
+				attrs = append(attrs, requestIDAttribute.String(id))
+			}
+
+			ctx, span := tracer.Start(ctx, route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(attrs...),
+			)
+			defer span.End()
+
+			rec := &statusRecorder{ResponseWriter: w}
+			h.ServeHTTP(rec, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(rec.Status())...)
+			span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(rec.Status()))
+		})
+	}
+}
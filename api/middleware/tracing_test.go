@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	var handlerSpan trace.SpanContext
+	router := mux.NewRouter()
+	router.Path("/note/{id}").Methods(http.MethodGet).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		if mux.Vars(r)["id"] == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	router.Use(RequestID, Tracing(provider.Tracer("test"), propagation.TraceContext{}))
+
+	t.Run("New trace", func(t *testing.T) {
+		exporter.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/note/1", nil)
+		req.Header.Set(RequestIDHeader, "request-1")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		span := spans[0]
+		require.Equal(t, "/note/{id}", span.Name)
+		require.Equal(t, trace.SpanKindServer, span.SpanKind)
+		require.False(t, span.Parent.IsValid())
+		require.Equal(t, span.SpanContext, handlerSpan)
+		require.Contains(t, span.Attributes, semconv.HTTPRouteKey.String("/note/{id}"))
+		require.Contains(t, span.Attributes, semconv.HTTPStatusCodeKey.Int(http.StatusOK))
+		require.Contains(t, span.Attributes, requestIDAttribute.String("request-1"))
+		require.Equal(t, codes.Unset, span.Status.Code)
+	})
+
+	t.Run("Propagated trace", func(t *testing.T) {
+		exporter.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/note/broken", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		span := spans[0]
+		require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String())
+		require.Equal(t, "00f067aa0ba902b7", span.Parent.SpanID().String())
+		require.True(t, span.Parent.IsRemote())
+		require.Equal(t, codes.Error, span.Status.Code)
+	})
+}
@@ -0,0 +1,54 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType is the media type of the RFC 7807 problem details.
+const ContentType = "application/problem+json"
+
+// TypePrefix is prepended to the error code to make
+// the problem type URI.
+const TypePrefix = "urn:noteapp:problem:"
+
+// FieldError is the error of a single field of the request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Problem is the RFC 7807 problem details of an error response.
+// The code is stable and meant to be handled by the clients, while
+// the title can be shown to the users.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Field     string       `json:"field,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	// Result is the outcome of the part of the request done before
+	// the error, e.g. the results of an interrupted import.
+	Result interface{} `json:"result,omitempty"`
+}
+
+// New returns a problem with its type derived from the code.
+func New(status int, code, title string) Problem {
+	return Problem{
+		Type:   TypePrefix + code,
+		Title:  title,
+		Status: status,
+		Code:   code,
+	}
+}
+
+// Write writes p as the response to w. The headers must be set
+// before calling Write.
+func Write(w http.ResponseWriter, p Problem) error {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	return json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"bufio"
+	"errors"
+	"github.com/gorilla/mux"
+	"net"
+	"net/http"
+	"noteapp/api"
+)
+
+// NewFailedAuthMiddleware returns a middleware limiting the failed
+// authentications with its name.
+func NewFailedAuthMiddleware(l *Limiter) api.NamedMiddleware {
+	return api.NewNamedMiddleware("FailedAuthRateLimit", FailedAuthMiddleware(l))
+}
+
+// FailedAuthMiddleware returns an http handler middleware which limits
+// the failed authentications of each ip address with the default limit
+// of l, so that the api keys and the tokens can't be guessed. Every 401
+// response takes a token, and the requests of an ip address without
+// tokens left are rejected with a 429 whatever their credentials.
+//
+// The middleware must come before the authentication.
+func FailedAuthMiddleware(l *Limiter) mux.MiddlewareFunc {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client := ipKey(r)
+			if res := l.Peek(client, ""); !res.Allowed {
+				writeTooManyRequests(w, r, res)
+				return
+			}
+
+			rec := &statusRecorder{ResponseWriter: w}
+			h.ServeHTTP(rec, r)
+
+			if rec.status == http.StatusUnauthorized {
+				l.Allow(client, "")
+			}
+		})
+	}
+}
+
+// statusRecorder is an http.ResponseWriter which keeps the status
+// code of the response. It passes the flushes and the hijacks to the
+// underlying writer for the event streams and the websockets.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *statusRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("ratelimit: response writer doesn't support hijacking")
+	}
+	return h.Hijack()
+}
@@ -0,0 +1,124 @@
+package ratelimit
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"strconv"
+)
+
+// UnaryServerInterceptor returns a gRPC interceptor which limits the
+// unary calls with l, the same way as the http middleware. The clients
+// share their limits across the http and the gRPC servers, and the
+// calls are limited by their full method name, e.g.
+// "/note.NoteService/Get".
+//
+// The interceptor must come after the authentication.
+func UnaryServerInterceptor(l *Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := allowGRPC(ctx, l, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor which limits the
+// streaming calls with l, the same way as the UnaryServerInterceptor.
+func StreamServerInterceptor(l *Limiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := allowGRPC(ss.Context(), l, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// FailedAuthUnaryServerInterceptor returns a gRPC interceptor which
+// limits the failed authentications of the unary calls of each ip
+// address with the default limit of l, the same way as the http
+// middleware. Every Unauthenticated error takes a token.
+//
+// The interceptor must come before the authentication.
+func FailedAuthUnaryServerInterceptor(l *Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		client := grpcIPKey(ctx)
+		if res := l.Peek(client, ""); !res.Allowed {
+			return nil, tooManyRequests(ctx, res)
+		}
+
+		resp, err := handler(ctx, req)
+		if status.Code(err) == codes.Unauthenticated {
+			l.Allow(client, "")
+		}
+		return resp, err
+	}
+}
+
+// FailedAuthStreamServerInterceptor returns a gRPC interceptor which
+// limits the failed authentications of the streaming calls, the same
+// way as the FailedAuthUnaryServerInterceptor.
+func FailedAuthStreamServerInterceptor(l *Limiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		client := grpcIPKey(ss.Context())
+		if res := l.Peek(client, ""); !res.Allowed {
+			return tooManyRequests(ss.Context(), res)
+		}
+
+		err := handler(srv, ss)
+		if status.Code(err) == codes.Unauthenticated {
+			l.Allow(client, "")
+		}
+		return err
+	}
+}
+
+// allowGRPC takes a token of the client of ctx for the method. The
+// limits are sent in the RateLimit-* headers like the http responses.
+func allowGRPC(ctx context.Context, l *Limiter, method string) error {
+	res := l.Allow(grpcClientKey(ctx), method)
+	if res.Limit > 0 {
+		_ = grpc.SetHeader(ctx, metadata.Pairs(
+			LimitHeader, strconv.Itoa(res.Limit),
+			RemainingHeader, strconv.Itoa(res.Remaining),
+			ResetHeader, ceilSeconds(res.Reset),
+		))
+	}
+
+	if !res.Allowed {
+		return tooManyRequests(ctx, res)
+	}
+	return nil
+}
+
+// tooManyRequests returns the error of a call over the limit of res.
+func tooManyRequests(ctx context.Context, res Result) error {
+	_ = grpc.SetHeader(ctx, metadata.Pairs("Retry-After", ceilSeconds(res.RetryAfter)))
+	return status.Error(codes.ResourceExhausted, "Too many requests")
+}
+
+// grpcClientKey returns the key of the client of ctx.
+func grpcClientKey(ctx context.Context) string {
+	if key, ok := principalKey(ctx); ok {
+		return key
+	}
+	return grpcIPKey(ctx)
+}
+
+// grpcIPKey returns the key of the ip address of the peer of ctx.
+func grpcIPKey(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "ip:"
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"noteapp/auth"
+	"testing"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	l := New(Config{Default: Limit{Rate: 1, Burst: 1}})
+	interceptor := UnaryServerInterceptor(l)
+	info := &grpc.UnaryServerInfo{FullMethod: "/note.NoteService/Get"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	call := func(ctx context.Context) error {
+		_, err := interceptor(ctx, nil, info, handler)
+		return err
+	}
+
+	alice := auth.WithPrincipal(peerContext("10.0.0.1:1234"), &auth.Principal{Subject: "alice", Method: auth.MethodAPIKey, KeyID: "ci"})
+	require.NoError(t, call(alice))
+	require.Equal(t, codes.ResourceExhausted, status.Code(call(alice)))
+
+	// The anonymous calls are limited by their ip address.
+	require.NoError(t, call(peerContext("10.0.0.1:5678")))
+	require.Equal(t, codes.ResourceExhausted, status.Code(call(peerContext("10.0.0.1:9012"))))
+	require.NoError(t, call(peerContext("10.0.0.2:1234")))
+}
+
+func TestFailedAuthUnaryServerInterceptor(t *testing.T) {
+	l := New(Config{Default: Limit{Rate: 1, Burst: 2}})
+	interceptor := FailedAuthUnaryServerInterceptor(l)
+	info := &grpc.UnaryServerInfo{FullMethod: "/note.NoteService/Get"}
+
+	var calls int
+	call := func(addr string, code codes.Code) error {
+		_, err := interceptor(peerContext(addr), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			calls++
+			return nil, status.Error(code, code.String())
+		})
+		return err
+	}
+
+	require.Equal(t, codes.Unauthenticated, status.Code(call("10.0.0.1:1234", codes.Unauthenticated)))
+	require.Equal(t, codes.Unauthenticated, status.Code(call("10.0.0.1:1234", codes.Unauthenticated)))
+	require.Equal(t, codes.ResourceExhausted, status.Code(call("10.0.0.1:1234", codes.OK)))
+	require.Equal(t, 2, calls)
+
+	// The other errors don't take a token.
+	require.Equal(t, codes.NotFound, status.Code(call("10.0.0.2:1234", codes.NotFound)))
+	require.Equal(t, codes.NotFound, status.Code(call("10.0.0.2:1234", codes.NotFound)))
+	require.Equal(t, codes.NotFound, status.Code(call("10.0.0.2:1234", codes.NotFound)))
+}
+
+func peerContext(addr string) context.Context {
+	tcpAddr, _ := net.ResolveTCPAddr("tcp", addr)
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: tcpAddr})
+}
@@ -0,0 +1,172 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the buckets which have refilled are
+// dropped, so that the clients which went away don't keep theirs.
+const sweepInterval = time.Minute
+
+// Limit is the limit of a token bucket.
+type Limit struct {
+	// Rate is the tokens added to the bucket per second. A zero
+	// rate doesn't limit the requests.
+	Rate float64
+	// Burst is the number of tokens the bucket holds, which is the
+	// number of requests allowed at once. It is at least one.
+	Burst int
+}
+
+// Config contains the limits of the limiter.
+type Config struct {
+	// Default is the limit of the routes without their own limit.
+	// The routes sharing it share the bucket of each client.
+	Default Limit
+	// Routes are the limits of the routes keyed by the method and
+	// the path template of the route, e.g. "POST /v1/note". Each of
+	// them has its own bucket per client.
+	Routes map[string]Limit
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	// Allowed reports whether the request is allowed.
+	Allowed bool
+	// Limit is the burst of the bucket, zero when the request is
+	// not limited.
+	Limit int
+	// Remaining is the number of the tokens left in the bucket.
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next token when the
+	// request is not allowed.
+	RetryAfter time.Duration
+}
+
+type bucketKey struct {
+	client string
+	route  string
+}
+
+type bucket struct {
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens accumulated since the last time.
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+		b.last = now
+	}
+}
+
+// Limiter limits the requests of each client with token buckets.
+type Limiter struct {
+	conf Config
+	now  func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*bucket
+	lastSweep time.Time
+}
+
+// New returns a limiter with the limits of conf.
+func New(conf Config) *Limiter {
+	conf.Default = checkLimit(conf.Default)
+	routes := make(map[string]Limit, len(conf.Routes))
+	for route, limit := range conf.Routes {
+		routes[route] = checkLimit(limit)
+	}
+	conf.Routes = routes
+
+	return &Limiter{
+		conf:    conf,
+		now:     time.Now,
+		buckets: make(map[bucketKey]*bucket),
+	}
+}
+
+func checkLimit(l Limit) Limit {
+	if l.Burst < 1 {
+		l.Burst = 1
+	}
+	return l
+}
+
+// Allow takes a token from the bucket of the client for the route,
+// the method and the path template of the route, and reports whether
+// the request is allowed.
+func (l *Limiter) Allow(client, route string) Result {
+	return l.take(client, route, 1)
+}
+
+// Peek is like the Allow but it doesn't take the token, it only
+// reports whether the bucket has one.
+func (l *Limiter) Peek(client, route string) Result {
+	return l.take(client, route, 0)
+}
+
+// take takes n tokens, zero or one, when the bucket has one.
+func (l *Limiter) take(client, route string, n float64) Result {
+	limit, ok := l.conf.Routes[route]
+	if !ok {
+		limit, route = l.conf.Default, ""
+	}
+
+	if limit.Rate <= 0 {
+		return Result{Allowed: true}
+	}
+
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	key := bucketKey{client: client, route: route}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limit: limit, tokens: float64(limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.refill(now)
+
+	res := Result{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens -= n
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / limit.Rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((float64(limit.Burst) - b.tokens) / limit.Rate)
+	return res
+}
+
+// sweep drops the buckets which have refilled, they are the same as
+// new ones. The caller must hold the lock.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
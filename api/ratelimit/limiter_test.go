@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := New(Config{
+		Default: Limit{Rate: 1, Burst: 2},
+		Routes: map[string]Limit{
+			"POST /v1/note": {Rate: 0.5},
+			"GET /metrics":  {},
+		},
+	})
+	l.now = func() time.Time { return now }
+
+	t.Run("The burst is allowed at once", func(t *testing.T) {
+		require.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second}, l.Allow("alice", "GET /v1/notes"))
+		require.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 2 * time.Second}, l.Allow("alice", "GET /v1/note/{id}"))
+
+		res := l.Allow("alice", "GET /v1/notes")
+		require.False(t, res.Allowed)
+		require.Equal(t, time.Second, res.RetryAfter)
+	})
+
+	t.Run("The tokens are added at the rate", func(t *testing.T) {
+		now = now.Add(500 * time.Millisecond)
+		res := l.Allow("alice", "GET /v1/notes")
+		require.False(t, res.Allowed)
+		require.Equal(t, 500*time.Millisecond, res.RetryAfter)
+
+		now = now.Add(500 * time.Millisecond)
+		require.True(t, l.Allow("alice", "GET /v1/notes").Allowed)
+	})
+
+	t.Run("The clients have their own buckets", func(t *testing.T) {
+		require.True(t, l.Allow("bob", "GET /v1/notes").Allowed)
+	})
+
+	t.Run("Peeking doesn't take a token", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			require.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 2}, l.Peek("dave", "GET /v1/notes"))
+		}
+	})
+
+	t.Run("The routes with a limit have their own buckets", func(t *testing.T) {
+		res := l.Allow("alice", "POST /v1/note")
+		require.Equal(t, Result{Allowed: true, Limit: 1, Remaining: 0, Reset: 2 * time.Second}, res)
+
+		res = l.Allow("alice", "POST /v1/note")
+		require.False(t, res.Allowed)
+		require.Equal(t, 2*time.Second, res.RetryAfter)
+	})
+
+	t.Run("The routes with a zero rate are not limited", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			require.Equal(t, Result{Allowed: true}, l.Allow("alice", "GET /metrics"))
+		}
+	})
+
+	t.Run("The refilled buckets are dropped", func(t *testing.T) {
+		now = now.Add(sweepInterval)
+		l.Allow("carol", "GET /v1/notes")
+		require.Len(t, l.buckets, 1)
+	})
+}
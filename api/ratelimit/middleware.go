@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/gorilla/mux"
+	"math"
+	"net"
+	"net/http"
+	"noteapp/api"
+	"noteapp/api/middleware"
+	"noteapp/api/problem"
+	"noteapp/auth"
+	"strconv"
+	"time"
+)
+
+// The headers of the limits, see the IETF draft "RateLimit Header
+// Fields for HTTP".
+const (
+	LimitHeader     = "RateLimit-Limit"
+	RemainingHeader = "RateLimit-Remaining"
+	ResetHeader     = "RateLimit-Reset"
+)
+
+// NewMiddleware returns a rate limiting middleware with its name.
+func NewMiddleware(l *Limiter) api.NamedMiddleware {
+	return api.NewNamedMiddleware("RateLimit", Middleware(l))
+}
+
+// Middleware returns an http handler middleware which limits the
+// requests with l. The clients are told their limit in the RateLimit-*
+// headers, and the requests over it are rejected with a 429 and the
+// Retry-After header.
+//
+// The authenticated requests are limited by their api key, or their
+// user when they don't have one, so the middleware must come after the
+// authentication. The other requests are limited by their ip address.
+func Middleware(l *Limiter) mux.MiddlewareFunc {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res := l.Allow(clientKey(r), routeKey(r))
+			if res.Limit > 0 {
+				w.Header().Set(LimitHeader, strconv.Itoa(res.Limit))
+				w.Header().Set(RemainingHeader, strconv.Itoa(res.Remaining))
+				w.Header().Set(ResetHeader, ceilSeconds(res.Reset))
+			}
+
+			if !res.Allowed {
+				writeTooManyRequests(w, r, res)
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeTooManyRequests rejects r, which is over the limit of res.
+func writeTooManyRequests(w http.ResponseWriter, r *http.Request, res Result) {
+	w.Header().Set("Retry-After", ceilSeconds(res.RetryAfter))
+
+	p := problem.New(http.StatusTooManyRequests, "rate_limited", "Too many requests")
+	p.RequestID = middleware.RequestIDFromContext(r.Context())
+	_ = problem.Write(w, p)
+}
+
+// clientKey returns the key of the client of r.
+func clientKey(r *http.Request) string {
+	if key, ok := principalKey(r.Context()); ok {
+		return key
+	}
+	return ipKey(r)
+}
+
+// principalKey returns the key of the api key, or the user, which
+// has authenticated ctx. It returns false when ctx is anonymous.
+func principalKey(ctx context.Context) (string, bool) {
+	p, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	if p.Method == auth.MethodAPIKey {
+		return "key:" + p.KeyID, true
+	}
+	return "user:" + p.Subject, true
+}
+
+// ipKey returns the key of the ip address of r.
+func ipKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// routeKey returns the method and the path template of the route of r.
+func routeKey(r *http.Request) string {
+	path := r.URL.Path
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			path = tpl
+		}
+	}
+	return r.Method + " " + path
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"noteapp/api/problem"
+	"noteapp/auth"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	l := New(Config{
+		Default: Limit{Rate: 1, Burst: 1},
+		Routes:  map[string]Limit{"POST /note/{id}": {Rate: 1, Burst: 2}},
+	})
+
+	router := mux.NewRouter()
+	router.Path("/note/{id}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	router.Use(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key := r.Header.Get(auth.APIKeyHeader); key != "" {
+				r = r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{Subject: "alice", Method: auth.MethodAPIKey, KeyID: key}))
+			}
+			h.ServeHTTP(w, r)
+		})
+	}, Middleware(l))
+
+	serve := func(method, path, key, remoteAddr string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = remoteAddr
+		if key != "" {
+			req.Header.Set(auth.APIKeyHeader, key)
+		}
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Allowed request", func(t *testing.T) {
+		rec := serve(http.MethodGet, "/note/1", "", "10.0.0.1:1234")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "1", rec.Header().Get(LimitHeader))
+		require.Equal(t, "0", rec.Header().Get(RemainingHeader))
+		require.Equal(t, "1", rec.Header().Get(ResetHeader))
+	})
+
+	t.Run("Request over the limit", func(t *testing.T) {
+		rec := serve(http.MethodGet, "/note/2", "", "10.0.0.1:5678")
+		require.Equal(t, http.StatusTooManyRequests, rec.Code)
+		require.Equal(t, "1", rec.Header().Get("Retry-After"))
+		require.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+
+		var p problem.Problem
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&p))
+		require.Equal(t, "rate_limited", p.Code)
+	})
+
+	t.Run("Route with its own limit", func(t *testing.T) {
+		rec := serve(http.MethodPost, "/note/1", "", "10.0.0.1:1234")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "2", rec.Header().Get(LimitHeader))
+	})
+
+	t.Run("The api keys are limited on their own", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, serve(http.MethodGet, "/note/1", "key", "10.0.0.1:1234").Code)
+		require.Equal(t, http.StatusOK, serve(http.MethodGet, "/note/1", "other", "10.0.0.1:1234").Code)
+		require.Equal(t, http.StatusTooManyRequests, serve(http.MethodGet, "/note/1", "key", "10.0.0.2:1234").Code)
+	})
+}
+
+func TestFailedAuthMiddleware(t *testing.T) {
+	l := New(Config{Default: Limit{Rate: 0.001, Burst: 2}})
+	h := FailedAuthMiddleware(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(auth.APIKeyHeader) != "valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+
+	serve := func(key, remoteAddr string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/note/1", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(auth.APIKeyHeader, key)
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("The authenticated requests are not limited", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			require.Equal(t, http.StatusOK, serve("valid", "10.0.0.1:1234"))
+		}
+	})
+
+	t.Run("The failed authentications are limited by ip address", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, serve("guess", "10.0.0.1:1234"))
+		require.Equal(t, http.StatusUnauthorized, serve("guess", "10.0.0.1:5678"))
+		require.Equal(t, http.StatusTooManyRequests, serve("guess", "10.0.0.1:1234"))
+		require.Equal(t, http.StatusTooManyRequests, serve("valid", "10.0.0.1:1234"))
+		require.Equal(t, http.StatusUnauthorized, serve("guess", "10.0.0.2:1234"))
+	})
+}
@@ -0,0 +1,22 @@
+package health
+
+import (
+	"context"
+	"fmt"
+)
+
+// DiskFree returns a checker which fails when the free space of the
+// file system of the path is less than min bytes.
+func DiskFree(path string, min uint64) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		free, err := freeBytes(path)
+		if err != nil {
+			return err
+		}
+
+		if free < min {
+			return fmt.Errorf("health: %d bytes free on %q, want at least %d", free, path, min)
+		}
+		return nil
+	})
+}
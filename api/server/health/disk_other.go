@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package health
+
+import "errors"
+
+func freeBytes(string) (uint64, error) {
+	return 0, errors.New("health: disk free is not supported on this platform")
+}
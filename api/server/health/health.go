@@ -0,0 +1,163 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrShuttingDown is the error of the readiness after the
+// server started shutting down.
+var ErrShuttingDown = errors.New("health: the server is shutting down")
+
+// shutdownCheck is the name of the check failing while the
+// server shuts down.
+const shutdownCheck = "shutdown"
+
+// Status is the status of a check or of the whole server.
+type Status string
+
+const (
+	// StatusOK is the status when the check passed.
+	StatusOK Status = "ok"
+	// StatusFail is the status when the check failed.
+	StatusFail Status = "fail"
+)
+
+// Checker checks whether a dependency of the server works.
+type Checker interface {
+	// Check returns an error when the dependency doesn't work. It
+	// should give up when ctx is done.
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc is an adapter to use a function as a Checker.
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx).
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// New takes the timeout of each check and returns a health
+// without checks.
+func New(timeout time.Duration) *Health {
+	return &Health{timeout: timeout}
+}
+
+// Health keeps the readiness checks of the server.
+type Health struct {
+	timeout time.Duration
+
+	mu       sync.RWMutex
+	checks   []namedChecker
+	draining bool
+}
+
+type namedChecker struct {
+	name    string
+	checker Checker
+}
+
+// Add adds a readiness check with a name. The name is the key
+// of the check in the report so it should be unique.
+func (h *Health) Add(name string, checker Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, namedChecker{name: name, checker: checker})
+}
+
+// Drain makes the readiness fail from now on, so that the load
+// balancers stop sending new requests before the server shuts down.
+func (h *Health) Drain() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.draining = true
+}
+
+// Report is the result of the readiness checks.
+type Report struct {
+	// Status is ok when all the checks passed.
+	Status Status `json:"status"`
+	// Checks are the results of the checks by their name.
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Result is the result of a single check.
+type Result struct {
+	Status   Status `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Check runs all the checks concurrently and returns their report.
+// A check which doesn't return within the timeout fails. None of the
+// checks run after the health is drained.
+func (h *Health) Check(ctx context.Context) Report {
+	h.mu.RLock()
+	checks := append([]namedChecker(nil), h.checks...)
+	draining := h.draining
+	h.mu.RUnlock()
+
+	if draining {
+		return Report{
+			Status: StatusFail,
+			Checks: map[string]Result{
+				shutdownCheck: {Status: StatusFail, Error: ErrShuttingDown.Error(), Duration: "0s"},
+			},
+		}
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		report = Report{Status: StatusOK}
+	)
+
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c namedChecker) {
+			defer wg.Done()
+			result := h.run(ctx, c.checker)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if report.Checks == nil {
+				report.Checks = make(map[string]Result, len(checks))
+			}
+			report.Checks[c.name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusFail
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (h *Health) run(ctx context.Context, checker Checker) Result {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	begin := time.Now()
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errChan:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := Result{Status: StatusOK, Duration: time.Since(begin).String()}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
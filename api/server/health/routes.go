@@ -0,0 +1,88 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"net/http"
+	"noteapp/api"
+	nhttp "noteapp/pkg/http"
+)
+
+const (
+	// LivenessPath is the path of the liveness endpoint.
+	LivenessPath = "/healthz"
+	// ReadinessPath is the path of the readiness endpoint.
+	ReadinessPath = "/readyz"
+)
+
+// Routes takes a health and returns the liveness and
+// the readiness routes.
+func Routes(h *Health) []api.Route {
+	return getRoutes(h)
+}
+
+func getRoutes(h *Health) (routes []api.Route) {
+
+	livenessHandler := httptransport.NewServer(
+		makeLivenessEndpoint(),
+		decodeRequest,
+		encodeResponse,
+	)
+
+	readinessHandler := httptransport.NewServer(
+		makeReadinessEndpoint(h),
+		decodeRequest,
+		encodeResponse,
+	)
+
+	routes = append(routes,
+		&nhttp.Route{
+			HandlerValue: livenessHandler,
+			MethodValue:  http.MethodGet,
+			PathValue:    LivenessPath,
+		},
+		&nhttp.Route{
+			HandlerValue: readinessHandler,
+			MethodValue:  http.MethodGet,
+			PathValue:    ReadinessPath,
+		},
+	)
+
+	return
+}
+
+func decodeRequest(context.Context, *http.Request) (request interface{}, err error) {
+	return nil, nil
+}
+
+// encodeResponse encodes the report with the 503 status
+// code when it is failed.
+func encodeResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+	report := response.(Report)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != StatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// makeLivenessEndpoint returns an endpoint which is always ok,
+// the server is alive as long as it responds.
+func makeLivenessEndpoint() endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		return Report{Status: StatusOK}, nil
+	}
+}
+
+func makeReadinessEndpoint(h *Health) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		return h.Check(ctx), nil
+	}
+}
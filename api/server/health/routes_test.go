@@ -0,0 +1,114 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/suite"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+type TestSuite struct {
+	suite.Suite
+	health *Health
+	router *mux.Router
+}
+
+func (t *TestSuite) SetupTest() {
+	t.health = New(50 * time.Millisecond)
+	router := mux.NewRouter()
+	for _, route := range Routes(t.health) {
+		router.Path(route.Path()).Methods(route.Method()).Handler(route.Handler())
+	}
+	t.router = router
+}
+
+func (t *TestSuite) get(path string) (int, Report) {
+	rec := httptest.NewRecorder()
+	t.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	t.Equal("application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	var got Report
+	t.Require().NoError(json.NewDecoder(rec.Body).Decode(&got))
+	return rec.Code, got
+}
+
+func (t *TestSuite) TestLiveness() {
+	t.health.Add("failing", CheckerFunc(func(context.Context) error { return errors.New("down") }))
+
+	code, got := t.get(LivenessPath)
+	t.Equal(http.StatusOK, code)
+	t.Equal(Report{Status: StatusOK}, got)
+}
+
+func (t *TestSuite) TestReadiness() {
+	t.Run("Without checks", func() {
+		code, got := t.get(ReadinessPath)
+		t.Equal(http.StatusOK, code)
+		t.Equal(StatusOK, got.Status)
+	})
+
+	t.Run("Passing checks", func() {
+		t.health.Add("store", CheckerFunc(func(context.Context) error { return nil }))
+		t.health.Add("disk", DiskFree(t.T().TempDir(), 0))
+
+		code, got := t.get(ReadinessPath)
+		t.Equal(http.StatusOK, code)
+		t.Equal(StatusOK, got.Status)
+		t.Require().Len(got.Checks, 2)
+		t.Equal(StatusOK, got.Checks["store"].Status)
+		t.Equal(StatusOK, got.Checks["disk"].Status)
+		t.NotEmpty(got.Checks["store"].Duration)
+	})
+
+	t.Run("Failing check", func() {
+		t.health.Add("file_store", CheckerFunc(func(context.Context) error { return errors.New("corrupted file") }))
+
+		code, got := t.get(ReadinessPath)
+		t.Equal(http.StatusServiceUnavailable, code)
+		t.Equal(StatusFail, got.Status)
+		t.Equal(StatusOK, got.Checks["store"].Status)
+		t.Equal(Result{Status: StatusFail, Error: "corrupted file", Duration: got.Checks["file_store"].Duration}, got.Checks["file_store"])
+	})
+}
+
+func (t *TestSuite) TestDrain() {
+	t.health.Add("store", CheckerFunc(func(context.Context) error { return nil }))
+	t.health.Drain()
+
+	code, got := t.get(ReadinessPath)
+	t.Equal(http.StatusServiceUnavailable, code)
+	t.Equal(StatusFail, got.Status)
+	t.Equal(ErrShuttingDown.Error(), got.Checks["shutdown"].Error)
+	t.NotContains(got.Checks, "store")
+
+	code, _ = t.get(LivenessPath)
+	t.Equal(http.StatusOK, code)
+}
+
+func (t *TestSuite) TestCheckTimeout() {
+	t.health.Add("slow", CheckerFunc(func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}))
+
+	got := t.health.Check(context.TODO())
+	t.Equal(StatusFail, got.Status)
+	t.Equal(context.DeadlineExceeded.Error(), got.Checks["slow"].Error)
+}
+
+func (t *TestSuite) TestDiskFree() {
+	dir := t.T().TempDir()
+	t.NoError(DiskFree(dir, 1).Check(context.TODO()))
+	t.Error(DiskFree(dir, math.MaxUint64).Check(context.TODO()))
+	t.Error(DiskFree("/does/not/exist", 0).Check(context.TODO()))
+}
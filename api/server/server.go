@@ -1,22 +1,25 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
+	"net"
 	"net/http"
 	"noteapp/api"
 	"os"
+	"strings"
+	"sync"
 	"text/tabwriter"
 )
 
-// TODO: Add a helth check endoint
-
 // New takes config for all the arguments that the server needs and
 // return a server instance.
 func New(conf *Config) *Server {
 	server := &Server{
 		Port:        conf.Port,
+		TLS:         conf.TLS,
 		Middlewares: conf.Middlewares,
 		HTTPRoutes:  conf.HTTPRoutes,
 	}
@@ -27,6 +30,9 @@ func New(conf *Config) *Server {
 type Config struct {
 	// Port is the port of the server
 	Port int
+	// TLS is the TLS configuration of the server. The server
+	// serves plain HTTP when it is nil.
+	TLS *TLSConfig
 	// Middlewares are the middlewares to be apply to the
 	// handler.
 	Middlewares []api.NamedMiddleware
@@ -37,10 +43,12 @@ type Config struct {
 // Server is the wrapper for all the bootstrapping of a typical server.
 type Server struct {
 	Port        int
+	TLS         *TLSConfig
 	Middlewares []api.NamedMiddleware
 	server      *http.Server
 	HTTPRoutes  []api.Route
-	isInited    bool
+	once        sync.Once
+	initErr     error
 }
 
 func (s *Server) init() {
@@ -52,6 +60,10 @@ func (s *Server) init() {
 		router.Path(routes.Path()).Methods(routes.Method()).Handler(routes.Handler())
 	}
 
+	// The OPTIONS requests, such as the CORS preflight requests, match
+	// a route so that they go through the middlewares.
+	router.Methods(http.MethodOptions).Handler(s.optionsHandler(router))
+
 	for _, mw := range s.Middlewares {
 		router.Use(mw.Middleware)
 	}
@@ -61,7 +73,40 @@ func (s *Server) init() {
 		Handler: router,
 	}
 
-	s.isInited = true
+	if s.TLS != nil {
+		s.server.TLSConfig, s.initErr = s.TLS.Config()
+	}
+}
+
+// optionsHandler answers the OPTIONS requests with the methods of
+// the routes matching their path, or not found when there is none.
+func (s *Server) optionsHandler(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		seen := make(map[string]bool)
+		for _, route := range s.HTTPRoutes {
+			method := route.Method()
+			if method == http.MethodOptions || seen[method] {
+				continue
+			}
+
+			req := *r
+			req.Method = method
+			var match mux.RouteMatch
+			if router.Match(&req, &match) && match.MatchErr == nil {
+				allowed = append(allowed, method)
+				seen[method] = true
+			}
+		}
+
+		if len(allowed) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Allow", strings.Join(append(allowed, http.MethodOptions), ", "))
+		w.WriteHeader(http.StatusNoContent)
+	})
 }
 
 func (s *Server) printInfo() {
@@ -84,18 +129,59 @@ func (s *Server) printInfo() {
 	writeToConsole("\n")
 }
 
-// ListenAndServe serves clients request by the server.
+// ListenAndServe serves clients request by the server, over TLS when
+// it is configured. It returns http.ErrServerClosed after the server
+// is shut down or closed.
 func (s *Server) ListenAndServe() error {
-	if !s.isInited {
-		s.init()
+	s.once.Do(s.init)
+	if s.initErr != nil {
+		return s.initErr
+	}
+
+	if s.server.TLSConfig != nil {
+		logrus.Infof("API listen on %s with TLS\n", s.server.Addr)
+		return s.server.ListenAndServeTLS("", "")
 	}
 
 	logrus.Infof("API listen on %s\n", s.server.Addr)
 	return s.server.ListenAndServe()
 }
 
-// Close closes the underlying server.
+// Serve serves clients request by the server on the listener l, over
+// TLS when it is configured. It returns http.ErrServerClosed after the
+// server is shut down or closed.
+func (s *Server) Serve(l net.Listener) error {
+	s.once.Do(s.init)
+	if s.initErr != nil {
+		return s.initErr
+	}
+
+	if s.server.TLSConfig != nil {
+		logrus.Infof("API listen on %s with TLS\n", l.Addr())
+		return s.server.ServeTLS(l, "", "")
+	}
+
+	logrus.Infof("API listen on %s\n", l.Addr())
+	return s.server.Serve(l)
+}
+
+// Shutdown stops the server from accepting new connections and waits
+// for the in-flight requests to finish. When ctx is done before they
+// finish, it closes the remaining connections and returns the ctx error.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.once.Do(s.init)
+
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		s.Close()
+	}
+	return err
+}
+
+// Close closes the underlying server and drops the active connections.
 func (s *Server) Close() {
+	s.once.Do(s.init)
+
 	if err := s.server.Close(); err != nil {
 		logrus.Error(err)
 	}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"net"
+	"net/http"
+	nhttp "noteapp/pkg/http"
+	"testing"
+	"time"
+)
+
+func Test(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+type TestSuite struct {
+	suite.Suite
+	srv      *Server
+	url      string
+	started  chan struct{}
+	release  chan struct{}
+	serveErr chan error
+}
+
+func (s *TestSuite) SetupTest() {
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	s.started, s.release = started, release
+
+	srv := New(&Config{})
+	srv.AddRoutes(&nhttp.Route{
+		HandlerValue: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			started <- struct{}{}
+			<-release
+			_, _ = io.WriteString(w, "done")
+		}),
+		MethodValue: http.MethodGet,
+		PathValue:   "/slow",
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	s.url = "http://" + l.Addr().String() + "/slow"
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.Serve(l) }()
+	s.srv, s.serveErr = srv, serveErr
+}
+
+// get starts a request to the slow route and waits until
+// the server is handling it.
+func (s *TestSuite) get() <-chan error {
+	errChan := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(s.url)
+		if err == nil {
+			_, err = io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+		}
+		errChan <- err
+	}()
+	<-s.started
+	return errChan
+}
+
+func (s *TestSuite) TestShutdown() {
+	reqErr := s.get()
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- s.srv.Shutdown(context.Background()) }()
+
+	select {
+	case err := <-shutdownErr:
+		s.FailNow("shutdown returned before the request finished", "err: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(s.release)
+	s.NoError(<-reqErr)
+	s.NoError(<-shutdownErr)
+	s.True(errors.Is(<-s.serveErr, http.ErrServerClosed))
+}
+
+func (s *TestSuite) TestShutdownAfterDrainTimeout() {
+	defer close(s.release)
+	reqErr := s.get()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	s.ErrorIs(s.srv.Shutdown(ctx), context.DeadlineExceeded)
+	s.Error(<-reqErr)
+}
+
+func (s *TestSuite) TestOptions() {
+	options := func(url string) *http.Response {
+		req, err := http.NewRequest(http.MethodOptions, url, nil)
+		s.Require().NoError(err)
+		resp, err := http.DefaultClient.Do(req)
+		s.Require().NoError(err)
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	resp := options(s.url)
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+	s.Equal("GET, OPTIONS", resp.Header.Get("Allow"))
+
+	resp = options(s.url + "/unknown")
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"os"
+	"sync"
+	"time"
+)
+
+// reloadCheckInterval is how often the certificate files are checked
+// for changes, at most once per interval on the new connections.
+const reloadCheckInterval = 10 * time.Second
+
+// TLSConfig contains the TLS configuration of the server.
+type TLSConfig struct {
+	// CertFile is the path of the PEM encoded certificate chain.
+	CertFile string
+	// KeyFile is the path of the PEM encoded private key.
+	KeyFile string
+	// MinVersion is the minimum TLS version, e.g. tls.VersionTLS13.
+	// TLS 1.2 is the minimum when it is zero.
+	MinVersion uint16
+	// ClientCAFile is the path of the PEM encoded certificates of the
+	// authorities verifying the client certificates. The clients are
+	// not asked for a certificate when it is empty.
+	ClientCAFile string
+	// RequireClientCert rejects the connections without a verified
+	// client certificate. Otherwise the certificate is verified only
+	// when the client sends one.
+	RequireClientCert bool
+}
+
+// ParseTLSVersion parses a TLS version such as "1.2". An empty
+// version is zero.
+func ParseTLSVersion(s string) (uint16, error) {
+	switch s {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("server: unknown tls version '%s'", s)
+}
+
+// Config returns the tls.Config of c. The certificate is reloaded
+// when its files change so that it can be renewed without a restart.
+// The other servers listening with the same certificate, such as the
+// gRPC server, use it.
+func (c *TLSConfig) Config() (*tls.Config, error) {
+	reloader, err := newCertReloader(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := &tls.Config{
+		MinVersion:     c.MinVersion,
+		GetCertificate: reloader.GetCertificate,
+	}
+	if conf.MinVersion == 0 {
+		conf.MinVersion = tls.VersionTLS12
+	}
+
+	if c.ClientCAFile == "" {
+		return conf, nil
+	}
+
+	b, err := os.ReadFile(c.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.ClientCAs = x509.NewCertPool()
+	if !conf.ClientCAs.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("server: no certificate found in '%s'", c.ClientCAFile)
+	}
+
+	conf.ClientAuth = tls.VerifyClientCertIfGiven
+	if c.RequireClientCert {
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return conf, nil
+}
+
+// certReloader keeps the certificate of a pair of files, and loads it
+// again when the modification time of one of them changes.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	now      func() time.Time
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	modTimes [2]time.Time
+	checked  time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("server: tls cert file and key file must not be empty")
+	}
+
+	r := &certReloader{certFile: certFile, keyFile: keyFile, now: time.Now}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate implements the tls.Config GetCertificate. A failed
+// reload keeps the previous certificate.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := r.now(); now.Sub(r.checked) >= reloadCheckInterval {
+		r.checked = now
+		if err := r.reload(); err != nil {
+			logrus.WithError(err).Error("server: unable to reload the tls certificate")
+		}
+	}
+	return r.cert, nil
+}
+
+// reload loads the certificate when its files have changed. The
+// caller must hold the lock, except the first time.
+func (r *certReloader) reload() error {
+	var modTimes [2]time.Time
+	for i, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		modTimes[i] = info.ModTime()
+	}
+
+	if r.cert != nil && modTimes[0].Equal(r.modTimes[0]) && modTimes[1].Equal(r.modTimes[1]) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	if r.cert != nil {
+		logrus.Info("server: tls certificate reloaded")
+	}
+	r.cert, r.modTimes = &cert, modTimes
+	return nil
+}
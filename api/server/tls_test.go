@@ -0,0 +1,247 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	nhttp "noteapp/pkg/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTLS(t *testing.T) {
+	suite.Run(t, new(TLSTestSuite))
+}
+
+type TLSTestSuite struct {
+	suite.Suite
+	dir    string
+	ca     *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	caPool *x509.CertPool
+}
+
+func (s *TLSTestSuite) SetupTest() {
+	s.dir = s.T().TempDir()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "noteapp test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	s.Require().NoError(err)
+
+	s.ca, err = x509.ParseCertificate(der)
+	s.Require().NoError(err)
+	s.caKey = key
+	s.caPool = x509.NewCertPool()
+	s.caPool.AddCert(s.ca)
+	s.writePEM("ca.pem", "CERTIFICATE", der)
+}
+
+func (s *TLSTestSuite) writePEM(name, typ string, der []byte) string {
+	path := filepath.Join(s.dir, name)
+	s.Require().NoError(os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600))
+	return path
+}
+
+// issue writes a certificate of the common name signed by the
+// test authority, and returns the paths of its files.
+func (s *TLSTestSuite) issue(name string, serial int64, usage x509.ExtKeyUsage) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, s.ca, &key.PublicKey, s.caKey)
+	s.Require().NoError(err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	s.Require().NoError(err)
+	return s.writePEM(name+".pem", "CERTIFICATE", der), s.writePEM(name+"-key.pem", "PRIVATE KEY", keyDER)
+}
+
+// serve starts a server with the TLS configuration and returns the url
+// of its route answering the common name of the client certificate.
+func (s *TLSTestSuite) serve(conf *TLSConfig) string {
+	srv := New(&Config{TLS: conf})
+	srv.AddRoutes(&nhttp.Route{
+		HandlerValue: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(r.TLS.VerifiedChains) > 0 {
+				_, _ = io.WriteString(w, r.TLS.VerifiedChains[0][0].Subject.CommonName)
+			}
+		}),
+		MethodValue: http.MethodGet,
+		PathValue:   "/whoami",
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	go func() { _ = srv.Serve(l) }()
+	s.T().Cleanup(srv.Close)
+
+	return "https://" + l.Addr().String() + "/whoami"
+}
+
+func (s *TLSTestSuite) get(url string, certs ...tls.Certificate) (string, error) {
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      s.caPool,
+		Certificates: certs,
+	}}}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	b, err := io.ReadAll(resp.Body)
+	return string(b), err
+}
+
+func (s *TLSTestSuite) clientCert() tls.Certificate {
+	certFile, keyFile := s.issue("alice", 3, x509.ExtKeyUsageClientAuth)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	s.Require().NoError(err)
+	return cert
+}
+
+func (s *TLSTestSuite) TestServe() {
+	certFile, keyFile := s.issue("server", 2, x509.ExtKeyUsageServerAuth)
+	url := s.serve(&TLSConfig{CertFile: certFile, KeyFile: keyFile})
+
+	got, err := s.get(url)
+	s.Require().NoError(err)
+	s.Empty(got)
+
+	got, err = s.get(url, s.clientCert())
+	s.Require().NoError(err)
+	s.Empty(got, "the client certificate is not asked without a client ca")
+}
+
+func (s *TLSTestSuite) TestMutualTLS() {
+	certFile, keyFile := s.issue("server", 2, x509.ExtKeyUsageServerAuth)
+	conf := &TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: filepath.Join(s.dir, "ca.pem")}
+
+	s.Run("Optional client certificate", func() {
+		url := s.serve(conf)
+
+		got, err := s.get(url)
+		s.Require().NoError(err)
+		s.Empty(got)
+
+		got, err = s.get(url, s.clientCert())
+		s.Require().NoError(err)
+		s.Equal("alice", got)
+	})
+
+	s.Run("Required client certificate", func() {
+		required := *conf
+		required.RequireClientCert = true
+		url := s.serve(&required)
+
+		_, err := s.get(url)
+		s.Error(err)
+
+		got, err := s.get(url, s.clientCert())
+		s.Require().NoError(err)
+		s.Equal("alice", got)
+	})
+}
+
+func (s *TLSTestSuite) TestInvalidConfig() {
+	certFile, keyFile := s.issue("server", 2, x509.ExtKeyUsageServerAuth)
+
+	tests := []struct {
+		name string
+		conf *TLSConfig
+	}{
+		{name: "Missing key file", conf: &TLSConfig{CertFile: certFile}},
+		{name: "Unknown cert file", conf: &TLSConfig{CertFile: filepath.Join(s.dir, "unknown.pem"), KeyFile: keyFile}},
+		{name: "Client ca without certificate", conf: &TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile}},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			s.Require().NoError(err)
+			defer func() { _ = l.Close() }()
+
+			s.Error(New(&Config{TLS: tt.conf}).Serve(l))
+		})
+	}
+}
+
+func (s *TLSTestSuite) TestCertReload() {
+	certFile, keyFile := s.issue("server", 2, x509.ExtKeyUsageServerAuth)
+	r, err := newCertReloader(certFile, keyFile)
+	s.Require().NoError(err)
+
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	serial := func() int64 {
+		cert, err := r.GetCertificate(nil)
+		s.Require().NoError(err)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		s.Require().NoError(err)
+		return leaf.SerialNumber.Int64()
+	}
+	s.Equal(int64(2), serial())
+
+	// The files are replaced with a later modification time
+	// so that the change doesn't depend on the file system.
+	s.issue("server", 4, x509.ExtKeyUsageServerAuth)
+	later := time.Now().Add(time.Minute)
+	s.Require().NoError(os.Chtimes(certFile, later, later))
+	s.Require().NoError(os.Chtimes(keyFile, later, later))
+	s.Equal(int64(2), serial(), "the files are checked once per interval")
+
+	now = now.Add(reloadCheckInterval)
+	s.Equal(int64(4), serial())
+
+	s.Require().NoError(os.WriteFile(keyFile, []byte("invalid"), 0600))
+	s.Require().NoError(os.Chtimes(keyFile, later.Add(time.Minute), later.Add(time.Minute)))
+	now = now.Add(reloadCheckInterval)
+	s.Equal(int64(4), serial(), "a failed reload keeps the previous certificate")
+}
+
+func (s *TLSTestSuite) TestParseTLSVersion() {
+	v, err := ParseTLSVersion("1.3")
+	s.Require().NoError(err)
+	s.Equal(uint16(tls.VersionTLS13), v)
+
+	v, err = ParseTLSVersion("")
+	s.Require().NoError(err)
+	s.Zero(v)
+
+	_, err = ParseTLSVersion("3")
+	s.Error(err)
+}
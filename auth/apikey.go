@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// apiKeyPrefix is the prefix of the minted api keys that makes
+	// them easy to spot in logs and secret scanners.
+	apiKeyPrefix = "nak_"
+	// apiKeySize is the number of random bytes of an api key.
+	apiKeySize = 32
+	// apiKeyHashPrefix is the prefix of the api key hashes.
+	apiKeyHashPrefix = "sha256:"
+)
+
+// ErrKeyNotFound is an error when the api key doesn't exist.
+var ErrKeyNotFound = errors.New("auth: api key not found")
+
+// APIKey is an api key kept by its hash so that a leaked key file or
+// config doesn't leak the keys themselves.
+type APIKey struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name,omitempty"`
+	Subject     string     `json:"subject"`
+	Hash        string     `json:"hash"`
+	CreatedTime time.Time  `json:"created_time"`
+	RevokedTime *time.Time `json:"revoked_time,omitempty"`
+}
+
+// Revoked returns true when the key has been revoked.
+func (k *APIKey) Revoked() bool {
+	return k.RevokedTime != nil
+}
+
+// HashAPIKey returns the hash of the api key to keep in the config
+// or the key file. The keys are random so a plain SHA-256 is enough.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return apiKeyHashPrefix + hex.EncodeToString(sum[:])
+}
+
+// MintAPIKey generates a new api key for the subject. The returned key
+// is the only time the key is known, only its hash is kept.
+func MintAPIKey(name, subject string) (key string, apiKey *APIKey, err error) {
+	if subject == "" {
+		return "", nil, errors.New("auth: api key subject must not be empty")
+	}
+
+	b := make([]byte, apiKeySize)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, &APIKey{
+		ID:          uuid.New().String(),
+		Name:        name,
+		Subject:     subject,
+		Hash:        HashAPIKey(key),
+		CreatedTime: time.Now().UTC(),
+	}, nil
+}
+
+// keyFileData is the JSON document of the api key file.
+type keyFileData struct {
+	Keys []*APIKey `json:"keys"`
+}
+
+// ReadKeyFile reads the api keys from the key file at path.
+// A missing file has no keys.
+func ReadKeyFile(path string) ([]*APIKey, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var data keyFileData
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, fmt.Errorf("auth: reading key file %s: %w", path, err)
+	}
+	return data.Keys, nil
+}
+
+// WriteKeyFile replaces the key file at path with the keys. The file
+// is replaced at once so that a server reading it never sees a
+// partially written file.
+func WriteKeyFile(path string, keys []*APIKey) error {
+	b, err := json.MarshalIndent(keyFileData{Keys: keys}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// RevokeAPIKey marks the key with an id as revoked.
+func RevokeAPIKey(keys []*APIKey, id string) error {
+	for _, k := range keys {
+		if k.ID == id {
+			if !k.Revoked() {
+				now := time.Now().UTC()
+				k.RevokedTime = &now
+			}
+			return nil
+		}
+	}
+	return ErrKeyNotFound
+}
+
+// APIKeyAuthenticator authenticates the requests with the api keys
+// of the config and of the key file. The key file is read again when
+// it changes so that the minted and revoked keys take effect without
+// a restart. This is safe for concurrent use.
+type APIKeyAuthenticator struct {
+	static []*APIKey
+	path   string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	byHash  map[string]*APIKey
+}
+
+// NewAPIKeyAuthenticator takes the static keys of the config and the
+// path of the key file and returns the authenticator. An empty path
+// uses the static keys only.
+func NewAPIKeyAuthenticator(static []*APIKey, path string) (*APIKeyAuthenticator, error) {
+	a := &APIKeyAuthenticator{static: static, path: path}
+	if err := a.reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Authenticate implements the Authenticator interface.
+func (a *APIKeyAuthenticator) Authenticate(_ context.Context, c Credentials) (*Principal, error) {
+	if c.APIKey == "" {
+		return nil, ErrNoCredentials
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.reload(); err != nil {
+		return nil, err
+	}
+
+	k, ok := a.byHash[HashAPIKey(c.APIKey)]
+	if !ok || k.Revoked() {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{Subject: k.Subject, Method: MethodAPIKey, KeyID: k.ID}, nil
+}
+
+// reload reads the key file when it has changed since the last read.
+func (a *APIKeyAuthenticator) reload() error {
+	var modTime time.Time
+	var size int64
+	if a.path != "" {
+		info, err := os.Stat(a.path)
+		switch {
+		case err == nil:
+			modTime, size = info.ModTime(), info.Size()
+		case !errors.Is(err, os.ErrNotExist):
+			return err
+		}
+	}
+
+	if a.byHash != nil && modTime.Equal(a.modTime) && size == a.size {
+		return nil
+	}
+
+	var keys []*APIKey
+	if a.path != "" {
+		var err error
+		keys, err = ReadKeyFile(a.path)
+		if err != nil {
+			return err
+		}
+	}
+
+	byHash := make(map[string]*APIKey, len(a.static)+len(keys))
+	for _, k := range append(append([]*APIKey(nil), a.static...), keys...) {
+		if !strings.HasPrefix(k.Hash, apiKeyHashPrefix) {
+			return fmt.Errorf("auth: api key %s: unsupported hash", k.ID)
+		}
+		byHash[strings.ToLower(k.Hash)] = k
+	}
+
+	a.byHash, a.modTime, a.size = byHash, modTime, size
+	return nil
+}
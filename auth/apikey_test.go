@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var dummyCtx = context.TODO()
+
+func TestAPIKey(t *testing.T) {
+	suite.Run(t, new(APIKeyTestSuite))
+}
+
+type APIKeyTestSuite struct {
+	suite.Suite
+	path string
+}
+
+func (s *APIKeyTestSuite) SetupTest() {
+	s.path = filepath.Join(s.T().TempDir(), "api_keys.json")
+}
+
+func (s *APIKeyTestSuite) mint(subject string) (string, *APIKey) {
+	key, apiKey, err := MintAPIKey("test", subject)
+	s.Require().NoError(err)
+	return key, apiKey
+}
+
+func (s *APIKeyTestSuite) TestMintAPIKey() {
+	key, apiKey := s.mint("alice")
+	s.Regexp(`^nak_[A-Za-z0-9_-]{43}$`, key)
+	s.Equal(HashAPIKey(key), apiKey.Hash)
+	s.NotContains(apiKey.Hash, key)
+	s.NotEmpty(apiKey.ID)
+	s.False(apiKey.Revoked())
+
+	other, _ := s.mint("alice")
+	s.NotEqual(key, other)
+
+	_, _, err := MintAPIKey("test", "")
+	s.Error(err)
+}
+
+func (s *APIKeyTestSuite) TestKeyFile() {
+	keys, err := ReadKeyFile(s.path)
+	s.Require().NoError(err)
+	s.Empty(keys)
+
+	_, first := s.mint("alice")
+	_, second := s.mint("bob")
+	s.Require().NoError(WriteKeyFile(s.path, []*APIKey{first, second}))
+
+	s.Require().NoError(RevokeAPIKey([]*APIKey{first, second}, second.ID))
+	s.ErrorIs(RevokeAPIKey([]*APIKey{first}, "missing"), ErrKeyNotFound)
+
+	got, err := ReadKeyFile(s.path)
+	s.Require().NoError(err)
+	s.Require().Len(got, 2)
+	s.Equal(first.ID, got[0].ID)
+	s.Equal(first.Hash, got[0].Hash)
+
+	s.Require().NoError(os.WriteFile(s.path, []byte("{not json"), 0600))
+	_, err = ReadKeyFile(s.path)
+	s.Error(err)
+}
+
+func (s *APIKeyTestSuite) TestAuthenticate() {
+	staticKey := "static-key"
+	static := &APIKey{ID: "static", Subject: "carol", Hash: HashAPIKey(staticKey)}
+
+	key, apiKey := s.mint("alice")
+	s.Require().NoError(WriteKeyFile(s.path, []*APIKey{apiKey}))
+
+	a, err := NewAPIKeyAuthenticator([]*APIKey{static}, s.path)
+	s.Require().NoError(err)
+
+	s.Run("Key from the file", func() {
+		p, err := a.Authenticate(dummyCtx, Credentials{APIKey: key})
+		s.Require().NoError(err)
+		s.Equal(&Principal{Subject: "alice", Method: MethodAPIKey, KeyID: apiKey.ID}, p)
+	})
+
+	s.Run("Static key", func() {
+		p, err := a.Authenticate(dummyCtx, Credentials{APIKey: staticKey})
+		s.Require().NoError(err)
+		s.Equal("carol", p.Subject)
+	})
+
+	s.Run("Missing key", func() {
+		_, err := a.Authenticate(dummyCtx, Credentials{BearerToken: "token"})
+		s.ErrorIs(err, ErrNoCredentials)
+	})
+
+	s.Run("Unknown key", func() {
+		_, err := a.Authenticate(dummyCtx, Credentials{APIKey: "nak_unknown"})
+		s.ErrorIs(err, ErrInvalidCredentials)
+	})
+
+	s.Run("Minted key takes effect without a restart", func() {
+		newKey, newAPIKey := s.mint("bob")
+		s.writeLater([]*APIKey{apiKey, newAPIKey})
+
+		p, err := a.Authenticate(dummyCtx, Credentials{APIKey: newKey})
+		s.Require().NoError(err)
+		s.Equal("bob", p.Subject)
+	})
+
+	s.Run("Revoked key is rejected", func() {
+		keys, err := ReadKeyFile(s.path)
+		s.Require().NoError(err)
+		s.Require().NoError(RevokeAPIKey(keys, apiKey.ID))
+		s.writeLater(keys)
+
+		_, err = a.Authenticate(dummyCtx, Credentials{APIKey: key})
+		s.ErrorIs(err, ErrInvalidCredentials)
+	})
+}
+
+// writeLater writes the key file with a modification time after
+// the current one so that the change is seen on coarse file systems.
+func (s *APIKeyTestSuite) writeLater(keys []*APIKey) {
+	info, err := os.Stat(s.path)
+	s.Require().NoError(err)
+
+	s.Require().NoError(WriteKeyFile(s.path, keys))
+	later := info.ModTime().Add(time.Second)
+	s.Require().NoError(os.Chtimes(s.path, later, later))
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrNoCredentials is an error when the request doesn't carry
+	// the credentials of an authenticator.
+	ErrNoCredentials = errors.New("auth: no credentials")
+	// ErrInvalidCredentials is an error when the credentials can't
+	// be verified.
+	ErrInvalidCredentials = errors.New("auth: invalid credentials")
+)
+
+// Method is the way a principal has been authenticated.
+type Method string
+
+const (
+	// MethodAPIKey is the authentication with an api key.
+	MethodAPIKey Method = "api_key"
+	// MethodJWT is the authentication with a JWT bearer token.
+	MethodJWT Method = "jwt"
+	// MethodClientCert is the authentication with the client
+	// certificate of a mutual TLS connection.
+	MethodClientCert Method = "client_cert"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// Subject is the id of the user the request acts for.
+	Subject string
+	// Method is the way the principal has been authenticated.
+	Method Method
+	// KeyID is the id of the api key when the method is MethodAPIKey.
+	KeyID string
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx with the principal p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal kept in ctx. It returns
+// false when the request has not been authenticated.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// Credentials are the credentials sent with a request.
+type Credentials struct {
+	// APIKey is the value of the X-API-Key header.
+	APIKey string
+	// BearerToken is the token of the bearer authorization header.
+	BearerToken string
+	// ClientCertificate is the verified client certificate
+	// of the TLS connection.
+	ClientCertificate *x509.Certificate
+}
+
+// ParseAuthorization returns the credentials of the authorization
+// header value. The other schemes than bearer are ignored.
+func ParseAuthorization(header string) Credentials {
+	const prefix = "bearer "
+	if len(header) > len(prefix) && strings.EqualFold(header[:len(prefix)], prefix) {
+		return Credentials{BearerToken: strings.TrimSpace(header[len(prefix):])}
+	}
+	return Credentials{}
+}
+
+// Authenticator verifies the credentials of a request. It returns
+// ErrNoCredentials when the credentials it verifies are missing so
+// that the next authenticator can be tried.
+type Authenticator interface {
+	Authenticate(ctx context.Context, c Credentials) (*Principal, error)
+}
+
+// Authenticators tries each of the authenticators in order until one
+// of them finds its credentials.
+type Authenticators []Authenticator
+
+// Authenticate implements the Authenticator interface.
+func (as Authenticators) Authenticate(ctx context.Context, c Credentials) (*Principal, error) {
+	for _, a := range as {
+		p, err := a.Authenticate(ctx, c)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return p, err
+	}
+	return nil, ErrNoCredentials
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+)
+
+// CertificateAuthenticator authenticates the requests with the client
+// certificate of a mutual TLS connection. The certificate is verified
+// by the TLS handshake, against the client authorities of the server.
+// The subject of the principal is the common name of the certificate.
+type CertificateAuthenticator struct{}
+
+// NewCertificateAuthenticator returns a client certificate authenticator.
+func NewCertificateAuthenticator() *CertificateAuthenticator {
+	return &CertificateAuthenticator{}
+}
+
+// Authenticate implements the Authenticator interface.
+func (a *CertificateAuthenticator) Authenticate(_ context.Context, c Credentials) (*Principal, error) {
+	if c.ClientCertificate == nil {
+		return nil, ErrNoCredentials
+	}
+
+	subject := c.ClientCertificate.Subject.CommonName
+	if subject == "" {
+		return nil, fmt.Errorf("auth: client certificate without common name: %w", ErrInvalidCredentials)
+	}
+	return &Principal{Subject: subject, Method: MethodClientCert}, nil
+}
@@ -0,0 +1,22 @@
+package auth
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCertificateAuthenticator(t *testing.T) {
+	a := NewCertificateAuthenticator()
+
+	p, err := a.Authenticate(dummyCtx, Credentials{ClientCertificate: &x509.Certificate{Subject: pkix.Name{CommonName: "alice"}}})
+	require.NoError(t, err)
+	require.Equal(t, &Principal{Subject: "alice", Method: MethodClientCert}, p)
+
+	_, err = a.Authenticate(dummyCtx, Credentials{APIKey: "key"})
+	require.ErrorIs(t, err, ErrNoCredentials)
+
+	_, err = a.Authenticate(dummyCtx, Credentials{ClientCertificate: &x509.Certificate{}})
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"noteapp/auth"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	keyFileName string
+	keyName     string
+	keySubject  string
+)
+
+func init() {
+	KeysCmd.PersistentFlags().StringVarP(&keyFileName, "file", "f", "api_keys.json", "The api key file of the server.")
+
+	mintCmd.Flags().StringVar(&keyName, "name", "", "The name to tell the key apart, e.g. where it is used.")
+	mintCmd.Flags().StringVar(&keySubject, "subject", "", "The user id the key authenticates.")
+	_ = mintCmd.MarkFlagRequired("subject")
+
+	KeysCmd.AddCommand(mintCmd, revokeCmd, listCmd)
+	Cmd.AddCommand(KeysCmd)
+}
+
+// Cmd is the root command for the auth package.
+var Cmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Parent command for any related operation with the authentication.",
+}
+
+// KeysCmd is a cli command where it contains the commands
+// to manage the api keys in the api key file.
+var KeysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "A subcommand for managing the api keys",
+	Long: `A subcommand for managing the api keys.
+
+The keys are kept by their hash in the api key file that is set
+in the "auth.api_keys_file" of the server config. The server reads
+the file again when it changes.
+`,
+}
+
+var mintCmd = &cobra.Command{
+	Use:     "mint",
+	Short:   "Use to mint a new api key",
+	Example: "noteapp_cli auth keys mint --file ./api_keys.json --name ci --subject alice",
+	Run: func(cmd *cobra.Command, args []string) {
+		keys, err := auth.ReadKeyFile(keyFileName)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		key, apiKey, err := auth.MintAPIKey(keyName, keySubject)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		if err := auth.WriteKeyFile(keyFileName, append(keys, apiKey)); err != nil {
+			logrus.Fatal(err)
+		}
+
+		fmt.Printf("ID:  %s\n", apiKey.ID)
+		fmt.Printf("Key: %s\n", key)
+		fmt.Println("The key is not shown again, keep it somewhere safe.")
+	},
+}
+
+var revokeCmd = &cobra.Command{
+	Use:     "revoke <id>",
+	Short:   "Use to revoke an api key",
+	Example: "noteapp_cli auth keys revoke 6f1c0e9e-2d5e-4f8e-9a55-2b0f5c4c3b1a --file ./api_keys.json",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		keys, err := auth.ReadKeyFile(keyFileName)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		if err := auth.RevokeAPIKey(keys, args[0]); err != nil {
+			logrus.Fatal(err)
+		}
+
+		if err := auth.WriteKeyFile(keyFileName, keys); err != nil {
+			logrus.Fatal(err)
+		}
+
+		fmt.Printf("Revoked %s\n", args[0])
+	},
+}
+
+var listCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "Use to list the api keys",
+	Example: "noteapp_cli auth keys list --file ./api_keys.json",
+	Run: func(cmd *cobra.Command, args []string) {
+		keys, err := auth.ReadKeyFile(keyFileName)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		defer func() { _ = w.Flush() }()
+
+		_, _ = fmt.Fprintln(w, "ID\tNAME\tSUBJECT\tCREATED\tREVOKED")
+		for _, k := range keys {
+			revoked := "-"
+			if k.Revoked() {
+				revoked = k.RevokedTime.Format(time.RFC3339)
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", k.ID, k.Name, k.Subject, k.CreatedTime.Format(time.RFC3339), revoked)
+		}
+	},
+}
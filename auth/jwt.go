@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// JWTConfig contains the keys and the expected claims of the
+// JWT bearer tokens.
+type JWTConfig struct {
+	// Issuer is the expected iss claim. It is not checked when empty.
+	Issuer string
+	// Audience is the expected aud claim. It is not checked when empty.
+	Audience string
+	// HMACSecret is the secret of the HS256 tokens.
+	HMACSecret []byte
+	// RSAPublicKey is the public key of the RS256 tokens.
+	RSAPublicKey *rsa.PublicKey
+}
+
+// JWTAuthenticator authenticates the requests with the HS256 or RS256
+// JWT bearer tokens. The subject of the token is the principal.
+type JWTAuthenticator struct {
+	conf   JWTConfig
+	parser *jwt.Parser
+}
+
+// NewJWTAuthenticator returns a JWTAuthenticator accepting the signing
+// methods of the keys in conf.
+func NewJWTAuthenticator(conf JWTConfig) (*JWTAuthenticator, error) {
+	var methods []string
+	if len(conf.HMACSecret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if conf.RSAPublicKey != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	if len(methods) == 0 {
+		return nil, errors.New("auth: jwt needs either an hmac secret or an rsa public key")
+	}
+
+	return &JWTAuthenticator{conf: conf, parser: jwt.NewParser(jwt.WithValidMethods(methods))}, nil
+}
+
+// Authenticate implements the Authenticator interface.
+func (a *JWTAuthenticator) Authenticate(_ context.Context, c Credentials) (*Principal, error) {
+	if c.BearerToken == "" {
+		return nil, ErrNoCredentials
+	}
+
+	var claims jwt.RegisteredClaims
+	_, err := a.parser.ParseWithClaims(c.BearerToken, &claims, a.key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCredentials, err)
+	}
+
+	// The expiry is optional for the parser.
+	if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w: token has no expiry", ErrInvalidCredentials)
+	}
+
+	if a.conf.Issuer != "" && !claims.VerifyIssuer(a.conf.Issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidCredentials)
+	}
+
+	if a.conf.Audience != "" && !claims.VerifyAudience(a.conf.Audience, true) {
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidCredentials)
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+
+	return &Principal{Subject: claims.Subject, Method: MethodJWT}, nil
+}
+
+// key returns the key verifying the signature of token. The signing
+// method has already been checked by the parser.
+func (a *JWTAuthenticator) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method {
+	case jwt.SigningMethodHS256:
+		return a.conf.HMACSecret, nil
+	case jwt.SigningMethodRS256:
+		return a.conf.RSAPublicKey, nil
+	default:
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+}
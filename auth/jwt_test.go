@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+var dummySecret = []byte("unit-test-secret")
+
+func TestJWT(t *testing.T) {
+	suite.Run(t, new(JWTTestSuite))
+}
+
+type JWTTestSuite struct {
+	suite.Suite
+	rsaKey *rsa.PrivateKey
+	a      *JWTAuthenticator
+}
+
+func (s *JWTTestSuite) SetupSuite() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().NoError(err)
+	s.rsaKey = key
+}
+
+func (s *JWTTestSuite) SetupTest() {
+	a, err := NewJWTAuthenticator(JWTConfig{
+		Issuer:       "https://issuer.example.com",
+		Audience:     "noteapp",
+		HMACSecret:   dummySecret,
+		RSAPublicKey: &s.rsaKey.PublicKey,
+	})
+	s.Require().NoError(err)
+	s.a = a
+}
+
+func (s *JWTTestSuite) claims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Issuer:    "https://issuer.example.com",
+		Audience:  jwt.ClaimStrings{"noteapp"},
+		Subject:   "alice",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+}
+
+func (s *JWTTestSuite) sign(method jwt.SigningMethod, key interface{}, claims jwt.Claims) string {
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	s.Require().NoError(err)
+	return token
+}
+
+func (s *JWTTestSuite) TestNewJWTAuthenticator() {
+	_, err := NewJWTAuthenticator(JWTConfig{Issuer: "issuer"})
+	s.Error(err)
+}
+
+func (s *JWTTestSuite) TestAuthenticate() {
+	s.Run("HS256 token", func() {
+		p, err := s.a.Authenticate(dummyCtx, Credentials{BearerToken: s.sign(jwt.SigningMethodHS256, dummySecret, s.claims())})
+		s.Require().NoError(err)
+		s.Equal(&Principal{Subject: "alice", Method: MethodJWT}, p)
+	})
+
+	s.Run("RS256 token", func() {
+		p, err := s.a.Authenticate(dummyCtx, Credentials{BearerToken: s.sign(jwt.SigningMethodRS256, s.rsaKey, s.claims())})
+		s.Require().NoError(err)
+		s.Equal("alice", p.Subject)
+	})
+
+	s.Run("Missing token", func() {
+		_, err := s.a.Authenticate(dummyCtx, Credentials{APIKey: "key"})
+		s.ErrorIs(err, ErrNoCredentials)
+	})
+
+	invalid := []struct {
+		name  string
+		token func() string
+	}{
+		{
+			name:  "Malformed token",
+			token: func() string { return "not.a.token" },
+		},
+		{
+			name:  "Wrong secret",
+			token: func() string { return s.sign(jwt.SigningMethodHS256, []byte("other"), s.claims()) },
+		},
+		{
+			name: "Unsupported signing method",
+			token: func() string {
+				return s.sign(jwt.SigningMethodHS512, dummySecret, s.claims())
+			},
+		},
+		{
+			name: "Unsigned token",
+			token: func() string {
+				return s.sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, s.claims())
+			},
+		},
+		{
+			name: "Expired token",
+			token: func() string {
+				claims := s.claims()
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+				return s.sign(jwt.SigningMethodHS256, dummySecret, claims)
+			},
+		},
+		{
+			name: "Token without expiry",
+			token: func() string {
+				claims := s.claims()
+				claims.ExpiresAt = nil
+				return s.sign(jwt.SigningMethodHS256, dummySecret, claims)
+			},
+		},
+		{
+			name: "Wrong issuer",
+			token: func() string {
+				claims := s.claims()
+				claims.Issuer = "https://other.example.com"
+				return s.sign(jwt.SigningMethodHS256, dummySecret, claims)
+			},
+		},
+		{
+			name: "Wrong audience",
+			token: func() string {
+				claims := s.claims()
+				claims.Audience = jwt.ClaimStrings{"other"}
+				return s.sign(jwt.SigningMethodHS256, dummySecret, claims)
+			},
+		},
+		{
+			name: "Token without subject",
+			token: func() string {
+				claims := s.claims()
+				claims.Subject = ""
+				return s.sign(jwt.SigningMethodHS256, dummySecret, claims)
+			},
+		},
+	}
+
+	for _, tt := range invalid {
+		s.Run(tt.name, func() {
+			_, err := s.a.Authenticate(dummyCtx, Credentials{BearerToken: tt.token()})
+			s.ErrorIs(err, ErrInvalidCredentials)
+		})
+	}
+
+	s.Run("HS256 token signed with the public key is rejected when only RS256 is accepted", func() {
+		a, err := NewJWTAuthenticator(JWTConfig{RSAPublicKey: &s.rsaKey.PublicKey})
+		s.Require().NoError(err)
+
+		_, err = a.Authenticate(dummyCtx, Credentials{BearerToken: s.sign(jwt.SigningMethodHS256, dummySecret, s.claims())})
+		s.ErrorIs(err, ErrInvalidCredentials)
+	})
+}
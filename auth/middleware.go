@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"noteapp/api"
+	"noteapp/api/middleware"
+	"noteapp/api/problem"
+	"noteapp/pkg/logging"
+	"strings"
+)
+
+// APIKeyHeader is the http header carrying the api key.
+const APIKeyHeader = "X-API-Key"
+
+// NewMiddleware returns an authentication middleware with its name.
+func NewMiddleware(a Authenticator, anonymousPaths ...string) api.NamedMiddleware {
+	return api.NewNamedMiddleware("Auth", Middleware(a, anonymousPaths...))
+}
+
+// Middleware returns an http handler middleware which authenticates
+// the requests with a and keeps the principal in the request context.
+// The requests without valid credentials are rejected, except the ones
+// to the anonymous paths such as the server metadata. An anonymous path
+// ending with a slash matches all the paths under it.
+func Middleware(a Authenticator, anonymousPaths ...string) func(http.Handler) http.Handler {
+	anonymous := make(map[string]bool, len(anonymousPaths))
+	var anonymousPrefixes []string
+	for _, p := range anonymousPaths {
+		if strings.HasSuffix(p, "/") {
+			anonymousPrefixes = append(anonymousPrefixes, p)
+			continue
+		}
+		anonymous[p] = true
+	}
+
+	isAnonymous := func(path string) bool {
+		if anonymous[path] {
+			return true
+		}
+
+		for _, prefix := range anonymousPrefixes {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isAnonymous(r.URL.Path) {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			c := ParseAuthorization(r.Header.Get("Authorization"))
+			c.APIKey = r.Header.Get(APIKeyHeader)
+			if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+				c.ClientCertificate = r.TLS.VerifiedChains[0][0]
+			}
+
+			p, err := a.Authenticate(r.Context(), c)
+			if err != nil {
+				writeUnauthorized(w, r, err)
+				return
+			}
+
+			h.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+		})
+	}
+}
+
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	var p problem.Problem
+	switch {
+	case errors.Is(err, ErrNoCredentials):
+		p = problem.New(http.StatusUnauthorized, "unauthenticated", "Authentication required")
+	case errors.Is(err, ErrInvalidCredentials):
+		p = problem.New(http.StatusUnauthorized, "invalid_credentials", "Invalid credentials")
+	default:
+		logging.Logger(r.Context()).Error(err)
+		p = problem.New(http.StatusInternalServerError, "internal_error", "Unexpected error")
+	}
+	p.RequestID = middleware.RequestIDFromContext(r.Context())
+
+	if p.Status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="noteapp"`)
+	}
+	_ = problem.Write(w, p)
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor which authenticates
+// the unary calls with a, the same way as the http middleware.
+func UnaryServerInterceptor(a Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticateGRPC(ctx, a)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor which authenticates
+// the streaming calls with a, the same way as the http middleware.
+func StreamServerInterceptor(a Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticateGRPC(ss.Context(), a)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authenticateGRPC(ctx context.Context, a Authenticator) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var c Credentials
+	if v := md.Get("authorization"); len(v) > 0 {
+		c = ParseAuthorization(v[0])
+	}
+	if v := md.Get("x-api-key"); len(v) > 0 {
+		c.APIKey = v[0]
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 {
+			c.ClientCertificate = info.State.VerifiedChains[0][0]
+		}
+	}
+
+	p, err := a.Authenticate(ctx, c)
+	switch {
+	case errors.Is(err, ErrNoCredentials):
+		return nil, status.Error(codes.Unauthenticated, "Authentication required")
+	case errors.Is(err, ErrInvalidCredentials):
+		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+	case err != nil:
+		logging.Logger(ctx).Error(err)
+		return nil, status.Error(codes.Internal, "Unexpected error")
+	}
+	return WithPrincipal(ctx, p), nil
+}
+
+// serverStream replaces the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"noteapp/api/problem"
+	"testing"
+)
+
+const dummyKey = "nak_unit-test"
+
+func TestMiddleware(t *testing.T) {
+	suite.Run(t, new(MiddlewareTestSuite))
+}
+
+type MiddlewareTestSuite struct {
+	suite.Suite
+	a       Authenticator
+	handler http.Handler
+	got     *Principal
+}
+
+func (s *MiddlewareTestSuite) SetupTest() {
+	keys, err := NewAPIKeyAuthenticator([]*APIKey{{ID: "key", Subject: "alice", Hash: HashAPIKey(dummyKey)}}, "")
+	s.Require().NoError(err)
+	s.a = keys
+
+	s.got = nil
+	s.handler = Middleware(s.a, "/meta", "/v1/shared/")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.got, _ = PrincipalFromContext(r.Context())
+	}))
+}
+
+func (s *MiddlewareTestSuite) serve(path string, header http.Header) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, values := range header {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+	s.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware() {
+	s.Run("Authenticated request", func() {
+		rec := s.serve("/v1/notes", http.Header{APIKeyHeader: {dummyKey}})
+		s.Equal(http.StatusOK, rec.Code)
+		s.Equal(&Principal{Subject: "alice", Method: MethodAPIKey, KeyID: "key"}, s.got)
+	})
+
+	s.Run("Anonymous path", func() {
+		s.got = nil
+		rec := s.serve("/meta", nil)
+		s.Equal(http.StatusOK, rec.Code)
+		s.Nil(s.got)
+	})
+
+	s.Run("Path under an anonymous prefix", func() {
+		s.got = nil
+		rec := s.serve("/v1/shared/token", http.Header{APIKeyHeader: {dummyKey}})
+		s.Equal(http.StatusOK, rec.Code)
+		s.Nil(s.got)
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		header http.Header
+		code   string
+	}{
+		{name: "Missing credentials", code: "unauthenticated"},
+		{name: "Path next to an anonymous path", path: "/meta/other", code: "unauthenticated"},
+		{name: "Invalid api key", header: http.Header{APIKeyHeader: {"nak_other"}}, code: "invalid_credentials"},
+		{name: "Unknown authorization scheme", header: http.Header{"Authorization": {"Basic YWxpY2U6c2VjcmV0"}}, code: "unauthenticated"},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			s.got = nil
+			path := tt.path
+			if path == "" {
+				path = "/v1/notes"
+			}
+			rec := s.serve(path, tt.header)
+			s.Equal(http.StatusUnauthorized, rec.Code)
+			s.Equal(problem.ContentType, rec.Header().Get("Content-Type"))
+			s.NotEmpty(rec.Header().Get("WWW-Authenticate"))
+			s.Nil(s.got)
+
+			var p problem.Problem
+			s.Require().NoError(json.NewDecoder(rec.Body).Decode(&p))
+			s.Equal(tt.code, p.Code)
+		})
+	}
+}
+
+func (s *MiddlewareTestSuite) TestParseAuthorization() {
+	s.Equal(Credentials{BearerToken: "token"}, ParseAuthorization("Bearer token"))
+	s.Equal(Credentials{BearerToken: "token"}, ParseAuthorization("bearer  token"))
+	s.Equal(Credentials{}, ParseAuthorization("Basic token"))
+	s.Equal(Credentials{}, ParseAuthorization("Bearer "))
+}
+
+func (s *MiddlewareTestSuite) TestUnaryServerInterceptor() {
+	interceptor := UnaryServerInterceptor(s.a)
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		p, _ := PrincipalFromContext(ctx)
+		return p, nil
+	}
+
+	call := func(md metadata.MD) (interface{}, error) {
+		ctx := metadata.NewIncomingContext(dummyCtx, md)
+		return interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	}
+
+	got, err := call(metadata.Pairs("x-api-key", dummyKey))
+	s.Require().NoError(err)
+	s.Equal("alice", got.(*Principal).Subject)
+
+	_, err = call(metadata.MD{})
+	s.Equal(codes.Unauthenticated, status.Code(err))
+
+	_, err = call(metadata.Pairs("x-api-key", "nak_other"))
+	s.Equal(codes.Unauthenticated, status.Code(err))
+}
+
+func (s *MiddlewareTestSuite) TestUnaryServerInterceptorClientCertificate() {
+	interceptor := UnaryServerInterceptor(Authenticators{s.a, NewCertificateAuthenticator()})
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		p, _ := PrincipalFromContext(ctx)
+		return p, nil
+	}
+
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "bob"}}
+	ctx := peer.NewContext(dummyCtx, &peer.Peer{AuthInfo: credentials.TLSInfo{
+		State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}},
+	}})
+
+	got, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	s.Require().NoError(err)
+	s.Equal("bob", got.(*Principal).Subject)
+	s.Equal(MethodClientCert, got.(*Principal).Method)
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+	"net/http"
+	"noteapp/auth"
+	"os"
+)
+
+const (
+	// EnvAPIKey is the environment variable of the api key
+	// used when the --api-key flag is not set.
+	EnvAPIKey = "NOTEAPP_API_KEY"
+	// EnvToken is the environment variable of the bearer token
+	// used when the --token flag is not set.
+	EnvToken = "NOTEAPP_TOKEN"
+)
+
+// Credentials are the credentials the commands send to the
+// noteapp server.
+type Credentials struct {
+	APIKey string
+	Token  string
+}
+
+// AddFlags adds the --api-key and the --token flags of the
+// credentials to the persistent flags of cmd.
+func (c *Credentials) AddFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&c.APIKey, "api-key", "", "The api key of the noteapp server. Defaults to $"+EnvAPIKey+".")
+	cmd.PersistentFlags().StringVar(&c.Token, "token", "", "The bearer token of the noteapp server. Defaults to $"+EnvToken+".")
+}
+
+// Authorize sets the credentials to the headers of req. The
+// credentials not set by the flags are read from the environment
+// so that they don't show up in the shell history.
+func (c *Credentials) Authorize(req *http.Request) {
+	apiKey, token := c.APIKey, c.Token
+	if apiKey == "" {
+		apiKey = os.Getenv(EnvAPIKey)
+	}
+	if token == "" {
+		token = os.Getenv(EnvToken)
+	}
+
+	if apiKey != "" {
+		req.Header.Set(auth.APIKeyHeader, apiKey)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
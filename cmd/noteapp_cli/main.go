@@ -2,12 +2,14 @@ package main
 
 import (
 	"log"
+	authcli "noteapp/auth/cli"
 	"noteapp/cli"
 	notecli "noteapp/note/cli"
 )
 
 func main() {
 	cli.RootCmd.AddCommand(notecli.Cmd)
+	cli.RootCmd.AddCommand(authcli.Cmd)
 	if err := cli.RootCmd.Execute(); err != nil {
 		log.Fatal(err)
 	}
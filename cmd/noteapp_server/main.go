@@ -1,17 +1,52 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"io"
 	"log"
+	"net"
+	"net/http"
 	"noteapp/api"
 	"noteapp/api/middleware"
+	"noteapp/api/ratelimit"
 	"noteapp/api/server"
+	"noteapp/api/server/health"
 	"noteapp/api/server/meta"
+	"noteapp/auth"
 	"noteapp/config"
+	"noteapp/note"
+	notegrpc "noteapp/note/api/v1/transport/grpc"
 	"noteapp/note/api/v1/transport/rest"
+	"noteapp/note/changefeed"
+	"noteapp/note/collab"
+	"noteapp/note/eventbus"
+	"noteapp/note/link"
 	noteservice "noteapp/note/service"
+	"noteapp/note/share"
 	filestore "noteapp/note/store/file"
+	storeinstrumenting "noteapp/note/store/instrumenting"
+	storetracing "noteapp/note/store/tracing"
+	"noteapp/note/validation"
+	"noteapp/note/webhook"
+	nhttp "noteapp/pkg/http"
+	"noteapp/pkg/logging"
+	"noteapp/user"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 )
 
@@ -26,22 +61,252 @@ var (
 
 const (
 	dbFileName = "note.pb"
+	// webhookFileName is the file of the webhook subscriptions
+	// and their delivery queue.
+	webhookFileName = "webhooks.json"
+	// userFileName is the file of the user registry.
+	userFileName = "users.json"
+	// shareFileName is the file of the share grants of the notes.
+	shareFileName = "shares.json"
+	// linkFileName is the file of the public links of the notes.
+	linkFileName = "links.json"
+
+	// metricsPath is the path of the Prometheus metrics, it's
+	// left unauthenticated for the scrapers.
+	metricsPath = "/metrics"
+
+	// eventReplaySize is the number of the latest note events kept
+	// for the clients resuming the change feed.
+	eventReplaySize = 1024
+
+	// collabSaveInterval is the interval between the saves of
+	// the note contents edited collaboratively.
+	collabSaveInterval = 5 * time.Second
+
+	// tracerFlushTimeout is the time the spans not exported
+	// yet have to be exported when the server stops.
+	tracerFlushTimeout = 5 * time.Second
 )
 
 func main() {
 
 	conf := config.New()
+	mustNoError(logging.Configure(logrus.StandardLogger(), conf.Log.Level, conf.Log.Format))
+
+	// closers are the stores closed after the servers shut down.
+	var closers []io.Closer
 
 	file, err := os.OpenFile(filepath.Join(conf.Store.File.Path, dbFileName), os.O_CREATE|os.O_RDWR, 0666)
 	mustNoError(err)
-	defer func() { _ = file.Close() }()
 
-	svc := noteservice.New(filestore.New(file))
+	webhookFile, err := os.OpenFile(filepath.Join(conf.Store.File.Path, webhookFileName), os.O_CREATE|os.O_RDWR, 0600)
+	mustNoError(err)
+
+	webhookStore, err := webhook.NewStore(webhookFile)
+	mustNoError(err)
+	closers = append(closers, webhookStore)
+
+	shareFile, err := os.OpenFile(filepath.Join(conf.Store.File.Path, shareFileName), os.O_CREATE|os.O_RDWR, 0600)
+	mustNoError(err)
+
+	shareStore, err := share.NewStore(shareFile)
+	mustNoError(err)
+	closers = append(closers, shareStore)
+
+	linkFile, err := os.OpenFile(filepath.Join(conf.Store.File.Path, linkFileName), os.O_CREATE|os.O_RDWR, 0600)
+	mustNoError(err)
+
+	linkStore, err := link.NewStore(linkFile)
+	mustNoError(err)
+	closers = append(closers, linkStore)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	allowedNetworks, err := parseNetworks(conf.Webhook.AllowedNetworks)
+	mustNoError(err)
+
+	dispatcher := webhook.NewDispatcher(webhookStore,
+		webhook.WithGrants(shareStore),
+		webhook.WithAllowedNetworks(allowedNetworks...),
+		webhook.WithDroppedCounter(droppedDeliveriesCounter()),
+	)
+	dispatcherDone := make(chan struct{})
+	go func() {
+		defer close(dispatcherDone)
+		dispatcher.Run(ctx)
+	}()
+
+	feed := changefeed.New(eventReplaySize)
+
+	// The change feed numbers its events after the bus, so it
+	// subscribes to all the events.
+	bus := eventbus.New()
+	bus.Subscribe(feed)
+	bus.Subscribe(dispatcher)
+	bus.Subscribe(shareStore, note.EventDeleted)
+
+	validator, err := validation.New(validation.Rules{
+		MaxTitleLength:           conf.Validation.MaxTitleLength,
+		MaxContentLength:         conf.Validation.MaxContentLength,
+		RequireTitle:             conf.Validation.RequireTitle,
+		AllowedTitleCharacters:   conf.Validation.AllowedTitleCharacters,
+		AllowedContentCharacters: conf.Validation.AllowedContentCharacters,
+		RejectServerOwnedFields:  conf.Validation.RejectServerOwnedFields,
+	})
+	mustNoError(err)
+
+	tracerProvider, err := newTracerProvider(ctx, conf.Tracing)
+	mustNoError(err)
+	if tracerProvider != nil {
+		otel.SetTracerProvider(tracerProvider)
+	}
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	tracer := otel.Tracer(tracerName)
+
+	fileStore := filestore.New(file)
+	closers = append(closers, fileStore)
+	prometheus.MustRegister(newFileStoreCollector(fileStore))
+
+	storeCount, storeLatency := requestMetrics("store", []string{"method", "error"}, []string{"method", "error"})
+	store := storetracing.New(storeinstrumenting.New(fileStore, storeCount, storeLatency), tracer)
+
+	serviceCount, serviceLatency := requestMetrics("service", []string{"method", "error"}, []string{"method", "error"})
+	svc := noteservice.Chain(
+		noteservice.TracingMiddleware(tracer),
+		noteservice.LoggingMiddleware(logrus.StandardLogger()),
+		noteservice.InstrumentingMiddleware(serviceCount, serviceLatency),
+		noteservice.ValidatingMiddleware(validator),
+		noteservice.EventPublishingMiddleware(bus),
+	)(noteservice.New(store,
+		noteservice.WithGrants(shareStore),
+		noteservice.WithQuota(noteservice.Quota{
+			MaxNotes: conf.Quota.MaxNotes,
+			MaxBytes: conf.Quota.MaxBytes,
+		}),
+	))
+
+	httpCount, httpLatency := requestMetrics("http", []string{"method", "route", "code"}, []string{"method", "route"})
+	middlewares := []api.NamedMiddleware{
+		middleware.NewRequestIDMiddleware(),
+		middleware.NewTracingMiddleware(tracer, otel.GetTextMapPropagator()),
+		middleware.NewMetricsMiddleware(httpCount, httpLatency),
+		middleware.NewLoggingMiddleware(logrus.StandardLogger()),
+	}
+
+	if conf.Compression.Enabled {
+		middlewares = append(middlewares, middleware.NewCompressionMiddleware(middleware.CompressionConfig{
+			MinSize: conf.Compression.MinSize,
+		}))
+	}
+
+	if conf.SecurityHeaders.Enabled {
+		middlewares = append(middlewares, middleware.NewSecurityHeadersMiddleware(middleware.SecurityHeadersConfig{
+			HSTSMaxAge:            conf.SecurityHeaders.HSTSMaxAge,
+			HSTSIncludeSubdomains: conf.SecurityHeaders.HSTSIncludeSubdomains,
+			FrameOptions:          conf.SecurityHeaders.FrameOptions,
+			ContentSecurityPolicy: conf.SecurityHeaders.ContentSecurityPolicy,
+		}))
+	}
+
+	// The preflight requests are answered before the authentication
+	// since the browsers send them without credentials.
+	if conf.CORS.Enabled {
+		cors, err := middleware.NewCORSMiddleware(middleware.CORSConfig{
+			AllowedOrigins:   conf.CORS.AllowedOrigins,
+			AllowedMethods:   conf.CORS.AllowedMethods,
+			AllowedHeaders:   conf.CORS.AllowedHeaders,
+			ExposedHeaders:   conf.CORS.ExposedHeaders,
+			AllowCredentials: conf.CORS.AllowCredentials,
+			MaxAge:           conf.CORS.MaxAge,
+		})
+		mustNoError(err)
+		middlewares = append(middlewares, cors)
+	}
+
+	// The http and the gRPC clients share their limits.
+	var rateLimiter, failedAuthLimiter *ratelimit.Limiter
+	if conf.RateLimit.Enabled {
+		rateLimiter = newRateLimiter(conf.RateLimit)
+		failedAuthLimiter = ratelimit.New(ratelimit.Config{
+			Default: ratelimit.Limit{Rate: conf.RateLimit.FailedAuthRate, Burst: conf.RateLimit.FailedAuthBurst},
+		})
+	}
+
+	var (
+		grpcOptions            []grpc.ServerOption
+		grpcUnaryInterceptors  []grpc.UnaryServerInterceptor
+		grpcStreamInterceptors []grpc.StreamServerInterceptor
+	)
+	if conf.Auth.Enabled {
+		authenticator, err := newAuthenticator(conf.Auth)
+		mustNoError(err)
+
+		// The clients verified by the mutual TLS act as the
+		// common name of their certificate.
+		if conf.Server.TLS.ClientCAFile != "" {
+			authenticator = auth.Authenticators{authenticator, auth.NewCertificateAuthenticator()}
+		}
+
+		userFile, err := os.OpenFile(filepath.Join(conf.Store.File.Path, userFileName), os.O_CREATE|os.O_RDWR, 0600)
+		mustNoError(err)
+
+		registry, err := user.NewRegistry(userFile)
+		mustNoError(err)
+		closers = append(closers, registry)
+
+		// The probes, the scrapers and the public links don't
+		// have credentials.
+		anonymousPaths := []string{
+			"/meta",
+			health.LivenessPath,
+			health.ReadinessPath,
+			metricsPath,
+			rest.SharedPathPrefix,
+		}
+
+		// The failed authentications are limited by ip address since
+		// their clients are unknown to the limiter after the authentication.
+		if conf.RateLimit.Enabled {
+			middlewares = append(middlewares, ratelimit.NewFailedAuthMiddleware(failedAuthLimiter))
+			grpcUnaryInterceptors = append(grpcUnaryInterceptors, ratelimit.FailedAuthUnaryServerInterceptor(failedAuthLimiter))
+			grpcStreamInterceptors = append(grpcStreamInterceptors, ratelimit.FailedAuthStreamServerInterceptor(failedAuthLimiter))
+		}
+
+		// The notes of the authenticated requests are owned by their user.
+		middlewares = append(middlewares,
+			auth.NewMiddleware(authenticator, anonymousPaths...),
+			user.NewMiddleware(registry),
+		)
+		grpcUnaryInterceptors = append(grpcUnaryInterceptors,
+			auth.UnaryServerInterceptor(authenticator),
+			user.UnaryServerInterceptor(registry),
+		)
+		grpcStreamInterceptors = append(grpcStreamInterceptors,
+			auth.StreamServerInterceptor(authenticator),
+			user.StreamServerInterceptor(registry),
+		)
+	}
+
+	// The clients are limited after the authentication
+	// so that they are limited by their api key.
+	if conf.RateLimit.Enabled {
+		middlewares = append(middlewares, ratelimit.NewMiddleware(rateLimiter))
+		grpcUnaryInterceptors = append(grpcUnaryInterceptors, ratelimit.UnaryServerInterceptor(rateLimiter))
+		grpcStreamInterceptors = append(grpcStreamInterceptors, ratelimit.StreamServerInterceptor(rateLimiter))
+	}
+	grpcOptions = append(grpcOptions,
+		grpc.ChainUnaryInterceptor(grpcUnaryInterceptors...),
+		grpc.ChainStreamInterceptor(grpcStreamInterceptors...),
+	)
+
+	tlsConf, err := newTLSConfig(conf.Server.TLS)
+	mustNoError(err)
+
 	srv := server.New(&server.Config{
-		Port: conf.Server.Port,
-		Middlewares: []api.NamedMiddleware{
-			middleware.NewLoggingMiddleware(),
-		},
+		Port:        conf.Server.Port,
+		TLS:         tlsConf,
+		Middlewares: middlewares,
 	})
 
 	srv.AddRoutes(meta.Routes(&meta.Metadata{
@@ -49,10 +314,250 @@ func main() {
 		BuildCommit: BuildCommit,
 		BuildDate:   BuildDate,
 	})...)
+	readiness := newHealth(conf, store, fileStore)
+	srv.AddRoutes(health.Routes(readiness)...)
+	srv.AddRoutes(&nhttp.Route{HandlerValue: promhttp.Handler(), MethodValue: http.MethodGet, PathValue: metricsPath})
 	srv.AddRoutes(rest.Routes(svc)...)
+	srv.AddRoutes(rest.EventRoutes(feed, shareStore)...)
+	hub := collab.NewHub(svc, shareStore, collabSaveInterval)
+	srv.AddRoutes(rest.CollabRoutes(svc, hub, shareStore)...)
+	srv.AddRoutes(rest.WebhookRoutes(webhookStore)...)
+	srv.AddRoutes(rest.ShareRoutes(share.NewService(svc, shareStore))...)
+
+	linkSvc, err := link.NewService(svc, shareStore, linkStore, link.Config{
+		Secret:     linkSecret(conf.Links.Secret),
+		DefaultTTL: conf.Links.DefaultTTL,
+		MaxTTL:     conf.Links.MaxTTL,
+	})
+	mustNoError(err)
+	srv.AddRoutes(rest.LinkRoutes(linkSvc)...)
+
+	// The gRPC server is served over TLS with the same
+	// certificate and client authorities as the API server.
+	if tlsConf != nil {
+		grpcTLS, err := tlsConf.Config()
+		mustNoError(err)
+		grpcOptions = append(grpcOptions, grpc.Creds(credentials.NewTLS(grpcTLS)))
+	}
+
+	grpcServer := grpc.NewServer(grpcOptions...)
+	notegrpc.Register(grpcServer, svc)
+	grpcListener, err := net.Listen("tcp", fmt.Sprintf(":%d", conf.Server.GRPCPort))
+	mustNoError(err)
 
-	defer srv.Close()
-	mustNoError(srv.ListenAndServe())
+	signalCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 2)
+	go func() {
+		if tlsConf != nil {
+			logrus.Infof("gRPC listen on %s with TLS\n", grpcListener.Addr())
+		} else {
+			logrus.Infof("gRPC listen on %s\n", grpcListener.Addr())
+		}
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			serveErr <- err
+		}
+	}()
+
+	go func() {
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+	}()
+
+	exitCode := 0
+	select {
+	case <-signalCtx.Done():
+		logrus.Info("Shutting down")
+	case err := <-serveErr:
+		logrus.Error(err)
+		exitCode = 1
+	}
+
+	shutdown(conf.Server, readiness, feed, srv, grpcServer)
+
+	// The collaborating clients are not drained by the server
+	// since their connections are hijacked, their sessions are
+	// ended and saved before the stores close.
+	if err := hub.Close(); err != nil {
+		logrus.Error(err)
+		exitCode = 1
+	}
+
+	// The dispatcher stops after the drained requests published
+	// their events, and adds their deliveries before the stores close.
+	cancel()
+	<-dispatcherDone
+
+	for _, c := range closers {
+		if err := c.Close(); err != nil {
+			logrus.Error(err)
+			exitCode = 1
+		}
+	}
+
+	if tracerProvider != nil {
+		flushCtx, cancelFlush := context.WithTimeout(context.Background(), tracerFlushTimeout)
+		if err := tracerProvider.Shutdown(flushCtx); err != nil {
+			logrus.Error(err)
+		}
+		cancelFlush()
+	}
+
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+// shutdown fails the readiness and closes the event streams of the feed,
+// which would otherwise never end, and waits for the load balancers to
+// see it. Then it drains the in-flight requests of both servers. The
+// requests still running after the drain timeout are dropped.
+func shutdown(conf config.Server, readiness *health.Health, feed *changefeed.Broker, srv *server.Server, grpcServer *grpc.Server) {
+	readiness.Drain()
+	feed.Close()
+	time.Sleep(conf.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), conf.DrainTimeout)
+	defer cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		logrus.WithError(err).Warn("Dropped the requests still running after the drain timeout")
+	}
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
+}
+
+// newTLSConfig returns the TLS configuration of the API server
+// set in conf, or nil when the server serves plain HTTP.
+func newTLSConfig(conf config.TLS) (*server.TLSConfig, error) {
+	if conf.CertFile == "" {
+		return nil, nil
+	}
+
+	minVersion, err := server.ParseTLSVersion(conf.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return &server.TLSConfig{
+		CertFile:          conf.CertFile,
+		KeyFile:           conf.KeyFile,
+		MinVersion:        minVersion,
+		ClientCAFile:      conf.ClientCAFile,
+		RequireClientCert: conf.RequireClientCert,
+	}, nil
+}
+
+// newRateLimiter returns the limiter of the default and
+// the route limits set in conf.
+func newRateLimiter(conf config.RateLimit) *ratelimit.Limiter {
+	routes := make(map[string]ratelimit.Limit, len(conf.Routes))
+	for _, r := range conf.Routes {
+		routes[strings.ToUpper(r.Method)+" "+r.Path] = ratelimit.Limit{Rate: r.Rate, Burst: r.Burst}
+	}
+
+	return ratelimit.New(ratelimit.Config{
+		Default: ratelimit.Limit{Rate: conf.Rate, Burst: conf.Burst},
+		Routes:  routes,
+	})
+}
+
+// parseNetworks parses the CIDRs of the networks, e.g. "10.0.0.0/8".
+func parseNetworks(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("network '%s': %w", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// newAuthenticator returns the authenticator of the api keys and
+// the JWT bearer tokens set in conf.
+func newAuthenticator(conf config.Auth) (auth.Authenticator, error) {
+	staticKeys := make([]*auth.APIKey, 0, len(conf.APIKeys))
+	for _, k := range conf.APIKeys {
+		staticKeys = append(staticKeys, &auth.APIKey{ID: k.ID, Subject: k.Subject, Hash: k.Hash})
+	}
+
+	keys, err := auth.NewAPIKeyAuthenticator(staticKeys, conf.APIKeysFile)
+	if err != nil {
+		return nil, err
+	}
+
+	authenticators := auth.Authenticators{keys}
+	if conf.JWT.HS256Secret == "" && conf.JWT.RS256PublicKeyFile == "" {
+		return authenticators, nil
+	}
+
+	jwtConf := auth.JWTConfig{
+		Issuer:     conf.JWT.Issuer,
+		Audience:   conf.JWT.Audience,
+		HMACSecret: []byte(conf.JWT.HS256Secret),
+	}
+
+	if conf.JWT.RS256PublicKeyFile != "" {
+		b, err := os.ReadFile(conf.JWT.RS256PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		jwtConf.RSAPublicKey, err = jwt.ParseRSAPublicKeyFromPEM(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	jwtAuthenticator, err := auth.NewJWTAuthenticator(jwtConf)
+	if err != nil {
+		return nil, err
+	}
+	return append(authenticators, jwtAuthenticator), nil
+}
+
+// newHealth returns the health with the readiness checks of
+// the store, the file store and the disk free of the store path.
+func newHealth(conf *config.Config, store note.Store, fileStore *filestore.Store) *health.Health {
+	h := health.New(conf.Health.CheckTimeout)
+	h.Add("store", health.CheckerFunc(func(ctx context.Context) error {
+		iter, err := store.Fetch(ctx, &note.Pagination{Size: 1, Page: 1})
+		if err != nil {
+			return err
+		}
+		return iter.Close()
+	}))
+	h.Add("file_store", health.CheckerFunc(fileStore.Init))
+	h.Add("disk", health.DiskFree(conf.Store.File.Path, conf.Health.MinDiskFree))
+	return h
+}
+
+// linkSecret returns the secret of the link tokens, or a random
+// one when it is not set in the config.
+func linkSecret(secret string) []byte {
+	if secret != "" {
+		return []byte(secret)
+	}
+
+	logrus.Warn("links.secret is not set, the public links won't survive a restart")
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	mustNoError(err)
+	return b
 }
 
 func mustNoError(err error) {
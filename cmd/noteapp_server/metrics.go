@@ -0,0 +1,85 @@
+package main
+
+import (
+	"github.com/go-kit/kit/metrics"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	filestore "noteapp/note/store/file"
+)
+
+// metricsNamespace is the namespace of the Prometheus metrics.
+const metricsNamespace = "noteapp"
+
+// requestMetrics returns the request counter and the request
+// latency histogram of the subsystem, e.g. "http" or "store".
+func requestMetrics(subsystem string, counterLabels, histogramLabels []string) (metrics.Counter, metrics.Histogram) {
+	count := kitprometheus.NewCounterFrom(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: subsystem,
+		Name:      "requests_total",
+		Help:      "Number of requests.",
+	}, counterLabels)
+
+	latency := kitprometheus.NewHistogramFrom(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: subsystem,
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the requests in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, histogramLabels)
+
+	return count, latency
+}
+
+// fileStoreCollector collects the gauges of the file store
+// from a single snapshot of its statistics.
+type fileStoreCollector struct {
+	store *filestore.Store
+
+	notes            *prometheus.Desc
+	fileSize         *prometheus.Desc
+	lastSyncDuration *prometheus.Desc
+}
+
+func newFileStoreCollector(store *filestore.Store) *fileStoreCollector {
+	name := func(n string) string {
+		return prometheus.BuildFQName(metricsNamespace, "file_store", n)
+	}
+
+	return &fileStoreCollector{
+		store:            store,
+		notes:            prometheus.NewDesc(name("notes"), "Number of notes in the file store.", nil, nil),
+		fileSize:         prometheus.NewDesc(name("file_size_bytes"), "Size of the file of the file store in bytes.", nil, nil),
+		lastSyncDuration: prometheus.NewDesc(name("last_fsync_duration_seconds"), "Duration of the last fsync of the file in seconds.", nil, nil),
+	}
+}
+
+func (c *fileStoreCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.notes
+	ch <- c.fileSize
+	ch <- c.lastSyncDuration
+}
+
+func (c *fileStoreCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.store.Stats()
+	if err != nil {
+		logrus.WithError(err).Error("collecting the file store metrics")
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.notes, prometheus.GaugeValue, float64(stats.Notes))
+	ch <- prometheus.MustNewConstMetric(c.fileSize, prometheus.GaugeValue, float64(stats.FileSize))
+	ch <- prometheus.MustNewConstMetric(c.lastSyncDuration, prometheus.GaugeValue, stats.LastSyncDuration.Seconds())
+}
+
+// droppedDeliveriesCounter returns the counter of the webhook
+// deliveries dropped because the dispatcher is overloaded.
+func droppedDeliveriesCounter() metrics.Counter {
+	return kitprometheus.NewCounterFrom(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "webhook",
+		Name:      "dropped_deliveries_total",
+		Help:      "Number of webhook deliveries dropped because the dispatcher is overloaded.",
+	}, nil)
+}
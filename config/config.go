@@ -4,6 +4,7 @@ import (
 	"github.com/spf13/afero"
 	"github.com/spf13/viper"
 	"sync"
+	"time"
 )
 
 var (
@@ -11,6 +12,10 @@ var (
 	conf *Config
 )
 
+// defaultContentSecurityPolicy only allows the inline style and
+// the password form of the shared note page.
+const defaultContentSecurityPolicy = "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; frame-ancestors 'none'; base-uri 'none'"
+
 // New initializes the configuration setting. It searches for the
 // config file with a filename of "config.yaml".
 //
@@ -56,6 +61,106 @@ func newConfig(fs afero.Fs) (*Config, error) {
 		viper.Set("server.port", 50001)
 	}
 
+	if viper.Get("server.grpc_port") == nil {
+		viper.Set("server.grpc_port", 50002)
+	}
+
+	if viper.Get("server.shutdown_delay") == nil {
+		viper.Set("server.shutdown_delay", "5s")
+	}
+
+	if viper.Get("server.drain_timeout") == nil {
+		viper.Set("server.drain_timeout", "20s")
+	}
+
+	if viper.Get("server.tls.min_version") == nil {
+		viper.Set("server.tls.min_version", "1.2")
+	}
+
+	if viper.Get("validation.max_title_length") == nil {
+		viper.Set("validation.max_title_length", 255)
+	}
+
+	if viper.Get("validation.max_content_length") == nil {
+		viper.Set("validation.max_content_length", 1<<20)
+	}
+
+	if viper.Get("links.default_ttl") == nil {
+		viper.Set("links.default_ttl", "24h")
+	}
+
+	if viper.Get("links.max_ttl") == nil {
+		viper.Set("links.max_ttl", "720h")
+	}
+
+	if viper.Get("health.check_timeout") == nil {
+		viper.Set("health.check_timeout", "2s")
+	}
+
+	if viper.Get("health.min_disk_free") == nil {
+		viper.Set("health.min_disk_free", 100<<20)
+	}
+
+	if viper.Get("log.level") == nil {
+		viper.Set("log.level", "info")
+	}
+
+	if viper.Get("log.format") == nil {
+		viper.Set("log.format", "json")
+	}
+
+	if viper.Get("rate_limit.rate") == nil {
+		viper.Set("rate_limit.rate", 10)
+	}
+
+	if viper.Get("rate_limit.burst") == nil {
+		viper.Set("rate_limit.burst", 20)
+	}
+
+	if viper.Get("rate_limit.failed_auth_rate") == nil {
+		viper.Set("rate_limit.failed_auth_rate", 0.1)
+	}
+
+	if viper.Get("rate_limit.failed_auth_burst") == nil {
+		viper.Set("rate_limit.failed_auth_burst", 10)
+	}
+
+	if viper.Get("cors.allowed_methods") == nil {
+		viper.Set("cors.allowed_methods", []string{"GET", "POST", "PUT", "DELETE"})
+	}
+
+	if viper.Get("cors.allowed_headers") == nil {
+		viper.Set("cors.allowed_headers", []string{"Authorization", "Content-Type", "Last-Event-ID", "X-API-Key", "X-Request-ID"})
+	}
+
+	if viper.Get("cors.exposed_headers") == nil {
+		viper.Set("cors.exposed_headers", []string{"X-Request-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"})
+	}
+
+	if viper.Get("cors.max_age") == nil {
+		viper.Set("cors.max_age", "10m")
+	}
+
+	if viper.Get("security_headers.hsts_max_age") == nil {
+		viper.Set("security_headers.hsts_max_age", "8760h")
+	}
+
+	if viper.Get("security_headers.frame_options") == nil {
+		viper.Set("security_headers.frame_options", "DENY")
+	}
+
+	if viper.Get("security_headers.content_security_policy") == nil {
+		viper.Set("security_headers.content_security_policy", defaultContentSecurityPolicy)
+	}
+
+	if viper.Get("compression.min_size") == nil {
+		viper.Set("compression.min_size", 1024)
+	}
+
+	if viper.Get("tracing.sample_ratio") == nil {
+		viper.Set("tracing.sample_ratio", 1)
+	}
+
 	var conf Config
 	err = viper.Unmarshal(&conf)
 	if err != nil {
@@ -71,6 +176,30 @@ type Config struct {
 	Server Server
 	// Store Database Configuration
 	Store Store
+	// Validation contains the validation rules of the notes.
+	Validation Validation
+	// Auth contains the authentication of the requests.
+	Auth Auth
+	// Links contains the public links of the notes.
+	Links Links
+	// Health contains the readiness checks of the server.
+	Health Health
+	// Tracing contains the export of the OpenTelemetry spans.
+	Tracing Tracing
+	// Log contains the level and the format of the logs.
+	Log Log
+	// RateLimit contains the limits of the requests of each client.
+	RateLimit RateLimit `mapstructure:"rate_limit"`
+	// Quota contains the storage quota of each owner.
+	Quota Quota
+	// CORS contains the cross-origin requests allowed.
+	CORS CORS
+	// SecurityHeaders contains the security headers of the responses.
+	SecurityHeaders SecurityHeaders `mapstructure:"security_headers"`
+	// Compression contains the compression of the responses.
+	Compression Compression
+	// Webhook contains the delivery of the webhooks.
+	Webhook Webhook
 }
 
 // Server contains the server configuration.
@@ -78,6 +207,39 @@ type Server struct {
 	// Port is the port of the server when its value is empty
 	// in config file the default "50001" will be use.
 	Port int
+	// GRPCPort is the port of the gRPC server when its value is empty
+	// in config file the default "50002" will be use.
+	GRPCPort int `mapstructure:"grpc_port"`
+	// ShutdownDelay is the time between failing the readiness and
+	// shutting down the server, so that the load balancers see the
+	// server is not ready before it stops accepting connections. When
+	// its value is empty in config file the default "5s" will be use.
+	ShutdownDelay time.Duration `mapstructure:"shutdown_delay"`
+	// DrainTimeout is the time the in-flight requests have to finish
+	// when the server shuts down before their connections are closed.
+	// When its value is empty in config file the default "20s" will be use.
+	DrainTimeout time.Duration `mapstructure:"drain_timeout"`
+	// TLS contains the TLS of the API server. The server serves
+	// plain HTTP when its cert file is empty.
+	TLS TLS
+}
+
+// TLS contains the TLS configuration of the API server.
+type TLS struct {
+	// CertFile is the path of the PEM encoded certificate chain.
+	CertFile string `mapstructure:"cert_file"`
+	// KeyFile is the path of the PEM encoded private key.
+	KeyFile string `mapstructure:"key_file"`
+	// MinVersion is the minimum TLS version, e.g. "1.3". When its
+	// value is empty in config file the default "1.2" will be use.
+	MinVersion string `mapstructure:"min_version"`
+	// ClientCAFile is the path of the PEM encoded certificates of the
+	// authorities of the client certificates. The clients authenticated
+	// with a certificate act as the common name of the certificate.
+	ClientCAFile string `mapstructure:"client_ca_file"`
+	// RequireClientCert rejects the connections without a client
+	// certificate, including the ones of the probes.
+	RequireClientCert bool `mapstructure:"require_client_cert"`
 }
 
 // Store contains the store database configuration.
@@ -91,3 +253,231 @@ type File struct {
 	// When its value is empty in config file the default "." will be use.
 	Path string
 }
+
+// Validation contains the validation rules of the notes.
+type Validation struct {
+	// MaxTitleLength is the maximum number of characters of the title.
+	// When its value is empty in config file the default "255" will be use.
+	MaxTitleLength int `mapstructure:"max_title_length"`
+	// MaxContentLength is the maximum number of characters of the content.
+	// When its value is empty in config file the default "1048576" will be use.
+	MaxContentLength int `mapstructure:"max_content_length"`
+	// RequireTitle rejects the notes without a title.
+	RequireTitle bool `mapstructure:"require_title"`
+	// AllowedTitleCharacters is a regular expression character class of
+	// the characters allowed in the title, e.g. "\\p{L}\\p{N} ". All but the
+	// control characters are allowed when its value is empty.
+	AllowedTitleCharacters string `mapstructure:"allowed_title_characters"`
+	// AllowedContentCharacters is the same as the AllowedTitleCharacters
+	// for the content.
+	AllowedContentCharacters string `mapstructure:"allowed_content_characters"`
+	// RejectServerOwnedFields rejects the notes setting the timestamps.
+	RejectServerOwnedFields bool `mapstructure:"reject_server_owned_fields"`
+}
+
+// Auth contains the authentication configuration. The requests are
+// anonymous unless it is enabled.
+type Auth struct {
+	// Enabled rejects the requests without valid credentials.
+	Enabled bool
+	// APIKeys are the static api keys.
+	APIKeys []APIKey `mapstructure:"api_keys"`
+	// APIKeysFile is the path of the api key file managed
+	// with the "noteapp_cli auth keys" commands.
+	APIKeysFile string `mapstructure:"api_keys_file"`
+	// JWT contains the verification of the JWT bearer tokens.
+	JWT JWT
+}
+
+// APIKey is a static api key.
+type APIKey struct {
+	ID string
+	// Subject is the user id the key authenticates.
+	Subject string
+	// Hash is the "sha256:" prefixed hex SHA-256 of the key.
+	Hash string
+}
+
+// JWT contains the verification of the JWT bearer tokens. The tokens
+// are not accepted when neither of the keys is set.
+type JWT struct {
+	// Issuer is the expected "iss" claim when it is not empty.
+	Issuer string
+	// Audience is the expected "aud" claim when it is not empty.
+	Audience string
+	// HS256Secret is the secret of the HS256 signed tokens.
+	HS256Secret string `mapstructure:"hs256_secret"`
+	// RS256PublicKeyFile is the path of the PEM encoded public
+	// key of the RS256 signed tokens.
+	RS256PublicKeyFile string `mapstructure:"rs256_public_key_file"`
+}
+
+// Links contains the configuration of the public links of the notes.
+type Links struct {
+	// Secret is the key signing the link tokens. When its value is
+	// empty a random key is used, so the links stop working when the
+	// server restarts.
+	Secret string
+	// DefaultTTL is the lifetime of the links created without one.
+	// When its value is empty in config file the default "24h" will be use.
+	DefaultTTL time.Duration `mapstructure:"default_ttl"`
+	// MaxTTL is the longest lifetime of a link. When its value is
+	// empty in config file the default "720h" will be use.
+	MaxTTL time.Duration `mapstructure:"max_ttl"`
+}
+
+// Health contains the configuration of the readiness checks.
+type Health struct {
+	// CheckTimeout is the time each check has before it fails. When
+	// its value is empty in config file the default "2s" will be use.
+	CheckTimeout time.Duration `mapstructure:"check_timeout"`
+	// MinDiskFree is the bytes which should be free on the disk of
+	// the store path for the server to be ready. When its value is
+	// empty in config file the default "104857600" will be use.
+	MinDiskFree uint64 `mapstructure:"min_disk_free"`
+}
+
+// Tracing contains the configuration of the OpenTelemetry tracing.
+type Tracing struct {
+	// Exporter is where the spans are exported, either "stdout" or
+	// "otlp". The tracing is disabled when its value is empty.
+	Exporter string
+	// Endpoint is the "host:port" of the OTLP gRPC collector. When its
+	// value is empty the exporter default "localhost:4317" will be use.
+	Endpoint string
+	// Insecure connects to the OTLP collector without TLS.
+	Insecure bool
+	// SampleRatio is the ratio of the new traces sampled, between 0
+	// and 1. The traces continued from the clients are sampled when the
+	// clients sampled them. When its value is empty in config file the
+	// default "1" will be use.
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+// Log contains the configuration of the logs.
+type Log struct {
+	// Level is the minimum level of the logged lines, e.g. "debug". When
+	// its value is empty in config file the default "info" will be use.
+	Level string
+	// Format is the format of the logged lines, either "json" or "text".
+	// When its value is empty in config file the default "json" will be use.
+	Format string
+}
+
+// RateLimit contains the configuration of the rate limiting of the
+// requests. The authenticated clients are limited by their api key or
+// their user, the others by their ip address.
+type RateLimit struct {
+	// Enabled rejects the requests over the limits.
+	Enabled bool
+	// Rate is the requests per second of each client on the routes
+	// without their own limit. When its value is empty in config
+	// file the default "10" will be use.
+	Rate float64
+	// Burst is the requests of each client allowed at once on the
+	// routes without their own limit. When its value is empty in
+	// config file the default "20" will be use.
+	Burst int
+	// Routes are the routes with their own limit.
+	Routes []RouteRateLimit
+	// FailedAuthRate is the failed authentications per second of each
+	// ip address, whose requests are rejected once over the limit. When
+	// its value is empty in config file the default "0.1" will be use.
+	FailedAuthRate float64 `mapstructure:"failed_auth_rate"`
+	// FailedAuthBurst is the failed authentications of each ip address
+	// allowed at once. When its value is empty in config file the
+	// default "10" will be use.
+	FailedAuthBurst int `mapstructure:"failed_auth_burst"`
+}
+
+// RouteRateLimit is the limit of a route.
+type RouteRateLimit struct {
+	// Method is the http method of the route, e.g. "POST".
+	Method string
+	// Path is the path template of the route, e.g. "/v1/note/{id}".
+	Path string
+	// Rate is the requests per second of each client. The route
+	// is not limited when its value is zero.
+	Rate float64
+	// Burst is the requests of each client allowed at once.
+	Burst int
+}
+
+// Quota contains the storage quota of each owner. The zero
+// values are unlimited.
+type Quota struct {
+	// MaxNotes is the maximum number of notes of an owner.
+	MaxNotes int `mapstructure:"max_notes"`
+	// MaxBytes is the maximum total size of the titles and the
+	// contents of the notes of an owner.
+	MaxBytes int64 `mapstructure:"max_bytes"`
+}
+
+// CORS contains the configuration of the cross-origin requests of
+// the browsers.
+type CORS struct {
+	// Enabled allows the cross-origin requests of the allowed origins.
+	Enabled bool
+	// AllowedOrigins are the origins allowed to call the API, e.g.
+	// "https://app.example.com". The "*" origin allows all of them.
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+	// AllowedMethods are the methods of the cross-origin requests. When
+	// its value is empty in config file the default "GET, POST, PUT,
+	// DELETE" will be use.
+	AllowedMethods []string `mapstructure:"allowed_methods"`
+	// AllowedHeaders are the request headers of the cross-origin
+	// requests. When its value is empty in config file the default
+	// "Authorization, Content-Type, Last-Event-ID, X-API-Key,
+	// X-Request-ID" will be use.
+	AllowedHeaders []string `mapstructure:"allowed_headers"`
+	// ExposedHeaders are the response headers exposed to the callers.
+	// When its value is empty in config file the default "X-Request-ID"
+	// and the rate limiting headers will be use.
+	ExposedHeaders []string `mapstructure:"exposed_headers"`
+	// AllowCredentials allows the requests with the cookies or the
+	// authorization headers. It can't be set with the "*" origin.
+	AllowCredentials bool `mapstructure:"allow_credentials"`
+	// MaxAge is how long the browsers cache the preflight responses.
+	// When its value is empty in config file the default "10m" will be use.
+	MaxAge time.Duration `mapstructure:"max_age"`
+}
+
+// SecurityHeaders contains the configuration of the security headers
+// of the responses.
+type SecurityHeaders struct {
+	// Enabled adds the security headers to the responses.
+	Enabled bool
+	// HSTSMaxAge is the max-age of the Strict-Transport-Security header.
+	// When its value is empty in config file the default "8760h" will
+	// be use, the header is not sent when its value is "0s".
+	HSTSMaxAge time.Duration `mapstructure:"hsts_max_age"`
+	// HSTSIncludeSubdomains applies the HSTS to the subdomains too.
+	HSTSIncludeSubdomains bool `mapstructure:"hsts_include_subdomains"`
+	// FrameOptions is the X-Frame-Options header. When its value is
+	// empty in config file the default "DENY" will be use.
+	FrameOptions string `mapstructure:"frame_options"`
+	// ContentSecurityPolicy is the Content-Security-Policy header. When
+	// its value is empty in config file the default only allowing the
+	// shared note page will be use.
+	ContentSecurityPolicy string `mapstructure:"content_security_policy"`
+}
+
+// Compression contains the configuration of the gzip and brotli
+// compression of the responses.
+type Compression struct {
+	// Enabled compresses the responses of the clients accepting it.
+	Enabled bool
+	// MinSize is the size in bytes under which the responses are not
+	// compressed. When its value is empty in config file the default
+	// "1024" will be use.
+	MinSize int `mapstructure:"min_size"`
+}
+
+// Webhook contains the configuration of the webhook deliveries.
+type Webhook struct {
+	// AllowedNetworks are the CIDRs of the internal networks the
+	// deliveries can be sent to, e.g. "10.0.0.0/8". The deliveries to
+	// the loopback, private and link-local addresses are refused
+	// unless they are in one of them.
+	AllowedNetworks []string `mapstructure:"allowed_networks"`
+}
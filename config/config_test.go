@@ -5,6 +5,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func Test(t *testing.T) {
@@ -47,16 +48,168 @@ store:
   file:
     path: /test
 server:
-  port: 8080`,
+  port: 8080
+  shutdown_delay: 0s
+  drain_timeout: 1m
+  tls:
+    cert_file: /etc/noteapp/tls/tls.crt
+    key_file: /etc/noteapp/tls/tls.key
+    min_version: "1.3"
+    client_ca_file: /etc/noteapp/tls/ca.crt
+    require_client_cert: true
+validation:
+  max_title_length: 100
+  require_title: true
+  allowed_title_characters: "\\p{L} "
+auth:
+  enabled: true
+  api_keys:
+    - id: ci
+      subject: alice
+      hash: sha256:abc
+  api_keys_file: /etc/noteapp/api_keys.json
+  jwt:
+    issuer: https://issuer.example.com
+    audience: noteapp
+    hs256_secret: secret
+links:
+  secret: link-secret
+  default_ttl: 1h
+health:
+  check_timeout: 5s
+  min_disk_free: 1024
+tracing:
+  exporter: otlp
+  endpoint: collector:4317
+  insecure: true
+  sample_ratio: 0.25
+log:
+  level: debug
+  format: text
+rate_limit:
+  enabled: true
+  rate: 5
+  burst: 10
+  routes:
+    - method: POST
+      path: /v1/note
+      rate: 0.5
+      burst: 2
+  failed_auth_rate: 1
+  failed_auth_burst: 5
+quota:
+  max_notes: 100
+  max_bytes: 1048576
+cors:
+  enabled: true
+  allowed_origins:
+    - https://app.example.com
+  allowed_methods: [GET]
+  allowed_headers: [Authorization]
+  exposed_headers: [X-Request-ID]
+  allow_credentials: true
+  max_age: 1h
+security_headers:
+  enabled: true
+  hsts_max_age: 0s
+  hsts_include_subdomains: true
+  frame_options: SAMEORIGIN
+  content_security_policy: "default-src 'self'"
+compression:
+  enabled: true
+  min_size: 256
+webhook:
+  allowed_networks:
+    - 10.0.0.0/8`,
 			want: &Config{
 				Server: Server{
-					Port: 8080,
+					Port:         8080,
+					GRPCPort:     50002,
+					DrainTimeout: time.Minute,
+					TLS: TLS{
+						CertFile:          "/etc/noteapp/tls/tls.crt",
+						KeyFile:           "/etc/noteapp/tls/tls.key",
+						MinVersion:        "1.3",
+						ClientCAFile:      "/etc/noteapp/tls/ca.crt",
+						RequireClientCert: true,
+					},
 				},
 				Store: Store{
 					File: File{
 						Path: "/test",
 					},
 				},
+				Validation: Validation{
+					MaxTitleLength:         100,
+					MaxContentLength:       1 << 20,
+					RequireTitle:           true,
+					AllowedTitleCharacters: `\p{L} `,
+				},
+				Auth: Auth{
+					Enabled:     true,
+					APIKeys:     []APIKey{{ID: "ci", Subject: "alice", Hash: "sha256:abc"}},
+					APIKeysFile: "/etc/noteapp/api_keys.json",
+					JWT: JWT{
+						Issuer:      "https://issuer.example.com",
+						Audience:    "noteapp",
+						HS256Secret: "secret",
+					},
+				},
+				Links: Links{
+					Secret:     "link-secret",
+					DefaultTTL: time.Hour,
+					MaxTTL:     720 * time.Hour,
+				},
+				Health: Health{
+					CheckTimeout: 5 * time.Second,
+					MinDiskFree:  1024,
+				},
+				Tracing: Tracing{
+					Exporter:    "otlp",
+					Endpoint:    "collector:4317",
+					Insecure:    true,
+					SampleRatio: 0.25,
+				},
+				Log: Log{
+					Level:  "debug",
+					Format: "text",
+				},
+				RateLimit: RateLimit{
+					Enabled: true,
+					Rate:    5,
+					Burst:   10,
+					Routes: []RouteRateLimit{
+						{Method: "POST", Path: "/v1/note", Rate: 0.5, Burst: 2},
+					},
+					FailedAuthRate:  1,
+					FailedAuthBurst: 5,
+				},
+				Quota: Quota{
+					MaxNotes: 100,
+					MaxBytes: 1 << 20,
+				},
+				CORS: CORS{
+					Enabled:          true,
+					AllowedOrigins:   []string{"https://app.example.com"},
+					AllowedMethods:   []string{"GET"},
+					AllowedHeaders:   []string{"Authorization"},
+					ExposedHeaders:   []string{"X-Request-ID"},
+					AllowCredentials: true,
+					MaxAge:           time.Hour,
+				},
+				SecurityHeaders: SecurityHeaders{
+					Enabled:               true,
+					HSTSIncludeSubdomains: true,
+					FrameOptions:          "SAMEORIGIN",
+					ContentSecurityPolicy: "default-src 'self'",
+				},
+				Compression: Compression{
+					Enabled: true,
+					MinSize: 256,
+				},
+				Webhook: Webhook{
+					AllowedNetworks: []string{"10.0.0.0/8"},
+				},
 			},
 		},
 		{
@@ -65,13 +218,58 @@ server:
 			input:    ``,
 			want: &Config{
 				Server: Server{
-					Port: 50001,
+					Port:          50001,
+					GRPCPort:      50002,
+					ShutdownDelay: 5 * time.Second,
+					DrainTimeout:  20 * time.Second,
+					TLS: TLS{
+						MinVersion: "1.2",
+					},
 				},
 				Store: Store{
 					File: File{
 						Path: ".",
 					},
 				},
+				Validation: Validation{
+					MaxTitleLength:   255,
+					MaxContentLength: 1 << 20,
+				},
+				Links: Links{
+					DefaultTTL: 24 * time.Hour,
+					MaxTTL:     720 * time.Hour,
+				},
+				Health: Health{
+					CheckTimeout: 2 * time.Second,
+					MinDiskFree:  100 << 20,
+				},
+				Tracing: Tracing{
+					SampleRatio: 1,
+				},
+				Log: Log{
+					Level:  "info",
+					Format: "json",
+				},
+				RateLimit: RateLimit{
+					Rate:            10,
+					Burst:           20,
+					FailedAuthRate:  0.1,
+					FailedAuthBurst: 10,
+				},
+				CORS: CORS{
+					AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
+					AllowedHeaders: []string{"Authorization", "Content-Type", "Last-Event-ID", "X-API-Key", "X-Request-ID"},
+					ExposedHeaders: []string{"X-Request-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"},
+					MaxAge:         10 * time.Minute,
+				},
+				SecurityHeaders: SecurityHeaders{
+					HSTSMaxAge:            365 * 24 * time.Hour,
+					FrameOptions:          "DENY",
+					ContentSecurityPolicy: defaultContentSecurityPolicy,
+				},
+				Compression: Compression{
+					MinSize: 1024,
+				},
 			},
 		},
 		//		{
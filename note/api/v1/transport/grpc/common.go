@@ -0,0 +1,78 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"noteapp/note"
+	"noteapp/note/proto/protoutil"
+	"noteapp/pkg/logging"
+)
+
+// encodeError converts err into a gRPC status error. A validation
+// error has the invalid fields in its bad request details. Only the
+// unexpected errors are logged at the error level with the logger of
+// ctx, the others are the mistakes of the clients.
+func encodeError(ctx context.Context, err error) error {
+	var code codes.Code
+	var message string
+
+	var validationErr *note.ValidationError
+	if errors.As(err, &validationErr) {
+		logging.Logger(ctx).Debug(err)
+		return encodeValidationError(validationErr)
+	}
+
+	switch {
+	case errors.Is(err, note.ErrNotFound):
+		code, message = codes.NotFound, "Note not found"
+	case errors.Is(err, note.ErrExists):
+		code, message = codes.AlreadyExists, "Note already exists"
+	case errors.Is(err, note.ErrNilID):
+		code, message = codes.InvalidArgument, "Empty note identifier"
+	case errors.Is(err, note.ErrNilNote):
+		code, message = codes.InvalidArgument, "Empty note"
+	case errors.Is(err, note.ErrPermissionDenied):
+		code, message = codes.PermissionDenied, "Permission denied"
+	case errors.Is(err, note.ErrQuotaExceeded):
+		code, message = codes.ResourceExhausted, "Quota exceeded"
+	case errors.Is(err, protoutil.ErrMissingNote), errors.Is(err, protoutil.ErrInvalidID), errors.Is(err, protoutil.ErrInvalidMask):
+		code, message = codes.InvalidArgument, err.Error()
+	case errors.Is(err, context.Canceled):
+		code, message = codes.Canceled, "Request cancelled"
+	case errors.Is(err, context.DeadlineExceeded):
+		code, message = codes.DeadlineExceeded, "Request deadline exceeded"
+	default:
+		logging.Logger(ctx).Error(err)
+		return status.Error(codes.Internal, "Unexpected error")
+	}
+
+	logging.Logger(ctx).Debug(err)
+	return status.Error(code, message)
+}
+
+func encodeValidationError(err *note.ValidationError) error {
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(err.Fields))
+	for _, f := range err.Fields {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Message})
+	}
+
+	st, detailsErr := status.New(codes.InvalidArgument, "Invalid note").
+		WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if detailsErr != nil {
+		return status.Error(codes.InvalidArgument, "Invalid note")
+	}
+	return st.Err()
+}
+
+func decodeID(s string) (uuid.UUID, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: %s", protoutil.ErrInvalidID, err)
+	}
+	return id, nil
+}
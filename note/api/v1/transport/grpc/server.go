@@ -0,0 +1,135 @@
+package grpc
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"noteapp/note"
+	pb "noteapp/note/proto"
+	"noteapp/note/proto/protoutil"
+)
+
+var _ pb.NoteServiceServer = (*Server)(nil)
+
+// Register registers the note service svc to the gRPC server s.
+func Register(s *grpc.Server, svc note.Service) {
+	pb.RegisterNoteServiceServer(s, NewServer(svc))
+}
+
+// NewServer takes the note service and returns a server that
+// adapts it to the gRPC NoteService.
+func NewServer(svc note.Service) *Server {
+	return &Server{svc: svc}
+}
+
+// Server implements the pb.NoteServiceServer interface.
+type Server struct {
+	pb.UnimplementedNoteServiceServer
+	svc note.Service
+}
+
+// Create creates a new note with optional value in id.
+func (s *Server) Create(ctx context.Context, req *pb.CreateRequest) (*pb.CreateResponse, error) {
+	n, err := protoutil.DecodeNote(req.GetNote(), nil)
+	if err != nil {
+		return nil, encodeError(ctx, err)
+	}
+
+	created, err := s.svc.Create(ctx, n)
+	if err != nil {
+		return nil, encodeError(ctx, err)
+	}
+
+	return &pb.CreateResponse{Note: protoutil.EncodeNote(created)}, nil
+}
+
+// Get gets the note with an id.
+func (s *Server) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
+	id, err := decodeID(req.GetId())
+	if err != nil {
+		return nil, encodeError(ctx, err)
+	}
+
+	n, err := s.svc.Get(ctx, id)
+	if err != nil {
+		return nil, encodeError(ctx, err)
+	}
+
+	return &pb.GetResponse{Note: protoutil.EncodeNote(n)}, nil
+}
+
+// Update updates the fields in the update mask of an existing note.
+func (s *Server) Update(ctx context.Context, req *pb.UpdateRequest) (*pb.UpdateResponse, error) {
+	n, err := protoutil.DecodeNote(req.GetNote(), req.GetUpdateMask().GetPaths())
+	if err != nil {
+		return nil, encodeError(ctx, err)
+	}
+
+	if n.ID == uuid.Nil {
+		return nil, encodeError(ctx, note.ErrNilID)
+	}
+
+	updated, err := s.svc.Update(ctx, n)
+	if err != nil {
+		return nil, encodeError(ctx, err)
+	}
+
+	return &pb.UpdateResponse{Note: protoutil.EncodeNote(updated)}, nil
+}
+
+// Delete deletes an existing note with an id.
+func (s *Server) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	id, err := decodeID(req.GetId())
+	if err != nil {
+		return nil, encodeError(ctx, err)
+	}
+
+	if err := s.svc.Delete(ctx, id); err != nil {
+		return nil, encodeError(ctx, err)
+	}
+
+	return &pb.DeleteResponse{}, nil
+}
+
+// Fetch streams the notes of the requested page.
+func (s *Server) Fetch(req *pb.FetchRequest, stream pb.NoteService_FetchServer) (err error) {
+	ctx := stream.Context()
+
+	iter, err := s.svc.Fetch(ctx, &note.Pagination{
+		Size:         req.GetSize(),
+		Page:         req.GetPage(),
+		SortBy:       note.GetSortBy(req.GetSortBy()),
+		SharedWithMe: req.GetSharedWithMe(),
+	})
+	if err != nil {
+		return encodeError(ctx, err)
+	}
+
+	defer func() {
+		cerr := iter.Close()
+		if cerr != nil && err == nil {
+			err = encodeError(ctx, cerr)
+		}
+	}()
+
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return encodeError(ctx, err)
+		}
+
+		err := stream.Send(&pb.FetchResponse{
+			Note:       protoutil.EncodeNote(iter.Note()),
+			TotalCount: iter.TotalCount(),
+			TotalPage:  iter.TotalPage(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := iter.Error(); err != nil {
+		return encodeError(ctx, err)
+	}
+
+	return nil
+}
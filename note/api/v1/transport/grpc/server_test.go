@@ -0,0 +1,178 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"io"
+	"net"
+	"noteapp/note"
+	pb "noteapp/note/proto"
+	"noteapp/note/service"
+	"noteapp/note/store/memory"
+	"testing"
+)
+
+var dummyCtx = context.TODO()
+
+func Test(t *testing.T) {
+	suite.Run(t, new(ServerTestSuite))
+}
+
+type ServerTestSuite struct {
+	suite.Suite
+	svc    note.Service
+	server *grpc.Server
+	conn   *grpc.ClientConn
+	client pb.NoteServiceClient
+}
+
+func (s *ServerTestSuite) SetupTest() {
+	listener := bufconn.Listen(1024 * 1024)
+	s.svc = service.New(memory.New())
+	server := grpc.NewServer()
+	Register(server, s.svc)
+	go func() { _ = server.Serve(listener) }()
+	s.server = server
+
+	conn, err := grpc.DialContext(dummyCtx, "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithInsecure(),
+	)
+	s.Require().NoError(err)
+	s.conn = conn
+	s.client = pb.NewNoteServiceClient(conn)
+}
+
+func (s *ServerTestSuite) TearDownTest() {
+	_ = s.conn.Close()
+	s.server.Stop()
+}
+
+func (s *ServerTestSuite) assertCode(err error, want codes.Code) {
+	s.Require().Error(err)
+	s.Equal(want, status.Code(err))
+}
+
+func (s *ServerTestSuite) TestCreateAndGet() {
+	created, err := s.client.Create(dummyCtx, &pb.CreateRequest{Note: &pb.Note{Title: "gRPC", Content: "Content", IsFavorite: true}})
+	s.Require().NoError(err)
+	s.NotEmpty(created.GetNote().GetId())
+	s.NotNil(created.GetNote().GetCreatedTime())
+	s.Nil(created.GetNote().GetUpdatedTime())
+
+	got, err := s.client.Get(dummyCtx, &pb.GetRequest{Id: string(created.GetNote().GetId())})
+	s.Require().NoError(err)
+	s.Equal("gRPC", got.GetNote().GetTitle())
+	s.True(got.GetNote().GetIsFavorite())
+
+	s.Run("Creating an existing note should return AlreadyExists", func() {
+		_, err := s.client.Create(dummyCtx, &pb.CreateRequest{Note: created.GetNote()})
+		s.assertCode(err, codes.AlreadyExists)
+	})
+
+	s.Run("Creating without a note should return InvalidArgument", func() {
+		_, err := s.client.Create(dummyCtx, &pb.CreateRequest{})
+		s.assertCode(err, codes.InvalidArgument)
+	})
+
+	s.Run("Getting a non-existing note should return NotFound", func() {
+		_, err := s.client.Get(dummyCtx, &pb.GetRequest{Id: uuid.New().String()})
+		s.assertCode(err, codes.NotFound)
+	})
+
+	s.Run("Getting with an invalid id should return InvalidArgument", func() {
+		_, err := s.client.Get(dummyCtx, &pb.GetRequest{Id: "invalid"})
+		s.assertCode(err, codes.InvalidArgument)
+	})
+}
+
+func (s *ServerTestSuite) TestUpdate() {
+	n, err := s.svc.Create(dummyCtx, new(note.Note).SetTitle("Title").SetContent("Content"))
+	s.Require().NoError(err)
+
+	s.Run("Updating only the fields in the mask", func() {
+		resp, err := s.client.Update(dummyCtx, &pb.UpdateRequest{
+			Note:       &pb.Note{Id: []byte(n.ID.String()), Content: "Updated"},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"content"}},
+		})
+		s.Require().NoError(err)
+		s.Equal("Title", resp.GetNote().GetTitle())
+		s.Equal("Updated", resp.GetNote().GetContent())
+		s.NotNil(resp.GetNote().GetUpdatedTime())
+	})
+
+	s.Run("Unknown field in the mask should return InvalidArgument", func() {
+		_, err := s.client.Update(dummyCtx, &pb.UpdateRequest{
+			Note:       &pb.Note{Id: []byte(n.ID.String())},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"created_time"}},
+		})
+		s.assertCode(err, codes.InvalidArgument)
+	})
+
+	s.Run("Updating a non-existing note should return NotFound", func() {
+		_, err := s.client.Update(dummyCtx, &pb.UpdateRequest{Note: &pb.Note{Id: []byte(uuid.New().String())}})
+		s.assertCode(err, codes.NotFound)
+	})
+
+	s.Run("Updating without id should return InvalidArgument", func() {
+		_, err := s.client.Update(dummyCtx, &pb.UpdateRequest{Note: &pb.Note{Title: "No ID"}})
+		s.assertCode(err, codes.InvalidArgument)
+	})
+}
+
+func (s *ServerTestSuite) TestDelete() {
+	n, err := s.svc.Create(dummyCtx, new(note.Note).SetTitle("Title"))
+	s.Require().NoError(err)
+
+	_, err = s.client.Delete(dummyCtx, &pb.DeleteRequest{Id: n.ID.String()})
+	s.Require().NoError(err)
+
+	_, err = s.svc.Get(dummyCtx, n.ID)
+	s.Equal(note.ErrNotFound, err)
+}
+
+func (s *ServerTestSuite) TestFetch() {
+	for i := 0; i < 20; i++ {
+		_, err := s.svc.Create(dummyCtx, new(note.Note).SetTitle(fmt.Sprintf("Title %02d", i)))
+		s.Require().NoError(err)
+	}
+
+	stream, err := s.client.Fetch(dummyCtx, &pb.FetchRequest{Size: 5, Page: 2, SortBy: "title"})
+	s.Require().NoError(err)
+
+	var got []string
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		s.Require().NoError(err)
+		s.Equal(uint64(20), resp.GetTotalCount())
+		s.Equal(uint64(4), resp.GetTotalPage())
+		got = append(got, resp.GetNote().GetTitle())
+	}
+
+	s.Equal([]string{"Title 05", "Title 06", "Title 07", "Title 08", "Title 09"}, got)
+
+	s.Run("Fetching the notes shared with me should not return my notes", func() {
+		stream, err := s.client.Fetch(dummyCtx, &pb.FetchRequest{SharedWithMe: true})
+		s.Require().NoError(err)
+
+		_, err = stream.Recv()
+		s.Equal(io.EOF, err)
+	})
+}
+
+func (s *ServerTestSuite) TestCancelled() {
+	ctx, cancel := context.WithCancel(dummyCtx)
+	cancel()
+	_, err := s.client.Get(ctx, &pb.GetRequest{Id: uuid.New().String()})
+	s.assertCode(err, codes.Canceled)
+}
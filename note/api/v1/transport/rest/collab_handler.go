@@ -0,0 +1,59 @@
+package rest
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"noteapp/note"
+	"noteapp/note/collab"
+	"noteapp/pkg/logging"
+)
+
+// collabHandler upgrades the request to a websocket and joins it
+// to the collaborative editing session of the note. The request can't
+// go through the go-kit server because the upgrade needs to take over
+// the connection of the request. The edits are saved by the hub, so
+// only the users who can update the note are let in.
+type collabHandler struct {
+	svc      getService
+	hub      *collab.Hub
+	grants   note.Grants
+	upgrader websocket.Upgrader
+}
+
+func newCollabHandler(svc getService, hub *collab.Hub, grants note.Grants) *collabHandler {
+	return &collabHandler{svc: svc, hub: hub, grants: grants}
+}
+
+func (h *collabHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := parseNoteID(mux.Vars(r)["id"])
+	if err != nil {
+		encodeError(r.Context(), newErrorWrapper(err), w)
+		return
+	}
+
+	// Check the note before the upgrade so that a missing
+	// note is reported with the usual error response.
+	n, err := h.svc.Get(r.Context(), id)
+	if err != nil {
+		encodeError(r.Context(), newErrorWrapper(err), w)
+		return
+	}
+
+	if !note.RoleOf(r.Context(), n, h.grants).CanWrite() {
+		encodeError(r.Context(), newErrorWrapper(note.ErrPermissionDenied), w)
+		return
+	}
+
+	// The upgrader replies with an error itself when it fails.
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logging.Logger(r.Context()).Error("rest/collab: ", err)
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	if err := h.hub.Join(r.Context(), id, conn); err != nil {
+		logging.Logger(r.Context()).Error("rest/collab: ", err)
+	}
+}
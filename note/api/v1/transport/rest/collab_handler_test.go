@@ -0,0 +1,94 @@
+package rest
+
+import (
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"noteapp/note/collab"
+	"noteapp/note/noteutil"
+	"strings"
+	"time"
+)
+
+func (s *HandlerTestSuite) TestCollab() {
+
+	setup := func() *httptest.Server {
+		hub := collab.NewHub(s.svc, nil, time.Hour)
+		return httptest.NewServer(makeCollabHandler(s.svc, hub, nil))
+	}
+
+	dial := func(srv *httptest.Server, id string) (*websocket.Conn, *http.Response, error) {
+		url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/note/" + id + "/collab"
+		return websocket.DefaultDialer.Dial(url, nil)
+	}
+
+	type message struct {
+		Type     string        `json:"type"`
+		Revision int           `json:"revision"`
+		Content  string        `json:"content,omitempty"`
+		Op       []interface{} `json:"op,omitempty"`
+	}
+
+	s.Run("Editing a note over a websocket", func() {
+		s.SetupTest()
+		srv := setup()
+		defer srv.Close()
+
+		n, err := s.svc.Create(dummyCtx, noteutil.Copy(dummyNote))
+		s.require.NoError(err)
+
+		first, _, err := dial(srv, n.ID.String())
+		s.require.NoError(err)
+		second, _, err := dial(srv, n.ID.String())
+		s.require.NoError(err)
+
+		var msg message
+		for _, conn := range []*websocket.Conn{first, second} {
+			s.require.NoError(conn.ReadJSON(&msg))
+			s.Equal("init", msg.Type)
+			s.Equal(dummyNote.GetContent(), msg.Content)
+		}
+
+		s.require.NoError(first.WriteJSON(message{Type: "op", Op: []interface{}{"Hey. ", len(dummyNote.GetContent())}}))
+
+		var ack message
+		s.require.NoError(first.ReadJSON(&ack))
+		s.Equal(message{Type: "ack", Revision: 1}, ack)
+
+		var op message
+		s.require.NoError(second.ReadJSON(&op))
+		s.Equal("op", op.Type)
+		s.Equal(1, op.Revision)
+
+		s.require.NoError(first.Close())
+		s.require.NoError(second.Close())
+
+		s.Eventually(func() bool {
+			got, err := s.svc.Get(dummyCtx, n.ID)
+			return err == nil && got.GetContent() == "Hey. "+dummyNote.GetContent()
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	s.Run("Editing a note that doesn't exist should return an error", func() {
+		s.SetupTest()
+		srv := setup()
+		defer srv.Close()
+
+		_, resp, err := dial(srv, uuid.New().String())
+		s.Error(err)
+		s.require.NotNil(resp)
+		s.Equal(http.StatusNotFound, resp.StatusCode)
+	})
+
+	s.Run("Invalid note id should return an error", func() {
+		s.SetupTest()
+		srv := setup()
+		defer srv.Close()
+
+		_, resp, err := dial(srv, "abc")
+		s.Error(err)
+		s.require.NotNil(resp)
+		s.Equal(http.StatusBadRequest, resp.StatusCode)
+	})
+}
@@ -3,92 +3,242 @@ package rest
 import (
 	"context"
 	"encoding/json"
-	"github.com/sirupsen/logrus"
+	"errors"
+	"fmt"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/google/uuid"
+	"io"
 	"net/http"
+	"noteapp/api/middleware"
+	"noteapp/api/problem"
 	"noteapp/note"
-	"noteapp/pkg/util/errorutil"
+	"noteapp/note/importer"
+	"noteapp/note/link"
+	"noteapp/note/proto/protoutil"
+	"noteapp/note/share"
+	"noteapp/note/webhook"
+	"noteapp/pkg/logging"
 )
 
 // StatusClientClosed is an http status where the client cancels a request.
 const StatusClientClosed = 499
 
+var (
+	// errInvalidID is an error when the note id in the path
+	// is not a valid uuid.
+	errInvalidID = errors.New("rest: invalid note id")
+	// errInvalidBody is an error when the request body can't be decoded.
+	errInvalidBody = errors.New("rest: invalid request body")
+	// errInvalidQuery is an error when a query parameter can't be decoded.
+	errInvalidQuery = errors.New("rest: invalid query parameter")
+	// errBodyTooLarge is an error when the request body is
+	// over the limit of its route.
+	errBodyTooLarge = errors.New("rest: request body too large")
+)
+
+// serverOptions are the options of all the go-kit servers so that
+// the errors of the decoders are reported as problems too, and the
+// responses are encoded in the media type accepted by the clients.
+var serverOptions = []httptransport.ServerOption{
+	httptransport.ServerBefore(httptransport.PopulateRequestContext),
+	httptransport.ServerErrorEncoder(encodeServerError),
+}
+
+// limitBody limits the body of r to n bytes. Reading
+// more returns errBodyTooLarge.
+func limitBody(r *http.Request, n int64) {
+	r.Body = &limitedBody{ReadCloser: http.MaxBytesReader(nil, r.Body, n), limit: n}
+}
+
+// limitedBody reports the errors of the http.MaxBytesReader it wraps
+// past the limit as errBodyTooLarge.
+type limitedBody struct {
+	io.ReadCloser
+	limit int64
+	read  int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if err != nil && err != io.EOF && b.read >= b.limit {
+		err = fmt.Errorf("rest: body over %d bytes: %w", b.limit, errBodyTooLarge)
+	}
+	return n, err
+}
+
+// requestError is an error of a request field that can't be decoded.
+type requestError struct {
+	field string
+	err   error
+}
+
+func newRequestError(field string, err error, cause error) *requestError {
+	return &requestError{field: field, err: fmt.Errorf("%s: %w", cause, err)}
+}
+
+func (e *requestError) Error() string {
+	return fmt.Sprintf("rest: %s: %s", e.field, e.err)
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+// parseNoteID parses the note id s of the request path.
+func parseNoteID(s string) (uuid.UUID, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, newRequestError("id", errInvalidID, err)
+	}
+	return id, nil
+}
+
+// decodeJSON decodes the JSON of r into v. A value of the
+// wrong type is reported with its field, and a body over its
+// limit with errBodyTooLarge.
+func decodeJSON(r io.Reader, v interface{}) error {
+	err := json.NewDecoder(r).Decode(v)
+	if err == nil || errors.Is(err, errBodyTooLarge) {
+		return err
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return newRequestError(typeErr.Field, errInvalidBody, err)
+	}
+	return fmt.Errorf("rest: %s: %w", err, errInvalidBody)
+}
+
+// apiError describes how an error is reported to the clients. The code
+// is stable and meant to be handled by the clients, while the title can
+// be shown to the users.
+type apiError struct {
+	status int
+	code   string
+	title  string
+	field  string
+}
+
+// apiErrors are the known errors in the order they are checked.
+var apiErrors = []struct {
+	err error
+	apiError
+}{
+	{note.ErrNotFound, apiError{http.StatusNotFound, "note_not_found", "Note not found", ""}},
+	{note.ErrExists, apiError{http.StatusConflict, "note_exists", "Note already exists", ""}},
+	{note.ErrNilID, apiError{http.StatusBadRequest, "note_id_required", "Empty note identifier", "id"}},
+	{note.ErrNilNote, apiError{http.StatusBadRequest, "note_required", "Empty note", "note"}},
+	{note.ErrPermissionDenied, apiError{http.StatusForbidden, "permission_denied", "Permission denied", ""}},
+	{note.ErrQuotaExceeded, apiError{http.StatusForbidden, "quota_exceeded", "Quota exceeded", ""}},
+	{note.ErrCancelled, apiError{StatusClientClosed, "request_cancelled", "Request cancelled", ""}},
+	{context.Canceled, apiError{StatusClientClosed, "request_cancelled", "Request cancelled", ""}},
+	{errInvalidID, apiError{http.StatusBadRequest, "invalid_note_id", "Invalid note identifier", "id"}},
+	{errInvalidBody, apiError{http.StatusBadRequest, "invalid_body", "Invalid request body", ""}},
+	{errInvalidQuery, apiError{http.StatusBadRequest, "invalid_query", "Invalid query parameter", ""}},
+	{errBodyTooLarge, apiError{http.StatusRequestEntityTooLarge, "body_too_large", "Request body too large", ""}},
+	{protoutil.ErrMessageTooLarge, apiError{http.StatusRequestEntityTooLarge, "message_too_large", "Message too large", ""}},
+	{errUnsupportedMediaType, apiError{http.StatusUnsupportedMediaType, "unsupported_media_type", "Unsupported media type", "Content-Type"}},
+	{errInvalidLastEventID, apiError{http.StatusBadRequest, "invalid_last_event_id", "Invalid last event id", "Last-Event-ID"}},
+	{importer.ErrInvalidConflictMode, apiError{http.StatusBadRequest, "invalid_conflict_mode", "Invalid conflict mode", "on_conflict"}},
+	{webhook.ErrNotFound, apiError{http.StatusNotFound, "webhook_not_found", "Webhook not found", ""}},
+	{errInvalidWebhookID, apiError{http.StatusBadRequest, "invalid_webhook_id", "Invalid webhook identifier", "id"}},
+	{webhook.ErrInvalidURL, apiError{http.StatusBadRequest, "invalid_webhook_url", "Invalid webhook url", "url"}},
+	{webhook.ErrInvalidEvent, apiError{http.StatusBadRequest, "invalid_webhook_event", "Invalid webhook event", "events"}},
+	{webhook.ErrEmptySecret, apiError{http.StatusBadRequest, "webhook_secret_required", "Empty webhook secret", "secret"}},
+	{link.ErrNotFound, apiError{http.StatusNotFound, "link_not_found", "Link not found", ""}},
+	{link.ErrInvalidToken, apiError{http.StatusNotFound, "link_not_found", "Link not found", ""}},
+	{link.ErrExpired, apiError{http.StatusGone, "link_expired", "Link expired", ""}},
+	{link.ErrRevoked, apiError{http.StatusGone, "link_revoked", "Link revoked", ""}},
+	{link.ErrPasswordRequired, apiError{http.StatusUnauthorized, "link_password_required", "Password required", "password"}},
+	{link.ErrInvalidPassword, apiError{http.StatusUnauthorized, "invalid_link_password", "Invalid password", "password"}},
+	{link.ErrInvalidTTL, apiError{http.StatusBadRequest, "invalid_link_ttl", "Invalid link ttl", "ttl"}},
+	{errInvalidLinkID, apiError{http.StatusBadRequest, "invalid_link_id", "Invalid link identifier", "link_id"}},
+	{share.ErrNotFound, apiError{http.StatusNotFound, "share_not_found", "Share not found", ""}},
+	{share.ErrInvalidRole, apiError{http.StatusBadRequest, "invalid_share_role", "Invalid share role", "role"}},
+	{share.ErrInvalidUser, apiError{http.StatusBadRequest, "invalid_share_user", "Invalid share user", "user_id"}},
+}
+
+var (
+	errValidation = apiError{http.StatusUnprocessableEntity, "invalid_note", "Invalid note", ""}
+	errUnexpected = apiError{http.StatusInternalServerError, "internal_error", "Unexpected error", ""}
+)
+
+// lookupError returns how err is reported to the clients.
+func lookupError(err error) apiError {
+	var validationErr *note.ValidationError
+	if errors.As(err, &validationErr) {
+		return errValidation
+	}
+
+	for _, known := range apiErrors {
+		if errors.Is(err, known.err) {
+			e := known.apiError
+			var reqErr *requestError
+			if errors.As(err, &reqErr) {
+				e.field = reqErr.field
+			}
+			return e
+		}
+	}
+	return errUnexpected
+}
+
 func newErrorWrapper(err error) errorWrapper {
 	return errorWrapper{
-		origErr:    err,
-		message:    getMessage(err),
-		statusCode: getStatusCode(err),
+		origErr:  err,
+		apiError: lookupError(err),
 	}
 }
 
 type errorWrapper struct {
-	origErr    error
-	message    string
-	statusCode int
-}
-
-func (e errorWrapper) error() error {
-	return errorutil.TryUnwrapErr(e.origErr)
+	origErr error
+	apiError
+	// result is the outcome of the part of the
+	// request done before the error, if any.
+	result interface{}
 }
 
 func (e errorWrapper) Error() string {
 	return e.origErr.Error()
 }
 
-func encodeResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	e, ok := response.(errorWrapper)
-	if ok && e.error() != nil {
-		encodeError(e, w)
+	if ok && e.origErr != nil {
+		encodeError(ctx, e, w)
 		return nil
 	}
 
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	return json.NewEncoder(w).Encode(response)
+	return encodeNegotiated(ctx, w, response)
 }
 
-func encodeError(ew errorWrapper, w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-
-	w.WriteHeader(ew.statusCode)
+// encodeServerError is the go-kit error encoder for the errors
+// returned by the decoders and the encoders.
+func encodeServerError(ctx context.Context, err error, w http.ResponseWriter) {
+	encodeError(ctx, newErrorWrapper(err), w)
+}
 
-	logrus.Error(ew.origErr)
+func encodeError(ctx context.Context, ew errorWrapper, w http.ResponseWriter) {
+	logging.Logger(ctx).Error(ew.origErr)
 
-	_ = json.NewEncoder(w).Encode(struct {
-		Message string `json:"message,omitempty"`
-	}{
-		Message: ew.message,
-	})
-}
+	p := problem.New(ew.status, ew.code, ew.title)
+	p.RequestID = middleware.RequestIDFromContext(ctx)
+	p.Field = ew.field
+	p.Result = ew.result
 
-func getStatusCode(err error) (statusCode int) {
-	err = errorutil.TryUnwrapErr(err)
-	switch err {
-	case note.ErrNotFound:
-		statusCode = http.StatusNotFound
-	case note.ErrNilID:
-		statusCode = http.StatusBadRequest
-	case note.ErrExists:
-		statusCode = http.StatusConflict
-	case note.ErrCancelled:
-		statusCode = StatusClientClosed
-	default:
-		statusCode = http.StatusInternalServerError
+	// The details of the unexpected errors may reveal
+	// the internals of the server.
+	if ew.status < http.StatusInternalServerError {
+		p.Detail = ew.origErr.Error()
 	}
-	return
-}
-
-func getMessage(err error) (message string) {
-	causeErr := errorutil.TryUnwrapErr(err)
-	switch causeErr {
-	case note.ErrExists:
-		message = "Note already exists"
-	case note.ErrCancelled, context.Canceled:
-		message = "Request cancelled"
-	case note.ErrNotFound:
-		message = "Note not found"
-	case note.ErrNilID:
-		message = "Empty note identifier"
-	default:
-		message = "Unexpected error"
+
+	var validationErr *note.ValidationError
+	if errors.As(ew.origErr, &validationErr) {
+		for _, f := range validationErr.Fields {
+			p.Errors = append(p.Errors, problem.FieldError{Field: f.Field, Message: f.Message})
+		}
 	}
-	return
+
+	_ = problem.Write(w, p)
 }
@@ -2,10 +2,12 @@ package rest
 
 import (
 	"context"
-	"encoding/json"
 	"github.com/go-kit/kit/endpoint"
+	"google.golang.org/protobuf/proto"
 	"net/http"
 	"noteapp/note"
+	pb "noteapp/note/proto"
+	"noteapp/note/proto/protoutil"
 )
 
 // createService is here to follow the interface segregation principle.
@@ -21,9 +23,33 @@ type createResponse struct {
 	Note *note.Note `json:"note"`
 }
 
+// decodeProto decodes the create request message. The id of
+// the note is optional.
+func (req *createRequest) decodeProto(b []byte) error {
+	var msg pb.CreateRequest
+	if err := decodeProtoMessage(b, &msg); err != nil {
+		return err
+	}
+
+	if msg.GetNote() == nil {
+		return nil
+	}
+
+	n, err := protoutil.DecodeNote(msg.GetNote(), nil)
+	if err != nil {
+		return decodeProtoError(err)
+	}
+	req.Note = n
+	return nil
+}
+
+func (resp createResponse) protoMessage() proto.Message {
+	return &pb.CreateResponse{Note: protoutil.EncodeNote(resp.Note)}
+}
+
 func decodeCreateRequest(_ context.Context, r *http.Request) (response interface{}, err error) {
 	var req createRequest
-	err = json.NewDecoder(r.Body).Decode(&req)
+	err = decodeBody(r, &req)
 	if err != nil {
 		return nil, err
 	}
@@ -42,11 +68,7 @@ func makeCreateEndpoint(svc createService) endpoint.Endpoint {
 		request := req.(createRequest)
 		newNote, err := svc.Create(ctx, request.Note)
 		if err != nil {
-			return errorWrapper{
-				origErr:    err,
-				message:    getMessage(err),
-				statusCode: getStatusCode(err),
-			}, nil
+			return newErrorWrapper(err), nil
 		}
 		return createResponse{Note: newNote}, nil
 	}
@@ -7,8 +7,12 @@ import (
 	"github.com/google/uuid"
 	"net/http"
 	"net/http/httptest"
+	"noteapp/api/problem"
 	"noteapp/note"
 	"noteapp/note/noteutil"
+	"noteapp/note/service"
+	"noteapp/note/validation"
+	"time"
 )
 
 func (s *HandlerTestSuite) TestCreate() {
@@ -53,7 +57,27 @@ func (s *HandlerTestSuite) TestCreate() {
 		responseRecorder := makeRequest(dummyCtx, newNote)
 		s.assertStatusCode(responseRecorder, http.StatusConflict)
 		resp := s.decodeResponse(responseRecorder)
-		s.assertMessage(resp, "Note already exists")
+		s.assertTitle(resp, "Note already exists")
+	})
+
+	s.Run("Requesting a create note that breaks the validation rules should return the field errors", func() {
+		v, err := validation.New(validation.Rules{MaxTitleLength: 5, RejectServerOwnedFields: true})
+		s.require.NoError(err)
+		routes := makeHandler(service.ValidatingMiddleware(v)(s.svc))
+
+		inputNote := noteutil.Copy(newNote).SetCreatedTime(time.Now())
+		var body bytes.Buffer
+		s.require.NoError(json.NewEncoder(&body).Encode(&request{Note: inputNote}))
+		responseRecorder := httptest.NewRecorder()
+		routes.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodPost, "/note", &body))
+
+		s.assertStatusCode(responseRecorder, http.StatusUnprocessableEntity)
+		resp := s.decodeResponse(responseRecorder)
+		s.assertTitle(resp, "Invalid note")
+		s.Equal([]problem.FieldError{
+			{Field: validation.FieldTitle, Message: "must be at most 5 characters"},
+			{Field: validation.FieldCreatedTime, Message: "is set by the server"},
+		}, resp.Errors)
 	})
 
 	s.Run("Cancelled request should return an error", func() {
@@ -63,6 +87,6 @@ func (s *HandlerTestSuite) TestCreate() {
 		responseRecorder := makeRequest(cancelledCtx, inputNote)
 		s.assertStatusCode(responseRecorder, StatusClientClosed)
 		resp := s.decodeResponse(responseRecorder)
-		s.assertMessage(resp, "Request cancelled")
+		s.assertTitle(resp, "Request cancelled")
 	})
 }
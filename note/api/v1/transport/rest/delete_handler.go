@@ -5,7 +5,9 @@ import (
 	"github.com/go-kit/kit/endpoint"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"google.golang.org/protobuf/proto"
 	"net/http"
+	pb "noteapp/note/proto"
 )
 
 type deleteService interface {
@@ -20,10 +22,16 @@ type deleteResponse struct {
 	Message string `json:"message"`
 }
 
+func (resp deleteResponse) protoMessage() proto.Message {
+	return &pb.DeleteResponse{}
+}
+
 func decodeDeleteRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-	return deleteRequest{ID: uuid.MustParse(id)}, nil
+	id, err := parseNoteID(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, err
+	}
+	return deleteRequest{ID: id}, nil
 }
 
 func makeDeleteEndpoint(svc deleteService) endpoint.Endpoint {
@@ -31,11 +39,7 @@ func makeDeleteEndpoint(svc deleteService) endpoint.Endpoint {
 		request := req.(deleteRequest)
 		err := svc.Delete(ctx, request.ID)
 		if err != nil {
-			return errorWrapper{
-				origErr:    err,
-				message:    getMessage(err),
-				statusCode: getStatusCode(err),
-			}, nil
+			return newErrorWrapper(err), nil
 		}
 		return deleteResponse{"Successfully Deleted"}, nil
 	}
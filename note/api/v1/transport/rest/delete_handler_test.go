@@ -36,7 +36,7 @@ func (s *HandlerTestSuite) TestDelete() {
 		s.Equal(http.StatusBadRequest, responseRecorder.Code)
 		got := s.decodeResponse(responseRecorder)
 		want := "Empty note identifier"
-		s.assertMessage(got, want)
+		s.assertTitle(got, want)
 	})
 
 	s.Run("Cancelled request should return an error", func() {
@@ -46,6 +46,6 @@ func (s *HandlerTestSuite) TestDelete() {
 		responseRecorder := makeRequest(cancelledCtx, newNote.ID)
 		s.assertStatusCode(responseRecorder, StatusClientClosed)
 		resp := s.decodeResponse(responseRecorder)
-		s.assertMessage(resp, "Request cancelled")
+		s.assertTitle(resp, "Request cancelled")
 	})
 }
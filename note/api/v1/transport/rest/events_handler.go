@@ -0,0 +1,169 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-kit/kit/endpoint"
+	"io"
+	"net/http"
+	"noteapp/note"
+	"noteapp/note/changefeed"
+	"noteapp/pkg/logging"
+	"strconv"
+	"time"
+)
+
+const (
+	// contentTypeEventStream is the media type of the server-sent events.
+	contentTypeEventStream = "text/event-stream"
+
+	// eventsKeepAliveInterval is the interval of the comments sent
+	// to keep an idle event stream open through proxies.
+	eventsKeepAliveInterval = 15 * time.Second
+)
+
+// errInvalidLastEventID is an error when the last event id
+// of the request is not a valid sequence number.
+var errInvalidLastEventID = errors.New("rest: invalid last event id")
+
+type eventSubscriber interface {
+	Subscribe(lastEventID uint64) (*changefeed.Subscription, changefeed.Replay)
+}
+
+type eventsRequest struct {
+	LastEventID string
+}
+
+type eventsResponse struct {
+	sub    *changefeed.Subscription
+	replay changefeed.Replay
+	// grants are the share grants of the notes, they can be
+	// nil when the notes are not shared.
+	grants note.Grants
+}
+
+// visible reports whether the event of e is sent to the client of
+// ctx. The owner in ctx only receives the events of the notes they
+// can read, while a context without owner receives all of them.
+func (r eventsResponse) visible(ctx context.Context, e note.Event) bool {
+	if _, ok := note.OwnerFromContext(ctx); !ok {
+		return true
+	}
+	return e.Note != nil && note.RoleOf(ctx, e.Note, r.grants).CanRead()
+}
+
+// decodeEventsRequest reads the last event id from the Last-Event-ID
+// header that is sent by the browsers when reconnecting, or from the
+// last_event_id query for the first connection.
+func decodeEventsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	return eventsRequest{LastEventID: lastEventID}, nil
+}
+
+func makeEventsEndpoint(feed eventSubscriber, grants note.Grants) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(eventsRequest)
+
+		var lastEventID uint64
+		if request.LastEventID != "" {
+			id, err := strconv.ParseUint(request.LastEventID, 10, 64)
+			if err != nil {
+				return newErrorWrapper(newRequestError("Last-Event-ID", errInvalidLastEventID, err)), nil
+			}
+			lastEventID = id
+		}
+
+		if err := ctx.Err(); err != nil {
+			return newErrorWrapper(err), nil
+		}
+
+		sub, replay := feed.Subscribe(lastEventID)
+		return eventsResponse{sub: sub, replay: replay, grants: grants}, nil
+	}
+}
+
+// encodeEventsResponse streams the note events to w until the client
+// goes away or the subscription is dropped for falling behind, in which
+// case the client is expected to reconnect with its last event id. When
+// some events after the last event id are no longer available a reset
+// event is sent first so that the client can reload its notes.
+func encodeEventsResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp, ok := response.(eventsResponse)
+	if !ok {
+		return encodeResponse(ctx, w, response)
+	}
+	defer resp.sub.Close()
+
+	w.Header().Set("Content-Type", contentTypeEventStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	if resp.replay.Missed {
+		if _, err := io.WriteString(w, "event: reset\ndata: {}\n\n"); err != nil {
+			logging.Logger(ctx).Error("rest/events: ", err)
+			return nil
+		}
+	}
+
+	for _, e := range resp.replay.Events {
+		if !resp.visible(ctx, e) {
+			continue
+		}
+
+		if err := writeEvent(w, e); err != nil {
+			logging.Logger(ctx).Error("rest/events: ", err)
+			return nil
+		}
+	}
+	flush()
+
+	keepAlive := time.NewTicker(eventsKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		case e, ok := <-resp.sub.Events():
+			if !ok {
+				return nil
+			}
+
+			if !resp.visible(ctx, e) {
+				continue
+			}
+
+			if err := writeEvent(w, e); err != nil {
+				logging.Logger(ctx).Error("rest/events: ", err)
+				return nil
+			}
+		}
+		flush()
+	}
+}
+
+func writeEvent(w io.Writer, e note.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
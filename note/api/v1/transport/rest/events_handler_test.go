@@ -0,0 +1,224 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/spf13/afero"
+	"net/http"
+	"net/http/httptest"
+	"noteapp/note"
+	"noteapp/note/changefeed"
+	"noteapp/note/eventbus"
+	"noteapp/note/noteutil"
+	"noteapp/note/service"
+	"noteapp/note/share"
+	"noteapp/note/store/memory"
+	"strings"
+	"time"
+)
+
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// readSSEEvents reads size events from the stream skipping the comments.
+func (s *HandlerTestSuite) readSSEEvents(r *bufio.Reader, size int) (events []sseEvent) {
+	var e sseEvent
+	for len(events) < size {
+		line, err := r.ReadString('\n')
+		s.require.NoError(err)
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "":
+			if e != (sseEvent{}) {
+				events = append(events, e)
+			}
+			e = sseEvent{}
+		case strings.HasPrefix(line, "id: "):
+			e.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			e.Event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			e.Data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	return
+}
+
+func (s *HandlerTestSuite) TestEvents() {
+
+	setup := func(replaySize int) (note.Service, *httptest.Server) {
+		feed, bus := changefeed.New(replaySize), eventbus.New()
+		bus.Subscribe(feed)
+		svc := service.EventPublishingMiddleware(bus)(service.New(memory.New()))
+		srv := httptest.NewServer(makeEventHandler(feed, nil))
+		return svc, srv
+	}
+
+	subscribe := func(ctx context.Context, srv *httptest.Server, lastEventID string) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/notes/events", nil)
+		s.require.NoError(err)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+
+		resp, err := srv.Client().Do(req)
+		s.require.NoError(err)
+		return resp
+	}
+
+	decodeEvent := func(data string) note.Event {
+		var e note.Event
+		s.require.NoError(json.Unmarshal([]byte(data), &e))
+		return e
+	}
+
+	s.Run("Changes should be streamed as events", func() {
+		svc, srv := setup(10)
+		defer srv.Close()
+
+		ctx, cancel := context.WithTimeout(dummyCtx, 5*time.Second)
+		defer cancel()
+
+		resp := subscribe(ctx, srv, "")
+		defer resp.Body.Close()
+		s.Equal(http.StatusOK, resp.StatusCode)
+		s.Equal(contentTypeEventStream, resp.Header.Get("Content-Type"))
+
+		created, err := svc.Create(ctx, noteutil.Copy(dummyNote))
+		s.require.NoError(err)
+		updated, err := svc.Update(ctx, noteutil.Copy(created).SetTitle("Updated"))
+		s.require.NoError(err)
+		s.require.NoError(svc.Delete(ctx, created.ID))
+
+		events := s.readSSEEvents(bufio.NewReader(resp.Body), 3)
+		s.Equal([]string{"1", "2", "3"}, []string{events[0].ID, events[1].ID, events[2].ID})
+		s.Equal([]string{"created", "updated", "deleted"}, []string{events[0].Event, events[1].Event, events[2].Event})
+
+		first := decodeEvent(events[0].Data)
+		s.Equal(uint64(1), first.Version)
+		s.Equal(created.ID, first.Note.ID)
+
+		second := decodeEvent(events[1].Data)
+		s.Equal(uint64(2), second.Version)
+		s.Equal(updated.GetTitle(), second.Note.GetTitle())
+	})
+
+	s.Run("Reconnecting should resume after the last event id", func() {
+		svc, srv := setup(10)
+		defer srv.Close()
+
+		ctx, cancel := context.WithTimeout(dummyCtx, 5*time.Second)
+		defer cancel()
+
+		for i := 0; i < 3; i++ {
+			_, err := svc.Create(ctx, noteutil.Copy(dummyNote))
+			s.require.NoError(err)
+		}
+
+		resp := subscribe(ctx, srv, "1")
+		defer resp.Body.Close()
+
+		events := s.readSSEEvents(bufio.NewReader(resp.Body), 2)
+		s.Equal("2", events[0].ID)
+		s.Equal("3", events[1].ID)
+	})
+
+	s.Run("Reconnecting after the replay buffer should reset", func() {
+		svc, srv := setup(2)
+		defer srv.Close()
+
+		ctx, cancel := context.WithTimeout(dummyCtx, 5*time.Second)
+		defer cancel()
+
+		for i := 0; i < 5; i++ {
+			_, err := svc.Create(ctx, noteutil.Copy(dummyNote))
+			s.require.NoError(err)
+		}
+
+		resp := subscribe(ctx, srv, "1")
+		defer resp.Body.Close()
+
+		events := s.readSSEEvents(bufio.NewReader(resp.Body), 3)
+		s.Equal("reset", events[0].Event)
+		s.Equal("4", events[1].ID)
+		s.Equal("5", events[2].ID)
+	})
+
+	s.Run("Only the events of the owner's notes should be streamed", func() {
+		feed, bus := changefeed.New(10), eventbus.New()
+		bus.Subscribe(feed)
+		svc := service.EventPublishingMiddleware(bus)(service.New(memory.New()))
+		handler := makeEventHandler(feed, nil)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler.ServeHTTP(w, r.WithContext(note.WithOwner(r.Context(), "alice")))
+		}))
+		defer srv.Close()
+
+		ctx, cancel := context.WithTimeout(dummyCtx, 5*time.Second)
+		defer cancel()
+
+		resp := subscribe(ctx, srv, "")
+		defer resp.Body.Close()
+
+		_, err := svc.Create(note.WithOwner(ctx, "bob"), noteutil.Copy(dummyNote))
+		s.require.NoError(err)
+		created, err := svc.Create(note.WithOwner(ctx, "alice"), noteutil.Copy(dummyNote))
+		s.require.NoError(err)
+
+		events := s.readSSEEvents(bufio.NewReader(resp.Body), 1)
+		s.Equal("2", events[0].ID)
+		s.Equal(created.ID, decodeEvent(events[0].Data).Note.ID)
+	})
+
+	s.Run("Events of the notes shared with the owner should be streamed", func() {
+		file, err := afero.NewMemMapFs().Create("shares.json")
+		s.require.NoError(err)
+		grants, err := share.NewStore(file)
+		s.require.NoError(err)
+
+		feed, bus := changefeed.New(10), eventbus.New()
+		bus.Subscribe(feed)
+		svc := service.EventPublishingMiddleware(bus)(service.New(memory.New(), service.WithGrants(grants)))
+		handler := makeEventHandler(feed, grants)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler.ServeHTTP(w, r.WithContext(note.WithOwner(r.Context(), "alice")))
+		}))
+		defer srv.Close()
+
+		ctx, cancel := context.WithTimeout(dummyCtx, 5*time.Second)
+		defer cancel()
+
+		shared, err := svc.Create(note.WithOwner(ctx, "bob"), noteutil.Copy(dummyNote))
+		s.require.NoError(err)
+		_, err = grants.Put(&note.Grant{NoteID: shared.ID, UserID: "alice", Role: note.RoleViewer})
+		s.require.NoError(err)
+
+		resp := subscribe(ctx, srv, "")
+		defer resp.Body.Close()
+
+		_, err = svc.Create(note.WithOwner(ctx, "bob"), noteutil.Copy(dummyNote))
+		s.require.NoError(err)
+		_, err = svc.Update(note.WithOwner(ctx, "bob"), noteutil.Copy(shared).SetTitle("Updated"))
+		s.require.NoError(err)
+
+		events := s.readSSEEvents(bufio.NewReader(resp.Body), 1)
+		s.Equal("3", events[0].ID)
+		s.Equal(string(note.EventUpdated), events[0].Event)
+		s.Equal(shared.ID, decodeEvent(events[0].Data).Note.ID)
+	})
+
+	s.Run("Invalid last event id should return an error", func() {
+		feed := changefeed.New(10)
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/notes/events?last_event_id=abc", nil)
+		makeEventHandler(feed, nil).ServeHTTP(rec, req)
+
+		s.assertStatusCode(rec, http.StatusBadRequest)
+		s.assertTitle(s.decodeResponse(rec), "Invalid last event id")
+	})
+}
@@ -0,0 +1,124 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/endpoint"
+	"io"
+	"net/http"
+	"noteapp/note"
+	"noteapp/note/proto/protoutil"
+	"noteapp/pkg/logging"
+)
+
+const (
+	// contentTypeNDJSON is the media type of newline-delimited JSON.
+	contentTypeNDJSON = "application/x-ndjson"
+	// contentTypeProtobuf is the media type of the note protocol
+	// buffer messages, length-prefixed when there are several.
+	contentTypeProtobuf = "application/x-protobuf"
+
+	// exportFlushSize is the number of notes to write
+	// before flushing the response to the client.
+	exportFlushSize = 100
+)
+
+type exportService interface {
+	Export(ctx context.Context) (note.Iterator, error)
+}
+
+type exportRequest struct {
+	ContentType string
+}
+
+type exportResponse struct {
+	contentType string
+	iter        note.Iterator
+}
+
+// decodeExportRequest negotiates the media type of the export. The
+// NDJSON is exported when the client accepts neither of them.
+func decodeExportRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	contentType := negotiate(r.Header.Get("Accept"), contentTypeNDJSON, contentTypeProtobuf)
+	if contentType == "" {
+		contentType = contentTypeNDJSON
+	}
+	return exportRequest{ContentType: contentType}, nil
+}
+
+func makeExportEndpoint(svc exportService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(exportRequest)
+		iter, err := svc.Export(ctx)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return exportResponse{contentType: request.ContentType, iter: iter}, nil
+	}
+}
+
+// encodeExportResponse streams the notes of the export iterator
+// to w. Once the first note has been written the status code can't be
+// changed anymore, so errors in the middle of the stream are only logged
+// and the stream is cut short.
+func encodeExportResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp, ok := response.(exportResponse)
+	if !ok {
+		return encodeResponse(ctx, w, response)
+	}
+
+	defer func() {
+		if err := resp.iter.Close(); err != nil {
+			logging.Logger(ctx).Error(err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", resp.contentType)
+	w.WriteHeader(http.StatusOK)
+
+	write := writeNoteNDJSON
+	if resp.contentType == contentTypeProtobuf {
+		write = writeNoteProtobuf
+	}
+
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	var written int
+	for resp.iter.Next() {
+		if err := ctx.Err(); err != nil {
+			logging.Logger(ctx).Error("rest/export: ", err)
+			return nil
+		}
+
+		if err := write(w, resp.iter.Note()); err != nil {
+			logging.Logger(ctx).Error("rest/export: ", err)
+			return nil
+		}
+
+		written++
+		if written%exportFlushSize == 0 {
+			flush()
+		}
+	}
+
+	if err := resp.iter.Error(); err != nil {
+		logging.Logger(ctx).Error("rest/export: ", err)
+	}
+
+	flush()
+	return nil
+}
+
+func writeNoteNDJSON(w io.Writer, n *note.Note) error {
+	// The json encoder terminates every value with a newline.
+	return json.NewEncoder(w).Encode(n)
+}
+
+func writeNoteProtobuf(w io.Writer, n *note.Note) error {
+	return protoutil.WriteProtoMessage(w, protoutil.NoteToProto(n))
+}
@@ -0,0 +1,99 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"noteapp/note"
+	"noteapp/note/proto/protoutil"
+	"sort"
+)
+
+func (s *HandlerTestSuite) TestExport() {
+
+	makeRequest := func(ctx context.Context, accept string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/notes/export", nil)
+		req.Header.Set("Accept", accept)
+		req = req.WithContext(ctx)
+		s.routes.ServeHTTP(rec, req)
+		return rec
+	}
+
+	setup := func(size int) (notes []*note.Note) {
+		for i := 0; i < size; i++ {
+			n := new(note.Note)
+			n.SetTitle(fmt.Sprintf("Title %d", i)).
+				SetContent(fmt.Sprintf("Content %d", i)).
+				SetIsFavorite(i%2 == 0)
+			newNote, err := s.svc.Create(dummyCtx, n)
+			s.require.NoError(err)
+			notes = append(notes, newNote)
+		}
+		sort.Sort(note.SortByIDSorter(notes))
+		return
+	}
+
+	s.Run("Exporting as newline-delimited JSON", func() {
+		s.SetupTest()
+		want := setup(150)
+
+		rec := makeRequest(dummyCtx, "application/x-ndjson")
+		s.assertStatusCode(rec, http.StatusOK)
+		s.Equal(contentTypeNDJSON, rec.Header().Get("Content-Type"))
+
+		var got []*note.Note
+		scanner := bufio.NewScanner(rec.Body)
+		for scanner.Scan() {
+			var n note.Note
+			s.require.NoError(json.Unmarshal(scanner.Bytes(), &n))
+			got = append(got, &n)
+		}
+		s.require.NoError(scanner.Err())
+		s.Equal(want, got)
+	})
+
+	s.Run("Exporting as length-prefixed protobuf", func() {
+		s.SetupTest()
+		want := setup(10)
+
+		rec := makeRequest(dummyCtx, "application/x-protobuf")
+		s.assertStatusCode(rec, http.StatusOK)
+		s.Equal(contentTypeProtobuf, rec.Header().Get("Content-Type"))
+
+		got, err := protoutil.ReadAllProtoMessages(rec.Body)
+		s.require.NoError(err)
+		s.Equal(want, got)
+	})
+
+	s.Run("Export media type should follow the preferences of the client", func() {
+		s.SetupTest()
+
+		for accept, want := range map[string]string{
+			"":          contentTypeNDJSON,
+			"*/*":       contentTypeNDJSON,
+			"text/html": contentTypeNDJSON,
+			"application/x-ndjson;q=0.5, application/x-protobuf":     contentTypeProtobuf,
+			"application/x-protobuf;q=0.1, application/*":            contentTypeNDJSON,
+			"application/x-ndjson;q=0, application/x-protobuf;q=0.2": contentTypeProtobuf,
+		} {
+			rec := makeRequest(dummyCtx, accept)
+			s.assertStatusCode(rec, http.StatusOK)
+			s.Equal(want, rec.Header().Get("Content-Type"), accept)
+		}
+	})
+
+	s.Run("Cancelled request should return an error", func() {
+		s.SetupTest()
+		setup(1)
+
+		ctx, cancel := context.WithCancel(dummyCtx)
+		cancel()
+		rec := makeRequest(ctx, "")
+		s.assertStatusCode(rec, StatusClientClosed)
+		s.assertTitle(s.decodeResponse(rec), "Request cancelled")
+	})
+}
@@ -3,8 +3,11 @@ package rest
 import (
 	"context"
 	"github.com/go-kit/kit/endpoint"
+	"google.golang.org/protobuf/proto"
 	"net/http"
 	"noteapp/note"
+	pb "noteapp/note/proto"
+	"noteapp/note/proto/protoutil"
 	"strconv"
 )
 
@@ -22,18 +25,51 @@ type fetchResponse struct {
 	TotalPage  uint64       `json:"total_page"`
 }
 
+// protoMessages returns a message per note like the gRPC Fetch
+// stream. An empty page has a message without note, so that the
+// clients still get the totals.
+func (resp fetchResponse) protoMessages() []proto.Message {
+	if len(resp.Notes) == 0 {
+		return []proto.Message{&pb.FetchResponse{TotalCount: resp.TotalCount, TotalPage: resp.TotalPage}}
+	}
+
+	messages := make([]proto.Message, 0, len(resp.Notes))
+	for _, n := range resp.Notes {
+		messages = append(messages, &pb.FetchResponse{
+			Note:       protoutil.EncodeNote(n),
+			TotalCount: resp.TotalCount,
+			TotalPage:  resp.TotalPage,
+		})
+	}
+	return messages
+}
+
 func decodeFetchRequest(_ context.Context, r *http.Request) (response interface{}, err error) {
 
-	page := r.URL.Query().Get("page")
-	size := r.URL.Query().Get("size")
+	page, err := parseUintQuery(r, "page")
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := parseUintQuery(r, "size")
+	if err != nil {
+		return nil, err
+	}
+
+	sharedWithMe, err := parseBoolQuery(r, "shared_with_me")
+	if err != nil {
+		return nil, err
+	}
+
 	sortBy := r.URL.Query().Get("sort_by")
 
 	response = fetchRequest{
 		Pagination: &note.Pagination{
-			Size:   convertAtoU(size),
-			Page:   convertAtoU(page),
-			SortBy: note.GetSortBy(sortBy),
-			Ascend: false,
+			Size:         size,
+			Page:         page,
+			SortBy:       note.GetSortBy(sortBy),
+			Ascend:       false,
+			SharedWithMe: sharedWithMe,
 		},
 	}
 
@@ -48,20 +84,8 @@ func makeFetchEndpoint(svc fetchService) endpoint.Endpoint {
 			return newErrorWrapper(err), nil
 		}
 
-		defer func() {
-			cerr := iter.Close()
-			if cerr != nil && err == nil {
-				err = cerr
-			}
-		}()
-
-		var notes []*note.Note
-		for iter.Next() {
-			n := iter.Note()
-			notes = append(notes, n)
-		}
-
-		if iter.Error() != nil {
+		notes, err := note.Collect(note.Values(iter))
+		if err != nil {
 			return newErrorWrapper(err), nil
 		}
 
@@ -75,7 +99,30 @@ func makeFetchEndpoint(svc fetchService) endpoint.Endpoint {
 	}
 }
 
-func convertAtoU(s string) uint64 {
-	v, _ := strconv.Atoi(s)
-	return uint64(v)
+// parseUintQuery parses the query parameter key of r. A missing
+// parameter will return zero.
+func parseUintQuery(r *http.Request, key string) (uint64, error) {
+	s := r.URL.Query().Get(key)
+	if s == "" {
+		return 0, nil
+	}
+
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, newRequestError(key, errInvalidQuery, err)
+	}
+	return v, nil
+}
+
+func parseBoolQuery(r *http.Request, key string) (bool, error) {
+	s := r.URL.Query().Get(key)
+	if s == "" {
+		return false, nil
+	}
+
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, newRequestError(key, errInvalidQuery, err)
+	}
+	return v, nil
 }
@@ -5,8 +5,11 @@ import (
 	"github.com/go-kit/kit/endpoint"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"google.golang.org/protobuf/proto"
 	"net/http"
 	"noteapp/note"
+	pb "noteapp/note/proto"
+	"noteapp/note/proto/protoutil"
 )
 
 type getService interface {
@@ -21,23 +24,25 @@ type getResponse struct {
 	Note *note.Note `json:"note"`
 }
 
+func (resp getResponse) protoMessage() proto.Message {
+	return &pb.GetResponse{Note: protoutil.EncodeNote(resp.Note)}
+}
+
 func makeGetEndpoint(svc getService) endpoint.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		request := req.(getRequest)
 		v, err := svc.Get(ctx, request.ID)
 		if err != nil {
-			return errorWrapper{
-				origErr:    err,
-				statusCode: getStatusCode(err),
-				message:    getMessage(err),
-			}, nil
+			return newErrorWrapper(err), nil
 		}
 		return getResponse{Note: v}, nil
 	}
 }
 
 func decodeGetRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-	return getRequest{ID: uuid.MustParse(id)}, nil
+	id, err := parseNoteID(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, err
+	}
+	return getRequest{ID: id}, nil
 }
@@ -51,7 +51,7 @@ func (s *HandlerTestSuite) TestGet() {
 		s.assertStatusCode(responseRecorder, http.StatusNotFound)
 		got := s.decodeResponse(responseRecorder)
 		want := "Note not found"
-		s.assertMessage(got, want)
+		s.assertTitle(got, want)
 	})
 
 	s.Run("Requesting a note but the ID is nil", func() {
@@ -59,7 +59,7 @@ func (s *HandlerTestSuite) TestGet() {
 		s.assertStatusCode(responseRecorder, http.StatusBadRequest)
 		got := s.decodeResponse(responseRecorder)
 		want := "Empty note identifier"
-		s.assertMessage(got, want)
+		s.assertTitle(got, want)
 	})
 
 	s.Run("Cancelled request should return an error", func() {
@@ -69,6 +69,6 @@ func (s *HandlerTestSuite) TestGet() {
 		responseRecorder := makeRequest(cancelledCtx, inputNote.ID)
 		s.assertStatusCode(responseRecorder, StatusClientClosed)
 		resp := s.decodeResponse(responseRecorder)
-		s.assertMessage(resp, "Request cancelled")
+		s.assertTitle(resp, "Request cancelled")
 	})
 }
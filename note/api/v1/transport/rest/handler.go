@@ -5,6 +5,9 @@ import (
 	"github.com/gorilla/mux"
 	"net/http"
 	"noteapp/note"
+	"noteapp/note/changefeed"
+	"noteapp/note/collab"
+	"noteapp/note/webhook"
 )
 
 // makeHandler initializes all the routes for the note service
@@ -15,30 +18,49 @@ func makeHandler(svc note.Service) http.Handler {
 		makeGetEndpoint(svc),
 		decodeGetRequest,
 		encodeResponse,
+		serverOptions...,
 	)
 
 	createHandler := httptransport.NewServer(
 		makeCreateEndpoint(svc),
 		decodeCreateRequest,
 		encodeResponse,
+		serverOptions...,
 	)
 
 	updateHandler := httptransport.NewServer(
 		makeUpdateEndpoint(svc),
 		decodeUpdateRequest,
 		encodeResponse,
+		serverOptions...,
 	)
 
 	deleteHandler := httptransport.NewServer(
 		makeDeleteEndpoint(svc),
 		decodeDeleteRequest,
 		encodeResponse,
+		serverOptions...,
 	)
 
 	fetchHandler := httptransport.NewServer(
 		makeFetchEndpoint(svc),
 		decodeFetchRequest,
 		encodeResponse,
+		serverOptions...,
+	)
+
+	exportHandler := httptransport.NewServer(
+		makeExportEndpoint(svc),
+		decodeExportRequest,
+		encodeExportResponse,
+		serverOptions...,
+	)
+
+	importHandler := httptransport.NewServer(
+		makeImportEndpoint(svc),
+		decodeImportRequest,
+		encodeResponse,
+		serverOptions...,
 	)
 
 	router.Handle("/note/{id}", getHandler).Methods(http.MethodGet)
@@ -46,6 +68,153 @@ func makeHandler(svc note.Service) http.Handler {
 	router.Handle("/note", updateHandler).Methods(http.MethodPut)
 	router.Handle("/note/{id}", deleteHandler).Methods(http.MethodDelete)
 	router.Handle("/notes", fetchHandler).Methods(http.MethodGet)
+	router.Handle("/notes/export", exportHandler).Methods(http.MethodGet)
+	router.Handle("/notes/import", importHandler).Methods(http.MethodPost)
+
+	return router
+}
+
+// makeEventHandler initializes the route for the note change
+// events published by the feed and return the routed handler.
+func makeEventHandler(feed *changefeed.Broker, grants note.Grants) http.Handler {
+	router := mux.NewRouter()
+	eventsHandler := httptransport.NewServer(
+		makeEventsEndpoint(feed, grants),
+		decodeEventsRequest,
+		encodeEventsResponse,
+		serverOptions...,
+	)
+
+	router.Handle("/notes/events", eventsHandler).Methods(http.MethodGet)
+
+	return router
+}
+
+// makeCollabHandler initializes the route for the collaborative
+// editing of the note contents and return the routed handler.
+func makeCollabHandler(svc note.Service, hub *collab.Hub, grants note.Grants) http.Handler {
+	router := mux.NewRouter()
+	router.Handle("/note/{id}/collab", newCollabHandler(svc, hub, grants)).Methods(http.MethodGet)
+	return router
+}
+
+// makeWebhookHandler initializes the routes for managing the webhook
+// subscriptions and return the routed handler.
+func makeWebhookHandler(store *webhook.Store) http.Handler {
+	router := mux.NewRouter()
+	createHandler := httptransport.NewServer(
+		makeCreateWebhookEndpoint(store),
+		decodeCreateWebhookRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	listHandler := httptransport.NewServer(
+		makeListWebhooksEndpoint(store),
+		decodeListWebhooksRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	getHandler := httptransport.NewServer(
+		makeGetWebhookEndpoint(store),
+		decodeWebhookIDRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	deleteHandler := httptransport.NewServer(
+		makeDeleteWebhookEndpoint(store),
+		decodeWebhookIDRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	deliveriesHandler := httptransport.NewServer(
+		makeDeliveriesEndpoint(store),
+		decodeWebhookIDRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	router.Handle("/webhooks", createHandler).Methods(http.MethodPost)
+	router.Handle("/webhooks", listHandler).Methods(http.MethodGet)
+	router.Handle("/webhooks/{id}", getHandler).Methods(http.MethodGet)
+	router.Handle("/webhooks/{id}", deleteHandler).Methods(http.MethodDelete)
+	router.Handle("/webhooks/{id}/deliveries", deliveriesHandler).Methods(http.MethodGet)
+
+	return router
+}
+
+// makeShareHandler initializes the routes for managing the share
+// grants of the notes and return the routed handler.
+func makeShareHandler(svc shareService) http.Handler {
+	router := mux.NewRouter()
+	createHandler := httptransport.NewServer(
+		makeCreateShareEndpoint(svc),
+		decodeCreateShareRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	listHandler := httptransport.NewServer(
+		makeListSharesEndpoint(svc),
+		decodeShareIDRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	revokeHandler := httptransport.NewServer(
+		makeRevokeShareEndpoint(svc),
+		decodeShareIDRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	router.Handle("/note/{id}/shares", createHandler).Methods(http.MethodPost)
+	router.Handle("/note/{id}/shares", listHandler).Methods(http.MethodGet)
+	router.Handle("/note/{id}/shares/{user_id}", revokeHandler).Methods(http.MethodDelete)
+
+	return router
+}
+
+// makeLinkHandler initializes the routes for managing the public
+// links of the notes and the routes of the shared notes, and return
+// the routed handler.
+func makeLinkHandler(svc linkService) http.Handler {
+	router := mux.NewRouter()
+	createHandler := httptransport.NewServer(
+		makeCreateLinkEndpoint(svc),
+		decodeCreateLinkRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	listHandler := httptransport.NewServer(
+		makeListLinksEndpoint(svc),
+		decodeLinkIDRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	revokeHandler := httptransport.NewServer(
+		makeRevokeLinkEndpoint(svc),
+		decodeLinkIDRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	sharedHandler := httptransport.NewServer(
+		makeSharedNoteEndpoint(svc),
+		decodeSharedNoteRequest,
+		encodeSharedNoteResponse,
+		serverOptions...,
+	)
+
+	router.Handle("/note/{id}/links", createHandler).Methods(http.MethodPost)
+	router.Handle("/note/{id}/links", listHandler).Methods(http.MethodGet)
+	router.Handle("/note/{id}/links/{link_id}", revokeHandler).Methods(http.MethodDelete)
+	router.Handle("/shared/{token}", sharedHandler).Methods(http.MethodGet, http.MethodPost)
 
 	return router
 }
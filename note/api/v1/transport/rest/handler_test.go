@@ -3,14 +3,18 @@ package rest
 import (
 	"context"
 	"encoding/json"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"net/http"
 	"net/http/httptest"
+	"noteapp/api/middleware"
+	"noteapp/api/problem"
 	"noteapp/note"
 	"noteapp/note/service"
 	"noteapp/note/store/memory"
 	"noteapp/pkg/ptrconv"
+	"strings"
 	"testing"
 )
 
@@ -27,8 +31,8 @@ type request struct {
 }
 
 type response struct {
-	Note    *note.Note `json:"note"`
-	Message string     `json:"message,omitempty"`
+	Note *note.Note `json:"note"`
+	problem.Problem
 }
 
 func TestHandler(t *testing.T) {
@@ -57,10 +61,101 @@ func (s *HandlerTestSuite) decodeResponse(rec *httptest.ResponseRecorder) respon
 	return resp
 }
 
-func (s *HandlerTestSuite) assertMessage(resp response, want string) {
-	s.Equal(want, resp.Message)
+func (s *HandlerTestSuite) assertTitle(resp response, want string) {
+	s.Equal(want, resp.Title)
 }
 
 func (s *HandlerTestSuite) assertStatusCode(rec *httptest.ResponseRecorder, want int) {
 	s.Equal(want, rec.Code)
 }
+
+func (s *HandlerTestSuite) TestProblem() {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+		code   string
+		field  string
+	}{
+		{
+			name:   "Invalid note id in the get request",
+			method: http.MethodGet,
+			target: "/note/abc",
+			status: http.StatusBadRequest,
+			code:   "invalid_note_id",
+			field:  "id",
+		},
+		{
+			name:   "Invalid note id in the delete request",
+			method: http.MethodDelete,
+			target: "/note/abc",
+			status: http.StatusBadRequest,
+			code:   "invalid_note_id",
+			field:  "id",
+		},
+		{
+			name:   "Malformed body in the create request",
+			method: http.MethodPost,
+			target: "/note",
+			body:   "{not json}",
+			status: http.StatusBadRequest,
+			code:   "invalid_body",
+		},
+		{
+			name:   "Wrong field type in the update request",
+			method: http.MethodPut,
+			target: "/note",
+			body:   `{"note":{"title":1}}`,
+			status: http.StatusBadRequest,
+			code:   "invalid_body",
+			field:  "note.title",
+		},
+		{
+			name:   "Invalid page in the fetch request",
+			method: http.MethodGet,
+			target: "/notes?page=-1",
+			status: http.StatusBadRequest,
+			code:   "invalid_query",
+			field:  "page",
+		},
+		{
+			name:   "Invalid shared with me in the fetch request",
+			method: http.MethodGet,
+			target: "/notes?shared_with_me=maybe",
+			status: http.StatusBadRequest,
+			code:   "invalid_query",
+			field:  "shared_with_me",
+		},
+		{
+			name:   "Missing note",
+			method: http.MethodGet,
+			target: "/note/" + uuid.New().String(),
+			status: http.StatusNotFound,
+			code:   "note_not_found",
+		},
+	}
+
+	routes := middleware.RequestID(s.routes)
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+			routes.ServeHTTP(rec, req)
+
+			s.assertStatusCode(rec, tt.status)
+			s.Equal(problem.ContentType, rec.Header().Get("Content-Type"))
+
+			resp := s.decodeResponse(rec)
+			s.Equal(problem.TypePrefix+tt.code, resp.Type)
+			s.Equal(tt.status, resp.Status)
+			s.Equal(tt.code, resp.Code)
+			s.Equal(tt.field, resp.Field)
+			s.Equal("test-request", resp.RequestID)
+			s.NotEmpty(resp.Title)
+			s.NotEmpty(resp.Detail)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package rest
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	"io"
+	"mime"
+	"net/http"
+	"noteapp/note"
+	"noteapp/note/importer"
+)
+
+// maxImportSize is the maximum size of an import body.
+const maxImportSize = 256 << 20
+
+type importRequest struct {
+	Body        io.Reader
+	ContentType string
+	Mode        string
+}
+
+type importResponse struct {
+	*importer.Summary
+}
+
+func decodeImportRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	limitBody(r, maxImportSize)
+
+	// The body is read by the endpoint while importing
+	// and closed by the http server afterwards.
+	return importRequest{
+		Body:        r.Body,
+		ContentType: contentType,
+		Mode:        r.URL.Query().Get("on_conflict"),
+	}, nil
+}
+
+func makeImportEndpoint(svc note.Service) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(importRequest)
+
+		mode, err := importer.ParseConflictMode(request.Mode)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+
+		dec := importer.NewNDJSONDecoder(request.Body)
+		if request.ContentType == contentTypeProtobuf {
+			dec = importer.NewProtobufDecoder(request.Body)
+		}
+
+		// The summary of the notes imported before an
+		// error is reported along with the error.
+		summary, err := importer.New(svc, mode).Import(ctx, dec)
+		if err != nil {
+			ew := newErrorWrapper(err)
+			ew.result = summary
+			return ew, nil
+		}
+		return importResponse{Summary: summary}, nil
+	}
+}
@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"noteapp/note"
+	"noteapp/note/importer"
+	"noteapp/note/proto/protoutil"
+	"strings"
+	"testing"
+)
+
+func (s *HandlerTestSuite) TestImport() {
+
+	makeRequest := func(query, contentType string, body *bytes.Buffer) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/notes/import"+query, body)
+		req.Header.Set("Content-Type", contentType)
+		s.routes.ServeHTTP(rec, req)
+		return rec
+	}
+
+	decodeSummary := func(rec *httptest.ResponseRecorder) (summary importer.Summary) {
+		s.require.NoError(json.NewDecoder(rec.Body).Decode(&summary))
+		return
+	}
+
+	s.Run("Importing newline-delimited JSON", func() {
+		existing, err := s.svc.Create(dummyCtx, new(note.Note).SetTitle("Existing"))
+		s.require.NoError(err)
+
+		var body bytes.Buffer
+		encoder := json.NewEncoder(&body)
+		s.require.NoError(encoder.Encode(new(note.Note).SetTitle("New")))
+		s.require.NoError(encoder.Encode(new(note.Note).SetID(existing.ID).SetTitle("Existing")))
+		body.WriteString("invalid\n")
+
+		rec := makeRequest("?on_conflict=skip", "application/x-ndjson", &body)
+		s.assertStatusCode(rec, http.StatusOK)
+
+		summary := decodeSummary(rec)
+		s.Len(summary.Results, 3)
+		s.Equal(1, summary.Counts[importer.StatusCreated])
+		s.Equal(1, summary.Counts[importer.StatusSkipped])
+		s.Equal(1, summary.Counts[importer.StatusInvalid])
+	})
+
+	s.Run("Importing protobuf stream", func() {
+		n := new(note.Note).SetID(uuid.New()).SetTitle("Protobuf")
+
+		var body bytes.Buffer
+		s.require.NoError(protoutil.WriteProtoMessage(&body, protoutil.NoteToProto(n)))
+
+		rec := makeRequest("", "application/x-protobuf", &body)
+		s.assertStatusCode(rec, http.StatusOK)
+		s.Equal(1, decodeSummary(rec).Counts[importer.StatusCreated])
+
+		got, err := s.svc.Get(dummyCtx, n.ID)
+		s.require.NoError(err)
+		s.Equal("Protobuf", got.GetTitle())
+	})
+
+	s.Run("Protobuf message over the maximum size should return an error", func() {
+		body := bytes.NewBuffer([]byte{0xff, 0xff, 0xff, 0xff})
+
+		rec := makeRequest("", "application/x-protobuf", body)
+		s.assertStatusCode(rec, http.StatusRequestEntityTooLarge)
+		s.assertTitle(s.decodeResponse(rec), "Message too large")
+	})
+
+	s.Run("Error should return the summary of the notes imported before it", func() {
+		var body bytes.Buffer
+		s.require.NoError(protoutil.WriteProtoMessage(&body, protoutil.NoteToProto(new(note.Note).SetID(uuid.New()).SetTitle("Before"))))
+		body.Write([]byte{0xff, 0xff, 0xff, 0xff})
+
+		rec := makeRequest("", "application/x-protobuf", &body)
+		s.assertStatusCode(rec, http.StatusRequestEntityTooLarge)
+
+		var resp struct {
+			Title  string           `json:"title"`
+			Result importer.Summary `json:"result"`
+		}
+		s.require.NoError(json.NewDecoder(rec.Body).Decode(&resp))
+		s.Equal("Message too large", resp.Title)
+		s.Require().Len(resp.Result.Results, 1)
+		s.Equal(importer.StatusCreated, resp.Result.Results[0].Status)
+	})
+
+	s.Run("Invalid conflict mode should return an error", func() {
+		rec := makeRequest("?on_conflict=merge", "application/x-ndjson", new(bytes.Buffer))
+		s.assertStatusCode(rec, http.StatusBadRequest)
+		s.assertTitle(s.decodeResponse(rec), "Invalid conflict mode")
+	})
+}
+
+func TestLimitBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/notes/import", strings.NewReader("abcdef"))
+	limitBody(req, 3)
+
+	b, err := io.ReadAll(req.Body)
+	require.ErrorIs(t, err, errBodyTooLarge)
+	require.Equal(t, "abc", string(b))
+
+	req = httptest.NewRequest(http.MethodPost, "/notes/import", strings.NewReader("abc"))
+	limitBody(req, 3)
+
+	b, err = io.ReadAll(req.Body)
+	require.NoError(t, err)
+	require.Equal(t, "abc", string(b))
+}
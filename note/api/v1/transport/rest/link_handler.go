@@ -0,0 +1,301 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"html/template"
+	"mime"
+	"net/http"
+	"noteapp/note"
+	"noteapp/note/link"
+	"noteapp/pkg/logging"
+	"strings"
+	"time"
+)
+
+const (
+	// contentTypeHTML is the media type of the shared note page.
+	contentTypeHTML = "text/html"
+
+	// LinkPasswordHeader is the http header carrying the
+	// password of a shared note link.
+	LinkPasswordHeader = "X-Link-Password"
+
+	// SharedPathPrefix is the path of the shared notes. The routes
+	// under it must be anonymous.
+	SharedPathPrefix = "/v1/shared/"
+)
+
+// errInvalidLinkID is an error when the link id in
+// the path is not a valid uuid.
+var errInvalidLinkID = errors.New("rest: invalid link id")
+
+type linkService interface {
+	Create(ctx context.Context, noteID uuid.UUID, ttl time.Duration, password string) (*link.Link, string, error)
+	List(ctx context.Context, noteID uuid.UUID) ([]*link.Link, error)
+	Revoke(ctx context.Context, noteID, linkID uuid.UUID) error
+	Resolve(ctx context.Context, token, password string) (*note.Note, *link.Link, error)
+}
+
+// linkView is the link without its password hash.
+type linkView struct {
+	ID          uuid.UUID  `json:"id"`
+	NoteID      uuid.UUID  `json:"note_id"`
+	CreatedTime time.Time  `json:"created_time"`
+	ExpiresTime time.Time  `json:"expires_time"`
+	RevokedTime *time.Time `json:"revoked_time,omitempty"`
+	Views       uint64     `json:"views"`
+	HasPassword bool       `json:"has_password"`
+}
+
+func newLinkView(l *link.Link) *linkView {
+	return &linkView{
+		ID:          l.ID,
+		NoteID:      l.NoteID,
+		CreatedTime: l.CreatedTime,
+		ExpiresTime: l.ExpiresTime,
+		RevokedTime: l.RevokedTime,
+		Views:       l.Views,
+		HasPassword: l.HasPassword(),
+	}
+}
+
+type createLinkRequest struct {
+	ID string `json:"-"`
+	// TTL is the lifetime of the link, e.g. "72h".
+	TTL      string `json:"ttl"`
+	Password string `json:"password"`
+}
+
+type linkIDRequest struct {
+	ID     string
+	LinkID string
+}
+
+type createLinkResponse struct {
+	Link  *linkView `json:"link"`
+	Token string    `json:"token"`
+	// URL is the path where the note is shared.
+	URL string `json:"url"`
+}
+
+type listLinksResponse struct {
+	Links []*linkView `json:"links"`
+}
+
+type sharedNoteRequest struct {
+	Token    string
+	Password string
+	HTML     bool
+}
+
+type sharedNoteResponse struct {
+	html bool
+	err  error
+	Note *note.Note `json:"note,omitempty"`
+	Link *linkView  `json:"link,omitempty"`
+}
+
+func decodeCreateLinkRequest(_ context.Context, r *http.Request) (response interface{}, err error) {
+	var req createLinkRequest
+	err = decodeBody(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		cerr := r.Body.Close()
+		if cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	req.ID = mux.Vars(r)["id"]
+	return req, nil
+}
+
+func decodeLinkIDRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	return linkIDRequest{ID: vars["id"], LinkID: vars["link_id"]}, nil
+}
+
+// decodeSharedNoteRequest reads the password from the password header,
+// or from the form of the password page.
+func decodeSharedNoteRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := sharedNoteRequest{
+		Token:    mux.Vars(r)["token"],
+		Password: r.Header.Get(LinkPasswordHeader),
+		HTML:     prefersHTML(r.Header.Get("Accept")),
+	}
+
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err != nil {
+			return nil, newRequestError("password", errInvalidBody, err)
+		}
+		req.Password = r.PostForm.Get("password")
+	}
+	return req, nil
+}
+
+// prefersHTML reports whether the client asks for the HTML
+// page before the JSON, as the browsers do.
+func prefersHTML(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case contentTypeHTML:
+			return true
+		case "application/json":
+			return false
+		}
+	}
+	return false
+}
+
+func makeCreateLinkEndpoint(svc linkService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(createLinkRequest)
+		id, err := parseNoteID(request.ID)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+
+		var ttl time.Duration
+		if request.TTL != "" {
+			ttl, err = time.ParseDuration(request.TTL)
+			if err != nil {
+				return newErrorWrapper(newRequestError("ttl", link.ErrInvalidTTL, err)), nil
+			}
+		}
+
+		l, token, err := svc.Create(ctx, id, ttl, request.Password)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return createLinkResponse{Link: newLinkView(l), Token: token, URL: SharedPathPrefix + token}, nil
+	}
+}
+
+func makeListLinksEndpoint(svc linkService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		id, err := parseNoteID(req.(linkIDRequest).ID)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+
+		links, err := svc.List(ctx, id)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+
+		views := []*linkView{}
+		for _, l := range links {
+			views = append(views, newLinkView(l))
+		}
+		return listLinksResponse{Links: views}, nil
+	}
+}
+
+func makeRevokeLinkEndpoint(svc linkService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(linkIDRequest)
+		id, err := parseNoteID(request.ID)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+
+		linkID, err := uuid.Parse(request.LinkID)
+		if err != nil {
+			return newErrorWrapper(newRequestError("link_id", errInvalidLinkID, err)), nil
+		}
+
+		if err := svc.Revoke(ctx, id, linkID); err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return deleteResponse{"Successfully Revoked"}, nil
+	}
+}
+
+func makeSharedNoteEndpoint(svc linkService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(sharedNoteRequest)
+		n, l, err := svc.Resolve(ctx, request.Token, request.Password)
+		if err != nil {
+			return sharedNoteResponse{html: request.HTML, err: err}, nil
+		}
+		return sharedNoteResponse{html: request.HTML, Note: n, Link: newLinkView(l)}, nil
+	}
+}
+
+// encodeSharedNoteResponse writes the shared note as JSON, or as an
+// HTML page for the browsers. The token is in the url, so the pages
+// are neither cached nor indexed and the url isn't sent as referrer.
+func encodeSharedNoteResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex")
+
+	resp, ok := response.(sharedNoteResponse)
+	if !ok {
+		return encodeResponse(ctx, w, response)
+	}
+
+	if !resp.html {
+		if resp.err != nil {
+			return encodeResponse(ctx, w, newErrorWrapper(resp.err))
+		}
+		return encodeResponse(ctx, w, resp)
+	}
+
+	page := sharedPage{Note: resp.Note}
+	status := http.StatusOK
+	if resp.err != nil {
+		e := lookupError(resp.err)
+		if e.status >= http.StatusInternalServerError {
+			logging.Logger(ctx).Error(resp.err)
+		}
+		status, page.Error = e.status, e.title
+		page.AskPassword = errors.Is(resp.err, link.ErrPasswordRequired) || errors.Is(resp.err, link.ErrInvalidPassword)
+	}
+
+	w.Header().Set("Content-Type", contentTypeHTML+"; charset=utf-8")
+	w.WriteHeader(status)
+	return sharedPageTemplate.Execute(w, page)
+}
+
+type sharedPage struct {
+	Note        *note.Note
+	Error       string
+	AskPassword bool
+}
+
+var sharedPageTemplate = template.Must(template.New("shared").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{with .Note}}{{.GetTitle}}{{else}}Shared note{{end}}</title>
+<style>body{font-family:sans-serif;max-width:42rem;margin:2rem auto;padding:0 1rem;line-height:1.5}pre{white-space:pre-wrap;font-family:inherit}</style>
+</head>
+<body>
+{{- if .Note}}
+<h1>{{.Note.GetTitle}}</h1>
+<pre>{{.Note.GetContent}}</pre>
+{{- else}}
+<h1>{{.Error}}</h1>
+{{- if .AskPassword}}
+<form method="post">
+<label>Password <input type="password" name="password" autofocus required></label>
+<button type="submit">View</button>
+</form>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,153 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/spf13/afero"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"noteapp/api/problem"
+	"noteapp/note"
+	"noteapp/note/link"
+	"noteapp/note/service"
+	"noteapp/note/store/memory"
+	"strings"
+	"time"
+)
+
+func (s *HandlerTestSuite) TestLinks() {
+
+	type linkResponseJSON struct {
+		Link  *linkView   `json:"link"`
+		Links []*linkView `json:"links"`
+		Token string      `json:"token"`
+		URL   string      `json:"url"`
+		Note  *note.Note  `json:"note"`
+		problem.Problem
+	}
+
+	file, err := afero.NewMemMapFs().Create("links.json")
+	s.require.NoError(err)
+	store, err := link.NewStore(file)
+	s.require.NoError(err)
+
+	svc := service.New(memory.New())
+	linkSvc, err := link.NewService(svc, nil, store, link.Config{Secret: []byte("secret"), DefaultTTL: time.Hour, MaxTTL: 24 * time.Hour})
+	s.require.NoError(err)
+	routes := makeLinkHandler(linkSvc)
+
+	aliceCtx := note.WithOwner(dummyCtx, "alice")
+	created, err := svc.Create(aliceCtx, new(note.Note).SetTitle("Public <note>").SetContent("Lorem Ipsum"))
+	s.require.NoError(err)
+	linksPath := "/note/" + created.ID.String() + "/links"
+
+	serve := func(req *http.Request) (*httptest.ResponseRecorder, linkResponseJSON) {
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, req)
+
+		var resp linkResponseJSON
+		if strings.HasPrefix(rec.Header().Get("Content-Type"), contentTypeHTML) {
+			return rec, resp
+		}
+		s.require.NoError(json.NewDecoder(bytes.NewReader(rec.Body.Bytes())).Decode(&resp))
+		return rec, resp
+	}
+
+	createLink := func(body map[string]string) linkResponseJSON {
+		var buf bytes.Buffer
+		s.require.NoError(json.NewEncoder(&buf).Encode(body))
+		req := httptest.NewRequest(http.MethodPost, linksPath, &buf)
+
+		rec, resp := serve(req.WithContext(aliceCtx))
+		s.assertStatusCode(rec, http.StatusOK)
+		return resp
+	}
+
+	s.Run("Resolving a link as JSON", func() {
+		resp := createLink(map[string]string{"ttl": "2h"})
+		s.NotEmpty(resp.Token)
+		s.Equal(SharedPathPrefix+resp.Token, resp.URL)
+		s.False(resp.Link.HasPassword)
+
+		rec, resp := serve(httptest.NewRequest(http.MethodGet, "/shared/"+resp.Token, nil))
+		s.assertStatusCode(rec, http.StatusOK)
+		s.Equal("no-store", rec.Header().Get("Cache-Control"))
+		s.require.NotNil(resp.Note)
+		s.Equal(created.ID, resp.Note.ID)
+		s.Equal(uint64(1), resp.Link.Views)
+	})
+
+	s.Run("Resolving a link as HTML", func() {
+		token := createLink(nil).Token
+
+		req := httptest.NewRequest(http.MethodGet, "/shared/"+token, nil)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+		rec, _ := serve(req)
+		s.assertStatusCode(rec, http.StatusOK)
+		s.Contains(rec.Body.String(), "<h1>Public &lt;note&gt;</h1>")
+		s.Contains(rec.Body.String(), "Lorem Ipsum")
+	})
+
+	s.Run("Resolving a link with a password", func() {
+		token := createLink(map[string]string{"password": "hunter2"}).Token
+
+		rec, resp := serve(httptest.NewRequest(http.MethodGet, "/shared/"+token, nil))
+		s.assertStatusCode(rec, http.StatusUnauthorized)
+		s.Equal("link_password_required", resp.Code)
+
+		req := httptest.NewRequest(http.MethodGet, "/shared/"+token, nil)
+		req.Header.Set(LinkPasswordHeader, "hunter2")
+		rec, _ = serve(req)
+		s.assertStatusCode(rec, http.StatusOK)
+
+		req = httptest.NewRequest(http.MethodGet, "/shared/"+token, nil)
+		req.Header.Set("Accept", contentTypeHTML)
+		rec, _ = serve(req)
+		s.assertStatusCode(rec, http.StatusUnauthorized)
+		s.Contains(rec.Body.String(), `<form method="post">`)
+
+		req = httptest.NewRequest(http.MethodPost, "/shared/"+token, strings.NewReader(url.Values{"password": {"hunter2"}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", contentTypeHTML)
+		rec, _ = serve(req)
+		s.assertStatusCode(rec, http.StatusOK)
+		s.Contains(rec.Body.String(), "Lorem Ipsum")
+	})
+
+	s.Run("Resolving a revoked link should return gone", func() {
+		resp := createLink(nil)
+
+		req := httptest.NewRequest(http.MethodDelete, linksPath+"/"+resp.Link.ID.String(), nil)
+		rec, _ := serve(req.WithContext(aliceCtx))
+		s.assertStatusCode(rec, http.StatusOK)
+
+		rec, resp = serve(httptest.NewRequest(http.MethodGet, "/shared/"+resp.Token, nil))
+		s.assertStatusCode(rec, http.StatusGone)
+		s.Equal("link_revoked", resp.Code)
+	})
+
+	s.Run("Resolving a forged token should return not found", func() {
+		rec, resp := serve(httptest.NewRequest(http.MethodGet, "/shared/abc.def", nil))
+		s.assertStatusCode(rec, http.StatusNotFound)
+		s.Equal("link_not_found", resp.Code)
+	})
+
+	s.Run("Listing the links", func() {
+		rec, resp := serve(httptest.NewRequest(http.MethodGet, linksPath, nil).WithContext(aliceCtx))
+		s.assertStatusCode(rec, http.StatusOK)
+		s.NotEmpty(resp.Links)
+
+		rec, resp = serve(httptest.NewRequest(http.MethodGet, linksPath, nil).WithContext(note.WithOwner(dummyCtx, "bob")))
+		s.assertStatusCode(rec, http.StatusNotFound)
+	})
+
+	s.Run("Invalid ttl should return an error", func() {
+		var buf bytes.Buffer
+		s.require.NoError(json.NewEncoder(&buf).Encode(map[string]string{"ttl": "forever"}))
+		rec, resp := serve(httptest.NewRequest(http.MethodPost, linksPath, &buf).WithContext(aliceCtx))
+		s.assertStatusCode(rec, http.StatusBadRequest)
+		s.Equal("invalid_link_ttl", resp.Code)
+		s.Equal("ttl", resp.Field)
+	})
+}
@@ -0,0 +1,270 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"mime"
+	"net/http"
+	"noteapp/note/proto/protoutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The media types of the request and the response bodies, along
+// with the contentTypeProtobuf of the note messages.
+const (
+	contentTypeJSON    = "application/json"
+	contentTypeMsgpack = "application/msgpack"
+)
+
+// maxBodySize is the size limit of the request bodies
+// other than the imports, the same as a protobuf message.
+const maxBodySize = protoutil.MaxMessageSize
+
+// errUnsupportedMediaType is an error when the request body
+// can't be decoded from its content type.
+var errUnsupportedMediaType = errors.New("rest: unsupported media type")
+
+// protoRequest is a request which can be decoded from a protocol
+// buffer message of note/proto.
+type protoRequest interface {
+	decodeProto(b []byte) error
+}
+
+// protoResponse is a response which can be encoded as a protocol
+// buffer message of note/proto.
+type protoResponse interface {
+	protoMessage() proto.Message
+}
+
+// protoStreamResponse is a response which is encoded as a stream of
+// protocol buffer messages of note/proto, each prefixed with its
+// size as written by protoutil.WriteProtoMessage.
+type protoStreamResponse interface {
+	protoMessages() []proto.Message
+}
+
+// decodeBody decodes the body of r into v by its content type. The
+// msgpack bodies have the same fields as the JSON ones, and the
+// protobuf bodies are the messages of note/proto for the requests
+// having one. A body without content type is decoded as JSON, while
+// the other content types are unsupported. The body is limited to
+// maxBodySize.
+func decodeBody(r *http.Request, v interface{}) error {
+	limitBody(r, maxBodySize)
+
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return decodeJSON(r.Body, v)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("rest: %s: %w", err, errUnsupportedMediaType)
+	}
+
+	switch mediaType {
+	case contentTypeJSON:
+		return decodeJSON(r.Body, v)
+	case contentTypeMsgpack:
+		var body interface{}
+		if err := msgpack.NewDecoder(r.Body).Decode(&body); err != nil {
+			if errors.Is(err, errBodyTooLarge) {
+				return err
+			}
+			return fmt.Errorf("rest: %s: %w", err, errInvalidBody)
+		}
+
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("rest: %s: %w", err, errInvalidBody)
+		}
+		return decodeJSON(bytes.NewReader(b), v)
+	case contentTypeProtobuf:
+		req, ok := v.(protoRequest)
+		if !ok {
+			return fmt.Errorf("rest: %s: %w", mediaType, errUnsupportedMediaType)
+		}
+
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		return req.decodeProto(b)
+	default:
+		return fmt.Errorf("rest: %s: %w", mediaType, errUnsupportedMediaType)
+	}
+}
+
+// decodeProtoMessage unmarshals the protobuf body b into m.
+func decodeProtoMessage(b []byte, m proto.Message) error {
+	if err := proto.Unmarshal(b, m); err != nil {
+		return fmt.Errorf("rest: %s: %w", err, errInvalidBody)
+	}
+	return nil
+}
+
+// decodeProtoError converts an error of the note message
+// conversion into a request error.
+func decodeProtoError(err error) error {
+	if errors.Is(err, protoutil.ErrInvalidID) {
+		return newRequestError("id", errInvalidID, err)
+	}
+	return fmt.Errorf("rest: %s: %w", err, errInvalidBody)
+}
+
+// negotiate returns the offered media type most preferred by the
+// accept header, or an empty string when none of them is acceptable.
+// The first offer is preferred when the client prefers several of
+// them the same.
+func negotiate(accept string, offers ...string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	type accepted struct {
+		mediaType string
+		q         float64
+	}
+
+	var ranges []accepted
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, accepted{mediaType: mediaType, q: q})
+	}
+
+	// The more specific ranges take precedence, see RFC 7231 5.3.2.
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return strings.Count(ranges[i].mediaType, "*") < strings.Count(ranges[j].mediaType, "*")
+	})
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		for _, r := range ranges {
+			if !matchMediaRange(r.mediaType, offer) {
+				continue
+			}
+			if r.q > bestQ {
+				best, bestQ = offer, r.q
+			}
+			break
+		}
+	}
+	return best
+}
+
+// matchMediaRange reports whether the media type is in the media
+// range such as "application/*".
+func matchMediaRange(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	return strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*"))
+}
+
+// encodeNegotiated writes the response in the media type negotiated
+// with the accept header of the request, JSON when none of the media
+// types of the response is acceptable.
+func encodeNegotiated(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	offers := []string{contentTypeJSON, contentTypeMsgpack}
+	switch response.(type) {
+	case protoResponse, protoStreamResponse:
+		offers = append(offers, contentTypeProtobuf)
+	}
+
+	accept, _ := ctx.Value(httptransport.ContextKeyRequestAccept).(string)
+	w.Header().Add("Vary", "Accept")
+
+	switch negotiate(accept, offers...) {
+	case contentTypeMsgpack:
+		return encodeMsgpack(w, response)
+	case contentTypeProtobuf:
+		return encodeProto(w, response)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(response)
+}
+
+// encodeMsgpack writes the response with the same fields as its JSON.
+func encodeMsgpack(w http.ResponseWriter, response interface{}) error {
+	b, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var body interface{}
+	if err := dec.Decode(&body); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", contentTypeMsgpack)
+	return msgpack.NewEncoder(w).Encode(msgpackValue(body))
+}
+
+// msgpackValue replaces the JSON numbers of v with integers, or
+// floats when they are not integers.
+func msgpackValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = msgpackValue(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = msgpackValue(e)
+		}
+	}
+	return v
+}
+
+// encodeProto writes the protocol buffer messages of the response.
+// The content type tells the message type, and whether the messages
+// are a stream of size prefixed messages.
+func encodeProto(w http.ResponseWriter, response interface{}) error {
+	if r, ok := response.(protoStreamResponse); ok {
+		messages := r.protoMessages()
+		w.Header().Set("Content-Type", protoContentType(messages[0])+"; delimited=true")
+		return protoutil.WriteAllProtoMessages(w, messages...)
+	}
+
+	m := response.(protoResponse).protoMessage()
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", protoContentType(m))
+	_, err = w.Write(b)
+	return err
+}
+
+func protoContentType(m proto.Message) string {
+	return contentTypeProtobuf + "; messageType=" + string(proto.MessageName(m))
+}
@@ -0,0 +1,183 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"noteapp/api/problem"
+	"noteapp/note/noteutil"
+	pb "noteapp/note/proto"
+	"noteapp/note/proto/protoutil"
+	"testing"
+)
+
+func (s *HandlerTestSuite) TestContentNegotiation() {
+	serve := func(method, path, contentType string, body []byte, accept string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		s.routes.ServeHTTP(rec, req)
+		return rec
+	}
+
+	marshal := func(m proto.Message) []byte {
+		b, err := proto.Marshal(m)
+		s.Require().NoError(err)
+		return b
+	}
+
+	created, err := s.svc.Create(dummyCtx, noteutil.Copy(dummyNote))
+	s.Require().NoError(err)
+
+	s.Run("Getting a note as protobuf", func() {
+		rec := serve(http.MethodGet, "/note/"+created.ID.String(), "", nil, "application/x-protobuf")
+		s.Equal(http.StatusOK, rec.Code)
+		s.Equal("application/x-protobuf; messageType=proto.GetResponse", rec.Header().Get("Content-Type"))
+		s.Equal("Accept", rec.Header().Get("Vary"))
+
+		var got pb.GetResponse
+		s.Require().NoError(proto.Unmarshal(rec.Body.Bytes(), &got))
+		s.Equal([]byte(created.ID.String()), got.GetNote().GetId())
+		s.Equal(created.GetTitle(), got.GetNote().GetTitle())
+	})
+
+	s.Run("Creating a note with protobuf", func() {
+		body := marshal(&pb.CreateRequest{Note: &pb.Note{Title: "Protobuf", Content: "Body"}})
+		rec := serve(http.MethodPost, "/note", "application/x-protobuf", body, "application/x-protobuf")
+		s.Equal(http.StatusOK, rec.Code)
+
+		var got pb.CreateResponse
+		s.Require().NoError(proto.Unmarshal(rec.Body.Bytes(), &got))
+		s.Equal("Protobuf", got.GetNote().GetTitle())
+		s.NotNil(got.GetNote().GetCreatedTime())
+	})
+
+	s.Run("Updating a note with a protobuf update mask", func() {
+		body := marshal(&pb.UpdateRequest{
+			Note:       &pb.Note{Id: []byte(created.ID.String()), Title: "Masked"},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}},
+		})
+		rec := serve(http.MethodPut, "/note", "application/x-protobuf", body, "")
+		s.Equal(http.StatusOK, rec.Code)
+
+		got := s.decodeResponse(rec)
+		s.Equal("Masked", got.Note.GetTitle())
+		s.Equal(created.GetContent(), got.Note.GetContent())
+	})
+
+	s.Run("Invalid protobuf body", func() {
+		rec := serve(http.MethodPost, "/note", "application/x-protobuf", []byte("invalid"), "")
+		s.Equal(http.StatusBadRequest, rec.Code)
+		s.Equal("invalid_body", s.decodeResponse(rec).Code)
+	})
+
+	s.Run("Fetching notes as protobuf", func() {
+		rec := serve(http.MethodGet, "/notes", "", nil, "application/x-protobuf")
+		s.Equal(http.StatusOK, rec.Code)
+		s.Equal("application/x-protobuf; messageType=proto.FetchResponse; delimited=true", rec.Header().Get("Content-Type"))
+
+		var titles []string
+		for {
+			b, err := protoutil.ReadProtoMessageBytes(rec.Body)
+			if err == io.EOF {
+				break
+			}
+			s.Require().NoError(err)
+
+			var msg pb.FetchResponse
+			s.Require().NoError(proto.Unmarshal(b, &msg))
+			s.Equal(uint64(2), msg.GetTotalCount())
+			titles = append(titles, msg.GetNote().GetTitle())
+		}
+		s.ElementsMatch([]string{"Masked", "Protobuf"}, titles)
+	})
+
+	s.Run("Creating and getting a note with msgpack", func() {
+		body, err := msgpack.Marshal(map[string]interface{}{"note": map[string]interface{}{"title": "Msgpack", "is_favorite": true}})
+		s.Require().NoError(err)
+
+		rec := serve(http.MethodPost, "/note", "application/msgpack", body, "application/msgpack")
+		s.Equal(http.StatusOK, rec.Code)
+		s.Equal("application/msgpack", rec.Header().Get("Content-Type"))
+
+		var got map[string]map[string]interface{}
+		s.Require().NoError(msgpack.Unmarshal(rec.Body.Bytes(), &got))
+		s.Equal("Msgpack", got["note"]["title"])
+		s.Equal(true, got["note"]["is_favorite"])
+		s.IsType("", got["note"]["id"])
+	})
+
+	s.Run("Protobuf body of a request without message", func() {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(nil))
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		makeWebhookHandler(nil).ServeHTTP(rec, req)
+		s.Equal(http.StatusUnsupportedMediaType, rec.Code)
+	})
+
+	s.Run("Unsupported content type", func() {
+		rec := serve(http.MethodPost, "/note", "text/plain", []byte(`{"title":"Plain"}`), "")
+		s.Equal(http.StatusUnsupportedMediaType, rec.Code)
+	})
+
+	s.Run("Body over the size limit", func() {
+		title := bytes.Repeat([]byte("a"), maxBodySize)
+
+		rec := serve(http.MethodPost, "/note", contentTypeJSON, append(append([]byte(`{"title":"`), title...), `"}`...), "")
+		s.Equal(http.StatusRequestEntityTooLarge, rec.Code)
+
+		body, err := msgpack.Marshal(map[string]string{"title": string(title)})
+		s.Require().NoError(err)
+		rec = serve(http.MethodPost, "/note", contentTypeMsgpack, body, "")
+		s.Equal(http.StatusRequestEntityTooLarge, rec.Code)
+	})
+
+	s.Run("Unacceptable media types fall back to JSON", func() {
+		rec := serve(http.MethodGet, "/note/"+created.ID.String(), "", nil, "text/csv")
+		s.Equal(http.StatusOK, rec.Code)
+		s.Equal("application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+
+		var got response
+		s.Require().NoError(json.NewDecoder(rec.Body).Decode(&got))
+		s.Equal(created.ID, got.Note.ID)
+	})
+
+	s.Run("Errors are problems whatever the accept header", func() {
+		rec := serve(http.MethodGet, "/note/invalid", "", nil, "application/x-protobuf")
+		s.Equal(http.StatusBadRequest, rec.Code)
+		s.Equal(problem.ContentType, rec.Header().Get("Content-Type"))
+	})
+}
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{contentTypeJSON, contentTypeMsgpack, contentTypeProtobuf}
+
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", contentTypeJSON},
+		{"*/*", contentTypeJSON},
+		{"application/x-protobuf", contentTypeProtobuf},
+		{"application/msgpack, application/json;q=0.9", contentTypeMsgpack},
+		{"application/*;q=0.5, application/x-protobuf", contentTypeProtobuf},
+		{"application/*, application/json;q=0", contentTypeMsgpack},
+		{"text/html", ""},
+		{"invalid", ""},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, negotiate(tt.accept, offers...), tt.accept)
+	}
+}
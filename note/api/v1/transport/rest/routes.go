@@ -5,6 +5,11 @@ import (
 	"net/http"
 	"noteapp/api"
 	"noteapp/note"
+	"noteapp/note/changefeed"
+	"noteapp/note/collab"
+	"noteapp/note/link"
+	"noteapp/note/share"
+	"noteapp/note/webhook"
 	nhttp "noteapp/pkg/http"
 )
 
@@ -14,36 +19,199 @@ func Routes(svc note.Service) []api.Route {
 	return getRoutes(svc)
 }
 
+// EventRoutes returns the routes of the note change events
+// published by the feed. The grants are the ones of the service,
+// they can be nil when the notes are not shared.
+func EventRoutes(feed *changefeed.Broker, grants note.Grants) []api.Route {
+	eventsHandler := httptransport.NewServer(
+		makeEventsEndpoint(feed, grants),
+		decodeEventsRequest,
+		encodeEventsResponse,
+		serverOptions...,
+	)
+
+	return []api.Route{
+		&nhttp.Route{HandlerValue: eventsHandler, MethodValue: http.MethodGet, PathValue: "/v1/notes/events"},
+	}
+}
+
+// CollabRoutes returns the routes of the collaborative
+// editing of the note contents. The grants are the ones of
+// the service, they can be nil when the notes are not shared.
+func CollabRoutes(svc note.Service, hub *collab.Hub, grants note.Grants) []api.Route {
+	return []api.Route{
+		&nhttp.Route{HandlerValue: newCollabHandler(svc, hub, grants), MethodValue: http.MethodGet, PathValue: "/v1/note/{id}/collab"},
+	}
+}
+
+// WebhookRoutes returns the routes for managing the webhook
+// subscriptions kept in the store.
+func WebhookRoutes(store *webhook.Store) []api.Route {
+	createHandler := httptransport.NewServer(
+		makeCreateWebhookEndpoint(store),
+		decodeCreateWebhookRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	listHandler := httptransport.NewServer(
+		makeListWebhooksEndpoint(store),
+		decodeListWebhooksRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	getHandler := httptransport.NewServer(
+		makeGetWebhookEndpoint(store),
+		decodeWebhookIDRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	deleteHandler := httptransport.NewServer(
+		makeDeleteWebhookEndpoint(store),
+		decodeWebhookIDRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	deliveriesHandler := httptransport.NewServer(
+		makeDeliveriesEndpoint(store),
+		decodeWebhookIDRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	return []api.Route{
+		&nhttp.Route{HandlerValue: createHandler, MethodValue: http.MethodPost, PathValue: "/v1/webhooks"},
+		&nhttp.Route{HandlerValue: listHandler, MethodValue: http.MethodGet, PathValue: "/v1/webhooks"},
+		&nhttp.Route{HandlerValue: getHandler, MethodValue: http.MethodGet, PathValue: "/v1/webhooks/{id}"},
+		&nhttp.Route{HandlerValue: deleteHandler, MethodValue: http.MethodDelete, PathValue: "/v1/webhooks/{id}"},
+		&nhttp.Route{HandlerValue: deliveriesHandler, MethodValue: http.MethodGet, PathValue: "/v1/webhooks/{id}/deliveries"},
+	}
+}
+
+// ShareRoutes returns the routes for managing the share
+// grants of the notes.
+func ShareRoutes(svc *share.Service) []api.Route {
+	createHandler := httptransport.NewServer(
+		makeCreateShareEndpoint(svc),
+		decodeCreateShareRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	listHandler := httptransport.NewServer(
+		makeListSharesEndpoint(svc),
+		decodeShareIDRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	revokeHandler := httptransport.NewServer(
+		makeRevokeShareEndpoint(svc),
+		decodeShareIDRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	return []api.Route{
+		&nhttp.Route{HandlerValue: createHandler, MethodValue: http.MethodPost, PathValue: "/v1/note/{id}/shares"},
+		&nhttp.Route{HandlerValue: listHandler, MethodValue: http.MethodGet, PathValue: "/v1/note/{id}/shares"},
+		&nhttp.Route{HandlerValue: revokeHandler, MethodValue: http.MethodDelete, PathValue: "/v1/note/{id}/shares/{user_id}"},
+	}
+}
+
+// LinkRoutes returns the routes for managing the public links
+// of the notes, and the routes of the shared notes. The routes of
+// the shared notes must be anonymous, see SharedPathPrefix.
+func LinkRoutes(svc *link.Service) []api.Route {
+	createHandler := httptransport.NewServer(
+		makeCreateLinkEndpoint(svc),
+		decodeCreateLinkRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	listHandler := httptransport.NewServer(
+		makeListLinksEndpoint(svc),
+		decodeLinkIDRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	revokeHandler := httptransport.NewServer(
+		makeRevokeLinkEndpoint(svc),
+		decodeLinkIDRequest,
+		encodeResponse,
+		serverOptions...,
+	)
+
+	sharedHandler := httptransport.NewServer(
+		makeSharedNoteEndpoint(svc),
+		decodeSharedNoteRequest,
+		encodeSharedNoteResponse,
+		serverOptions...,
+	)
+
+	return []api.Route{
+		&nhttp.Route{HandlerValue: createHandler, MethodValue: http.MethodPost, PathValue: "/v1/note/{id}/links"},
+		&nhttp.Route{HandlerValue: listHandler, MethodValue: http.MethodGet, PathValue: "/v1/note/{id}/links"},
+		&nhttp.Route{HandlerValue: revokeHandler, MethodValue: http.MethodDelete, PathValue: "/v1/note/{id}/links/{link_id}"},
+		&nhttp.Route{HandlerValue: sharedHandler, MethodValue: http.MethodGet, PathValue: SharedPathPrefix + "{token}"},
+		&nhttp.Route{HandlerValue: sharedHandler, MethodValue: http.MethodPost, PathValue: SharedPathPrefix + "{token}"},
+	}
+}
+
 func getRoutes(svc note.Service) []api.Route {
 
 	getHandler := httptransport.NewServer(
 		makeGetEndpoint(svc),
 		decodeGetRequest,
 		encodeResponse,
+		serverOptions...,
 	)
 
 	createHandler := httptransport.NewServer(
 		makeCreateEndpoint(svc),
 		decodeCreateRequest,
 		encodeResponse,
+		serverOptions...,
 	)
 
 	updateHandler := httptransport.NewServer(
 		makeUpdateEndpoint(svc),
 		decodeUpdateRequest,
 		encodeResponse,
+		serverOptions...,
 	)
 
 	deleteHandler := httptransport.NewServer(
 		makeDeleteEndpoint(svc),
 		decodeDeleteRequest,
 		encodeResponse,
+		serverOptions...,
 	)
 
 	fetchHandler := httptransport.NewServer(
 		makeFetchEndpoint(svc),
 		decodeFetchRequest,
 		encodeResponse,
+		serverOptions...,
+	)
+
+	exportHandler := httptransport.NewServer(
+		makeExportEndpoint(svc),
+		decodeExportRequest,
+		encodeExportResponse,
+		serverOptions...,
+	)
+
+	importHandler := httptransport.NewServer(
+		makeImportEndpoint(svc),
+		decodeImportRequest,
+		encodeResponse,
+		serverOptions...,
 	)
 
 	routes := []api.Route{
@@ -52,6 +220,8 @@ func getRoutes(svc note.Service) []api.Route {
 		&nhttp.Route{HandlerValue: updateHandler, MethodValue: http.MethodPut, PathValue: "/v1/note"},
 		&nhttp.Route{HandlerValue: deleteHandler, MethodValue: http.MethodDelete, PathValue: "/v1/note/{id}"},
 		&nhttp.Route{HandlerValue: fetchHandler, MethodValue: http.MethodGet, PathValue: "/v1/notes"},
+		&nhttp.Route{HandlerValue: exportHandler, MethodValue: http.MethodGet, PathValue: "/v1/notes/export"},
+		&nhttp.Route{HandlerValue: importHandler, MethodValue: http.MethodPost, PathValue: "/v1/notes/import"},
 	}
 	return routes
 }
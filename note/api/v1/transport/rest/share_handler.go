@@ -0,0 +1,103 @@
+package rest
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"net/http"
+	"noteapp/note"
+)
+
+type shareService interface {
+	Share(ctx context.Context, noteID uuid.UUID, userID string, role note.Role) (*note.Grant, error)
+	Grants(ctx context.Context, noteID uuid.UUID) ([]*note.Grant, error)
+	Revoke(ctx context.Context, noteID uuid.UUID, userID string) error
+}
+
+type createShareRequest struct {
+	ID     string    `json:"-"`
+	UserID string    `json:"user_id"`
+	Role   note.Role `json:"role"`
+}
+
+type shareIDRequest struct {
+	ID     string
+	UserID string
+}
+
+type shareResponse struct {
+	Share *note.Grant `json:"share"`
+}
+
+type listSharesResponse struct {
+	Shares []*note.Grant `json:"shares"`
+}
+
+func decodeCreateShareRequest(_ context.Context, r *http.Request) (response interface{}, err error) {
+	var req createShareRequest
+	err = decodeBody(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		cerr := r.Body.Close()
+		if cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	req.ID = mux.Vars(r)["id"]
+	return req, nil
+}
+
+func decodeShareIDRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	return shareIDRequest{ID: vars["id"], UserID: vars["user_id"]}, nil
+}
+
+func makeCreateShareEndpoint(svc shareService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(createShareRequest)
+		id, err := parseNoteID(request.ID)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+
+		grant, err := svc.Share(ctx, id, request.UserID, request.Role)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return shareResponse{Share: grant}, nil
+	}
+}
+
+func makeListSharesEndpoint(svc shareService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		id, err := parseNoteID(req.(shareIDRequest).ID)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+
+		grants, err := svc.Grants(ctx, id)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return listSharesResponse{Shares: grants}, nil
+	}
+}
+
+func makeRevokeShareEndpoint(svc shareService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(shareIDRequest)
+		id, err := parseNoteID(request.ID)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+
+		if err := svc.Revoke(ctx, id, request.UserID); err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return deleteResponse{"Successfully Revoked"}, nil
+	}
+}
@@ -0,0 +1,96 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/spf13/afero"
+	"net/http"
+	"net/http/httptest"
+	"noteapp/api/problem"
+	"noteapp/note"
+	"noteapp/note/service"
+	"noteapp/note/share"
+	"noteapp/note/store/memory"
+)
+
+func (s *HandlerTestSuite) TestShares() {
+
+	type shareResponseJSON struct {
+		Share  *note.Grant   `json:"share"`
+		Shares []*note.Grant `json:"shares"`
+		Notes  []*note.Note  `json:"notes"`
+		problem.Problem
+	}
+
+	file, err := afero.NewMemMapFs().Create("shares.json")
+	s.require.NoError(err)
+	store, err := share.NewStore(file)
+	s.require.NoError(err)
+
+	svc := service.New(memory.New(), service.WithGrants(store))
+	shareRoutes, noteRoutes := makeShareHandler(share.NewService(svc, store)), makeHandler(svc)
+
+	created, err := svc.Create(note.WithOwner(dummyCtx, "alice"), new(note.Note).SetTitle("Shared"))
+	s.require.NoError(err)
+
+	serve := func(routes http.Handler, owner, method, path string, body interface{}) (*httptest.ResponseRecorder, shareResponseJSON) {
+		var buf bytes.Buffer
+		if body != nil {
+			s.require.NoError(json.NewEncoder(&buf).Encode(body))
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, &buf)
+		routes.ServeHTTP(rec, req.WithContext(note.WithOwner(req.Context(), owner)))
+
+		var resp shareResponseJSON
+		s.require.NoError(json.NewDecoder(rec.Body).Decode(&resp))
+		return rec, resp
+	}
+
+	sharesPath := "/note/" + created.ID.String() + "/shares"
+
+	s.Run("Sharing a note", func() {
+		rec, resp := serve(shareRoutes, "alice", http.MethodPost, sharesPath, map[string]string{"user_id": "bob", "role": "viewer"})
+		s.assertStatusCode(rec, http.StatusOK)
+		s.require.NotNil(resp.Share)
+		s.Equal(note.RoleViewer, resp.Share.Role)
+
+		rec, resp = serve(shareRoutes, "bob", http.MethodGet, sharesPath, nil)
+		s.assertStatusCode(rec, http.StatusOK)
+		s.Len(resp.Shares, 1)
+
+		rec, resp = serve(noteRoutes, "bob", http.MethodGet, "/notes?shared_with_me=true", nil)
+		s.assertStatusCode(rec, http.StatusOK)
+		s.require.Len(resp.Notes, 1)
+		s.Equal(created.ID, resp.Notes[0].ID)
+	})
+
+	s.Run("Updating a note shared with the viewer role should be forbidden", func() {
+		rec, resp := serve(noteRoutes, "bob", http.MethodPut, "/note", map[string]interface{}{
+			"note": map[string]string{"id": created.ID.String(), "title": "Edited"},
+		})
+		s.assertStatusCode(rec, http.StatusForbidden)
+		s.Equal("permission_denied", resp.Code)
+	})
+
+	s.Run("Invalid share role should return an error", func() {
+		rec, resp := serve(shareRoutes, "alice", http.MethodPost, sharesPath, map[string]string{"user_id": "bob", "role": "admin"})
+		s.assertStatusCode(rec, http.StatusBadRequest)
+		s.Equal("invalid_share_role", resp.Code)
+		s.Equal("role", resp.Field)
+	})
+
+	s.Run("Revoking a share", func() {
+		rec, _ := serve(shareRoutes, "alice", http.MethodDelete, sharesPath+"/bob", nil)
+		s.assertStatusCode(rec, http.StatusOK)
+
+		rec, resp := serve(noteRoutes, "bob", http.MethodGet, "/note/"+created.ID.String(), nil)
+		s.assertStatusCode(rec, http.StatusNotFound)
+		s.Equal("note_not_found", resp.Code)
+
+		rec, resp = serve(shareRoutes, "alice", http.MethodDelete, sharesPath+"/bob", nil)
+		s.assertStatusCode(rec, http.StatusNotFound)
+		s.Equal("share_not_found", resp.Code)
+	})
+}
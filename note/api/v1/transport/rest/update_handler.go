@@ -2,10 +2,12 @@ package rest
 
 import (
 	"context"
-	"encoding/json"
 	"github.com/go-kit/kit/endpoint"
+	"google.golang.org/protobuf/proto"
 	"net/http"
 	"noteapp/note"
+	pb "noteapp/note/proto"
+	"noteapp/note/proto/protoutil"
 )
 
 type updateService interface {
@@ -20,9 +22,33 @@ type updateResponse struct {
 	Note *note.Note `json:"note"`
 }
 
+// decodeProto decodes the update request message. Only the fields
+// of the update mask are updated, all of them when it is empty.
+func (req *updateRequest) decodeProto(b []byte) error {
+	var msg pb.UpdateRequest
+	if err := decodeProtoMessage(b, &msg); err != nil {
+		return err
+	}
+
+	if msg.GetNote() == nil {
+		return nil
+	}
+
+	n, err := protoutil.DecodeNote(msg.GetNote(), msg.GetUpdateMask().GetPaths())
+	if err != nil {
+		return decodeProtoError(err)
+	}
+	req.Note = n
+	return nil
+}
+
+func (resp updateResponse) protoMessage() proto.Message {
+	return &pb.UpdateResponse{Note: protoutil.EncodeNote(resp.Note)}
+}
+
 func decodeUpdateRequest(_ context.Context, r *http.Request) (reqOut interface{}, err error) {
 	var req updateRequest
-	err = json.NewDecoder(r.Body).Decode(&req)
+	err = decodeBody(r, &req)
 	if err != nil {
 		return nil, err
 	}
@@ -42,11 +68,7 @@ func makeUpdateEndpoint(svc updateService) endpoint.Endpoint {
 
 		updatedNote, err := svc.Update(ctx, request.Note)
 		if err != nil {
-			return errorWrapper{
-				origErr:    err,
-				message:    getMessage(err),
-				statusCode: getStatusCode(err),
-			}, nil
+			return newErrorWrapper(err), nil
 		}
 		return updateResponse{Note: updatedNote}, nil
 	}
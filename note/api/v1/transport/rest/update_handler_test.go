@@ -62,7 +62,7 @@ func (s *HandlerTestSuite) TestUpdate() {
 		responseRecorder := makeRequest(dummyCtx, updatedNote)
 		s.assertStatusCode(responseRecorder, http.StatusNotFound)
 		resp := s.decodeResponse(responseRecorder)
-		s.assertMessage(resp, "Note not found")
+		s.assertTitle(resp, "Note not found")
 	})
 
 	s.Run("Cancelled request should return an error", func() {
@@ -75,6 +75,6 @@ func (s *HandlerTestSuite) TestUpdate() {
 		responseRecorder := makeRequest(cancelledCtx, updatedNote)
 		s.assertStatusCode(responseRecorder, StatusClientClosed)
 		resp := s.decodeResponse(responseRecorder)
-		s.assertMessage(resp, "Request cancelled")
+		s.assertTitle(resp, "Request cancelled")
 	})
 }
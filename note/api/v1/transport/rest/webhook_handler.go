@@ -0,0 +1,164 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"net/http"
+	"noteapp/note"
+	"noteapp/note/webhook"
+	"time"
+)
+
+// errInvalidWebhookID is an error when the webhook id in
+// the path is not a valid uuid.
+var errInvalidWebhookID = errors.New("rest: invalid webhook id")
+
+type webhookService interface {
+	CreateSubscription(ctx context.Context, sub *webhook.Subscription) (*webhook.Subscription, error)
+	Subscriptions(ctx context.Context) []*webhook.Subscription
+	Subscription(ctx context.Context, id uuid.UUID) (*webhook.Subscription, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	Deliveries(ctx context.Context, id uuid.UUID) ([]*webhook.Delivery, error)
+}
+
+// webhookView is the subscription without its secret.
+type webhookView struct {
+	ID          uuid.UUID        `json:"id"`
+	URL         string           `json:"url"`
+	Events      []note.EventType `json:"events"`
+	CreatedTime time.Time        `json:"created_time"`
+}
+
+func newWebhookView(sub *webhook.Subscription) *webhookView {
+	events := sub.Events
+	if events == nil {
+		events = []note.EventType{}
+	}
+	return &webhookView{ID: sub.ID, URL: sub.URL, Events: events, CreatedTime: sub.CreatedTime}
+}
+
+type createWebhookRequest struct {
+	URL    string           `json:"url"`
+	Events []note.EventType `json:"events"`
+	Secret string           `json:"secret"`
+}
+
+type webhookIDRequest struct {
+	ID string
+}
+
+type webhookResponse struct {
+	Webhook *webhookView `json:"webhook"`
+}
+
+type listWebhooksResponse struct {
+	Webhooks []*webhookView `json:"webhooks"`
+}
+
+type deliveriesResponse struct {
+	Deliveries []*webhook.Delivery `json:"deliveries"`
+}
+
+func decodeCreateWebhookRequest(_ context.Context, r *http.Request) (response interface{}, err error) {
+	var req createWebhookRequest
+	err = decodeBody(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		cerr := r.Body.Close()
+		if cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	return req, nil
+}
+
+func decodeListWebhooksRequest(_ context.Context, _ *http.Request) (interface{}, error) {
+	return struct{}{}, nil
+}
+
+func decodeWebhookIDRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return webhookIDRequest{ID: mux.Vars(r)["id"]}, nil
+}
+
+func parseWebhookID(s string) (uuid.UUID, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, newRequestError("id", errInvalidWebhookID, err)
+	}
+	return id, nil
+}
+
+func makeCreateWebhookEndpoint(svc webhookService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		request := req.(createWebhookRequest)
+		sub, err := svc.CreateSubscription(ctx, &webhook.Subscription{
+			URL:    request.URL,
+			Events: request.Events,
+			Secret: request.Secret,
+		})
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return webhookResponse{Webhook: newWebhookView(sub)}, nil
+	}
+}
+
+func makeListWebhooksEndpoint(svc webhookService) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		views := []*webhookView{}
+		for _, sub := range svc.Subscriptions(ctx) {
+			views = append(views, newWebhookView(sub))
+		}
+		return listWebhooksResponse{Webhooks: views}, nil
+	}
+}
+
+func makeGetWebhookEndpoint(svc webhookService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		id, err := parseWebhookID(req.(webhookIDRequest).ID)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+
+		sub, err := svc.Subscription(ctx, id)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return webhookResponse{Webhook: newWebhookView(sub)}, nil
+	}
+}
+
+func makeDeleteWebhookEndpoint(svc webhookService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		id, err := parseWebhookID(req.(webhookIDRequest).ID)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+
+		if err := svc.DeleteSubscription(ctx, id); err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return deleteResponse{"Successfully Deleted"}, nil
+	}
+}
+
+func makeDeliveriesEndpoint(svc webhookService) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		id, err := parseWebhookID(req.(webhookIDRequest).ID)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+
+		deliveries, err := svc.Deliveries(ctx, id)
+		if err != nil {
+			return newErrorWrapper(err), nil
+		}
+		return deliveriesResponse{Deliveries: deliveries}, nil
+	}
+}
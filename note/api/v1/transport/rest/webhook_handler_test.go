@@ -0,0 +1,161 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"net/http"
+	"net/http/httptest"
+	"noteapp/note"
+	"noteapp/note/webhook"
+)
+
+func (s *HandlerTestSuite) TestWebhooks() {
+
+	type webhookJSON struct {
+		ID     uuid.UUID        `json:"id"`
+		URL    string           `json:"url"`
+		Events []note.EventType `json:"events"`
+		Secret string           `json:"secret"`
+	}
+
+	type webhookResponseJSON struct {
+		Webhook    *webhookJSON        `json:"webhook"`
+		Webhooks   []*webhookJSON      `json:"webhooks"`
+		Deliveries []*webhook.Delivery `json:"deliveries"`
+		Message    string              `json:"message"`
+		Title      string              `json:"title"`
+	}
+
+	setup := func() (*webhook.Store, http.Handler) {
+		file, err := afero.NewMemMapFs().Create("webhooks.json")
+		s.require.NoError(err)
+		store, err := webhook.NewStore(file)
+		s.require.NoError(err)
+		return store, makeWebhookHandler(store)
+	}
+
+	serve := func(routes http.Handler, method, path string, body interface{}) (*httptest.ResponseRecorder, webhookResponseJSON) {
+		var buf bytes.Buffer
+		if body != nil {
+			s.require.NoError(json.NewEncoder(&buf).Encode(body))
+		}
+
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, httptest.NewRequest(method, path, &buf))
+
+		var resp webhookResponseJSON
+		s.require.NoError(json.NewDecoder(rec.Body).Decode(&resp))
+		return rec, resp
+	}
+
+	s.Run("Managing webhooks", func() {
+		_, routes := setup()
+
+		rec, resp := serve(routes, http.MethodPost, "/webhooks", map[string]interface{}{
+			"url":    "https://example.com/hook",
+			"events": []string{"created", "deleted"},
+			"secret": "secret",
+		})
+		s.assertStatusCode(rec, http.StatusOK)
+		s.require.NotNil(resp.Webhook)
+		s.Equal("https://example.com/hook", resp.Webhook.URL)
+		s.Equal([]note.EventType{note.EventCreated, note.EventDeleted}, resp.Webhook.Events)
+		s.Empty(resp.Webhook.Secret, "Expecting the secret to not be returned")
+		id := resp.Webhook.ID.String()
+
+		rec, resp = serve(routes, http.MethodGet, "/webhooks", nil)
+		s.assertStatusCode(rec, http.StatusOK)
+		s.Len(resp.Webhooks, 1)
+
+		rec, resp = serve(routes, http.MethodGet, "/webhooks/"+id, nil)
+		s.assertStatusCode(rec, http.StatusOK)
+		s.Equal(id, resp.Webhook.ID.String())
+
+		rec, resp = serve(routes, http.MethodGet, "/webhooks/"+id+"/deliveries", nil)
+		s.assertStatusCode(rec, http.StatusOK)
+		s.NotNil(resp.Deliveries)
+		s.Empty(resp.Deliveries)
+
+		rec, resp = serve(routes, http.MethodDelete, "/webhooks/"+id, nil)
+		s.assertStatusCode(rec, http.StatusOK)
+		s.Equal("Successfully Deleted", resp.Message)
+
+		rec, resp = serve(routes, http.MethodGet, "/webhooks/"+id, nil)
+		s.assertStatusCode(rec, http.StatusNotFound)
+		s.Equal("Webhook not found", resp.Title)
+	})
+
+	s.Run("Listing without webhooks should return an empty list", func() {
+		_, routes := setup()
+		rec, resp := serve(routes, http.MethodGet, "/webhooks", nil)
+		s.assertStatusCode(rec, http.StatusOK)
+		s.NotNil(resp.Webhooks)
+		s.Empty(resp.Webhooks)
+	})
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    interface{}
+		status  int
+		message string
+	}{
+		{
+			name:    "Invalid url should return an error",
+			method:  http.MethodPost,
+			path:    "/webhooks",
+			body:    map[string]interface{}{"url": "example.com", "secret": "secret"},
+			status:  http.StatusBadRequest,
+			message: "Invalid webhook url",
+		},
+		{
+			name:    "Invalid event should return an error",
+			method:  http.MethodPost,
+			path:    "/webhooks",
+			body:    map[string]interface{}{"url": "https://example.com", "events": []string{"moved"}, "secret": "secret"},
+			status:  http.StatusBadRequest,
+			message: "Invalid webhook event",
+		},
+		{
+			name:    "Empty secret should return an error",
+			method:  http.MethodPost,
+			path:    "/webhooks",
+			body:    map[string]interface{}{"url": "https://example.com"},
+			status:  http.StatusBadRequest,
+			message: "Empty webhook secret",
+		},
+		{
+			name:    "Invalid webhook id should return an error",
+			method:  http.MethodGet,
+			path:    "/webhooks/abc",
+			status:  http.StatusBadRequest,
+			message: "Invalid webhook identifier",
+		},
+		{
+			name:    "Deleting a webhook that doesn't exist should return an error",
+			method:  http.MethodDelete,
+			path:    "/webhooks/" + uuid.New().String(),
+			status:  http.StatusNotFound,
+			message: "Webhook not found",
+		},
+		{
+			name:    "Deliveries of a webhook that doesn't exist should return an error",
+			method:  http.MethodGet,
+			path:    "/webhooks/" + uuid.New().String() + "/deliveries",
+			status:  http.StatusNotFound,
+			message: "Webhook not found",
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			_, routes := setup()
+			rec, resp := serve(routes, tt.method, tt.path, tt.body)
+			s.assertStatusCode(rec, tt.status)
+			s.Equal(tt.message, resp.Title)
+		})
+	}
+}
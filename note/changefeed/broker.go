@@ -0,0 +1,153 @@
+package changefeed
+
+import (
+	"context"
+	"noteapp/note"
+	"sync"
+)
+
+// subscriptionBufferSize is the number of events that a subscriber
+// can fall behind before it is dropped.
+const subscriptionBufferSize = 64
+
+// Broker fans out the note events to the subscribers of the change
+// feed. It keeps the latest events in a bounded buffer so that a
+// subscriber can resume from the last event it has seen. The broker
+// expects the events in the order of their ids without gaps, so it
+// should handle all the events of the event bus. This is safe for
+// concurrent use.
+type Broker struct {
+	mu          sync.Mutex
+	seq         uint64
+	buffer      []note.Event
+	start       int
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// New returns a broker that keeps at most replaySize events
+// for resuming subscribers.
+func New(replaySize int) *Broker {
+	if replaySize < 1 {
+		replaySize = 1
+	}
+
+	return &Broker{
+		buffer:      make([]note.Event, 0, replaySize),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Handle passes the event e to all the subscribers. It never blocks,
+// a subscriber that can't keep up is dropped and has to subscribe again.
+func (b *Broker) Handle(_ context.Context, e note.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq = e.ID
+	if len(b.buffer) < cap(b.buffer) {
+		b.buffer = append(b.buffer, e)
+	} else {
+		b.buffer[b.start] = e
+		b.start = (b.start + 1) % len(b.buffer)
+	}
+
+	for sub := range b.subscribers {
+		select {
+		case sub.events <- e:
+		default:
+			b.unsubscribe(sub)
+		}
+	}
+}
+
+// Replay contains the buffered events after the last event
+// seen by a subscriber.
+type Replay struct {
+	Events []note.Event
+	// Missed is true when some of the events after the last event
+	// are no longer in the buffer.
+	Missed bool
+}
+
+// Subscribe returns a subscription for the events published after
+// it and the replay of the buffered events after the lastEventID. A zero
+// lastEventID means the subscriber hasn't seen any event yet and won't
+// get a replay. A lastEventID after the latest event, such as one from
+// before a restart, is taken as missed. The subscription is already
+// closed when the broker is closed.
+func (b *Broker) Subscribe(lastEventID uint64) (*Subscription, Replay) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &Subscription{broker: b, events: make(chan note.Event, subscriptionBufferSize)}
+	if b.closed {
+		close(sub.events)
+		return sub, Replay{}
+	}
+	b.subscribers[sub] = struct{}{}
+
+	var replay Replay
+	if lastEventID == 0 || lastEventID == b.seq {
+		return sub, replay
+	}
+
+	if lastEventID > b.seq {
+		replay.Missed = true
+		return sub, replay
+	}
+
+	for i := 0; i < len(b.buffer); i++ {
+		e := b.buffer[(b.start+i)%len(b.buffer)]
+		if e.ID > lastEventID {
+			replay.Events = append(replay.Events, e)
+		}
+	}
+
+	oldest := b.seq + 1
+	if len(replay.Events) > 0 {
+		oldest = replay.Events[0].ID
+	}
+	replay.Missed = oldest > lastEventID+1
+
+	return sub, replay
+}
+
+// Close closes all the subscriptions and the ones made afterwards,
+// so that the subscribers reconnect to another server while this one
+// shuts down. The events are still kept for the replays.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		b.unsubscribe(sub)
+	}
+}
+
+func (b *Broker) unsubscribe(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Subscription receives the events published by the broker.
+type Subscription struct {
+	broker *Broker
+	events chan note.Event
+}
+
+// Events returns the channel of the published events. The channel
+// is closed when the subscription is closed or dropped by the broker.
+func (s *Subscription) Events() <-chan note.Event {
+	return s.events
+}
+
+// Close stops the subscription.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.unsubscribe(s)
+}
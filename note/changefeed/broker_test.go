@@ -0,0 +1,198 @@
+package changefeed
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"noteapp/note"
+	"noteapp/note/eventbus"
+	"testing"
+)
+
+var dummyCtx = context.TODO()
+
+func Test(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+type TestSuite struct {
+	suite.Suite
+}
+
+// newBroker returns a broker handling all the events of the bus.
+func newBroker(replaySize int) (*Broker, *eventbus.Bus) {
+	b, bus := New(replaySize), eventbus.New()
+	bus.Subscribe(b)
+	return b, bus
+}
+
+func newNote(title string) *note.Note {
+	n := new(note.Note)
+	n.SetID(uuid.New()).SetTitle(title)
+	return n
+}
+
+func (s *TestSuite) receive(sub *Subscription, size int) (events []note.Event) {
+	for i := 0; i < size; i++ {
+		e, ok := <-sub.Events()
+		s.Require().True(ok, "Expecting the subscription to be open")
+		events = append(events, e)
+	}
+	return
+}
+
+func (s *TestSuite) TestPublish() {
+	s.Run("Subscribers should receive the events in order", func() {
+		b, bus := newBroker(10)
+		first, _ := b.Subscribe(0)
+		second, _ := b.Subscribe(0)
+		defer first.Close()
+		defer second.Close()
+
+		n := newNote("Test")
+		bus.Publish(dummyCtx, note.EventCreated, n)
+		bus.Publish(dummyCtx, note.EventUpdated, n)
+		bus.Publish(dummyCtx, note.EventDeleted, n)
+
+		for _, sub := range []*Subscription{first, second} {
+			events := s.receive(sub, 3)
+			for i, want := range []note.EventType{note.EventCreated, note.EventUpdated, note.EventDeleted} {
+				s.Equal(uint64(i+1), events[i].ID)
+				s.Equal(want, events[i].Type)
+				s.Equal(n, events[i].Note)
+			}
+		}
+	})
+
+	s.Run("Slow subscriber should be dropped", func() {
+		b, bus := newBroker(10)
+		sub, _ := b.Subscribe(0)
+
+		n := newNote("Test")
+		for i := 0; i < subscriptionBufferSize+1; i++ {
+			bus.Publish(dummyCtx, note.EventUpdated, n)
+		}
+
+		var received int
+		for range sub.Events() {
+			received++
+		}
+		s.Equal(subscriptionBufferSize, received)
+
+		// Closing a dropped subscription should do nothing.
+		sub.Close()
+	})
+}
+
+func (s *TestSuite) TestSubscribe() {
+	publish := func(bus *eventbus.Bus, size int) {
+		for i := 0; i < size; i++ {
+			bus.Publish(dummyCtx, note.EventCreated, newNote("Test"))
+		}
+	}
+
+	ids := func(events []note.Event) (ids []uint64) {
+		for _, e := range events {
+			ids = append(ids, e.ID)
+		}
+		return
+	}
+
+	s.Run("Zero last event id should not replay", func() {
+		b, bus := newBroker(10)
+		publish(bus, 3)
+
+		sub, replay := b.Subscribe(0)
+		defer sub.Close()
+		s.Empty(replay.Events)
+		s.False(replay.Missed)
+	})
+
+	s.Run("Resuming should replay the events after the last event id", func() {
+		b, bus := newBroker(10)
+		publish(bus, 5)
+
+		sub, replay := b.Subscribe(2)
+		defer sub.Close()
+		s.Equal([]uint64{3, 4, 5}, ids(replay.Events))
+		s.False(replay.Missed)
+
+		publish(bus, 1)
+		s.Equal(uint64(6), s.receive(sub, 1)[0].ID)
+	})
+
+	s.Run("Resuming from the latest event should not replay", func() {
+		b, bus := newBroker(10)
+		publish(bus, 5)
+
+		sub, replay := b.Subscribe(5)
+		defer sub.Close()
+		s.Empty(replay.Events)
+		s.False(replay.Missed)
+	})
+
+	s.Run("Resuming from an event outside the buffer should be missed", func() {
+		b, bus := newBroker(3)
+		publish(bus, 10)
+
+		sub, replay := b.Subscribe(2)
+		defer sub.Close()
+		s.Equal([]uint64{8, 9, 10}, ids(replay.Events))
+		s.True(replay.Missed)
+	})
+
+	s.Run("Resuming from the event before the buffer should not be missed", func() {
+		b, bus := newBroker(3)
+		publish(bus, 10)
+
+		sub, replay := b.Subscribe(7)
+		defer sub.Close()
+		s.Equal([]uint64{8, 9, 10}, ids(replay.Events))
+		s.False(replay.Missed)
+	})
+
+	s.Run("Resuming from an event after the latest event should be missed", func() {
+		b, bus := newBroker(3)
+		publish(bus, 2)
+
+		sub, replay := b.Subscribe(5)
+		defer sub.Close()
+		s.Empty(replay.Events)
+		s.True(replay.Missed)
+	})
+
+	s.Run("Closed subscription should not receive events", func() {
+		b, bus := newBroker(3)
+		sub, _ := b.Subscribe(0)
+		sub.Close()
+		publish(bus, 1)
+
+		_, ok := <-sub.Events()
+		s.False(ok)
+	})
+}
+
+func (s *TestSuite) TestClose() {
+	s.Run("Closing should close the subscriptions", func() {
+		b, bus := newBroker(3)
+		sub, _ := b.Subscribe(0)
+		b.Close()
+		bus.Publish(dummyCtx, note.EventCreated, newNote("Test"))
+
+		_, ok := <-sub.Events()
+		s.False(ok)
+		sub.Close()
+	})
+
+	s.Run("Subscribing after closing should return a closed subscription", func() {
+		b, bus := newBroker(3)
+		bus.Publish(dummyCtx, note.EventCreated, newNote("Test"))
+		bus.Publish(dummyCtx, note.EventCreated, newNote("Test"))
+		b.Close()
+
+		sub, replay := b.Subscribe(1)
+		_, ok := <-sub.Events()
+		s.False(ok)
+		s.Empty(replay.Events)
+	})
+}
@@ -1,9 +1,15 @@
 package cli
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/spf13/cobra"
+	"noteapp/note/cli/exportcmd"
+	"noteapp/note/cli/importcmd"
+)
 
 func init() {
 	Cmd.AddCommand(UtilsCmd)
+	Cmd.AddCommand(importcmd.ImportCmd)
+	Cmd.AddCommand(exportcmd.ExportCmd)
 }
 
 // Cmd is the root command for the note package.
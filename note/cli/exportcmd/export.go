@@ -0,0 +1,101 @@
+package exportcmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"net/http"
+	"noteapp/cli"
+	"noteapp/note"
+	"noteapp/note/importer"
+	noteservice "noteapp/note/service"
+	filestore "noteapp/note/store/file"
+	"os"
+	"strings"
+)
+
+var (
+	serverURL  string
+	dbFileName string
+	creds      cli.Credentials
+)
+
+func init() {
+	ExportCmd.PersistentFlags().StringVarP(&serverURL, "server", "s", "", "The noteapp server URL to export the notes from.")
+	ExportCmd.PersistentFlags().StringVar(&dbFileName, "db", "note.pb", "The file store to read the notes from when there's no server.")
+	creds.AddFlags(ExportCmd)
+
+	ExportCmd.AddCommand(markdownCmd)
+}
+
+// ExportCmd is a cli command where it contains the
+// commands to export the notes into other formats.
+var ExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "A subcommand for exporting the notes",
+}
+
+// forEachNote calls fn for each of the notes either exported from
+// the server or read from the local file store.
+func forEachNote(ctx context.Context, fn func(n *note.Note) error) error {
+	if serverURL != "" {
+		return forEachServerNote(ctx, fn)
+	}
+
+	db, err := os.Open(dbFileName)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	iter, err := noteservice.New(filestore.New(db)).Export(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = iter.Close() }()
+	for iter.Next() {
+		if err := fn(iter.Note()); err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
+func forEachServerNote(ctx context.Context, fn func(n *note.Note) error) error {
+	url := strings.TrimSuffix(serverURL, "/") + "/v1/notes/export"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/x-ndjson")
+	creds.Authorize(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("exportcmd: server responded %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	dec := importer.NewNDJSONDecoder(resp.Body)
+	for {
+		n, err := dec.Decode()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := fn(n); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package exportcmd
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"noteapp/note"
+	"noteapp/note/markdown"
+)
+
+var markdownDir string
+
+func init() {
+	markdownCmd.Flags().StringVarP(&markdownDir, "dir", "d", "", "The directory to write the markdown files to.")
+	_ = markdownCmd.MarkFlagRequired("dir")
+}
+
+var markdownCmd = &cobra.Command{
+	Use:   "markdown",
+	Short: "Use to export the notes into a directory of markdown files",
+	Long: `Use to export the notes into a directory of markdown files.
+
+Each note is written into its own markdown file named after its
+title. The YAML front matter holds the id, title, created_time,
+updated_time and is_favorite of the note.
+`,
+	Example: "noteapp_cli note export markdown --dir ./vault --db ./note.pb",
+	Run: func(cmd *cobra.Command, args []string) {
+		w, err := markdown.NewDirWriter(markdownDir)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		var count int
+		err = forEachNote(cmd.Context(), func(n *note.Note) error {
+			count++
+			return w.Write(n)
+		})
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		fmt.Printf("📚 Exported %d notes to %s\n", count, markdownDir)
+	},
+}
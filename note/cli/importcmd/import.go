@@ -0,0 +1,194 @@
+package importcmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io"
+	"net/http"
+	"noteapp/cli"
+	"noteapp/note"
+	"noteapp/note/importer"
+	noteservice "noteapp/note/service"
+	filestore "noteapp/note/store/file"
+	"noteapp/note/validation"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	formatNDJSON   = "ndjson"
+	formatProtobuf = "protobuf"
+)
+
+// rules are the validation rules of the notes written to the local
+// file store, the same as the defaults of the server.
+var rules = validation.Rules{
+	MaxTitleLength:   255,
+	MaxContentLength: 1 << 20,
+}
+
+var (
+	fileName   string
+	format     string
+	serverURL  string
+	dbFileName string
+	onConflict string
+	owner      string
+	creds      cli.Credentials
+)
+
+func init() {
+	ImportCmd.Flags().StringVarP(&fileName, "file", "f", "", "The filepath of the notes to import.")
+	ImportCmd.Flags().StringVar(&format, "format", "", "The format of the file, either ndjson or protobuf. Defaults to protobuf for .pb files, otherwise ndjson.")
+	ImportCmd.PersistentFlags().StringVarP(&serverURL, "server", "s", "", "The noteapp server URL to push the notes to.")
+	ImportCmd.PersistentFlags().StringVar(&dbFileName, "db", "note.pb", "The file store to write the notes to when there's no server.")
+	ImportCmd.PersistentFlags().StringVar(&onConflict, "on-conflict", string(importer.ConflictFail), "What to do with existing notes: fail, skip or upsert.")
+	ImportCmd.PersistentFlags().StringVar(&owner, "owner", "", "The user owning the notes written to the local file store.")
+	creds.AddFlags(ImportCmd)
+	_ = ImportCmd.MarkFlagRequired("file")
+
+	ImportCmd.AddCommand(markdownCmd)
+}
+
+// ImportCmd is a cli cmd that imports the notes from a file either
+// to a noteapp server or directly to a local file store.
+var ImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Use to import notes from a file",
+	Long: `Use to import notes from a file.
+
+The file contains either one JSON note per line, or the
+size prefixed protocol buffers like the file store. When the
+server is set the notes are pushed to the server, otherwise they
+are written straight into the local file store.
+`,
+	Example: `noteapp_cli note import --file ./notes.ndjson --server http://localhost:50001 --api-key $KEY
+noteapp_cli note import --file ./backup.pb --db ./note.pb --owner alice --on-conflict skip`,
+	Run: func(cmd *cobra.Command, args []string) {
+		mode, err := importer.ParseConflictMode(onConflict)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		file, err := os.Open(fileName)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		defer func() { _ = file.Close() }()
+
+		contentType, dec := "application/x-ndjson", importer.NewNDJSONDecoder(file)
+		if fileFormat() == formatProtobuf {
+			contentType, dec = "application/x-protobuf", importer.NewProtobufDecoder(file)
+		}
+
+		var summary *importer.Summary
+		if serverURL != "" {
+			summary, err = pushToServer(cmd.Context(), file, contentType, mode)
+		} else {
+			summary, err = writeToFileStore(cmd.Context(), dec, mode)
+		}
+
+		if summary != nil {
+			printSummary(summary)
+		}
+
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	},
+}
+
+func fileFormat() string {
+	if format != "" {
+		return format
+	}
+
+	if strings.EqualFold(filepath.Ext(fileName), ".pb") {
+		return formatProtobuf
+	}
+	return formatNDJSON
+}
+
+func pushToServer(ctx context.Context, r io.Reader, contentType string, mode importer.ConflictMode) (*importer.Summary, error) {
+	url := fmt.Sprintf("%s/v1/notes/import?on_conflict=%s", strings.TrimSuffix(serverURL, "/"), mode)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, r)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	creds.Authorize(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("importcmd: server responded %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var summary importer.Summary
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		return nil, err
+	}
+
+	return &summary, nil
+}
+
+// writeToFileStore imports the notes of dec into the local file store.
+// The notes are validated like the server does, and owned by the owner
+// when it is set.
+func writeToFileStore(ctx context.Context, dec importer.Decoder, mode importer.ConflictMode) (summary *importer.Summary, err error) {
+	v, err := validation.New(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := os.OpenFile(dbFileName, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	store := filestore.New(db)
+	defer func() {
+		if closeErr := store.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if owner != "" {
+		ctx = note.WithOwner(ctx, owner)
+	}
+
+	svc := noteservice.ValidatingMiddleware(v)(noteservice.New(store))
+	return importer.New(svc, mode).Import(ctx, dec)
+}
+
+func printSummary(summary *importer.Summary) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 4, ' ', tabwriter.TabIndent)
+	defer func() { _ = w.Flush() }()
+
+	for _, result := range summary.Results {
+		_, _ = fmt.Fprintf(w, "#%d\t%s\t%s\t%s\n", result.Index, result.Status, result.ID, result.Error)
+	}
+
+	_, _ = fmt.Fprintln(w)
+	for _, status := range []importer.Status{
+		importer.StatusCreated,
+		importer.StatusUpdated,
+		importer.StatusSkipped,
+		importer.StatusConflict,
+		importer.StatusInvalid,
+		importer.StatusFailed,
+	} {
+		_, _ = fmt.Fprintf(w, "📚 %s:\t%d\n", status, summary.Counts[status])
+	}
+}
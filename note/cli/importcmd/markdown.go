@@ -0,0 +1,92 @@
+package importcmd
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io"
+	"noteapp/note/importer"
+	"noteapp/note/markdown"
+	"os"
+)
+
+var markdownDir string
+
+func init() {
+	markdownCmd.Flags().StringVarP(&markdownDir, "dir", "d", "", "The directory of the markdown files to import.")
+	_ = markdownCmd.MarkFlagRequired("dir")
+}
+
+var markdownCmd = &cobra.Command{
+	Use:   "markdown",
+	Short: "Use to import notes from a directory of markdown files",
+	Long: `Use to import notes from a directory of markdown files.
+
+Each markdown file, including the ones in sub directories, is
+a note. The YAML front matter holds the id, title, created_time,
+updated_time and is_favorite of the note. Files without an id
+get one derived from their path so importing the same directory
+again updates the same notes.
+`,
+	Example: "noteapp_cli note import markdown --dir ./vault --on-conflict upsert",
+	Run: func(cmd *cobra.Command, args []string) {
+		mode, err := importer.ParseConflictMode(onConflict)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		dec, err := markdown.NewDirDecoder(os.DirFS(markdownDir))
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		var summary *importer.Summary
+		if serverURL != "" {
+			summary, err = pushToServer(cmd.Context(), newNDJSONReader(dec), "application/x-ndjson", mode)
+		} else {
+			summary, err = writeToFileStore(cmd.Context(), dec, mode)
+		}
+
+		if summary != nil {
+			printSummary(summary)
+		}
+
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	},
+}
+
+// newNDJSONReader returns a reader of the notes from dec encoded as
+// newline-delimited JSON. Notes that can't be decoded are logged and
+// left out.
+func newNDJSONReader(dec importer.Decoder) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		encoder := json.NewEncoder(pw)
+		for {
+			n, err := dec.Decode()
+			if err == io.EOF {
+				_ = pw.Close()
+				return
+			}
+
+			var invalid *importer.InvalidNoteError
+			if errors.As(err, &invalid) {
+				logrus.Warn(invalid)
+				continue
+			}
+
+			if err == nil {
+				err = encoder.Encode(n)
+			}
+
+			if err != nil {
+				_ = pw.CloseWithError(err)
+				return
+			}
+		}
+	}()
+	return pr
+}
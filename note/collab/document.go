@@ -0,0 +1,59 @@
+package collab
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidRevision is an error when an operation is based on
+// a revision the document doesn't have.
+var ErrInvalidRevision = errors.New("collab: invalid revision")
+
+// Document is the server copy of a text document edited by many
+// clients. It keeps the history of the applied operations to transform
+// the ones made on older revisions. This is not safe for concurrent use.
+type Document struct {
+	content string
+	history []*Operation
+}
+
+// NewDocument returns a document with the content at revision zero.
+func NewDocument(content string) *Document {
+	return &Document{content: content}
+}
+
+// Content returns the current content of the document.
+func (d *Document) Content() string {
+	return d.content
+}
+
+// Revision returns the number of operations applied to the document.
+func (d *Document) Revision() int {
+	return len(d.history)
+}
+
+// Apply applies op that has been made on the document at revision.
+// The op is transformed against the operations applied since that
+// revision and the transformed op is returned.
+func (d *Document) Apply(revision int, op *Operation) (*Operation, error) {
+	if revision < 0 || revision > len(d.history) {
+		return nil, fmt.Errorf("collab: revision %d of %d: %w", revision, len(d.history), ErrInvalidRevision)
+	}
+
+	for _, concurrent := range d.history[revision:] {
+		var err error
+		op, _, err = Transform(op, concurrent)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	content, err := op.Apply(d.content)
+	if err != nil {
+		return nil, err
+	}
+
+	d.content = content
+	d.history = append(d.history, op)
+	return op, nil
+}
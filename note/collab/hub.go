@@ -0,0 +1,399 @@
+package collab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"noteapp/note"
+	"sync"
+	"time"
+)
+
+// clientBufferSize is the number of messages that a client can
+// fall behind before it is disconnected.
+const clientBufferSize = 64
+
+// Conn is a message connection to a collaborating client. The
+// gorilla websocket connection implements it. ReadJSON and WriteJSON
+// are not called concurrently.
+type Conn interface {
+	ReadJSON(v interface{}) error
+	WriteJSON(v interface{}) error
+	Close() error
+}
+
+// messageType is the type of message exchanged with the clients.
+type messageType string
+
+const (
+	// messageInit is sent to a client when it joins with the
+	// content of the note and its revision.
+	messageInit messageType = "init"
+	// messageOp is sent by a client with an operation made on the
+	// revision it has seen, and to the other clients with the
+	// transformed operation and the new revision.
+	messageOp messageType = "op"
+	// messageAck is sent to a client when its operation has been
+	// applied with the new revision.
+	messageAck messageType = "ack"
+	// messageError is sent to a client before it is disconnected
+	// because of an invalid message.
+	messageError messageType = "error"
+)
+
+type message struct {
+	Type     messageType `json:"type"`
+	Revision int         `json:"revision"`
+	Content  *string     `json:"content,omitempty"`
+	Op       *Operation  `json:"op,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// Hub manages the collaborative editing sessions of the note contents.
+// A session is started when the first client joins a note and ends
+// when the last one leaves. The converged content is saved through
+// the service periodically and when the session ends, as the owner
+// of the note. The role of a client is checked on every operation so
+// that revoking its grant takes effect right away.
+type Hub struct {
+	svc      note.Service
+	grants   note.Grants
+	interval time.Duration
+
+	mu       sync.Mutex
+	sessions map[uuid.UUID]*session
+	closed   bool
+}
+
+// ErrClosed is an error when a client joins after the hub is closed.
+var ErrClosed = errors.New("collab: hub closed")
+
+// NewHub takes the service for loading and saving the notes, the share
+// grants of the notes and the interval between the saves of the edited
+// contents and returns a hub. The grants can be nil when the notes are
+// not shared.
+func NewHub(svc note.Service, grants note.Grants, interval time.Duration) *Hub {
+	return &Hub{
+		svc:      svc,
+		grants:   grants,
+		interval: interval,
+		sessions: make(map[uuid.UUID]*session),
+	}
+}
+
+// Join joins the client on conn to the editing session of the note
+// with an id and serves it until the connection is closed. The client
+// acts as the owner in ctx, see note.WithOwner. It returns ErrNotFound
+// when the note doesn't exist, and note.ErrPermissionDenied when the
+// owner can't update the note anymore, and ErrClosed when the hub is
+// closed.
+func (h *Hub) Join(ctx context.Context, id uuid.UUID, conn Conn) error {
+	s, err := h.acquire(ctx, id)
+	if err != nil {
+		return err
+	}
+	defer h.release(s)
+
+	c := s.join(conn)
+	defer s.leave(c)
+
+	for {
+		var msg message
+		if err := conn.ReadJSON(&msg); err != nil {
+			// The client has gone away.
+			return nil
+		}
+
+		if err := h.authorize(ctx, s); err != nil {
+			c.send(message{Type: messageError, Error: err.Error()})
+			return err
+		}
+
+		if err := s.receive(c, msg); err != nil {
+			if errors.Is(err, errSessionEnded) {
+				// The hub has been closed.
+				return nil
+			}
+			c.send(message{Type: messageError, Error: err.Error()})
+			return err
+		}
+	}
+}
+
+func (h *Hub) acquire(ctx context.Context, id uuid.UUID) (*session, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrClosed
+	}
+
+	if s, ok := h.sessions[id]; ok {
+		s.refs++
+		return s, nil
+	}
+
+	n, err := h.svc.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &session{
+		id:      id,
+		ownerID: n.OwnerID,
+		doc:     NewDocument(n.GetContent()),
+		clients: make(map[*client]struct{}),
+		refs:    1,
+		done:    make(chan struct{}),
+	}
+	h.sessions[id] = s
+
+	go h.autosave(s)
+	return s, nil
+}
+
+// release ends the session when its last client leaves. The content
+// is saved while holding the hub lock so that a client joining again
+// loads the latest content.
+func (h *Hub) release(s *session) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s.refs--
+	if s.refs > 0 || h.sessions[s.id] != s {
+		// The session is still edited or has been
+		// ended when the hub was closed.
+		return
+	}
+
+	delete(h.sessions, s.id)
+	close(s.done)
+	h.save(s)
+}
+
+// Close ends all the sessions and disconnects their clients, then it
+// saves the edited contents. It should be called before closing the
+// store of the service since the clients are not waited for by the
+// shutdown of the server.
+func (h *Hub) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for id, s := range h.sessions {
+		delete(h.sessions, id)
+		close(s.done)
+		s.end()
+		h.save(s)
+	}
+	return nil
+}
+
+// authorize returns note.ErrPermissionDenied unless the
+// owner in ctx can update the note of the session s.
+func (h *Hub) authorize(ctx context.Context, s *session) error {
+	n := &note.Note{ID: s.id, OwnerID: s.ownerID}
+	if role := note.RoleOf(ctx, n, h.grants); !role.CanWrite() {
+		return fmt.Errorf("collab: note '%s' is read-only for the %s: %w", s.id, role, note.ErrPermissionDenied)
+	}
+	return nil
+}
+
+func (h *Hub) autosave(s *session) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			h.save(s)
+		}
+	}
+}
+
+// save updates the note content when it has changed since the last save.
+func (h *Hub) save(s *session) {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	content, revision := s.snapshot()
+	if revision == s.saved {
+		return
+	}
+
+	// The content is saved as the owner of the note so that
+	// it is checked against the quota of the owner.
+	ctx := context.Background()
+	if s.ownerID != "" {
+		ctx = note.WithOwner(ctx, s.ownerID)
+	}
+
+	n := new(note.Note)
+	n.SetID(s.id).SetContent(content)
+	if _, err := h.svc.Update(ctx, n); err != nil {
+		if !errors.Is(err, note.ErrNotFound) {
+			logrus.Error("collab: ", err)
+			return
+		}
+		// The note has been deleted while editing, there
+		// is nothing left to save the content to.
+	}
+
+	s.saved = revision
+}
+
+// session is the editing session of a single note.
+type session struct {
+	id uuid.UUID
+	// ownerID is the id of the owner of the note.
+	ownerID string
+	// refs is the number of clients joined or joining
+	// the session. It is guarded by the hub lock.
+	refs int
+	done chan struct{}
+
+	mu      sync.Mutex
+	doc     *Document
+	clients map[*client]struct{}
+	// ended is true when no more operations are applied.
+	ended bool
+
+	// saveMu serializes the saves and guards saved which
+	// is the revision of the last saved content.
+	saveMu sync.Mutex
+	saved  int
+}
+
+func (s *session) snapshot() (string, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.doc.Content(), s.doc.Revision()
+}
+
+func (s *session) join(conn Conn) *client {
+	c := &client{conn: conn, out: make(chan message, clientBufferSize), done: make(chan struct{})}
+	go c.writeLoop()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content := s.doc.Content()
+	c.send(message{Type: messageInit, Revision: s.doc.Revision(), Content: &content})
+	s.clients[c] = struct{}{}
+	return c
+}
+
+// end stops applying the operations and disconnects the clients.
+func (s *session) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ended = true
+	for c := range s.clients {
+		c.close()
+	}
+}
+
+func (s *session) leave(c *client) {
+	s.mu.Lock()
+	delete(s.clients, c)
+	s.mu.Unlock()
+
+	// Nothing is sent to the client after it has been removed
+	// from the session. The writer flushes the queued messages
+	// and then closes the connection.
+	close(c.out)
+}
+
+// receive applies the operation of the client c and sends it to the
+// other clients. The messages are sent while holding the session lock
+// so that every client receives the operations in revision order.
+func (s *session) receive(c *client, msg message) error {
+	if msg.Type != messageOp || msg.Op == nil {
+		return errInvalidMessage
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ended {
+		return errSessionEnded
+	}
+
+	op, err := s.doc.Apply(msg.Revision, msg.Op)
+	if err != nil {
+		return err
+	}
+
+	revision := s.doc.Revision()
+	for other := range s.clients {
+		if other == c {
+			other.send(message{Type: messageAck, Revision: revision})
+		} else {
+			other.send(message{Type: messageOp, Revision: revision, Op: op})
+		}
+	}
+	return nil
+}
+
+// errInvalidMessage is an error when a client sends a
+// message other than an operation.
+var errInvalidMessage = errors.New("collab: invalid message")
+
+// errSessionEnded is an error when an operation is
+// received after the session has been ended.
+var errSessionEnded = errors.New("collab: session ended")
+
+// client is a connection joined to a session. Its messages are
+// written by its own goroutine so that a slow client doesn't
+// hold up the others.
+type client struct {
+	conn Conn
+	out  chan message
+
+	once sync.Once
+	done chan struct{}
+}
+
+// send queues msg for writing. A client that can't keep up is
+// disconnected and has to join again.
+func (c *client) send(msg message) {
+	select {
+	case <-c.done:
+	case c.out <- msg:
+	default:
+		c.close()
+	}
+}
+
+func (c *client) close() {
+	c.once.Do(func() {
+		close(c.done)
+		// Closing the connection stops the read loop of
+		// the client in the hub.
+		_ = c.conn.Close()
+	})
+}
+
+func (c *client) writeLoop() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case msg, ok := <-c.out:
+			if !ok {
+				c.close()
+				return
+			}
+
+			if err := c.conn.WriteJSON(msg); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,453 @@
+package collab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"math/rand"
+	"noteapp/note"
+	"noteapp/note/service"
+	"noteapp/note/store/memory"
+	"sync"
+	"testing"
+	"time"
+)
+
+var dummyCtx = context.TODO()
+
+// pipeConn is one end of an in-memory connection. The messages
+// are encoded in JSON the same way as on a websocket.
+type pipeConn struct {
+	in   <-chan []byte
+	out  chan<- []byte
+	once *sync.Once
+	done chan struct{}
+}
+
+// newPipe returns both ends of an in-memory connection. Closing
+// one end closes the other after its pending messages are read.
+func newPipe() (*pipeConn, *pipeConn) {
+	a, b := make(chan []byte, 1024), make(chan []byte, 1024)
+	once, done := new(sync.Once), make(chan struct{})
+	return &pipeConn{in: a, out: b, once: once, done: done},
+		&pipeConn{in: b, out: a, once: once, done: done}
+}
+
+func (c *pipeConn) ReadJSON(v interface{}) error {
+	select {
+	case b := <-c.in:
+		return json.Unmarshal(b, v)
+	default:
+	}
+
+	select {
+	case b := <-c.in:
+		return json.Unmarshal(b, v)
+	case <-c.done:
+		return io.EOF
+	}
+}
+
+func (c *pipeConn) WriteJSON(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-c.done:
+		return io.ErrClosedPipe
+	case c.out <- b:
+		return nil
+	}
+}
+
+func (c *pipeConn) Close() error {
+	c.once.Do(func() { close(c.done) })
+	return nil
+}
+
+// testClient is a collaborating client that keeps at most one
+// operation waiting for the acknowledgement of the server and
+// buffers its later edits until then.
+type testClient struct {
+	s    *HubTestSuite
+	name string
+	conn *pipeConn
+
+	content     string
+	revision    int
+	outstanding *Operation
+	buffer      *Operation
+
+	// pending are the messages read from the server
+	// but not processed yet.
+	pending []message
+	// unread is the number of messages sent by the
+	// server but not read yet.
+	unread int
+}
+
+func (c *testClient) read() message {
+	var msg message
+	c.s.Require().NoError(c.conn.ReadJSON(&msg), c.name)
+	c.unread--
+	return msg
+}
+
+// edit makes op on the local content and sends it unless another
+// operation is waiting for the acknowledgement.
+func (c *testClient) edit(op *Operation) {
+	content, err := op.Apply(c.content)
+	c.s.Require().NoError(err, c.name)
+	c.content = content
+
+	switch {
+	case c.outstanding == nil:
+		c.outstanding = op
+		c.s.sendOp(c, op)
+	case c.buffer == nil:
+		c.buffer = op
+	default:
+		c.buffer, err = Compose(c.buffer, op)
+		c.s.Require().NoError(err, c.name)
+	}
+}
+
+// deliver processes the next message from the server.
+func (c *testClient) deliver() {
+	if len(c.pending) == 0 {
+		c.pending = append(c.pending, c.read())
+	}
+
+	msg := c.pending[0]
+	c.pending = c.pending[1:]
+	c.revision = msg.Revision
+
+	switch msg.Type {
+	case messageAck:
+		c.s.Require().NotNil(c.outstanding, c.name)
+		c.outstanding, c.buffer = c.buffer, nil
+		if c.outstanding != nil {
+			c.s.sendOp(c, c.outstanding)
+		}
+	case messageOp:
+		op := msg.Op
+		var err error
+		if c.outstanding != nil {
+			c.outstanding, op, err = Transform(c.outstanding, op)
+			c.s.Require().NoError(err, c.name)
+		}
+		if c.buffer != nil {
+			c.buffer, op, err = Transform(c.buffer, op)
+			c.s.Require().NoError(err, c.name)
+		}
+
+		c.content, err = op.Apply(c.content)
+		c.s.Require().NoError(err, c.name)
+	default:
+		c.s.Failf("Unexpected message", "%s: %+v", c.name, msg)
+	}
+}
+
+func (c *testClient) isSettled() bool {
+	return c.outstanding == nil && len(c.pending) == 0 && c.unread == 0
+}
+
+// grants is the share grants of the users keyed by the note id.
+type grants map[uuid.UUID]map[string]note.Role
+
+func (g grants) Role(noteID uuid.UUID, userID string) note.Role {
+	return g[noteID][userID]
+}
+
+func (g grants) SharedWith(userID string) []uuid.UUID {
+	var ids []uuid.UUID
+	for id, roles := range g {
+		if _, ok := roles[userID]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func TestHub(t *testing.T) {
+	suite.Run(t, new(HubTestSuite))
+}
+
+type HubTestSuite struct {
+	suite.Suite
+	svc     note.Service
+	hub     *Hub
+	clients []*testClient
+	wg      sync.WaitGroup
+}
+
+func (s *HubTestSuite) SetupTest() {
+	s.svc = service.New(memory.New())
+	s.hub = NewHub(s.svc, nil, time.Hour)
+	s.clients = nil
+}
+
+func (s *HubTestSuite) createNote(content string) uuid.UUID {
+	n := new(note.Note)
+	n.SetTitle("Collab").SetContent(content)
+	created, err := s.svc.Create(dummyCtx, n)
+	s.Require().NoError(err)
+	return created.ID
+}
+
+func (s *HubTestSuite) join(id uuid.UUID, name string) *testClient {
+	return s.joinAs(dummyCtx, id, name)
+}
+
+// joinAs joins the client with the name to the note with an id
+// as the owner in ctx.
+func (s *HubTestSuite) joinAs(ctx context.Context, id uuid.UUID, name string) *testClient {
+	clientConn, serverConn := newPipe()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		_ = s.hub.Join(ctx, id, serverConn)
+	}()
+
+	c := &testClient{s: s, name: name, conn: clientConn, unread: 1}
+	init := c.read()
+	s.Require().Equal(messageInit, init.Type)
+	s.Require().NotNil(init.Content)
+	c.content, c.revision = *init.Content, init.Revision
+
+	s.clients = append(s.clients, c)
+	return c
+}
+
+// sendOp sends op of the client c and waits until the server has
+// acknowledged it, so that the server applies the operations in the
+// order they are sent no matter which client sends them.
+func (s *HubTestSuite) sendOp(c *testClient, op *Operation) {
+	s.Require().NoError(c.conn.WriteJSON(message{Type: messageOp, Revision: c.revision, Op: op}))
+
+	for _, other := range s.clients {
+		other.unread++
+	}
+
+	for {
+		msg := c.read()
+		c.pending = append(c.pending, msg)
+		if msg.Type == messageAck {
+			return
+		}
+	}
+}
+
+func (s *HubTestSuite) leaveAll() {
+	for _, c := range s.clients {
+		s.Require().NoError(c.conn.Close())
+	}
+	s.wg.Wait()
+}
+
+func (s *HubTestSuite) sessionContent(id uuid.UUID) string {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	content, _ := s.hub.sessions[id].snapshot()
+	return content
+}
+
+func (s *HubTestSuite) TestConvergence() {
+	for seed := int64(1); seed <= 20; seed++ {
+		seed := seed
+		s.Run(fmt.Sprintf("Clients should converge with seed %d", seed), func() {
+			s.SetupTest()
+			rng := rand.New(rand.NewSource(seed))
+			id := s.createNote("The quick brown fox")
+
+			for i := 0; i < 3; i++ {
+				s.join(id, fmt.Sprintf("client-%d", i))
+			}
+
+			for step := 0; step < 300; step++ {
+				if step == 150 {
+					s.join(id, "late-client")
+				}
+
+				c := s.clients[rng.Intn(len(s.clients))]
+				if rng.Intn(2) == 0 && (c.unread > 0 || len(c.pending) > 0) {
+					c.deliver()
+				} else {
+					c.edit(randomOperation(rng, c.content))
+				}
+			}
+
+			for settled := false; !settled; {
+				settled = true
+				for _, c := range s.clients {
+					if !c.isSettled() {
+						c.deliver()
+						settled = false
+					}
+				}
+			}
+
+			want := s.sessionContent(id)
+			for _, c := range s.clients {
+				s.Equal(want, c.content, c.name)
+			}
+
+			s.leaveAll()
+
+			n, err := s.svc.Get(dummyCtx, id)
+			s.Require().NoError(err)
+			s.Equal(want, n.GetContent())
+			s.Equal("Collab", n.GetTitle())
+		})
+	}
+}
+
+func (s *HubTestSuite) TestJoin() {
+	s.Run("Joining a note that doesn't exist should return an error", func() {
+		s.SetupTest()
+		_, serverConn := newPipe()
+		s.ErrorIs(s.hub.Join(dummyCtx, uuid.New(), serverConn), note.ErrNotFound)
+	})
+
+	s.Run("Invalid operation should disconnect the client", func() {
+		s.SetupTest()
+		id := s.createNote("hello")
+		c := s.join(id, "client")
+
+		s.Require().NoError(c.conn.WriteJSON(message{Type: messageOp, Op: NewOperation().Retain(3)}))
+
+		var msg message
+		s.Require().NoError(c.conn.ReadJSON(&msg))
+		s.Equal(messageError, msg.Type)
+		s.NotEmpty(msg.Error)
+
+		s.wg.Wait()
+		s.Error(c.conn.ReadJSON(&msg))
+	})
+
+	s.Run("Operation on a future revision should disconnect the client", func() {
+		s.SetupTest()
+		id := s.createNote("hello")
+		c := s.join(id, "client")
+
+		s.Require().NoError(c.conn.WriteJSON(message{Type: messageOp, Revision: 1, Op: NewOperation().Retain(5)}))
+
+		var msg message
+		s.Require().NoError(c.conn.ReadJSON(&msg))
+		s.Equal(messageError, msg.Type)
+		s.wg.Wait()
+	})
+
+	s.Run("Unchanged content should not be saved", func() {
+		s.SetupTest()
+		id := s.createNote("hello")
+		s.join(id, "client")
+		s.leaveAll()
+
+		n, err := s.svc.Get(dummyCtx, id)
+		s.Require().NoError(err)
+		s.Nil(n.UpdatedTime)
+	})
+}
+
+func (s *HubTestSuite) TestPermissions() {
+	aliceCtx, bobCtx := note.WithOwner(dummyCtx, "alice"), note.WithOwner(dummyCtx, "bob")
+
+	s.Run("Revoking the grant should stop the edits right away", func() {
+		g := grants{}
+		s.svc = service.New(memory.New(), service.WithGrants(g))
+		s.hub = NewHub(s.svc, g, time.Hour)
+		s.clients = nil
+
+		n, err := s.svc.Create(aliceCtx, new(note.Note).SetTitle("Shared").SetContent("hello"))
+		s.Require().NoError(err)
+		g[n.ID] = map[string]note.Role{"bob": note.RoleEditor}
+
+		c := s.joinAs(bobCtx, n.ID, "bob")
+		c.edit(NewOperation().Retain(5).Insert(" bob"))
+
+		delete(g, n.ID)
+		s.Require().NoError(c.conn.WriteJSON(message{Type: messageOp, Revision: 1, Op: NewOperation().Retain(9).Insert("!")}))
+
+		msg := c.read()
+		for msg.Type == messageAck {
+			msg = c.read()
+		}
+		s.Equal(messageError, msg.Type)
+		s.Contains(msg.Error, note.ErrPermissionDenied.Error())
+		s.wg.Wait()
+
+		got, err := s.svc.Get(aliceCtx, n.ID)
+		s.Require().NoError(err)
+		s.Equal("hello bob", got.GetContent())
+	})
+
+	s.Run("Content should be saved within the quota of the owner", func() {
+		s.svc = service.New(memory.New(), service.WithQuota(service.Quota{MaxBytes: 16}))
+		s.hub = NewHub(s.svc, nil, time.Hour)
+		s.clients = nil
+
+		n, err := s.svc.Create(aliceCtx, new(note.Note).SetTitle("Quota").SetContent("hello"))
+		s.Require().NoError(err)
+
+		c := s.joinAs(aliceCtx, n.ID, "alice")
+		c.edit(NewOperation().Retain(5).Insert(" over the quota"))
+		s.leaveAll()
+
+		got, err := s.svc.Get(aliceCtx, n.ID)
+		s.Require().NoError(err)
+		s.Equal("hello", got.GetContent())
+	})
+}
+
+func (s *HubTestSuite) TestAutosave() {
+	s.Run("Content should be saved periodically while editing", func() {
+		s.SetupTest()
+		s.hub = NewHub(s.svc, nil, 10*time.Millisecond)
+		id := s.createNote("hello")
+		c := s.join(id, "client")
+
+		c.edit(NewOperation().Retain(5).Insert(" world"))
+
+		s.Eventually(func() bool {
+			n, err := s.svc.Get(dummyCtx, id)
+			return err == nil && n.GetContent() == "hello world"
+		}, time.Second, 10*time.Millisecond)
+
+		s.leaveAll()
+	})
+}
+
+func (s *HubTestSuite) TestClose() {
+	s.Run("Closing should save the contents and disconnect the clients", func() {
+		s.SetupTest()
+		id := s.createNote("hello")
+		c := s.join(id, "client")
+		c.edit(NewOperation().Retain(5).Insert(" world"))
+
+		s.Require().NoError(s.hub.Close())
+
+		n, err := s.svc.Get(dummyCtx, id)
+		s.Require().NoError(err)
+		s.Equal("hello world", n.GetContent())
+
+		// The hub returns once the client is disconnected.
+		s.wg.Wait()
+		s.Empty(s.hub.sessions)
+	})
+
+	s.Run("Joining after closing should return an error", func() {
+		s.SetupTest()
+		id := s.createNote("hello")
+		s.Require().NoError(s.hub.Close())
+
+		_, serverConn := newPipe()
+		s.ErrorIs(s.hub.Join(dummyCtx, id, serverConn), ErrClosed)
+	})
+}
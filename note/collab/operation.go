@@ -0,0 +1,408 @@
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidOperation is an error when an operation can't be
+// decoded or doesn't match the document it is applied to.
+var ErrInvalidOperation = errors.New("collab: invalid operation")
+
+// maxLength is the maximum length of a document and of the
+// components of an operation, so that their sums don't overflow.
+const maxLength = math.MaxInt32
+
+// component is a single step of an operation. Only one of
+// its fields is set.
+type component struct {
+	retain int
+	insert string
+	delete int
+}
+
+func (c component) isRetain() bool { return c.retain > 0 }
+func (c component) isInsert() bool { return c.insert != "" }
+func (c component) isDelete() bool { return c.delete > 0 }
+
+// Operation is a change to a text document. It walks over the whole
+// document retaining, inserting and deleting characters. The positions
+// and lengths are counted in Unicode code points.
+//
+// An operation is encoded in JSON as an array where a positive number
+// retains, a negative number deletes and a string inserts characters,
+// e.g. [5, "hello", -3].
+type Operation struct {
+	components []component
+	// baseLen is the length of the document the
+	// operation can be applied to.
+	baseLen int
+	// targetLen is the length of the document after
+	// the operation has been applied.
+	targetLen int
+	// overflow is true when one of the lengths went over
+	// maxLength. The operation can't be applied then.
+	overflow bool
+}
+
+// NewOperation returns an empty operation.
+func NewOperation() *Operation {
+	return new(Operation)
+}
+
+// BaseLen returns the length of the document the operation can be applied to.
+func (o *Operation) BaseLen() int {
+	return o.baseLen
+}
+
+// TargetLen returns the length of the document after the operation.
+func (o *Operation) TargetLen() int {
+	return o.targetLen
+}
+
+// IsNoop returns true when the operation doesn't change the document.
+func (o *Operation) IsNoop() bool {
+	return len(o.components) == 0 || (len(o.components) == 1 && o.components[0].isRetain())
+}
+
+func (o *Operation) last() *component {
+	if len(o.components) == 0 {
+		return nil
+	}
+	return &o.components[len(o.components)-1]
+}
+
+// grow adds the base and the target lengths of a component. The lengths
+// are left unchanged when they would go over maxLength.
+func (o *Operation) grow(base, target int) bool {
+	if base > maxLength-o.baseLen || target > maxLength-o.targetLen {
+		o.overflow = true
+		return false
+	}
+
+	o.baseLen += base
+	o.targetLen += target
+	return true
+}
+
+// valid returns ErrInvalidOperation when the lengths of
+// the operation have gone over maxLength.
+func (o *Operation) valid() error {
+	if o.overflow {
+		return fmt.Errorf("collab: operation longer than %d: %w", maxLength, ErrInvalidOperation)
+	}
+	return nil
+}
+
+// Retain skips over the next n characters. An operation over
+// maxLength characters is invalid.
+func (o *Operation) Retain(n int) *Operation {
+	if n <= 0 || !o.grow(n, n) {
+		return o
+	}
+
+	if last := o.last(); last != nil && last.isRetain() {
+		last.retain += n
+	} else {
+		o.components = append(o.components, component{retain: n})
+	}
+	return o
+}
+
+// Insert inserts s at the current position.
+func (o *Operation) Insert(s string) *Operation {
+	if s == "" || !o.grow(0, utf8.RuneCountInString(s)) {
+		return o
+	}
+
+	size := len(o.components)
+	switch last := o.last(); {
+	case last != nil && last.isInsert():
+		last.insert += s
+	case last != nil && last.isDelete():
+		// Inserts are kept before deletes so that equal
+		// operations have the same components.
+		if size > 1 && o.components[size-2].isInsert() {
+			o.components[size-2].insert += s
+		} else {
+			o.components = append(o.components, *last)
+			o.components[size-1] = component{insert: s}
+		}
+	default:
+		o.components = append(o.components, component{insert: s})
+	}
+	return o
+}
+
+// Delete deletes the next n characters. An operation over
+// maxLength characters is invalid.
+func (o *Operation) Delete(n int) *Operation {
+	if n <= 0 || !o.grow(n, 0) {
+		return o
+	}
+	if last := o.last(); last != nil && last.isDelete() {
+		last.delete += n
+	} else {
+		o.components = append(o.components, component{delete: n})
+	}
+	return o
+}
+
+// Apply applies the operation to the document s and returns the result.
+func (o *Operation) Apply(s string) (string, error) {
+	if err := o.valid(); err != nil {
+		return "", err
+	}
+
+	runes := []rune(s)
+	if len(runes) != o.baseLen {
+		return "", fmt.Errorf("collab: operation base length %d doesn't match the document length %d: %w", o.baseLen, len(runes), ErrInvalidOperation)
+	}
+
+	var b strings.Builder
+	pos := 0
+	for _, c := range o.components {
+		if c.retain > len(runes)-pos || c.delete > len(runes)-pos {
+			return "", fmt.Errorf("collab: operation past the document length %d: %w", len(runes), ErrInvalidOperation)
+		}
+
+		switch {
+		case c.isRetain():
+			b.WriteString(string(runes[pos : pos+c.retain]))
+			pos += c.retain
+		case c.isInsert():
+			b.WriteString(c.insert)
+		default:
+			pos += c.delete
+		}
+	}
+	return b.String(), nil
+}
+
+// MarshalJSON encodes the operation into a JSON array.
+func (o *Operation) MarshalJSON() ([]byte, error) {
+	values := make([]interface{}, 0, len(o.components))
+	for _, c := range o.components {
+		switch {
+		case c.isRetain():
+			values = append(values, c.retain)
+		case c.isInsert():
+			values = append(values, c.insert)
+		default:
+			values = append(values, -c.delete)
+		}
+	}
+	return json.Marshal(values)
+}
+
+// UnmarshalJSON decodes the JSON array b into the operation.
+func (o *Operation) UnmarshalJSON(b []byte) error {
+	var values []interface{}
+	if err := json.Unmarshal(b, &values); err != nil {
+		return fmt.Errorf("collab: %s: %w", err, ErrInvalidOperation)
+	}
+
+	op := NewOperation()
+	for _, value := range values {
+		switch v := value.(type) {
+		case string:
+			op.Insert(v)
+		case float64:
+			if math.Abs(v) > maxLength {
+				return fmt.Errorf("collab: length %v over %d: %w", v, maxLength, ErrInvalidOperation)
+			}
+			if v != float64(int(v)) {
+				return fmt.Errorf("collab: fractional length %v: %w", v, ErrInvalidOperation)
+			}
+			if v > 0 {
+				op.Retain(int(v))
+			} else {
+				op.Delete(int(-v))
+			}
+		default:
+			return fmt.Errorf("collab: unexpected component %v: %w", v, ErrInvalidOperation)
+		}
+	}
+
+	if err := op.valid(); err != nil {
+		return err
+	}
+
+	*o = *op
+	return nil
+}
+
+// cursor walks over the components of an operation. The current
+// component is a copy so that it can be consumed partially.
+type cursor struct {
+	components []component
+	index      int
+	current    component
+	ok         bool
+}
+
+func newCursor(o *Operation) *cursor {
+	c := &cursor{components: o.components, index: -1}
+	c.next()
+	return c
+}
+
+func (c *cursor) next() {
+	c.index++
+	c.ok = c.index < len(c.components)
+	if c.ok {
+		c.current = c.components[c.index]
+	} else {
+		c.current = component{}
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Compose returns an operation that has the same effect as applying
+// a and then b.
+func Compose(a, b *Operation) (*Operation, error) {
+	if err := a.valid(); err != nil {
+		return nil, err
+	}
+	if err := b.valid(); err != nil {
+		return nil, err
+	}
+
+	if a.targetLen != b.baseLen {
+		return nil, fmt.Errorf("collab: compose: target length %d doesn't match base length %d: %w", a.targetLen, b.baseLen, ErrInvalidOperation)
+	}
+
+	result := NewOperation()
+	c1, c2 := newCursor(a), newCursor(b)
+	for c1.ok || c2.ok {
+		if c1.current.isDelete() {
+			result.Delete(c1.current.delete)
+			c1.next()
+			continue
+		}
+
+		if c2.current.isInsert() {
+			result.Insert(c2.current.insert)
+			c2.next()
+			continue
+		}
+
+		if !c1.ok || !c2.ok {
+			return nil, fmt.Errorf("collab: compose: operations have different lengths: %w", ErrInvalidOperation)
+		}
+
+		op1, op2 := &c1.current, &c2.current
+		switch {
+		case op1.isRetain() && op2.isRetain():
+			n := min(op1.retain, op2.retain)
+			result.Retain(n)
+			op1.retain -= n
+			op2.retain -= n
+		case op1.isInsert() && op2.isDelete():
+			runes := []rune(op1.insert)
+			n := min(len(runes), op2.delete)
+			op1.insert = string(runes[n:])
+			op2.delete -= n
+		case op1.isInsert() && op2.isRetain():
+			runes := []rune(op1.insert)
+			n := min(len(runes), op2.retain)
+			result.Insert(string(runes[:n]))
+			op1.insert = string(runes[n:])
+			op2.retain -= n
+		case op1.isRetain() && op2.isDelete():
+			n := min(op1.retain, op2.delete)
+			result.Delete(n)
+			op1.retain -= n
+			op2.delete -= n
+		}
+
+		if *op1 == (component{}) {
+			c1.next()
+		}
+		if *op2 == (component{}) {
+			c2.next()
+		}
+	}
+	return result, nil
+}
+
+// Transform takes the concurrent operations a and b made on the same
+// document and returns a' and b' such that applying a then b' has the
+// same result as applying b then a'. When both insert at the same
+// position the insert of a is placed first.
+func Transform(a, b *Operation) (*Operation, *Operation, error) {
+	if err := a.valid(); err != nil {
+		return nil, nil, err
+	}
+	if err := b.valid(); err != nil {
+		return nil, nil, err
+	}
+
+	if a.baseLen != b.baseLen {
+		return nil, nil, fmt.Errorf("collab: transform: base length %d doesn't match base length %d: %w", a.baseLen, b.baseLen, ErrInvalidOperation)
+	}
+
+	aPrime, bPrime := NewOperation(), NewOperation()
+	c1, c2 := newCursor(a), newCursor(b)
+	for c1.ok || c2.ok {
+		if c1.current.isInsert() {
+			aPrime.Insert(c1.current.insert)
+			bPrime.Retain(utf8.RuneCountInString(c1.current.insert))
+			c1.next()
+			continue
+		}
+
+		if c2.current.isInsert() {
+			aPrime.Retain(utf8.RuneCountInString(c2.current.insert))
+			bPrime.Insert(c2.current.insert)
+			c2.next()
+			continue
+		}
+
+		if !c1.ok || !c2.ok {
+			return nil, nil, fmt.Errorf("collab: transform: operations have different lengths: %w", ErrInvalidOperation)
+		}
+
+		op1, op2 := &c1.current, &c2.current
+		switch {
+		case op1.isRetain() && op2.isRetain():
+			n := min(op1.retain, op2.retain)
+			aPrime.Retain(n)
+			bPrime.Retain(n)
+			op1.retain -= n
+			op2.retain -= n
+		case op1.isDelete() && op2.isDelete():
+			n := min(op1.delete, op2.delete)
+			op1.delete -= n
+			op2.delete -= n
+		case op1.isDelete() && op2.isRetain():
+			n := min(op1.delete, op2.retain)
+			aPrime.Delete(n)
+			op1.delete -= n
+			op2.retain -= n
+		case op1.isRetain() && op2.isDelete():
+			n := min(op1.retain, op2.delete)
+			bPrime.Delete(n)
+			op1.retain -= n
+			op2.delete -= n
+		}
+
+		if *op1 == (component{}) {
+			c1.next()
+		}
+		if *op2 == (component{}) {
+			c2.next()
+		}
+	}
+	return aPrime, bPrime, nil
+}
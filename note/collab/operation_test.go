@@ -0,0 +1,231 @@
+package collab
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/suite"
+	"math/rand"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestOperation(t *testing.T) {
+	suite.Run(t, new(OperationTestSuite))
+}
+
+type OperationTestSuite struct {
+	suite.Suite
+}
+
+var alphabet = []rune("abc xyz\néü界")
+
+func randomString(rng *rand.Rand, max int) string {
+	runes := make([]rune, rng.Intn(max+1))
+	for i := range runes {
+		runes[i] = alphabet[rng.Intn(len(alphabet))]
+	}
+	return string(runes)
+}
+
+// randomOperation returns a random operation that can
+// be applied to the document s.
+func randomOperation(rng *rand.Rand, s string) *Operation {
+	op := NewOperation()
+	left := utf8.RuneCountInString(s)
+	for left > 0 {
+		n := 1 + rng.Intn(min(left, 5))
+		switch rng.Intn(3) {
+		case 0:
+			op.Retain(n)
+			left -= n
+		case 1:
+			op.Delete(n)
+			left -= n
+		default:
+			op.Insert(randomString(rng, 3))
+		}
+	}
+
+	if rng.Intn(2) == 0 {
+		op.Insert(randomString(rng, 3))
+	}
+	return op
+}
+
+func (s *OperationTestSuite) TestApply() {
+	tests := []struct {
+		name    string
+		doc     string
+		op      *Operation
+		want    string
+		wantErr error
+	}{
+		{
+			name: "Inserting, retaining and deleting",
+			doc:  "hello world",
+			op:   NewOperation().Retain(6).Delete(5).Insert("there"),
+			want: "hello there",
+		},
+		{
+			name: "Counting in code points",
+			doc:  "界界 ok",
+			op:   NewOperation().Delete(2).Insert("é").Retain(3),
+			want: "é ok",
+		},
+		{
+			name: "Inserting into an empty document",
+			doc:  "",
+			op:   NewOperation().Insert("new"),
+			want: "new",
+		},
+		{
+			name:    "Mismatched length should return an error",
+			doc:     "hello",
+			op:      NewOperation().Retain(3),
+			wantErr: ErrInvalidOperation,
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			got, err := tt.op.Apply(tt.doc)
+			if tt.wantErr != nil {
+				s.ErrorIs(err, tt.wantErr)
+				return
+			}
+			s.NoError(err)
+			s.Equal(tt.want, got)
+		})
+	}
+}
+
+func (s *OperationTestSuite) TestBuilder() {
+	s.Run("Consecutive components should be merged", func() {
+		op := NewOperation().Retain(1).Retain(2).Insert("a").Insert("b").Delete(1).Delete(2)
+		s.Equal([]component{{retain: 3}, {insert: "ab"}, {delete: 3}}, op.components)
+		s.Equal(6, op.BaseLen())
+		s.Equal(5, op.TargetLen())
+	})
+
+	s.Run("Insert after delete should be placed before it", func() {
+		op := NewOperation().Retain(1).Delete(2).Insert("a")
+		s.Equal([]component{{retain: 1}, {insert: "a"}, {delete: 2}}, op.components)
+
+		op.Insert("b")
+		s.Equal([]component{{retain: 1}, {insert: "ab"}, {delete: 2}}, op.components)
+	})
+
+	s.Run("Operation over the maximum length should not be applied", func() {
+		op := NewOperation().Retain(maxLength).Insert("a").Retain(maxLength).Insert("a").Retain(5)
+		_, err := op.Apply("hello")
+		s.ErrorIs(err, ErrInvalidOperation)
+
+		_, _, err = Transform(op, NewOperation().Retain(5))
+		s.ErrorIs(err, ErrInvalidOperation)
+	})
+
+	s.Run("Retain only operation should be a noop", func() {
+		s.True(NewOperation().IsNoop())
+		s.True(NewOperation().Retain(5).IsNoop())
+		s.False(NewOperation().Retain(5).Insert("a").IsNoop())
+	})
+}
+
+func (s *OperationTestSuite) TestJSON() {
+	s.Run("Encoding and decoding an operation", func() {
+		op := NewOperation().Retain(5).Insert("hello").Delete(3)
+
+		b, err := json.Marshal(op)
+		s.Require().NoError(err)
+		s.JSONEq(`[5, "hello", -3]`, string(b))
+
+		got := NewOperation()
+		s.Require().NoError(json.Unmarshal(b, got))
+		s.Equal(op, got)
+	})
+
+	for _, input := range []string{
+		`{}`, `[1.5]`, `[true]`, `[null]`,
+		`[4611686018427387904, "a", 4611686018427387904, "a", 4611686018427387904, "a", 4611686018427387904, 5]`,
+		`[2147483647, "a", 2147483647]`,
+		`[-1e30]`,
+	} {
+		input := input
+		s.Run("Decoding "+input+" should return an error", func() {
+			s.ErrorIs(json.Unmarshal([]byte(input), NewOperation()), ErrInvalidOperation)
+		})
+	}
+}
+
+func (s *OperationTestSuite) TestCompose() {
+	s.Run("Composed operation should equal applying both", func() {
+		rng := rand.New(rand.NewSource(1))
+		for i := 0; i < 500; i++ {
+			doc := randomString(rng, 20)
+			a := randomOperation(rng, doc)
+			afterA, err := a.Apply(doc)
+			s.Require().NoError(err)
+
+			b := randomOperation(rng, afterA)
+			want, err := b.Apply(afterA)
+			s.Require().NoError(err)
+
+			ab, err := Compose(a, b)
+			s.Require().NoError(err)
+			got, err := ab.Apply(doc)
+			s.Require().NoError(err)
+			s.Equal(want, got)
+		}
+	})
+
+	s.Run("Mismatched lengths should return an error", func() {
+		_, err := Compose(NewOperation().Retain(2), NewOperation().Retain(3))
+		s.ErrorIs(err, ErrInvalidOperation)
+	})
+}
+
+func (s *OperationTestSuite) TestTransform() {
+	s.Run("Transformed operations should converge", func() {
+		rng := rand.New(rand.NewSource(2))
+		for i := 0; i < 500; i++ {
+			doc := randomString(rng, 20)
+			a, b := randomOperation(rng, doc), randomOperation(rng, doc)
+
+			aPrime, bPrime, err := Transform(a, b)
+			s.Require().NoError(err)
+
+			afterA, err := a.Apply(doc)
+			s.Require().NoError(err)
+			afterB, err := b.Apply(doc)
+			s.Require().NoError(err)
+
+			left, err := bPrime.Apply(afterA)
+			s.Require().NoError(err)
+			right, err := aPrime.Apply(afterB)
+			s.Require().NoError(err)
+			s.Equal(left, right)
+		}
+	})
+
+	s.Run("Insert of the first operation should be placed first", func() {
+		a := NewOperation().Retain(1).Insert("a")
+		b := NewOperation().Retain(1).Insert("b")
+
+		aPrime, bPrime, err := Transform(a, b)
+		s.Require().NoError(err)
+
+		afterA, _ := a.Apply("x")
+		got, err := bPrime.Apply(afterA)
+		s.Require().NoError(err)
+		s.Equal("xab", got)
+
+		afterB, _ := b.Apply("x")
+		got, err = aPrime.Apply(afterB)
+		s.Require().NoError(err)
+		s.Equal("xab", got)
+	})
+
+	s.Run("Mismatched lengths should return an error", func() {
+		_, _, err := Transform(NewOperation().Retain(2), NewOperation().Retain(3))
+		s.ErrorIs(err, ErrInvalidOperation)
+	})
+}
@@ -0,0 +1,42 @@
+package note
+
+import (
+	"context"
+	"time"
+)
+
+// EventType describes the kind of change made to a note.
+type EventType string
+
+const (
+	// EventCreated is the type of event when a note has been created.
+	EventCreated EventType = "created"
+	// EventUpdated is the type of event when a note has been updated.
+	EventUpdated EventType = "updated"
+	// EventDeleted is the type of event when a note has been deleted.
+	EventDeleted EventType = "deleted"
+)
+
+// Event represents a change made to a note.
+type Event struct {
+	// ID is the sequence number of the event. It increases with
+	// every published event.
+	ID uint64 `json:"id"`
+	// Type is the kind of change made to the note.
+	Type EventType `json:"type"`
+	// Version is the number of changes made to the note so far,
+	// including this one.
+	Version uint64 `json:"version"`
+	// Time is the timestamp when the event was published.
+	Time time.Time `json:"time"`
+	// Note is the note after the change. For a deleted note it
+	// is the note before the deletion.
+	Note *Note `json:"note"`
+}
+
+// Publisher is implemented by objects that can publish the
+// changes made to the notes.
+type Publisher interface {
+	// Publish publishes the t change made to the note n.
+	Publish(ctx context.Context, t EventType, n *Note)
+}
@@ -0,0 +1,111 @@
+package eventbus
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"noteapp/note"
+	"noteapp/note/noteutil"
+	"sync"
+	"time"
+)
+
+var _ note.Publisher = (*Bus)(nil)
+
+// Handler handles the note events it has subscribed to.
+type Handler interface {
+	Handle(ctx context.Context, e note.Event)
+}
+
+// HandlerFunc is an adapter to use an ordinary function as a Handler.
+type HandlerFunc func(ctx context.Context, e note.Event)
+
+// Handle calls f(ctx, e).
+func (f HandlerFunc) Handle(ctx context.Context, e note.Event) {
+	f(ctx, e)
+}
+
+type subscription struct {
+	handler Handler
+	types   map[note.EventType]bool
+}
+
+func (s *subscription) accepts(t note.EventType) bool {
+	return len(s.types) == 0 || s.types[t]
+}
+
+// Bus is the in-process bus of the note domain events. It numbers the
+// events and counts the versions of the notes, and passes the events to
+// the handlers subscribed to their types.
+//
+// The handlers are called one at a time in the order of the events so
+// that every handler sees the same sequence. The event is shared among
+// the handlers, so a handler must not modify it. A handler must not block
+// and must not publish to the bus. This is safe for concurrent use.
+type Bus struct {
+	mu       sync.Mutex
+	seq      uint64
+	versions map[uuid.UUID]uint64
+	subs     []*subscription
+}
+
+// New returns an empty bus.
+func New() *Bus {
+	return &Bus{versions: make(map[uuid.UUID]uint64)}
+}
+
+// Subscribe subscribes h to the events of types, or to all the events
+// when there are no types. It returns a function to unsubscribe h.
+func (b *Bus) Subscribe(h Handler, types ...note.EventType) (unsubscribe func()) {
+	sub := &subscription{handler: h, types: make(map[note.EventType]bool)}
+	for _, t := range types {
+		sub.types[t] = true
+	}
+
+	b.mu.Lock()
+	b.subs = append(b.subs, sub)
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			subs := make([]*subscription, 0, len(b.subs))
+			for _, s := range b.subs {
+				if s != sub {
+					subs = append(subs, s)
+				}
+			}
+			b.subs = subs
+		})
+	}
+}
+
+// Publish publishes the t change made to the note n as an event.
+func (b *Bus) Publish(ctx context.Context, t note.EventType, n *note.Note) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	version := b.versions[n.ID] + 1
+	if t == note.EventDeleted {
+		delete(b.versions, n.ID)
+	} else {
+		b.versions[n.ID] = version
+	}
+
+	e := note.Event{
+		ID:      b.seq,
+		Type:    t,
+		Version: version,
+		Time:    time.Now().UTC(),
+		Note:    noteutil.Copy(n),
+	}
+
+	for _, sub := range b.subs {
+		if sub.accepts(t) {
+			sub.handler.Handle(ctx, e)
+		}
+	}
+}
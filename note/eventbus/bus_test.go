@@ -0,0 +1,136 @@
+package eventbus
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"noteapp/note"
+	"testing"
+)
+
+var dummyCtx = context.TODO()
+
+func Test(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+type TestSuite struct {
+	suite.Suite
+}
+
+type recorder struct {
+	events []note.Event
+}
+
+func (r *recorder) Handle(_ context.Context, e note.Event) {
+	r.events = append(r.events, e)
+}
+
+func (r *recorder) types() (types []note.EventType) {
+	for _, e := range r.events {
+		types = append(types, e.Type)
+	}
+	return
+}
+
+func newNote(title string) *note.Note {
+	n := new(note.Note)
+	n.SetID(uuid.New()).SetTitle(title)
+	return n
+}
+
+func (s *TestSuite) TestPublish() {
+	s.Run("Events should be numbered in order", func() {
+		bus := New()
+		all := new(recorder)
+		bus.Subscribe(all)
+
+		n := newNote("Test")
+		bus.Publish(dummyCtx, note.EventCreated, n)
+		bus.Publish(dummyCtx, note.EventUpdated, n)
+		bus.Publish(dummyCtx, note.EventDeleted, n)
+
+		s.Require().Len(all.events, 3)
+		for i, e := range all.events {
+			s.Equal(uint64(i+1), e.ID)
+			s.Equal(n, e.Note)
+			s.False(e.Time.IsZero())
+		}
+		s.Equal([]note.EventType{note.EventCreated, note.EventUpdated, note.EventDeleted}, all.types())
+	})
+
+	s.Run("Version should count the changes per note", func() {
+		bus := New()
+		all := new(recorder)
+		bus.Subscribe(all)
+
+		first, second := newNote("First"), newNote("Second")
+		bus.Publish(dummyCtx, note.EventCreated, first)
+		bus.Publish(dummyCtx, note.EventCreated, second)
+		bus.Publish(dummyCtx, note.EventUpdated, first)
+		bus.Publish(dummyCtx, note.EventDeleted, first)
+		bus.Publish(dummyCtx, note.EventCreated, first)
+
+		var got []uint64
+		for _, e := range all.events {
+			got = append(got, e.Version)
+		}
+		s.Equal([]uint64{1, 1, 2, 3, 1}, got)
+	})
+
+	s.Run("Published note should not be affected by later changes", func() {
+		bus := New()
+		all := new(recorder)
+		bus.Subscribe(all)
+
+		n := newNote("Before")
+		bus.Publish(dummyCtx, note.EventCreated, n)
+		n.SetTitle("After")
+
+		s.Equal("Before", all.events[0].Note.GetTitle())
+	})
+
+	s.Run("Handlers should only receive the types they subscribed to", func() {
+		bus := New()
+		all, deleted, changed := new(recorder), new(recorder), new(recorder)
+		bus.Subscribe(all)
+		bus.Subscribe(deleted, note.EventDeleted)
+		bus.Subscribe(changed, note.EventCreated, note.EventUpdated)
+
+		n := newNote("Test")
+		bus.Publish(dummyCtx, note.EventCreated, n)
+		bus.Publish(dummyCtx, note.EventUpdated, n)
+		bus.Publish(dummyCtx, note.EventDeleted, n)
+
+		s.Len(all.events, 3)
+		s.Equal([]note.EventType{note.EventDeleted}, deleted.types())
+		s.Equal(uint64(3), deleted.events[0].ID)
+		s.Equal([]note.EventType{note.EventCreated, note.EventUpdated}, changed.types())
+	})
+
+	s.Run("Unsubscribed handler should not receive events", func() {
+		bus := New()
+		first, second := new(recorder), new(recorder)
+		unsubscribe := bus.Subscribe(first)
+		bus.Subscribe(second)
+
+		bus.Publish(dummyCtx, note.EventCreated, newNote("First"))
+		unsubscribe()
+		unsubscribe()
+		bus.Publish(dummyCtx, note.EventCreated, newNote("Second"))
+
+		s.Len(first.events, 1)
+		s.Len(second.events, 2)
+	})
+
+	s.Run("Function should be usable as a handler", func() {
+		bus := New()
+		var got []note.EventType
+		bus.Subscribe(HandlerFunc(func(_ context.Context, e note.Event) {
+			got = append(got, e.Type)
+		}))
+
+		bus.Publish(dummyCtx, note.EventUpdated, newNote("Test"))
+		s.Equal([]note.EventType{note.EventUpdated}, got)
+	})
+}
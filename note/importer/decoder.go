@@ -0,0 +1,99 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"noteapp/note"
+	"noteapp/note/proto/protoutil"
+)
+
+var errMissingNote = errors.New("importer: missing note")
+
+// Decoder reads the notes from an import stream.
+type Decoder interface {
+	// Decode returns the next note from the stream. It returns io.EOF
+	// at the end of the stream and an *InvalidNoteError when only the
+	// current note can't be decoded and the stream can continue.
+	Decode() (*note.Note, error)
+}
+
+// InvalidNoteError is an error when a single note from the
+// stream can't be decoded.
+type InvalidNoteError struct {
+	Err error
+}
+
+func (e *InvalidNoteError) Error() string {
+	return fmt.Sprintf("importer: invalid note: %s", e.Err)
+}
+
+// Unwrap returns the cause of the error.
+func (e *InvalidNoteError) Unwrap() error {
+	return e.Err
+}
+
+// NewNDJSONDecoder returns a decoder that reads one JSON
+// encoded note per line from r. Blank lines are ignored.
+func NewNDJSONDecoder(r io.Reader) Decoder {
+	return &ndjsonDecoder{r: bufio.NewReader(r)}
+}
+
+type ndjsonDecoder struct {
+	r *bufio.Reader
+}
+
+func (d *ndjsonDecoder) Decode() (*note.Note, error) {
+	for {
+		line, err := d.r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			continue
+		}
+
+		var n *note.Note
+		if uerr := json.Unmarshal(line, &n); uerr != nil {
+			return nil, &InvalidNoteError{Err: uerr}
+		}
+
+		if n == nil {
+			return nil, &InvalidNoteError{Err: errMissingNote}
+		}
+
+		return n, nil
+	}
+}
+
+// NewProtobufDecoder returns a decoder that reads the 4-byte size
+// prefixed note protobuf messages from r, the same format of the
+// file store.
+func NewProtobufDecoder(r io.Reader) Decoder {
+	return &protobufDecoder{r: r}
+}
+
+type protobufDecoder struct {
+	r io.Reader
+}
+
+func (d *protobufDecoder) Decode() (*note.Note, error) {
+	msg, err := protoutil.ReadProtoMessageBytes(d.r)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := protoutil.UnmarshalNote(msg)
+	if err != nil {
+		return nil, &InvalidNoteError{Err: err}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,189 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"noteapp/note"
+)
+
+// ErrInvalidConflictMode is an error when the conflict mode is not supported.
+var ErrInvalidConflictMode = errors.New("importer: invalid conflict mode")
+
+// ConflictMode describes what to do when an imported note
+// already exists.
+type ConflictMode string
+
+const (
+	// ConflictFail stops the import on the first existing note.
+	ConflictFail ConflictMode = "fail"
+	// ConflictSkip leaves the existing note as is and continues.
+	ConflictSkip ConflictMode = "skip"
+	// ConflictUpsert updates the existing note with the imported one.
+	ConflictUpsert ConflictMode = "upsert"
+)
+
+// ParseConflictMode parses s into a ConflictMode. An empty s will
+// return the ConflictFail.
+func ParseConflictMode(s string) (ConflictMode, error) {
+	switch mode := ConflictMode(s); mode {
+	case "":
+		return ConflictFail, nil
+	case ConflictFail, ConflictSkip, ConflictUpsert:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("importer: conflict mode '%s': %w", s, ErrInvalidConflictMode)
+	}
+}
+
+// Status is the outcome of importing a single note.
+type Status string
+
+const (
+	// StatusCreated is when the note has been created.
+	StatusCreated Status = "created"
+	// StatusUpdated is when the existing note has been updated.
+	StatusUpdated Status = "updated"
+	// StatusSkipped is when the existing note has been left as is.
+	StatusSkipped Status = "skipped"
+	// StatusConflict is when the note already exists and the import stopped.
+	StatusConflict Status = "conflict"
+	// StatusInvalid is when the note can't be decoded or rejected by the service.
+	StatusInvalid Status = "invalid"
+	// StatusFailed is when the service refused to import the note, e.g.
+	// when the existing note can't be updated or the quota is exceeded.
+	StatusFailed Status = "failed"
+)
+
+// Result is the outcome of importing the note at Index position
+// of the import stream.
+type Result struct {
+	Index  int       `json:"index"`
+	ID     uuid.UUID `json:"id,omitempty"`
+	Status Status    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Summary contains the results of an import.
+type Summary struct {
+	Results []Result `json:"results"`
+	// Counts is the number of results per status.
+	Counts map[Status]int `json:"counts"`
+}
+
+func (s *Summary) add(r Result) {
+	s.Results = append(s.Results, r)
+	s.Counts[r.Status]++
+}
+
+// Importer imports notes through the note.Service so that the
+// notes are validated the same way as the ones created one by one.
+type Importer struct {
+	svc  note.Service
+	mode ConflictMode
+}
+
+// New takes the service to create the notes with and the mode
+// to use for existing notes and returns an importer.
+func New(svc note.Service, mode ConflictMode) *Importer {
+	return &Importer{svc: svc, mode: mode}
+}
+
+// Import reads all the notes from dec and imports them. It returns
+// the summary of the imported notes even when it encountered an error.
+// Notes that can't be decoded or are rejected by the service are
+// reported as invalid or failed and don't stop the import, while
+// errors from the store and context cancellation do.
+func (im *Importer) Import(ctx context.Context, dec Decoder) (*Summary, error) {
+	summary := &Summary{Results: []Result{}, Counts: make(map[Status]int)}
+
+	for index := 0; ; index++ {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+
+		n, err := dec.Decode()
+		if err == io.EOF {
+			return summary, nil
+		}
+
+		var invalid *InvalidNoteError
+		if errors.As(err, &invalid) {
+			summary.add(Result{Index: index, Status: StatusInvalid, Error: invalid.Error()})
+			continue
+		}
+
+		if err != nil {
+			return summary, err
+		}
+
+		result, err := im.importNote(ctx, n)
+		result.Index = index
+		if err != nil {
+			return summary, err
+		}
+
+		summary.add(result)
+		if result.Status == StatusConflict {
+			return summary, nil
+		}
+	}
+}
+
+// importNote creates or updates the note n. The fields set by the server
+// are cleared first since the exported notes have them, so that the notes
+// are not rejected when the clients can't set them. The note is owned by
+// the owner in ctx and timestamped again by the service.
+func (im *Importer) importNote(ctx context.Context, n *note.Note) (Result, error) {
+	n.CreatedTime, n.UpdatedTime, n.OwnerID = nil, nil, ""
+
+	created, err := im.svc.Create(ctx, n)
+	if err == nil {
+		return Result{ID: created.ID, Status: StatusCreated}, nil
+	}
+
+	if !errors.Is(err, note.ErrExists) {
+		return rejected(n, err)
+	}
+
+	switch im.mode {
+	case ConflictSkip:
+		return Result{ID: n.ID, Status: StatusSkipped}, nil
+	case ConflictUpsert:
+		updated, err := im.svc.Update(ctx, n)
+		if err != nil {
+			return rejected(n, err)
+		}
+		return Result{ID: updated.ID, Status: StatusUpdated}, nil
+	default:
+		return Result{ID: n.ID, Status: StatusConflict, Error: err.Error()}, nil
+	}
+}
+
+// rejected returns the result of the note n the service returned err
+// for. The error is returned when it is not specific to the note.
+func rejected(n *note.Note, err error) (Result, error) {
+	switch {
+	case isInvalid(err):
+		return Result{ID: n.ID, Status: StatusInvalid, Error: err.Error()}, nil
+	case isFailed(err):
+		return Result{ID: n.ID, Status: StatusFailed, Error: err.Error()}, nil
+	default:
+		return Result{ID: n.ID}, err
+	}
+}
+
+// isInvalid returns true when the service rejected the note
+// because of its content.
+func isInvalid(err error) bool {
+	var invalid *note.ValidationError
+	return errors.Is(err, note.ErrNilID) || errors.Is(err, note.ErrNilNote) || errors.As(err, &invalid)
+}
+
+// isFailed returns true when the service refused to write the note,
+// e.g. because the existing note is owned by another user.
+func isFailed(err error) bool {
+	return errors.Is(err, note.ErrNotFound) || errors.Is(err, note.ErrPermissionDenied) || errors.Is(err, note.ErrQuotaExceeded)
+}
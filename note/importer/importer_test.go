@@ -0,0 +1,192 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"noteapp/note"
+	"noteapp/note/proto/protoutil"
+	"noteapp/note/service"
+	"noteapp/note/store/memory"
+	"noteapp/note/validation"
+	"testing"
+)
+
+var dummyCtx = context.TODO()
+
+func Test(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+type TestSuite struct {
+	suite.Suite
+	svc note.Service
+}
+
+func (s *TestSuite) SetupTest() {
+	s.svc = service.New(memory.New())
+}
+
+func (s *TestSuite) TestImportNDJSON() {
+	existing, err := s.svc.Create(dummyCtx, new(note.Note).SetTitle("Existing"))
+	s.Require().NoError(err)
+
+	newNote := new(note.Note).SetID(uuid.New()).SetTitle("New")
+	updatedExisting := new(note.Note).SetID(existing.ID).SetTitle("Updated")
+
+	input := ndjson(s, newNote) + "{not json}\n\nnull\n" + ndjson(s, updatedExisting) + ndjson(s, new(note.Note).SetTitle("Last"))
+
+	table := []struct {
+		mode     ConflictMode
+		want     []Status
+		wantNote string
+	}{
+		{
+			mode:     ConflictFail,
+			want:     []Status{StatusCreated, StatusInvalid, StatusInvalid, StatusConflict},
+			wantNote: "Existing",
+		},
+		{
+			mode:     ConflictSkip,
+			want:     []Status{StatusCreated, StatusInvalid, StatusInvalid, StatusSkipped, StatusCreated},
+			wantNote: "Existing",
+		},
+		{
+			mode:     ConflictUpsert,
+			want:     []Status{StatusCreated, StatusInvalid, StatusInvalid, StatusUpdated, StatusCreated},
+			wantNote: "Updated",
+		},
+	}
+
+	for _, row := range table {
+		s.Run(string(row.mode), func() {
+			s.Require().NoError(s.svc.Delete(dummyCtx, newNote.ID))
+			s.Require().NoError(s.svc.Delete(dummyCtx, existing.ID))
+			_, err := s.svc.Create(dummyCtx, new(note.Note).SetID(existing.ID).SetTitle("Existing"))
+			s.Require().NoError(err)
+
+			summary, err := New(s.svc, row.mode).Import(dummyCtx, NewNDJSONDecoder(bytes.NewBufferString(input)))
+			s.Require().NoError(err)
+
+			var got []Status
+			for i, result := range summary.Results {
+				s.Equal(i, result.Index)
+				got = append(got, result.Status)
+			}
+			s.Equal(row.want, got)
+			s.Equal(newNote.ID, summary.Results[0].ID)
+
+			gotNote, err := s.svc.Get(dummyCtx, existing.ID)
+			s.Require().NoError(err)
+			s.Equal(row.wantNote, gotNote.GetTitle())
+		})
+	}
+}
+
+func (s *TestSuite) TestImportProtobuf() {
+	notes := []*note.Note{
+		new(note.Note).SetID(uuid.New()).SetTitle("First"),
+		new(note.Note).SetID(uuid.New()).SetTitle("Second"),
+	}
+
+	var buf bytes.Buffer
+	err := protoutil.WriteAllProtoMessages(&buf, protoutil.ConvertToProtoMessage(protoutil.ConvertNotesToProtos(notes))...)
+	s.Require().NoError(err)
+
+	summary, err := New(s.svc, ConflictFail).Import(dummyCtx, NewProtobufDecoder(&buf))
+	s.Require().NoError(err)
+	s.Equal(2, summary.Counts[StatusCreated])
+
+	for _, n := range notes {
+		got, err := s.svc.Get(dummyCtx, n.ID)
+		s.Require().NoError(err)
+		s.Equal(n.GetTitle(), got.GetTitle())
+	}
+}
+
+func (s *TestSuite) TestImportRejected() {
+	v, err := validation.New(validation.Rules{MaxTitleLength: 5})
+	s.Require().NoError(err)
+	svc := service.ValidatingMiddleware(v)(s.svc)
+
+	input := ndjson(s, new(note.Note).SetTitle("Too long")) + ndjson(s, new(note.Note).SetTitle("Short"))
+
+	summary, err := New(svc, ConflictFail).Import(dummyCtx, NewNDJSONDecoder(bytes.NewBufferString(input)))
+	s.Require().NoError(err)
+	s.Require().Len(summary.Results, 2)
+	s.Equal(StatusInvalid, summary.Results[0].Status)
+	s.Contains(summary.Results[0].Error, "title must be at most 5 characters")
+	s.Equal(StatusCreated, summary.Results[1].Status)
+}
+
+func (s *TestSuite) TestImportExported() {
+	v, err := validation.New(validation.Rules{RejectServerOwnedFields: true})
+	s.Require().NoError(err)
+	svc := service.ValidatingMiddleware(v)(s.svc)
+	aliceCtx := note.WithOwner(dummyCtx, "alice")
+
+	exported, err := s.svc.Create(aliceCtx, new(note.Note).SetTitle("Exported"))
+	s.Require().NoError(err)
+	s.Require().NotNil(exported.CreatedTime)
+	s.Require().NoError(s.svc.Delete(aliceCtx, exported.ID))
+
+	for _, mode := range []ConflictMode{ConflictFail, ConflictUpsert} {
+		s.Run(string(mode), func() {
+			summary, err := New(svc, mode).Import(aliceCtx, NewNDJSONDecoder(bytes.NewBufferString(ndjson(s, exported))))
+			s.Require().NoError(err)
+			s.Require().Len(summary.Results, 1)
+			s.NotEqual(StatusInvalid, summary.Results[0].Status, summary.Results[0].Error)
+
+			got, err := s.svc.Get(aliceCtx, exported.ID)
+			s.Require().NoError(err)
+			s.Equal("alice", got.OwnerID)
+			s.Equal(exported.GetTitle(), got.GetTitle())
+		})
+	}
+}
+
+func (s *TestSuite) TestImportUpsertRefused() {
+	bobs, err := s.svc.Create(note.WithOwner(dummyCtx, "bob"), new(note.Note).SetTitle("Bob's"))
+	s.Require().NoError(err)
+
+	input := ndjson(s, new(note.Note).SetID(bobs.ID).SetTitle("Alice's")) + ndjson(s, new(note.Note).SetTitle("Next"))
+	summary, err := New(s.svc, ConflictUpsert).Import(note.WithOwner(dummyCtx, "alice"), NewNDJSONDecoder(bytes.NewBufferString(input)))
+	s.Require().NoError(err)
+	s.Require().Len(summary.Results, 2)
+	s.Equal(StatusFailed, summary.Results[0].Status)
+	s.NotEmpty(summary.Results[0].Error)
+	s.Equal(StatusCreated, summary.Results[1].Status)
+
+	got, err := s.svc.Get(dummyCtx, bobs.ID)
+	s.Require().NoError(err)
+	s.Equal("Bob's", got.GetTitle())
+}
+
+func (s *TestSuite) TestImportCancelled() {
+	ctx, cancel := context.WithCancel(dummyCtx)
+	cancel()
+
+	_, err := New(s.svc, ConflictFail).Import(ctx, NewNDJSONDecoder(bytes.NewBufferString(ndjson(s, new(note.Note)))))
+	s.Equal(context.Canceled, err)
+}
+
+func (s *TestSuite) TestParseConflictMode() {
+	mode, err := ParseConflictMode("")
+	s.NoError(err)
+	s.Equal(ConflictFail, mode)
+
+	mode, err = ParseConflictMode("upsert")
+	s.NoError(err)
+	s.Equal(ConflictUpsert, mode)
+
+	_, err = ParseConflictMode("merge")
+	s.ErrorIs(err, ErrInvalidConflictMode)
+}
+
+func ndjson(s *TestSuite, n *note.Note) string {
+	b, err := json.Marshal(n)
+	s.Require().NoError(err)
+	return string(b) + "\n"
+}
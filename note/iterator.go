@@ -13,3 +13,33 @@ type Iterator interface {
 	// TotalPage returns the approximate number of pages.
 	TotalPage() uint64
 }
+
+// Values adapts it into an iterator.ValueIterator where each value
+// is a *Note. It lets the note iterators use the combinators in the
+// iterator package.
+func Values(it Iterator) iterator.ValueIterator {
+	return valueIterator{it}
+}
+
+type valueIterator struct {
+	Iterator
+}
+
+func (v valueIterator) Value() interface{} {
+	return v.Note()
+}
+
+// Collect loads all the notes of it into a slice then closes it.
+// Every value of it must be a *Note.
+func Collect(it iterator.ValueIterator) ([]*Note, error) {
+	values, err := iterator.Collect(it)
+	if err != nil {
+		return nil, err
+	}
+
+	notes := make([]*Note, 0, len(values))
+	for _, v := range values {
+		notes = append(notes, v.(*Note))
+	}
+	return notes, nil
+}
@@ -0,0 +1,110 @@
+package link
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is an error when the link doesn't exist.
+	ErrNotFound = errors.New("link: not found")
+	// ErrInvalidToken is an error when the token is malformed or
+	// its signature doesn't match.
+	ErrInvalidToken = errors.New("link: invalid token")
+	// ErrExpired is an error when the link has expired.
+	ErrExpired = errors.New("link: expired")
+	// ErrRevoked is an error when the link has been revoked.
+	ErrRevoked = errors.New("link: revoked")
+	// ErrPasswordRequired is an error when the link has a password
+	// and the request doesn't carry one.
+	ErrPasswordRequired = errors.New("link: password required")
+	// ErrInvalidPassword is an error when the password doesn't match.
+	ErrInvalidPassword = errors.New("link: invalid password")
+	// ErrInvalidTTL is an error when the lifetime of the new link is
+	// negative or longer than the maximum.
+	ErrInvalidTTL = errors.New("link: invalid ttl")
+)
+
+// Link is a public read-only link to a note. The link itself is
+// resolved by its token, which is only given out when it is created.
+type Link struct {
+	ID     uuid.UUID `json:"id"`
+	NoteID uuid.UUID `json:"note_id"`
+	// CreatedBy is the owner id of the user who created the link.
+	CreatedBy   string     `json:"created_by,omitempty"`
+	CreatedTime time.Time  `json:"created_time"`
+	ExpiresTime time.Time  `json:"expires_time"`
+	RevokedTime *time.Time `json:"revoked_time,omitempty"`
+	// Views is the number of times the note has been viewed
+	// through the link.
+	Views uint64 `json:"views"`
+	// PasswordHash is the bcrypt hash of the password of the link.
+	// The link doesn't have a password when it is empty.
+	PasswordHash string `json:"password_hash,omitempty"`
+}
+
+// Revoked reports whether the link has been revoked.
+func (l *Link) Revoked() bool {
+	return l.RevokedTime != nil
+}
+
+// Expired reports whether the link has expired at t.
+func (l *Link) Expired(t time.Time) bool {
+	return !t.Before(l.ExpiresTime)
+}
+
+// HasPassword reports whether the link is protected by a password.
+func (l *Link) HasPassword() bool {
+	return l.PasswordHash != ""
+}
+
+// signer signs the tokens of the links with HMAC-SHA256. A token is
+// the base64url of the link id and the expiry time, and the base64url
+// of their signature, separated by a dot. The expiry is in the token
+// so that the expired links are rejected without a lookup.
+type signer struct {
+	secret []byte
+}
+
+func (s signer) sign(id uuid.UUID, expires time.Time) string {
+	payload := make([]byte, len(id)+8)
+	copy(payload, id[:])
+	binary.BigEndian.PutUint64(payload[len(id):], uint64(expires.Unix()))
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload))
+}
+
+// verify returns the link id and the expiry time of token.
+func (s signer) verify(token string) (uuid.UUID, time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return uuid.Nil, time.Time{}, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || len(payload) != len(uuid.UUID{})+8 {
+		return uuid.Nil, time.Time{}, ErrInvalidToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(sig, s.mac(payload)) {
+		return uuid.Nil, time.Time{}, ErrInvalidToken
+	}
+
+	var id uuid.UUID
+	copy(id[:], payload)
+	expires := time.Unix(int64(binary.BigEndian.Uint64(payload[len(id):])), 0).UTC()
+	return id, expires, nil
+}
+
+func (s signer) mac(payload []byte) []byte {
+	m := hmac.New(sha256.New, s.secret)
+	_, _ = m.Write(payload)
+	return m.Sum(nil)
+}
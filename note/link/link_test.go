@@ -0,0 +1,47 @@
+package link
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSigner(t *testing.T) {
+	suite.Run(t, new(SignerTestSuite))
+}
+
+type SignerTestSuite struct {
+	suite.Suite
+}
+
+func (s *SignerTestSuite) TestVerify() {
+	sg := signer{secret: []byte("secret")}
+	id, expires := uuid.New(), time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+	token := sg.sign(id, expires)
+
+	gotID, gotExpires, err := sg.verify(token)
+	s.Require().NoError(err)
+	s.Equal(id, gotID)
+	s.True(expires.Equal(gotExpires))
+
+	parts := strings.Split(token, ".")
+	other := signer{secret: []byte("other")}.sign(uuid.New(), expires)
+
+	invalid := map[string]string{
+		"Empty token":         "",
+		"Missing signature":   parts[0],
+		"Malformed payload":   "!!." + parts[1],
+		"Malformed signature": parts[0] + ".!!",
+		"Swapped signature":   parts[0] + "." + strings.Split(other, ".")[1],
+		"Other secret":        other,
+	}
+
+	for name, token := range invalid {
+		s.Run(name, func() {
+			_, _, err := sg.verify(token)
+			s.ErrorIs(err, ErrInvalidToken)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package link
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"noteapp/note"
+	"time"
+)
+
+// Config is the configuration of the links.
+type Config struct {
+	// Secret is the key signing the tokens.
+	Secret []byte
+	// DefaultTTL is the lifetime of the links created without one.
+	DefaultTTL time.Duration
+	// MaxTTL is the longest lifetime of a link.
+	MaxTTL time.Duration
+}
+
+// Service manages the public links of the notes and resolves them.
+// The notes are looked up with the note service, so only the users
+// who can manage a note can create, list and revoke its links.
+type Service struct {
+	notes  note.Service
+	grants note.Grants
+	store  *Store
+	signer signer
+	conf   Config
+	now    func() time.Time
+}
+
+// NewService returns a service managing the links in store. The
+// grants are the ones of the note service, they can be nil when the
+// notes are not shared.
+func NewService(notes note.Service, grants note.Grants, store *Store, conf Config) (*Service, error) {
+	if len(conf.Secret) == 0 {
+		return nil, errors.New("link: empty secret")
+	}
+
+	if conf.MaxTTL <= 0 || conf.DefaultTTL <= 0 || conf.DefaultTTL > conf.MaxTTL {
+		return nil, fmt.Errorf("link: default ttl %s must be positive and not longer than max ttl %s", conf.DefaultTTL, conf.MaxTTL)
+	}
+
+	return &Service{
+		notes:  notes,
+		grants: grants,
+		store:  store,
+		signer: signer{secret: conf.Secret},
+		conf:   conf,
+		now:    time.Now,
+	}, nil
+}
+
+// Create creates a link to the note with an id which expires after the
+// ttl, or the default ttl when it is zero. The link is protected by the
+// password when it isn't empty. It returns the link with its token.
+func (s *Service) Create(ctx context.Context, noteID uuid.UUID, ttl time.Duration, password string) (*Link, string, error) {
+	if ttl == 0 {
+		ttl = s.conf.DefaultTTL
+	}
+
+	if ttl < 0 || ttl > s.conf.MaxTTL {
+		return nil, "", fmt.Errorf("link: ttl %s is not in (0, %s]: %w", ttl, s.conf.MaxTTL, ErrInvalidTTL)
+	}
+
+	if err := s.manage(ctx, noteID); err != nil {
+		return nil, "", err
+	}
+
+	now := s.now().UTC()
+	l := &Link{
+		ID:     uuid.New(),
+		NoteID: noteID,
+		// The token only keeps the seconds of the expiry.
+		ExpiresTime: now.Add(ttl).Truncate(time.Second),
+		CreatedTime: now,
+	}
+	l.CreatedBy, _ = note.OwnerFromContext(ctx)
+
+	if password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, "", err
+		}
+		l.PasswordHash = string(hash)
+	}
+
+	if err := s.store.Create(l); err != nil {
+		return nil, "", err
+	}
+	return l, s.signer.sign(l.ID, l.ExpiresTime), nil
+}
+
+// List returns the links of the note with an id.
+func (s *Service) List(ctx context.Context, noteID uuid.UUID) ([]*Link, error) {
+	if err := s.manage(ctx, noteID); err != nil {
+		return nil, err
+	}
+	return s.store.List(noteID), nil
+}
+
+// Revoke revokes the link with an id of the note with an id.
+// The link stops resolving right away.
+func (s *Service) Revoke(ctx context.Context, noteID, linkID uuid.UUID) error {
+	if err := s.manage(ctx, noteID); err != nil {
+		return err
+	}
+
+	l, err := s.store.Get(linkID)
+	if err != nil {
+		return err
+	}
+
+	if l.NoteID != noteID {
+		return ErrNotFound
+	}
+	return s.store.Revoke(linkID, s.now().UTC())
+}
+
+// Resolve returns the note of the link with the token, along with
+// the link, and counts the view. The password is only checked for
+// the links protected by a password. A link stops resolving as
+// revoked as soon as its creator can't manage the note anymore,
+// such as when the grant it has been created with is revoked.
+//
+// The ctx must not have an owner, the link gives the access to the
+// note whoever resolves it.
+func (s *Service) Resolve(ctx context.Context, token, password string) (*note.Note, *Link, error) {
+	id, expires, err := s.signer.verify(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !s.now().Before(expires) {
+		return nil, nil, ErrExpired
+	}
+
+	l, err := s.store.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if l.Revoked() {
+		return nil, nil, ErrRevoked
+	}
+
+	if l.HasPassword() {
+		if password == "" {
+			return nil, nil, ErrPasswordRequired
+		}
+
+		if bcrypt.CompareHashAndPassword([]byte(l.PasswordHash), []byte(password)) != nil {
+			return nil, nil, ErrInvalidPassword
+		}
+	}
+
+	n, err := s.notes.Get(ctx, l.NoteID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if l.CreatedBy != "" && !note.RoleOf(note.WithOwner(ctx, l.CreatedBy), n, s.grants).CanManage() {
+		return nil, nil, fmt.Errorf("link: '%s' can't manage note '%s' anymore: %w", l.CreatedBy, l.NoteID, ErrRevoked)
+	}
+
+	if err := s.store.CountView(l.ID); err != nil {
+		return nil, nil, err
+	}
+	l.Views++
+	return n, l, nil
+}
+
+// manage returns an error unless the role of the owner
+// in ctx can manage the note with an id.
+func (s *Service) manage(ctx context.Context, noteID uuid.UUID) error {
+	n, err := s.notes.Get(ctx, noteID)
+	if err != nil {
+		return err
+	}
+
+	if role := note.RoleOf(ctx, n, s.grants); !role.CanManage() {
+		return fmt.Errorf("link: note '%s' can't be linked by the %s: %w", noteID, role, note.ErrPermissionDenied)
+	}
+	return nil
+}
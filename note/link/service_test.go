@@ -0,0 +1,191 @@
+package link
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/suite"
+	"noteapp/note"
+	"noteapp/note/service"
+	"noteapp/note/store/memory"
+	"testing"
+	"time"
+)
+
+var dummyCtx = context.TODO()
+
+var (
+	aliceCtx = note.WithOwner(dummyCtx, "alice")
+	bobCtx   = note.WithOwner(dummyCtx, "bob")
+)
+
+// grants is the share grants of the users keyed by the note id.
+type grants map[uuid.UUID]map[string]note.Role
+
+func (g grants) Role(noteID uuid.UUID, userID string) note.Role {
+	return g[noteID][userID]
+}
+
+func (g grants) SharedWith(userID string) []uuid.UUID {
+	var ids []uuid.UUID
+	for id, roles := range g {
+		if _, ok := roles[userID]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func TestService(t *testing.T) {
+	suite.Run(t, new(ServiceTestSuite))
+}
+
+type ServiceTestSuite struct {
+	suite.Suite
+	store  *Store
+	grants grants
+	svc    *Service
+	note   *note.Note
+	now    time.Time
+}
+
+func (s *ServiceTestSuite) SetupTest() {
+	file, err := afero.NewMemMapFs().Create("links.json")
+	s.Require().NoError(err)
+
+	s.store, err = NewStore(file)
+	s.Require().NoError(err)
+
+	s.grants = grants{}
+	notes := service.New(memory.New(), service.WithGrants(s.grants))
+	s.svc, err = NewService(notes, s.grants, s.store, Config{
+		Secret:     []byte("secret"),
+		DefaultTTL: time.Hour,
+		MaxTTL:     24 * time.Hour,
+	})
+	s.Require().NoError(err)
+
+	s.now = time.Now()
+	s.svc.now = func() time.Time { return s.now }
+
+	s.note, err = notes.Create(aliceCtx, new(note.Note).SetTitle("Linked"))
+	s.Require().NoError(err)
+}
+
+func (s *ServiceTestSuite) create(ttl time.Duration, password string) (*Link, string) {
+	l, token, err := s.svc.Create(aliceCtx, s.note.ID, ttl, password)
+	s.Require().NoError(err)
+	return l, token
+}
+
+func (s *ServiceTestSuite) TestNewService() {
+	_, err := NewService(nil, nil, s.store, Config{DefaultTTL: time.Hour, MaxTTL: time.Hour})
+	s.Error(err)
+
+	_, err = NewService(nil, nil, s.store, Config{Secret: []byte("secret"), DefaultTTL: 2 * time.Hour, MaxTTL: time.Hour})
+	s.Error(err)
+}
+
+func (s *ServiceTestSuite) TestCreate() {
+	s.Run("Creating a link with the default ttl", func() {
+		l, token := s.create(0, "")
+		s.NotEmpty(token)
+		s.Equal(s.note.ID, l.NoteID)
+		s.Equal("alice", l.CreatedBy)
+		s.WithinDuration(s.now.Add(time.Hour), l.ExpiresTime, time.Second)
+		s.False(l.HasPassword())
+	})
+
+	s.Run("Creating a link longer than the max ttl should return an error", func() {
+		_, _, err := s.svc.Create(aliceCtx, s.note.ID, 48*time.Hour, "")
+		s.ErrorIs(err, ErrInvalidTTL)
+	})
+
+	s.Run("Only the users managing the note can create a link", func() {
+		_, _, err := s.svc.Create(bobCtx, s.note.ID, 0, "")
+		s.ErrorIs(err, note.ErrNotFound)
+	})
+}
+
+func (s *ServiceTestSuite) TestResolve() {
+	s.Run("Resolving should count the views", func() {
+		l, token := s.create(0, "")
+
+		for i := 1; i <= 2; i++ {
+			n, got, err := s.svc.Resolve(dummyCtx, token, "")
+			s.Require().NoError(err)
+			s.Equal(s.note.ID, n.ID)
+			s.Equal(uint64(i), got.Views)
+		}
+
+		links, err := s.svc.List(aliceCtx, s.note.ID)
+		s.Require().NoError(err)
+		s.Require().Len(links, 1)
+		s.Equal(l.ID, links[0].ID)
+		s.Equal(uint64(2), links[0].Views)
+	})
+
+	s.Run("Resolving an expired link should return an error", func() {
+		_, token := s.create(time.Minute, "")
+
+		s.now = s.now.Add(2 * time.Minute)
+		defer func() { s.now = s.now.Add(-2 * time.Minute) }()
+
+		_, _, err := s.svc.Resolve(dummyCtx, token, "")
+		s.ErrorIs(err, ErrExpired)
+	})
+
+	s.Run("Resolving a revoked link should return an error", func() {
+		l, token := s.create(0, "")
+		s.Require().NoError(s.svc.Revoke(aliceCtx, s.note.ID, l.ID))
+
+		_, _, err := s.svc.Resolve(dummyCtx, token, "")
+		s.ErrorIs(err, ErrRevoked)
+	})
+
+	s.Run("Resolving a link with a password", func() {
+		l, token := s.create(0, "hunter2")
+		s.True(l.HasPassword())
+		s.NotContains(l.PasswordHash, "hunter2")
+
+		_, _, err := s.svc.Resolve(dummyCtx, token, "")
+		s.ErrorIs(err, ErrPasswordRequired)
+
+		_, _, err = s.svc.Resolve(dummyCtx, token, "wrong")
+		s.ErrorIs(err, ErrInvalidPassword)
+
+		_, _, err = s.svc.Resolve(dummyCtx, token, "hunter2")
+		s.NoError(err)
+	})
+
+	s.Run("Resolving a link of a revoked grant should return an error", func() {
+		s.grants[s.note.ID] = map[string]note.Role{"bob": note.RoleOwner}
+		defer delete(s.grants, s.note.ID)
+
+		_, token, err := s.svc.Create(bobCtx, s.note.ID, 0, "")
+		s.Require().NoError(err)
+
+		_, _, err = s.svc.Resolve(dummyCtx, token, "")
+		s.Require().NoError(err)
+
+		delete(s.grants, s.note.ID)
+		_, _, err = s.svc.Resolve(dummyCtx, token, "")
+		s.ErrorIs(err, ErrRevoked)
+	})
+
+	s.Run("Resolving a link of a deleted note should return an error", func() {
+		_, token := s.create(0, "")
+		s.Require().NoError(s.svc.notes.Delete(aliceCtx, s.note.ID))
+
+		_, _, err := s.svc.Resolve(dummyCtx, token, "")
+		s.ErrorIs(err, note.ErrNotFound)
+	})
+}
+
+func (s *ServiceTestSuite) TestRevoke() {
+	l, _ := s.create(0, "")
+
+	s.ErrorIs(s.svc.Revoke(bobCtx, s.note.ID, l.ID), note.ErrNotFound)
+	s.ErrorIs(s.svc.Revoke(aliceCtx, s.note.ID, uuid.New()), ErrNotFound)
+	s.NoError(s.svc.Revoke(aliceCtx, s.note.ID, l.ID))
+}
@@ -0,0 +1,182 @@
+package link
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"sync"
+	"time"
+)
+
+// File is the file where the store keeps its data.
+type File interface {
+	io.ReadWriteSeeker
+	io.Closer
+	Sync() error
+	Truncate(size int64) error
+}
+
+// fileData is the JSON document of the store file.
+type fileData struct {
+	Links []*Link `json:"links"`
+}
+
+// Store keeps the links. Every change is written to the file
+// before it returns. This is safe for concurrent use.
+type Store struct {
+	file File
+
+	mu    sync.Mutex
+	links []*Link
+}
+
+// NewStore reads the store data from file and returns the store.
+// An empty file is an empty store.
+func NewStore(file File) (*Store, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	b, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var data fileData
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &data); err != nil {
+			return nil, fmt.Errorf("link: reading store: %w", err)
+		}
+	}
+
+	return &Store{file: file, links: data.Links}, nil
+}
+
+// Create adds l.
+func (s *Store) Create(l *Link) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.links = append(s.links, copyLink(l))
+	if err := s.write(); err != nil {
+		s.links = s.links[:len(s.links)-1]
+		return err
+	}
+	return nil
+}
+
+// Get returns the link with an id.
+func (s *Store) Get(id uuid.UUID) (*Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.find(id)
+	if l == nil {
+		return nil, ErrNotFound
+	}
+	return copyLink(l), nil
+}
+
+// List returns the links of the note with an id in the order
+// they have been created.
+func (s *Store) List(noteID uuid.UUID) []*Link {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	links := []*Link{}
+	for _, l := range s.links {
+		if l.NoteID == noteID {
+			links = append(links, copyLink(l))
+		}
+	}
+	return links
+}
+
+// Revoke revokes the link with an id at t. Revoking a revoked
+// link keeps the time it has been revoked first.
+func (s *Store) Revoke(id uuid.UUID, t time.Time) error {
+	return s.update(id, func(l *Link) {
+		if l.RevokedTime == nil {
+			l.RevokedTime = &t
+		}
+	})
+}
+
+// CountView adds a view to the link with an id.
+func (s *Store) CountView(id uuid.UUID) error {
+	return s.update(id, func(l *Link) {
+		l.Views++
+	})
+}
+
+func (s *Store) update(id uuid.UUID, fn func(l *Link)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.find(id)
+	if l == nil {
+		return ErrNotFound
+	}
+
+	prev := copyLink(l)
+	fn(l)
+	if err := s.write(); err != nil {
+		*l = *prev
+		return err
+	}
+	return nil
+}
+
+func (s *Store) find(id uuid.UUID) *Link {
+	for _, l := range s.links {
+		if l.ID == id {
+			return l
+		}
+	}
+	return nil
+}
+
+// Close syncs and closes the file of the store. The store
+// must not be used after it is closed.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.file.Sync(); err != nil {
+		_ = s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+// write rewrites the whole file with the current data.
+func (s *Store) write() error {
+	b, err := json.Marshal(fileData{Links: s.links})
+	if err != nil {
+		return err
+	}
+
+	if err := s.file.Truncate(0); err != nil {
+		return err
+	}
+
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if _, err := s.file.Write(b); err != nil {
+		return err
+	}
+
+	return s.file.Sync()
+}
+
+func copyLink(l *Link) *Link {
+	cpy := *l
+	if l.RevokedTime != nil {
+		t := *l.RevokedTime
+		cpy.RevokedTime = &t
+	}
+	return &cpy
+}
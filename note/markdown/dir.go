@@ -0,0 +1,117 @@
+package markdown
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"io/fs"
+	"noteapp/note"
+	"noteapp/note/importer"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DirWriter writes notes as markdown files into a directory.
+type DirWriter struct {
+	dir  string
+	used map[string]bool
+}
+
+// NewDirWriter creates dir when it doesn't exist and returns
+// a writer for it.
+func NewDirWriter(dir string) (*DirWriter, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	return &DirWriter{dir: dir, used: make(map[string]bool)}, nil
+}
+
+// Write writes n into its own markdown file. When two notes have the
+// same file name the latter will have its short id in the name.
+func (w *DirWriter) Write(n *note.Note) error {
+	b, err := Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	name := FileName(n)
+	if w.used[strings.ToLower(name)] {
+		name = fmt.Sprintf("%s (%s)%s", strings.TrimSuffix(name, Extension), n.ID.String()[:8], Extension)
+	}
+	w.used[strings.ToLower(name)] = true
+
+	return os.WriteFile(filepath.Join(w.dir, name), b, 0666)
+}
+
+// NewDirDecoder returns an importer.Decoder that reads all the
+// markdown files in fsys, including the ones in sub directories.
+//
+// A note without an id in its front matter gets one derived from its
+// file path so that importing the same directory again will update the
+// same note. A note without a title gets the file name as its title.
+func NewDirDecoder(fsys fs.FS) (importer.Decoder, error) {
+	var paths []string
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip hidden directories such as .git and .obsidian.
+		if d.IsDir() && p != "." && strings.HasPrefix(d.Name(), ".") {
+			return fs.SkipDir
+		}
+
+		if !d.IsDir() && strings.EqualFold(path.Ext(p), Extension) {
+			paths = append(paths, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(paths)
+	return &dirDecoder{fsys: fsys, paths: paths}, nil
+}
+
+type dirDecoder struct {
+	fsys  fs.FS
+	paths []string
+}
+
+func (d *dirDecoder) Decode() (*note.Note, error) {
+	if len(d.paths) == 0 {
+		return nil, io.EOF
+	}
+
+	p := d.paths[0]
+	d.paths = d.paths[1:]
+
+	b, err := fs.ReadFile(d.fsys, p)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := Unmarshal(b)
+	if err != nil {
+		return nil, &importer.InvalidNoteError{Err: fmt.Errorf("%s: %w", p, err)}
+	}
+
+	if n.ID == uuid.Nil {
+		n.SetID(pathID(p))
+	}
+
+	if n.Title == nil {
+		n.SetTitle(strings.TrimSuffix(path.Base(p), path.Ext(p)))
+	}
+
+	return n, nil
+}
+
+// pathID returns a stable id for the markdown file at p.
+func pathID(p string) uuid.UUID {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("noteapp://markdown/"+p))
+}
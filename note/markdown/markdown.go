@@ -0,0 +1,144 @@
+package markdown
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v2"
+	"noteapp/note"
+	"strings"
+	"time"
+)
+
+// Extension is the file extension of the markdown notes.
+const Extension = ".md"
+
+const frontMatterDelimiter = "---\n"
+
+// ErrInvalidFrontMatter is an error when the front matter of
+// the markdown can't be parsed.
+var ErrInvalidFrontMatter = errors.New("markdown: invalid front matter")
+
+// frontMatter is the YAML header of the markdown note.
+type frontMatter struct {
+	ID          string `yaml:"id"`
+	Title       string `yaml:"title,omitempty"`
+	CreatedTime string `yaml:"created_time,omitempty"`
+	UpdatedTime string `yaml:"updated_time,omitempty"`
+	IsFavorite  bool   `yaml:"is_favorite"`
+}
+
+// Marshal encodes n into a markdown document. The note fields
+// are written in the YAML front matter followed by the content.
+func Marshal(n *note.Note) ([]byte, error) {
+	fm := frontMatter{
+		ID:         n.ID.String(),
+		Title:      n.GetTitle(),
+		IsFavorite: n.GetIsFavorite(),
+	}
+
+	if n.CreatedTime != nil {
+		fm.CreatedTime = n.GetCreatedTime().Format(time.RFC3339Nano)
+	}
+
+	if n.UpdatedTime != nil {
+		fm.UpdatedTime = n.GetUpdatedTime().Format(time.RFC3339Nano)
+	}
+
+	header, err := yaml.Marshal(fm)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(frontMatterDelimiter)
+	buf.Write(header)
+	buf.WriteString(frontMatterDelimiter)
+	buf.WriteString(n.GetContent())
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the markdown document b into a note. A document
+// without a front matter is taken as the content only. The returned
+// note has a nil ID when the front matter doesn't have one.
+func Unmarshal(b []byte) (*note.Note, error) {
+	n := new(note.Note)
+
+	content := string(b)
+	if !strings.HasPrefix(content, frontMatterDelimiter) {
+		n.SetContent(content)
+		return n, nil
+	}
+
+	end := strings.Index(content[len(frontMatterDelimiter)-1:], "\n"+frontMatterDelimiter)
+	if end < 0 {
+		return nil, fmt.Errorf("%w: missing closing delimiter", ErrInvalidFrontMatter)
+	}
+
+	header := content[len(frontMatterDelimiter) : len(frontMatterDelimiter)+end]
+	body := content[len(frontMatterDelimiter)+end+len(frontMatterDelimiter):]
+
+	var fm frontMatter
+	if err := yaml.Unmarshal([]byte(header), &fm); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidFrontMatter, err)
+	}
+
+	if fm.ID != "" {
+		id, err := uuid.Parse(fm.ID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: id: %s", ErrInvalidFrontMatter, err)
+		}
+		n.SetID(id)
+	}
+
+	for _, field := range []struct {
+		value string
+		set   func(time.Time) *note.Note
+	}{
+		{fm.CreatedTime, n.SetCreatedTime},
+		{fm.UpdatedTime, n.SetUpdatedTime},
+	} {
+		if field.value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339Nano, field.value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFrontMatter, err)
+		}
+		field.set(t)
+	}
+
+	if fm.Title != "" {
+		n.SetTitle(fm.Title)
+	}
+
+	n.SetContent(body).SetIsFavorite(fm.IsFavorite)
+	return n, nil
+}
+
+// FileName returns the base file name for n derived from its title.
+// Characters that are not allowed in file names are replaced and an
+// empty title will use "untitled".
+func FileName(n *note.Note) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x20, strings.ContainsRune(`/\:*?"<>|`, r):
+			return '-'
+		default:
+			return r
+		}
+	}, n.GetTitle())
+
+	name = strings.Trim(name, " .")
+	if runes := []rune(name); len(runes) > 100 {
+		name = strings.TrimSpace(string(runes[:100]))
+	}
+
+	if name == "" {
+		name = "untitled"
+	}
+
+	return name + Extension
+}
@@ -0,0 +1,186 @@
+package markdown
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"noteapp/note"
+	"noteapp/note/importer"
+	"noteapp/note/service"
+	"noteapp/note/store/memory"
+	"noteapp/note/validation"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func Test(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+type TestSuite struct {
+	suite.Suite
+}
+
+func newNote(title string) *note.Note {
+	return new(note.Note).
+		SetID(uuid.New()).
+		SetTitle(title).
+		SetContent("# Heading\n\nSome *content*.\n---\nwith a rule\n").
+		SetCreatedTime(time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)).
+		SetUpdatedTime(time.Date(2021, 5, 2, 11, 30, 0, 0, time.UTC)).
+		SetIsFavorite(true)
+}
+
+func (s *TestSuite) TestMarshalRoundTrip() {
+	want := newNote("Weekly: plan")
+
+	b, err := Marshal(want)
+	s.Require().NoError(err)
+	s.Contains(string(b), "id: "+want.ID.String())
+	s.Contains(string(b), "is_favorite: true")
+
+	got, err := Unmarshal(b)
+	s.Require().NoError(err)
+	s.Equal(want, got)
+}
+
+func (s *TestSuite) TestUnmarshal() {
+	s.Run("Document without front matter is the content", func() {
+		got, err := Unmarshal([]byte("Just text\n"))
+		s.Require().NoError(err)
+		s.Equal(uuid.Nil, got.ID)
+		s.Nil(got.Title)
+		s.Equal("Just text\n", got.GetContent())
+	})
+
+	s.Run("Empty front matter", func() {
+		got, err := Unmarshal([]byte("---\n---\nBody"))
+		s.Require().NoError(err)
+		s.Equal("Body", got.GetContent())
+	})
+
+	s.Run("Invalid front matter should return an error", func() {
+		for _, input := range []string{
+			"---\nid: 1\n",
+			"---\nid: not-a-uuid\n---\n",
+			"---\ncreated_time: yesterday\n---\n",
+			"---\n: [\n---\n",
+		} {
+			_, err := Unmarshal([]byte(input))
+			s.ErrorIs(err, ErrInvalidFrontMatter, input)
+		}
+	})
+}
+
+func (s *TestSuite) TestFileName() {
+	s.Equal("a-b-c.md", FileName(new(note.Note).SetTitle("a/b:c")))
+	s.Equal("untitled.md", FileName(new(note.Note)))
+	s.Equal("untitled.md", FileName(new(note.Note).SetTitle(" .. ")))
+}
+
+func (s *TestSuite) TestDirRoundTrip() {
+	dir := s.T().TempDir()
+	notes := []*note.Note{newNote("Same"), newNote("same"), newNote("Other")}
+
+	w, err := NewDirWriter(dir)
+	s.Require().NoError(err)
+	for _, n := range notes {
+		s.Require().NoError(w.Write(n))
+	}
+
+	entries, err := os.ReadDir(dir)
+	s.Require().NoError(err)
+	s.Len(entries, 3)
+
+	dec, err := NewDirDecoder(os.DirFS(dir))
+	s.Require().NoError(err)
+
+	got := make(map[uuid.UUID]*note.Note)
+	for {
+		n, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		s.Require().NoError(err)
+		got[n.ID] = n
+	}
+
+	s.Len(got, len(notes))
+	for _, n := range notes {
+		s.Equal(n, got[n.ID])
+	}
+}
+
+func (s *TestSuite) TestDirImport() {
+	dir := s.T().TempDir()
+	notes := []*note.Note{newNote("First"), newNote("Second")}
+
+	w, err := NewDirWriter(dir)
+	s.Require().NoError(err)
+	for _, n := range notes {
+		s.Require().NoError(w.Write(n))
+	}
+
+	v, err := validation.New(validation.Rules{RejectServerOwnedFields: true})
+	s.Require().NoError(err)
+	svc := service.ValidatingMiddleware(v)(service.New(memory.New()))
+
+	dec, err := NewDirDecoder(os.DirFS(dir))
+	s.Require().NoError(err)
+
+	summary, err := importer.New(svc, importer.ConflictFail).Import(context.TODO(), dec)
+	s.Require().NoError(err)
+	s.Equal(len(notes), summary.Counts[importer.StatusCreated])
+
+	for _, n := range notes {
+		got, err := svc.Get(context.TODO(), n.ID)
+		s.Require().NoError(err)
+		s.Equal(n.GetTitle(), got.GetTitle())
+		s.Equal(n.GetContent(), got.GetContent())
+	}
+}
+
+func (s *TestSuite) TestDirDecoder() {
+	fsys := fstest.MapFS{
+		"plain.md":          {Data: []byte("Plain content")},
+		"sub/nested.md":     {Data: []byte("---\ntitle: Nested\n---\nNested content")},
+		"broken.md":         {Data: []byte("---\nid: nope\n---\n")},
+		".obsidian/conf.md": {Data: []byte("ignored")},
+		"image.png":         {Data: []byte("ignored")},
+	}
+
+	decodeAll := func() (notes []*note.Note, invalid int) {
+		dec, err := NewDirDecoder(fsys)
+		s.Require().NoError(err)
+		for {
+			n, err := dec.Decode()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				var invalidErr *importer.InvalidNoteError
+				s.Require().ErrorAs(err, &invalidErr)
+				invalid++
+				continue
+			}
+			notes = append(notes, n)
+		}
+	}
+
+	first, invalid := decodeAll()
+	s.Equal(1, invalid)
+	s.Require().Len(first, 2)
+	s.Equal("plain", first[0].GetTitle())
+	s.Equal("Nested", first[1].GetTitle())
+	s.Equal("Nested content", first[1].GetContent())
+
+	// Importing the same directory again should give the same ids.
+	second, _ := decodeAll()
+	s.Equal(first[0].ID, second[0].ID)
+	s.Equal(first[1].ID, second[1].ID)
+	s.Equal(pathID(filepath.ToSlash("sub/nested.md")), second[1].ID)
+}
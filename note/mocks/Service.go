@@ -53,6 +53,52 @@ func (_m *Service) Delete(ctx context.Context, id uuid.UUID) error {
 	return r0
 }
 
+// Export provides a mock function with given fields: ctx
+func (_m *Service) Export(ctx context.Context) (note.Iterator, error) {
+	ret := _m.Called(ctx)
+
+	var r0 note.Iterator
+	if rf, ok := ret.Get(0).(func(context.Context) note.Iterator); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(note.Iterator)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Fetch provides a mock function with given fields: ctx, pagination
+func (_m *Service) Fetch(ctx context.Context, pagination *note.Pagination) (note.Iterator, error) {
+	ret := _m.Called(ctx, pagination)
+
+	var r0 note.Iterator
+	if rf, ok := ret.Get(0).(func(context.Context, *note.Pagination) note.Iterator); ok {
+		r0 = rf(ctx, pagination)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(note.Iterator)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *note.Pagination) error); ok {
+		r1 = rf(ctx, pagination)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Get provides a mock function with given fields: ctx, id
 func (_m *Service) Get(ctx context.Context, id uuid.UUID) (*note.Note, error) {
 	ret := _m.Called(ctx, id)
@@ -30,6 +30,29 @@ func (_m *Store) Delete(ctx context.Context, id uuid.UUID) error {
 	return r0
 }
 
+// Export provides a mock function with given fields: ctx
+func (_m *Store) Export(ctx context.Context) (note.Iterator, error) {
+	ret := _m.Called(ctx)
+
+	var r0 note.Iterator
+	if rf, ok := ret.Get(0).(func(context.Context) note.Iterator); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(note.Iterator)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Fetch provides a mock function with given fields: ctx, p
 func (_m *Store) Fetch(ctx context.Context, p *note.Pagination) (note.Iterator, error) {
 	ret := _m.Called(ctx, p)
@@ -112,3 +135,24 @@ func (_m *Store) Update(ctx context.Context, n *note.Note) (*note.Note, error) {
 
 	return r0, r1
 }
+
+// Usage provides a mock function with given fields: ctx, ownerID
+func (_m *Store) Usage(ctx context.Context, ownerID string) (note.Usage, error) {
+	ret := _m.Called(ctx, ownerID)
+
+	var r0 note.Usage
+	if rf, ok := ret.Get(0).(func(context.Context, string) note.Usage); ok {
+		r0 = rf(ctx, ownerID)
+	} else {
+		r0 = ret.Get(0).(note.Usage)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, ownerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -20,6 +20,8 @@ var (
 	ErrCancelled = context.Canceled
 	// ErrNilID is an error when the uuid ID is nil value.
 	ErrNilID = errors.New("note: note id must not empty value")
+	// ErrNilNote is an error when the note is missing.
+	ErrNilNote = errors.New("note: note must not be nil")
 )
 
 // Note represents a note.
@@ -36,6 +38,9 @@ type Note struct {
 	UpdatedTime *time.Time `json:"updated_time,omitempty"`
 	// IsFavorite is a flag when then the note is marked as favorite
 	IsFavorite *bool `json:"is_favorite,omitempty"`
+	// OwnerID is the id of the user owning the note. It is set by
+	// the service from the owner of the request context.
+	OwnerID string `json:"owner_id,omitempty"`
 }
 
 // SetID sets the id of the note.
@@ -78,6 +83,12 @@ func (n *Note) SetIsFavorite(b bool) *Note {
 	return n
 }
 
+// SetOwnerID sets the owner id of the note.
+func (n *Note) SetOwnerID(id string) *Note {
+	n.OwnerID = id
+	return n
+}
+
 // GetTitle gets the string value title of the note.
 func (n *Note) GetTitle() string {
 	return ptrconv.StringValue(n.Title)
@@ -115,6 +126,9 @@ func (n *Note) String() string {
 	write("📚 Created Time:\t%s\n", n.GetCreatedTime())
 	write("📚 Updated Time:\t%s\n", n.GetUpdatedTime())
 	write("📚 Favorite:\t%v\n", n.GetIsFavorite())
+	if n.OwnerID != "" {
+		write("📚 Owner:\t%s\n", n.OwnerID)
+	}
 	write("\n")
 	_ = w.Flush()
 	return buff.String()
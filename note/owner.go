@@ -0,0 +1,20 @@
+package note
+
+import "context"
+
+type ownerKey struct{}
+
+// WithOwner returns a copy of ctx where the service acts for the
+// user with the owner id. The service only sees the notes of the
+// owner in such a context.
+func WithOwner(ctx context.Context, ownerID string) context.Context {
+	return context.WithValue(ctx, ownerKey{}, ownerID)
+}
+
+// OwnerFromContext returns the owner id kept in ctx. It returns false
+// when ctx doesn't have an owner, such as in the local tools working
+// on the store directly, where the service sees all the notes.
+func OwnerFromContext(ctx context.Context) (string, bool) {
+	ownerID, ok := ctx.Value(ownerKey{}).(string)
+	return ownerID, ok
+}
@@ -3,6 +3,7 @@ package protoutil
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -13,6 +14,24 @@ import (
 
 var errUnexpected = errors.New("unexpected write count")
 
+// MaxMessageSize is the maximum size of a message read by
+// ReadProtoMessageBytes so that a size prefix can't make it
+// allocate more memory than any note needs.
+const MaxMessageSize = 16 << 20
+
+var (
+	// ErrMissingNote is an error when a message doesn't have a note.
+	ErrMissingNote = errors.New("protoutil: missing note")
+	// ErrInvalidID is an error when the note id is not a valid uuid.
+	ErrInvalidID = errors.New("protoutil: invalid note id")
+	// ErrInvalidMask is an error when the update mask has an
+	// unknown field.
+	ErrInvalidMask = errors.New("protoutil: invalid update mask")
+	// ErrMessageTooLarge is an error when the size prefix of
+	// a message is over MaxMessageSize.
+	ErrMessageTooLarge = errors.New("protoutil: message too large")
+)
+
 // WriteProtoMessage marshals the m protocol buffer then writes to
 // w writer. It prepend a 4-byte size prior to protobuf binary.
 func WriteProtoMessage(w io.Writer, message proto.Message) error {
@@ -67,6 +86,19 @@ func WriteAllProtoMessages(w io.Writer, messages ...proto.Message) error {
 // It un-marshals the content into a note protobuf message then
 // returns the note. If there's an error it could be an io.EOF error.
 func ReadProtoMessage(r io.Reader) (*note.Note, error) {
+	msg, err := ReadProtoMessageBytes(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return UnmarshalNote(msg)
+}
+
+// ReadProtoMessageBytes reads the next 4-byte size prefixed protobuf
+// binary from r without un-marshaling it. If there's an error it could
+// be an io.EOF error, or ErrMessageTooLarge when the size is over
+// MaxMessageSize.
+func ReadProtoMessageBytes(r io.Reader) ([]byte, error) {
 	msgLen := make([]byte, 4)
 	_, err := io.ReadFull(r, msgLen)
 	if err != nil {
@@ -74,6 +106,9 @@ func ReadProtoMessage(r io.Reader) (*note.Note, error) {
 	}
 
 	size := binary.LittleEndian.Uint32(msgLen)
+	if size > MaxMessageSize {
+		return nil, fmt.Errorf("protoutil: message of %d bytes: %w", size, ErrMessageTooLarge)
+	}
 	gotSize := int(size)
 
 	msg := make([]byte, gotSize)
@@ -82,8 +117,14 @@ func ReadProtoMessage(r io.Reader) (*note.Note, error) {
 		return nil, err
 	}
 
+	return msg, nil
+}
+
+// UnmarshalNote un-marshals the note protobuf binary msg
+// into a note.
+func UnmarshalNote(msg []byte) (*note.Note, error) {
 	var got pb.Note
-	err = proto.Unmarshal(msg, &got)
+	err := proto.Unmarshal(msg, &got)
 	if err != nil {
 		return nil, err
 	}
@@ -124,7 +165,8 @@ func ProtoToNote(p *pb.Note) (*note.Note, error) {
 		SetContent(p.Content).
 		SetCreatedTime(p.CreatedTime.AsTime()).
 		SetUpdatedTime(p.UpdatedTime.AsTime()).
-		SetIsFavorite(p.IsFavorite)
+		SetIsFavorite(p.IsFavorite).
+		SetOwnerID(p.OwnerId)
 	return n, nil
 }
 
@@ -137,6 +179,7 @@ func NoteToProto(n *note.Note) *pb.Note {
 		CreatedTime: timestamppb.New(n.GetCreatedTime()),
 		UpdatedTime: timestamppb.New(n.GetUpdatedTime()),
 		IsFavorite:  n.GetIsFavorite(),
+		OwnerId:     n.OwnerID,
 	}
 }
 
@@ -158,3 +201,62 @@ func ConvertToProtoMessage(pbs []*pb.Note) []proto.Message {
 	}
 	return messages
 }
+
+// DecodeNote converts the note message into a note with only the
+// fields in the paths. Empty paths will take all the fields. The
+// timestamps are owned by the service and are never taken.
+func DecodeNote(p *pb.Note, paths []string) (*note.Note, error) {
+	if p == nil {
+		return nil, ErrMissingNote
+	}
+
+	n := new(note.Note)
+	if len(p.GetId()) > 0 {
+		id, err := uuid.ParseBytes(p.GetId())
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidID, err)
+		}
+		n.SetID(id)
+	}
+
+	if len(paths) == 0 {
+		paths = []string{"title", "content", "is_favorite"}
+	}
+
+	for _, path := range paths {
+		switch path {
+		case "title":
+			n.SetTitle(p.GetTitle())
+		case "content":
+			n.SetContent(p.GetContent())
+		case "is_favorite":
+			n.SetIsFavorite(p.GetIsFavorite())
+		default:
+			return nil, fmt.Errorf("%w: unknown field '%s'", ErrInvalidMask, path)
+		}
+	}
+
+	return n, nil
+}
+
+// EncodeNote converts n into a note message. Unlike the
+// NoteToProto the missing timestamps are left unset.
+func EncodeNote(n *note.Note) *pb.Note {
+	p := &pb.Note{
+		Id:         []byte(n.ID.String()),
+		Title:      n.GetTitle(),
+		Content:    n.GetContent(),
+		IsFavorite: n.GetIsFavorite(),
+		OwnerId:    n.OwnerID,
+	}
+
+	if n.CreatedTime != nil {
+		p.CreatedTime = timestamppb.New(n.GetCreatedTime())
+	}
+
+	if n.UpdatedTime != nil {
+		p.UpdatedTime = timestamppb.New(n.GetUpdatedTime())
+	}
+
+	return p
+}
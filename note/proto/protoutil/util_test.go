@@ -94,6 +94,14 @@ func TestReadProtoMessage(t *testing.T) {
 	assert.Equal(t, want, got)
 }
 
+func TestReadProtoMessageTooLarge(t *testing.T) {
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, MaxMessageSize+1)
+
+	_, err := ReadProtoMessageBytes(bytes.NewReader(size))
+	assert.ErrorIs(t, err, ErrMessageTooLarge)
+}
+
 func getMessage(t *testing.T, buff *bytes.Buffer) (int, *pb.Note) {
 	msgLen := make([]byte, 4)
 	_, err := io.ReadFull(buff, msgLen)
@@ -0,0 +1,28 @@
+package note
+
+import "errors"
+
+// ErrQuotaExceeded is an error when an operation would take the
+// notes of an owner over its storage quota.
+var ErrQuotaExceeded = errors.New("note: quota exceeded")
+
+// Usage is the storage used by the notes of an owner.
+type Usage struct {
+	// Notes is the number of notes of the owner.
+	Notes int
+	// Bytes is the total size of the notes of the owner.
+	Bytes int64
+}
+
+// Size returns the bytes of the title and the content of n, which
+// count towards the storage quota of its owner.
+func (n *Note) Size() int64 {
+	var size int64
+	if n.Title != nil {
+		size += int64(len(*n.Title))
+	}
+	if n.Content != nil {
+		size += int64(len(*n.Content))
+	}
+	return size
+}
@@ -21,4 +21,7 @@ type Service interface {
 	// Fetch fetches notes from the store using the pagination setting.
 	// It returns an iterator of the note results.
 	Fetch(ctx context.Context, pagination *Pagination) (Iterator, error)
+	// Export returns an iterator of all the notes from a consistent
+	// snapshot of the store.
+	Export(ctx context.Context) (Iterator, error)
 }
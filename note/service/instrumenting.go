@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kit/kit/metrics"
+	"github.com/google/uuid"
+	"noteapp/note"
+	"time"
+)
+
+// InstrumentingMiddleware counts the calls of the service and
+// observes their latency in seconds. Both are labeled with the
+// "method" and whether it returned an "error".
+func InstrumentingMiddleware(requestCount metrics.Counter, requestLatency metrics.Histogram) Middleware {
+	return func(next note.Service) note.Service {
+		return &instrumentingMiddleware{
+			next:           next,
+			requestCount:   requestCount,
+			requestLatency: requestLatency,
+		}
+	}
+}
+
+type instrumentingMiddleware struct {
+	next           note.Service
+	requestCount   metrics.Counter
+	requestLatency metrics.Histogram
+}
+
+func (mw *instrumentingMiddleware) observe(method string, begin time.Time, err error) {
+	labels := []string{"method", method, "error", fmt.Sprint(err != nil)}
+	mw.requestCount.With(labels...).Add(1)
+	mw.requestLatency.With(labels...).Observe(time.Since(begin).Seconds())
+}
+
+func (mw *instrumentingMiddleware) Create(ctx context.Context, n *note.Note) (created *note.Note, err error) {
+	defer func(begin time.Time) { mw.observe("Create", begin, err) }(time.Now())
+	return mw.next.Create(ctx, n)
+}
+
+func (mw *instrumentingMiddleware) Update(ctx context.Context, n *note.Note) (updated *note.Note, err error) {
+	defer func(begin time.Time) { mw.observe("Update", begin, err) }(time.Now())
+	return mw.next.Update(ctx, n)
+}
+
+func (mw *instrumentingMiddleware) Delete(ctx context.Context, id uuid.UUID) (err error) {
+	defer func(begin time.Time) { mw.observe("Delete", begin, err) }(time.Now())
+	return mw.next.Delete(ctx, id)
+}
+
+func (mw *instrumentingMiddleware) Get(ctx context.Context, id uuid.UUID) (n *note.Note, err error) {
+	defer func(begin time.Time) { mw.observe("Get", begin, err) }(time.Now())
+	return mw.next.Get(ctx, id)
+}
+
+func (mw *instrumentingMiddleware) Fetch(ctx context.Context, pagination *note.Pagination) (iter note.Iterator, err error) {
+	defer func(begin time.Time) { mw.observe("Fetch", begin, err) }(time.Now())
+	return mw.next.Fetch(ctx, pagination)
+}
+
+func (mw *instrumentingMiddleware) Export(ctx context.Context) (iter note.Iterator, err error) {
+	defer func(begin time.Time) { mw.observe("Export", begin, err) }(time.Now())
+	return mw.next.Export(ctx)
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"noteapp/note"
+	"noteapp/note/noteutil"
+)
+
+var _ note.Iterator = (*sliceIterator)(nil)
+
+// sliceIterator is a page of notes loaded into a slice.
+type sliceIterator struct {
+	notes      []*note.Note
+	current    *note.Note
+	totalCount int
+	totalPage  int
+}
+
+// TotalPage implements the note.Iterator
+func (i *sliceIterator) TotalPage() uint64 {
+	return uint64(i.totalPage)
+}
+
+// Close implements the note.Iterator
+func (i *sliceIterator) Close() error {
+	i.notes = nil
+	return nil
+}
+
+// Next implements note.Iterator
+func (i *sliceIterator) Next() bool {
+	if len(i.notes) == 0 {
+		i.current = nil
+		return false
+	}
+
+	i.current, i.notes = i.notes[0], i.notes[1:]
+	return true
+}
+
+// Error implements the note.Iterator
+func (i *sliceIterator) Error() error {
+	return nil
+}
+
+func (i *sliceIterator) Note() *note.Note {
+	// The caller may mutate the note, so hand out a copy.
+	return noteutil.Copy(i.current)
+}
+
+func (i *sliceIterator) TotalCount() uint64 {
+	return uint64(i.totalCount)
+}
@@ -0,0 +1,44 @@
+package service
+
+import "sync"
+
+// ownerLocks are mutexes keyed by the owner id. A mutex only lives
+// while it is held or waited for, so the owners don't accumulate.
+type ownerLocks struct {
+	mu    sync.Mutex
+	locks map[string]*ownerLock
+}
+
+type ownerLock struct {
+	sync.Mutex
+	// refs is the number of callers holding or waiting for
+	// the lock. It is guarded by the ownerLocks mutex.
+	refs int
+}
+
+// lock locks the mutex of the owner and returns its unlock function.
+func (l *ownerLocks) lock(ownerID string) (unlock func()) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*ownerLock)
+	}
+
+	ol, ok := l.locks[ownerID]
+	if !ok {
+		ol = new(ownerLock)
+		l.locks[ownerID] = ol
+	}
+	ol.refs++
+	l.mu.Unlock()
+
+	ol.Lock()
+	return func() {
+		ol.Unlock()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if ol.refs--; ol.refs == 0 {
+			delete(l.locks, ownerID)
+		}
+	}
+}
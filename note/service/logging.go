@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"noteapp/note"
+	"noteapp/pkg/logging"
+	"time"
+)
+
+// LoggingMiddleware logs every call of the service with its
+// duration and error in debug level. The calls are logged with
+// the logger of their context when it has one, and the id of the
+// note is added to that logger for the next log lines of the call.
+func LoggingMiddleware(logger logrus.FieldLogger) Middleware {
+	return func(next note.Service) note.Service {
+		return &loggingMiddleware{next: next, logger: logger}
+	}
+}
+
+type loggingMiddleware struct {
+	next   note.Service
+	logger logrus.FieldLogger
+}
+
+func (mw *loggingMiddleware) log(ctx context.Context, method string, id uuid.UUID, begin time.Time, err error) {
+	logger := mw.logger
+	if l, ok := logging.FromContext(ctx); ok {
+		logger = l
+	}
+
+	fields := logrus.Fields{"method": method, "took": time.Since(begin)}
+	if id != uuid.Nil {
+		fields["note_id"] = id
+	}
+	if err != nil {
+		fields["err"] = err
+	}
+	logger.WithFields(fields).Debug("note service call")
+}
+
+// withNoteID adds the note id to the logger of ctx.
+func withNoteID(ctx context.Context, id uuid.UUID) {
+	if id != uuid.Nil {
+		logging.AddFields(ctx, logrus.Fields{"note_id": id})
+	}
+}
+
+func (mw *loggingMiddleware) Create(ctx context.Context, n *note.Note) (created *note.Note, err error) {
+	defer func(begin time.Time) {
+		var id uuid.UUID
+		if created != nil {
+			id = created.ID
+		}
+		withNoteID(ctx, id)
+		mw.log(ctx, "Create", id, begin, err)
+	}(time.Now())
+	return mw.next.Create(ctx, n)
+}
+
+func (mw *loggingMiddleware) Update(ctx context.Context, n *note.Note) (updated *note.Note, err error) {
+	defer func(begin time.Time) {
+		var id uuid.UUID
+		if n != nil {
+			id = n.ID
+		}
+		mw.log(ctx, "Update", id, begin, err)
+	}(time.Now())
+	if n != nil {
+		withNoteID(ctx, n.ID)
+	}
+	return mw.next.Update(ctx, n)
+}
+
+func (mw *loggingMiddleware) Delete(ctx context.Context, id uuid.UUID) (err error) {
+	defer func(begin time.Time) {
+		mw.log(ctx, "Delete", id, begin, err)
+	}(time.Now())
+	withNoteID(ctx, id)
+	return mw.next.Delete(ctx, id)
+}
+
+func (mw *loggingMiddleware) Get(ctx context.Context, id uuid.UUID) (n *note.Note, err error) {
+	defer func(begin time.Time) {
+		mw.log(ctx, "Get", id, begin, err)
+	}(time.Now())
+	withNoteID(ctx, id)
+	return mw.next.Get(ctx, id)
+}
+
+func (mw *loggingMiddleware) Fetch(ctx context.Context, pagination *note.Pagination) (iter note.Iterator, err error) {
+	defer func(begin time.Time) {
+		mw.log(ctx, "Fetch", uuid.Nil, begin, err)
+	}(time.Now())
+	return mw.next.Fetch(ctx, pagination)
+}
+
+func (mw *loggingMiddleware) Export(ctx context.Context) (iter note.Iterator, err error) {
+	defer func(begin time.Time) {
+		mw.log(ctx, "Export", uuid.Nil, begin, err)
+	}(time.Now())
+	return mw.next.Export(ctx)
+}
@@ -0,0 +1,18 @@
+package service
+
+import "noteapp/note"
+
+// Middleware is a chainable decorator of a note.Service.
+type Middleware func(note.Service) note.Service
+
+// Chain is a helper function for composing middlewares. Requests will
+// traverse them in the order they're declared. That is, the first
+// middleware is treated as the outermost middleware.
+func Chain(outer Middleware, others ...Middleware) Middleware {
+	return func(next note.Service) note.Service {
+		for i := len(others) - 1; i >= 0; i-- {
+			next = others[i](next)
+		}
+		return outer(next)
+	}
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/go-kit/kit/metrics"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"noteapp/note"
+	"noteapp/note/noteutil"
+	"noteapp/note/store/memory"
+	storetracing "noteapp/note/store/tracing"
+	"noteapp/note/validation"
+	"noteapp/pkg/logging"
+	"strings"
+	"sync"
+)
+
+type recordedEvent struct {
+	Type note.EventType
+	Note *note.Note
+}
+
+type publisherRecorder struct {
+	events []recordedEvent
+}
+
+func (p *publisherRecorder) Publish(_ context.Context, t note.EventType, n *note.Note) {
+	p.events = append(p.events, recordedEvent{Type: t, Note: noteutil.Copy(n)})
+}
+
+// metricsRecorder records the label values of the observations
+// of both the counter and the histogram.
+type metricsRecorder struct {
+	mu     sync.Mutex
+	labels []string
+}
+
+type recordedMetric struct {
+	recorder *metricsRecorder
+	lvs      []string
+}
+
+func (m recordedMetric) With(labelValues ...string) metrics.Counter {
+	return recordedMetric{recorder: m.recorder, lvs: append(append([]string(nil), m.lvs...), labelValues...)}
+}
+
+func (m recordedMetric) Add(float64) {
+	m.recorder.mu.Lock()
+	defer m.recorder.mu.Unlock()
+	m.recorder.labels = append(m.recorder.labels, "count:"+strings.Join(m.lvs, ","))
+}
+
+type recordedHistogram struct {
+	recordedMetric
+}
+
+func (h recordedHistogram) With(labelValues ...string) metrics.Histogram {
+	return recordedHistogram{h.recordedMetric.With(labelValues...).(recordedMetric)}
+}
+
+func (h recordedHistogram) Observe(float64) {
+	h.recorder.mu.Lock()
+	defer h.recorder.mu.Unlock()
+	h.recorder.labels = append(h.recorder.labels, "latency:"+strings.Join(h.lvs, ","))
+}
+
+func newNote() *note.Note {
+	n := noteutil.Copy(dummyNote)
+	n.ID = uuid.Nil
+	return n
+}
+
+func (s *TestSuite) TestChain() {
+	var calls []string
+	tracing := func(name string) Middleware {
+		return func(next note.Service) note.Service {
+			calls = append(calls, name)
+			return next
+		}
+	}
+
+	Chain(tracing("first"), tracing("second"), tracing("third"))(New(memory.New()))
+	// The innermost middleware wraps the service first.
+	s.Equal([]string{"third", "second", "first"}, calls)
+}
+
+func (s *TestSuite) TestLoggingMiddleware() {
+	logger, hook := logtest.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	svc := LoggingMiddleware(logger)(New(memory.New()))
+
+	created, err := svc.Create(dummyCtx, newNote())
+	s.Require().NoError(err)
+
+	entry := hook.LastEntry()
+	s.Require().NotNil(entry)
+	s.Equal(logrus.DebugLevel, entry.Level)
+	s.Equal("Create", entry.Data["method"])
+	s.Equal(created.ID, entry.Data["note_id"])
+	s.Contains(entry.Data, "took")
+	s.NotContains(entry.Data, "err")
+
+	id := uuid.New()
+	_, err = svc.Get(dummyCtx, id)
+	s.Require().Error(err)
+
+	entry = hook.LastEntry()
+	s.Equal("Get", entry.Data["method"])
+	s.Equal(id, entry.Data["note_id"])
+	s.Equal(err, entry.Data["err"])
+
+	s.Run("Logger of the context", func() {
+		ctxLogger, ctxHook := logtest.NewNullLogger()
+		ctxLogger.SetLevel(logrus.DebugLevel)
+		ctx := logging.NewContext(dummyCtx, ctxLogger.WithField("request_id", "request-1"))
+
+		_, err := svc.Get(ctx, created.ID)
+		s.Require().NoError(err)
+
+		entry := ctxHook.LastEntry()
+		s.Require().NotNil(entry)
+		s.Equal("request-1", entry.Data["request_id"])
+		s.Equal("Get", entry.Data["method"])
+
+		// The next lines of the request have the note id.
+		logging.Logger(ctx).Info("done")
+		s.Equal(created.ID, ctxHook.LastEntry().Data["note_id"])
+	})
+}
+
+func (s *TestSuite) TestInstrumentingMiddleware() {
+	recorder := new(metricsRecorder)
+	svc := InstrumentingMiddleware(
+		recordedMetric{recorder: recorder},
+		recordedHistogram{recordedMetric{recorder: recorder}},
+	)(New(memory.New()))
+
+	_, err := svc.Create(dummyCtx, newNote())
+	s.Require().NoError(err)
+	_, err = svc.Get(dummyCtx, uuid.New())
+	s.Require().Error(err)
+
+	s.Equal([]string{
+		"count:method,Create,error,false",
+		"latency:method,Create,error,false",
+		"count:method,Get,error,true",
+		"latency:method,Get,error,true",
+	}, recorder.labels)
+}
+
+func (s *TestSuite) TestTracingMiddleware() {
+	exporter := tracetest.NewInMemoryExporter()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)).Tracer("test")
+	svc := TracingMiddleware(tracer)(New(storetracing.New(memory.New(), tracer)))
+
+	created, err := svc.Create(dummyCtx, newNote())
+	s.Require().NoError(err)
+
+	spans := exporter.GetSpans()
+	s.Require().Len(spans, 2)
+	storeSpan, serviceSpan := spans[0], spans[1]
+	s.Equal("note.Store/Insert", storeSpan.Name)
+	s.Equal("note.Service/Create", serviceSpan.Name)
+	s.Equal(serviceSpan.SpanContext.SpanID(), storeSpan.Parent.SpanID())
+	s.Contains(serviceSpan.Attributes, noteIDAttribute.String(created.ID.String()))
+
+	exporter.Reset()
+	_, err = svc.Get(dummyCtx, uuid.New())
+	s.Require().Error(err)
+
+	spans = exporter.GetSpans()
+	s.Require().Len(spans, 2)
+	s.Equal("note.Service/Get", spans[1].Name)
+	s.Equal(codes.Error, spans[1].Status.Code)
+}
+
+func (s *TestSuite) TestValidatingMiddleware() {
+	v, err := validation.New(validation.Rules{RequireTitle: true, MaxTitleLength: 5})
+	s.Require().NoError(err)
+	svc := ValidatingMiddleware(v)(New(memory.New()))
+
+	_, err = svc.Create(dummyCtx, new(note.Note).SetTitle("Too long"))
+	var validationErr *note.ValidationError
+	s.Require().ErrorAs(err, &validationErr)
+	s.Equal([]note.FieldError{{Field: "title", Message: "must be at most 5 characters"}}, validationErr.Fields)
+
+	created, err := svc.Create(dummyCtx, new(note.Note).SetTitle("Title"))
+	s.Require().NoError(err)
+
+	_, err = svc.Update(dummyCtx, new(note.Note).SetID(created.ID).SetTitle(""))
+	s.Require().ErrorAs(err, &validationErr)
+	s.Equal([]note.FieldError{{Field: "title", Message: "must not be empty"}}, validationErr.Fields)
+
+	_, err = svc.Update(dummyCtx, new(note.Note).SetID(created.ID).SetContent("Without changing the title"))
+	s.NoError(err)
+
+	_, err = svc.Create(dummyCtx, nil)
+	s.ErrorIs(err, note.ErrNilNote)
+
+	_, err = svc.Update(dummyCtx, nil)
+	s.ErrorIs(err, note.ErrNilNote)
+
+	_, err = svc.Update(dummyCtx, newNote())
+	s.ErrorIs(err, note.ErrNilID)
+
+	_, err = svc.Get(dummyCtx, uuid.Nil)
+	s.ErrorIs(err, note.ErrNilID)
+
+	s.ErrorIs(svc.Delete(dummyCtx, uuid.Nil), note.ErrNilID)
+
+	iter, err := svc.Fetch(dummyCtx, nil)
+	s.Require().NoError(err)
+	s.NoError(iter.Close())
+}
+
+func (s *TestSuite) TestEventPublishingMiddleware() {
+	s.Run("Changes should be published after they are stored", func() {
+		publisher := new(publisherRecorder)
+		svc := EventPublishingMiddleware(publisher)(New(memory.New()))
+
+		created, err := svc.Create(dummyCtx, newNote())
+		s.Require().NoError(err)
+
+		updated, err := svc.Update(dummyCtx, noteutil.Copy(created).SetTitle("Updated"))
+		s.Require().NoError(err)
+
+		s.Require().NoError(svc.Delete(dummyCtx, created.ID))
+
+		s.Equal([]recordedEvent{
+			{Type: note.EventCreated, Note: created},
+			{Type: note.EventUpdated, Note: updated},
+			{Type: note.EventDeleted, Note: updated},
+		}, publisher.events)
+	})
+
+	s.Run("Failed changes should not be published", func() {
+		publisher := new(publisherRecorder)
+		svc := EventPublishingMiddleware(publisher)(New(memory.New()))
+
+		_, err := svc.Update(dummyCtx, noteutil.Copy(dummyNote))
+		s.Error(err)
+		s.NoError(svc.Delete(dummyCtx, dummyNote.ID))
+
+		ctx, cancel := context.WithCancel(dummyCtx)
+		cancel()
+		_, err = svc.Create(ctx, newNote())
+		s.True(errors.Is(err, context.Canceled))
+
+		s.Empty(publisher.events)
+	})
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"noteapp/note"
+)
+
+// EventPublishingMiddleware publishes the changes made to the notes
+// to p after the service has made them.
+func EventPublishingMiddleware(p note.Publisher) Middleware {
+	return func(next note.Service) note.Service {
+		return &eventPublishingMiddleware{next: next, publisher: p}
+	}
+}
+
+type eventPublishingMiddleware struct {
+	next      note.Service
+	publisher note.Publisher
+}
+
+func (mw *eventPublishingMiddleware) Create(ctx context.Context, n *note.Note) (*note.Note, error) {
+	created, err := mw.next.Create(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+
+	mw.publisher.Publish(ctx, note.EventCreated, created)
+	return created, nil
+}
+
+func (mw *eventPublishingMiddleware) Update(ctx context.Context, n *note.Note) (*note.Note, error) {
+	updated, err := mw.next.Update(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+
+	mw.publisher.Publish(ctx, note.EventUpdated, updated)
+	return updated, nil
+}
+
+// Delete publishes the note as it was before the deletion. Deleting
+// a note that doesn't exist doesn't publish anything.
+func (mw *eventPublishingMiddleware) Delete(ctx context.Context, id uuid.UUID) error {
+	n, err := mw.next.Get(ctx, id)
+	if errors.Is(err, note.ErrNotFound) {
+		return mw.next.Delete(ctx, id)
+	} else if err != nil {
+		return err
+	}
+
+	if err := mw.next.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	mw.publisher.Publish(ctx, note.EventDeleted, n)
+	return nil
+}
+
+func (mw *eventPublishingMiddleware) Get(ctx context.Context, id uuid.UUID) (*note.Note, error) {
+	return mw.next.Get(ctx, id)
+}
+
+func (mw *eventPublishingMiddleware) Fetch(ctx context.Context, pagination *note.Pagination) (note.Iterator, error) {
+	return mw.next.Fetch(ctx, pagination)
+}
+
+func (mw *eventPublishingMiddleware) Export(ctx context.Context) (note.Iterator, error) {
+	return mw.next.Export(ctx)
+}
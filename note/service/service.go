@@ -4,7 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/google/uuid"
-	"github.com/sirupsen/logrus"
+	"math"
 	"noteapp/note"
 	"noteapp/note/noteutil"
 	"noteapp/pkg/timestamp"
@@ -13,20 +13,129 @@ import (
 var _ note.Service = (*Service)(nil)
 
 // Service implements note.Service interface.
+//
+// When the context has an owner, see note.WithOwner, the service only
+// works on the notes of the owner and the notes shared with it. The
+// other notes are reported as not found so that their existence isn't
+// revealed. The only exception is creating a note with an id already
+// taken, which returns the same note.ErrExists whichever owner has the
+// note so that only the use of the random id is revealed. The
+// operations the role of a share grant doesn't allow return
+// note.ErrPermissionDenied.
 type Service struct {
-	store note.Store
+	store  note.Store
+	grants note.Grants
+	quota  Quota
+	// quotaLocks serializes the writes of each owner checked against
+	// the quota so that concurrent writes can't go over it together.
+	quotaLocks ownerLocks
+}
+
+// Quota is the storage quota of each owner. A zero limit is unlimited.
+type Quota struct {
+	// MaxNotes is the maximum number of notes of an owner.
+	MaxNotes int
+	// MaxBytes is the maximum total size of the titles and the
+	// contents of the notes of an owner.
+	MaxBytes int64
+}
+
+// enabled reports whether q limits anything.
+func (q Quota) enabled() bool {
+	return q.MaxNotes > 0 || q.MaxBytes > 0
+}
+
+// Option is an optional setting of the service.
+type Option func(s *Service)
+
+// WithGrants sets the share grants of the notes. The notes are
+// not shared without it.
+func WithGrants(grants note.Grants) Option {
+	return func(s *Service) {
+		s.grants = grants
+	}
+}
+
+// WithQuota sets the storage quota of the owners. The creates and
+// the updates going over it return note.ErrQuotaExceeded. The quota
+// is the one of the owner of the note whoever writes it, only the
+// notes without owner are not limited.
+func WithQuota(q Quota) Option {
+	return func(s *Service) {
+		s.quota = q
+	}
 }
 
 // Fetch fetches notes from the store using the pagination setting.
 // It returns an iterator of the note results.
 func (s *Service) Fetch(ctx context.Context, pagination *note.Pagination) (note.Iterator, error) {
 	pagination.Check()
+	if pagination.SharedWithMe {
+		return s.fetchShared(ctx, pagination)
+	}
+
+	pagination.OwnerID, _ = note.OwnerFromContext(ctx)
 	return s.store.Fetch(ctx, pagination)
 }
 
+// fetchShared returns a page of the notes shared with the owner in
+// ctx. The grants are paginated by note id before the notes are loaded
+// so that only the notes of the page are read, whatever the sort of
+// the pagination. The notes deleted since the page was listed are
+// skipped.
+func (s *Service) fetchShared(ctx context.Context, pagination *note.Pagination) (note.Iterator, error) {
+	ownerID, ok := note.OwnerFromContext(ctx)
+	if !ok || s.grants == nil {
+		return &sliceIterator{}, nil
+	}
+
+	ids := s.grants.SharedWith(ownerID)
+	iter := &sliceIterator{totalCount: len(ids), totalPage: len(ids) / int(pagination.Size)}
+
+	offset := (pagination.Page - 1) * pagination.Size
+	if offset >= uint64(len(ids)) {
+		return iter, nil
+	}
+
+	end := offset + pagination.Size
+	if end > uint64(len(ids)) {
+		end = uint64(len(ids))
+	}
+
+	for _, id := range ids[offset:end] {
+		n, err := s.store.Get(ctx, id)
+		if err == note.ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		iter.notes = append(iter.notes, n)
+	}
+	return iter, nil
+}
+
+// Export returns an iterator of all the notes from a consistent
+// snapshot of the store.
+func (s *Service) Export(ctx context.Context) (note.Iterator, error) {
+	if ownerID, ok := note.OwnerFromContext(ctx); ok {
+		return s.store.Fetch(ctx, &note.Pagination{
+			Size:    math.MaxInt32,
+			Page:    1,
+			SortBy:  note.SortByID,
+			Ascend:  true,
+			OwnerID: ownerID,
+		})
+	}
+	return s.store.Export(ctx)
+}
+
 // New takes store and returns a service instance.
-func New(store note.Store) *Service {
-	return &Service{store: store}
+func New(store note.Store, opts ...Option) *Service {
+	s := &Service{store: store}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // Create creates a new note n with optional value in ID field.
@@ -39,19 +148,29 @@ func (s *Service) Create(ctx context.Context, n *note.Note) (*note.Note, error)
 			return nil, err
 		}
 
+		// The ids are shared by all the owners, so the same error
+		// is returned whoever owns the note taking the id.
 		if isExists {
-			errMessageFormat := "service: unable to create a note with id '%s' because it exists: %w"
-			return nil, fmt.Errorf(errMessageFormat, n.ID, note.ErrExists)
+			return nil, fmt.Errorf("service: unable to create a note with id '%s': %w", n.ID, note.ErrExists)
 		}
 	} else {
 		n.ID = uuid.New()
 	}
 
 	n.CreatedTime = timestamp.GenerateTimestamp()
+	if ownerID, ok := note.OwnerFromContext(ctx); ok {
+		n.OwnerID = ownerID
+	}
 
-	err := s.store.Insert(ctx, n)
+	if s.quota.enabled() && n.OwnerID != "" {
+		defer s.quotaLocks.lock(n.OwnerID)()
 
-	logrus.Debug(err)
+		if err := s.checkQuota(ctx, n.OwnerID, 1, n.Size()); err != nil {
+			return nil, err
+		}
+	}
+
+	err := s.store.Insert(ctx, n)
 	if err != nil {
 		return nil, err
 	}
@@ -70,17 +189,38 @@ func (s *Service) Update(ctx context.Context, n *note.Note) (*note.Note, error)
 	}
 
 	// Check first if the note is exists
-	isExists, err := s.checkNoteIfExists(ctx, cpyNote.ID)
-	if err != nil {
+	existingNote, role, err := s.get(ctx, cpyNote.ID)
+	if err == note.ErrNotFound {
+		return nil, fmt.Errorf("service/update: note '%s' not found: %w", cpyNote.ID, note.ErrNotFound)
+	} else if err != nil {
 		return nil, err
 	}
 
-	if !isExists {
-		return nil, fmt.Errorf("service/update: note '%s' not found: %w", cpyNote.ID, note.ErrNotFound)
+	if !role.CanWrite() {
+		return nil, fmt.Errorf("service/update: note '%s' is read-only for the %s: %w", cpyNote.ID, role, note.ErrPermissionDenied)
 	}
 
+	cpyNote.OwnerID = existingNote.OwnerID
 	cpyNote.UpdatedTime = timestamp.GenerateTimestamp()
 
+	// The quota is the one of the owner of the note, even when
+	// it is updated by a user it is shared with.
+	if s.quota.enabled() && cpyNote.OwnerID != "" {
+		defer s.quotaLocks.lock(cpyNote.OwnerID)()
+
+		merged := noteutil.Copy(existingNote)
+		if cpyNote.Title != nil {
+			merged.Title = cpyNote.Title
+		}
+		if cpyNote.Content != nil {
+			merged.Content = cpyNote.Content
+		}
+
+		if err := s.checkQuota(ctx, cpyNote.OwnerID, 0, merged.Size()-existingNote.Size()); err != nil {
+			return nil, err
+		}
+	}
+
 	updatedNote, err := s.store.Update(ctx, cpyNote)
 	if err != nil {
 		return nil, err
@@ -89,9 +229,31 @@ func (s *Service) Update(ctx context.Context, n *note.Note) (*note.Note, error)
 	return updatedNote, nil
 }
 
+// checkQuota returns note.ErrQuotaExceeded when adding notes and
+// bytes to the notes of the owner would take them over the quota.
+func (s *Service) checkQuota(ctx context.Context, ownerID string, notes int, bytes int64) error {
+	if notes <= 0 && bytes <= 0 {
+		return nil
+	}
+
+	usage, err := s.store.Usage(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+	count, size := usage.Notes, usage.Bytes
+
+	if s.quota.MaxNotes > 0 && count+notes > s.quota.MaxNotes {
+		return fmt.Errorf("service: owner '%s' has %d of %d notes: %w", ownerID, count, s.quota.MaxNotes, note.ErrQuotaExceeded)
+	}
+
+	if s.quota.MaxBytes > 0 && size+bytes > s.quota.MaxBytes {
+		return fmt.Errorf("service: owner '%s' would use %d of %d bytes: %w", ownerID, size+bytes, s.quota.MaxBytes, note.ErrQuotaExceeded)
+	}
+	return nil
+}
+
 func (s *Service) checkNoteIfExists(ctx context.Context, id uuid.UUID) (bool, error) {
 	existingNote, err := s.store.Get(ctx, id)
-	logrus.Debug("checking note:", existingNote, err)
 	if err == nil && existingNote != nil {
 		return true, nil
 	} else if err == note.ErrNotFound {
@@ -105,6 +267,17 @@ func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
 	if id == uuid.Nil {
 		return note.ErrNilID
 	}
+
+	if _, ok := note.OwnerFromContext(ctx); ok {
+		_, role, err := s.get(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if !role.CanManage() {
+			return fmt.Errorf("service/delete: note '%s' can't be deleted by the %s: %w", id, role, note.ErrPermissionDenied)
+		}
+	}
 	return s.store.Delete(ctx, id)
 }
 
@@ -115,7 +288,7 @@ func (s *Service) Get(ctx context.Context, id uuid.UUID) (*note.Note, error) {
 		return nil, note.ErrNilID
 	}
 
-	n, err := s.store.Get(ctx, id)
+	n, _, err := s.get(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -123,3 +296,19 @@ func (s *Service) Get(ctx context.Context, id uuid.UUID) (*note.Note, error) {
 	return n, nil
 
 }
+
+// get gets the note with an id from the store along with the role of
+// the owner in ctx on it. It returns note.ErrNotFound when the note
+// belongs to another owner and it isn't shared with the one in ctx.
+func (s *Service) get(ctx context.Context, id uuid.UUID) (*note.Note, note.Role, error) {
+	n, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	role := note.RoleOf(ctx, n, s.grants)
+	if !role.CanRead() {
+		return nil, "", note.ErrNotFound
+	}
+	return n, role, nil
+}
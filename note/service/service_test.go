@@ -13,6 +13,7 @@ import (
 	"noteapp/pkg/timestamp"
 	"noteapp/pkg/util/errorutil"
 	"sort"
+	"sync"
 	"testing"
 )
 
@@ -246,3 +247,154 @@ func (s *TestSuite) TestFetch() {
 		s.Len(got, 25)
 	})
 }
+
+func (s *TestSuite) TestOwnerIsolation() {
+	aliceCtx := note.WithOwner(dummyCtx, "alice")
+	bobCtx := note.WithOwner(dummyCtx, "bob")
+
+	aliceNote, err := s.svc.Create(aliceCtx, noteFactory(0).SetID(uuid.Nil))
+	s.Require().NoError(err)
+	s.Equal("alice", aliceNote.OwnerID)
+
+	bobNote, err := s.svc.Create(bobCtx, noteFactory(1).SetID(uuid.Nil).SetOwnerID("alice"))
+	s.Require().NoError(err)
+	s.Equal("bob", bobNote.OwnerID)
+
+	s.Run("Getting another owner's note should return not found", func() {
+		_, err := s.svc.Get(bobCtx, aliceNote.ID)
+		s.ErrorIs(err, note.ErrNotFound)
+
+		got, err := s.svc.Get(aliceCtx, aliceNote.ID)
+		s.Require().NoError(err)
+		s.Equal(aliceNote.ID, got.ID)
+	})
+
+	s.Run("Updating another owner's note should return not found", func() {
+		_, err := s.svc.Update(bobCtx, noteutil.Copy(aliceNote).SetTitle("Taken"))
+		s.ErrorIs(err, note.ErrNotFound)
+
+		got, err := s.svc.Update(aliceCtx, noteutil.Copy(aliceNote).SetTitle("Mine").SetOwnerID("bob"))
+		s.Require().NoError(err)
+		s.Equal("alice", got.OwnerID)
+	})
+
+	s.Run("Deleting another owner's note should return not found", func() {
+		s.ErrorIs(s.svc.Delete(bobCtx, aliceNote.ID), note.ErrNotFound)
+
+		_, err := s.store.Get(dummyCtx, aliceNote.ID)
+		s.NoError(err)
+	})
+
+	s.Run("Creating a note with another owner's id should return the same error as the owner", func() {
+		_, ownErr := s.svc.Create(aliceCtx, noteFactory(2).SetID(aliceNote.ID))
+		s.Require().ErrorIs(ownErr, note.ErrExists)
+
+		_, err := s.svc.Create(bobCtx, noteFactory(2).SetID(aliceNote.ID))
+		s.Require().ErrorIs(err, note.ErrExists)
+		s.Equal(ownErr.Error(), err.Error())
+
+		got, err := s.svc.Get(aliceCtx, aliceNote.ID)
+		s.Require().NoError(err)
+		s.Equal("alice", got.OwnerID)
+	})
+
+	s.Run("Fetching should only return the owner's notes", func() {
+		iter, err := s.svc.Fetch(bobCtx, &note.Pagination{})
+		s.Require().NoError(err)
+		s.Equal(uint64(1), iter.TotalCount())
+
+		got, err := note.Collect(note.Values(iter))
+		s.Require().NoError(err)
+		s.Require().Len(got, 1)
+		s.Equal(bobNote.ID, got[0].ID)
+	})
+
+	s.Run("Exporting should only return the owner's notes", func() {
+		iter, err := s.svc.Export(aliceCtx)
+		s.Require().NoError(err)
+
+		got, err := note.Collect(note.Values(iter))
+		s.Require().NoError(err)
+		s.Require().Len(got, 1)
+		s.Equal(aliceNote.ID, got[0].ID)
+	})
+
+	s.Run("A context without owner should see all the notes", func() {
+		iter, err := s.svc.Fetch(dummyCtx, &note.Pagination{})
+		s.Require().NoError(err)
+		s.Equal(uint64(2), iter.TotalCount())
+		s.NoError(iter.Close())
+	})
+}
+
+func (s *TestSuite) TestQuota() {
+	svc := New(s.store, WithQuota(Quota{MaxNotes: 2, MaxBytes: 20}))
+	aliceCtx := note.WithOwner(dummyCtx, "alice")
+	bobCtx := note.WithOwner(dummyCtx, "bob")
+
+	first, err := svc.Create(aliceCtx, new(note.Note).SetTitle("first").SetContent("12345"))
+	s.Require().NoError(err)
+
+	s.Run("Creating a note over the bytes should return quota exceeded", func() {
+		_, err := svc.Create(aliceCtx, new(note.Note).SetTitle("second").SetContent("12345"))
+		s.ErrorIs(err, note.ErrQuotaExceeded)
+	})
+
+	s.Run("Updating a note over the bytes should return quota exceeded", func() {
+		_, err := svc.Update(aliceCtx, new(note.Note).SetID(first.ID).SetContent("1234567890123456"))
+		s.ErrorIs(err, note.ErrQuotaExceeded)
+
+		_, err = svc.Update(aliceCtx, new(note.Note).SetID(first.ID).SetContent("1"))
+		s.NoError(err)
+	})
+
+	s.Run("Creating a note over the count should return quota exceeded", func() {
+		_, err := svc.Create(aliceCtx, new(note.Note).SetTitle("second"))
+		s.Require().NoError(err)
+
+		_, err = svc.Create(aliceCtx, new(note.Note).SetTitle("third"))
+		s.ErrorIs(err, note.ErrQuotaExceeded)
+	})
+
+	s.Run("The quota is per owner", func() {
+		_, err := svc.Create(bobCtx, new(note.Note).SetTitle("first"))
+		s.NoError(err)
+	})
+
+	s.Run("Deleting a note should free the quota", func() {
+		s.Require().NoError(svc.Delete(aliceCtx, first.ID))
+
+		_, err := svc.Create(aliceCtx, new(note.Note).SetTitle("third"))
+		s.NoError(err)
+	})
+
+	s.Run("The notes without owner are not limited", func() {
+		for i := 0; i < 3; i++ {
+			_, err := svc.Create(dummyCtx, noteFactory(i).SetID(uuid.Nil))
+			s.Require().NoError(err)
+		}
+	})
+
+	s.Run("The notes of an owner are limited without owner in the context", func() {
+		_, err := svc.Create(dummyCtx, new(note.Note).SetTitle("fourth").SetOwnerID("alice"))
+		s.ErrorIs(err, note.ErrQuotaExceeded)
+	})
+
+	s.Run("Concurrent creates should not go over the quota", func() {
+		carolCtx := note.WithOwner(dummyCtx, "carol")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = svc.Create(carolCtx, new(note.Note).SetTitle("concurrent"))
+			}()
+		}
+		wg.Wait()
+
+		usage, err := s.store.Usage(dummyCtx, "carol")
+		s.Require().NoError(err)
+		s.Equal(2, usage.Notes)
+	})
+}
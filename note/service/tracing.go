@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"noteapp/note"
+)
+
+// noteIDAttribute is the span attribute of the id of the note.
+const noteIDAttribute = attribute.Key("note.id")
+
+// TracingMiddleware starts a span for every call of the service
+// named after its method, e.g. "note.Service/Create". The spans
+// record the id of the note, when known, and the returned error.
+func TracingMiddleware(tracer trace.Tracer) Middleware {
+	return func(next note.Service) note.Service {
+		return &tracingMiddleware{next: next, tracer: tracer}
+	}
+}
+
+type tracingMiddleware struct {
+	next   note.Service
+	tracer trace.Tracer
+}
+
+func (mw *tracingMiddleware) start(ctx context.Context, method string) (context.Context, trace.Span) {
+	return mw.tracer.Start(ctx, "note.Service/"+method)
+}
+
+func endSpan(span trace.Span, id uuid.UUID, err error) {
+	if id != uuid.Nil {
+		span.SetAttributes(noteIDAttribute.String(id.String()))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (mw *tracingMiddleware) Create(ctx context.Context, n *note.Note) (created *note.Note, err error) {
+	ctx, span := mw.start(ctx, "Create")
+	defer func() {
+		var id uuid.UUID
+		if created != nil {
+			id = created.ID
+		}
+		endSpan(span, id, err)
+	}()
+	return mw.next.Create(ctx, n)
+}
+
+func (mw *tracingMiddleware) Update(ctx context.Context, n *note.Note) (updated *note.Note, err error) {
+	ctx, span := mw.start(ctx, "Update")
+	defer func() {
+		var id uuid.UUID
+		if n != nil {
+			id = n.ID
+		}
+		endSpan(span, id, err)
+	}()
+	return mw.next.Update(ctx, n)
+}
+
+func (mw *tracingMiddleware) Delete(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := mw.start(ctx, "Delete")
+	defer func() { endSpan(span, id, err) }()
+	return mw.next.Delete(ctx, id)
+}
+
+func (mw *tracingMiddleware) Get(ctx context.Context, id uuid.UUID) (n *note.Note, err error) {
+	ctx, span := mw.start(ctx, "Get")
+	defer func() { endSpan(span, id, err) }()
+	return mw.next.Get(ctx, id)
+}
+
+func (mw *tracingMiddleware) Fetch(ctx context.Context, pagination *note.Pagination) (iter note.Iterator, err error) {
+	ctx, span := mw.start(ctx, "Fetch")
+	defer func() { endSpan(span, uuid.Nil, err) }()
+	return mw.next.Fetch(ctx, pagination)
+}
+
+func (mw *tracingMiddleware) Export(ctx context.Context) (iter note.Iterator, err error) {
+	ctx, span := mw.start(ctx, "Export")
+	defer func() { endSpan(span, uuid.Nil, err) }()
+	return mw.next.Export(ctx)
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"noteapp/note"
+	"noteapp/note/validation"
+)
+
+// ValidatingMiddleware rejects the calls with a missing note, a
+// missing note id or a note that doesn't pass the rules of v before
+// they reach the service. A missing pagination is replaced with the
+// default one.
+func ValidatingMiddleware(v *validation.Validator) Middleware {
+	return func(next note.Service) note.Service {
+		return &validatingMiddleware{next: next, validator: v}
+	}
+}
+
+type validatingMiddleware struct {
+	next      note.Service
+	validator *validation.Validator
+}
+
+func (mw *validatingMiddleware) Create(ctx context.Context, n *note.Note) (*note.Note, error) {
+	if n == nil {
+		return nil, note.ErrNilNote
+	}
+
+	if err := mw.validator.ValidateCreate(n); err != nil {
+		return nil, err
+	}
+	return mw.next.Create(ctx, n)
+}
+
+func (mw *validatingMiddleware) Update(ctx context.Context, n *note.Note) (*note.Note, error) {
+	if n == nil {
+		return nil, note.ErrNilNote
+	}
+
+	if n.ID == uuid.Nil {
+		return nil, note.ErrNilID
+	}
+
+	if err := mw.validator.ValidateUpdate(n); err != nil {
+		return nil, err
+	}
+	return mw.next.Update(ctx, n)
+}
+
+func (mw *validatingMiddleware) Delete(ctx context.Context, id uuid.UUID) error {
+	if id == uuid.Nil {
+		return note.ErrNilID
+	}
+	return mw.next.Delete(ctx, id)
+}
+
+func (mw *validatingMiddleware) Get(ctx context.Context, id uuid.UUID) (*note.Note, error) {
+	if id == uuid.Nil {
+		return nil, note.ErrNilID
+	}
+	return mw.next.Get(ctx, id)
+}
+
+func (mw *validatingMiddleware) Fetch(ctx context.Context, pagination *note.Pagination) (note.Iterator, error) {
+	if pagination == nil {
+		pagination = new(note.Pagination)
+	}
+	return mw.next.Fetch(ctx, pagination)
+}
+
+func (mw *validatingMiddleware) Export(ctx context.Context) (note.Iterator, error) {
+	return mw.next.Export(ctx)
+}
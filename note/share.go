@@ -0,0 +1,83 @@
+package note
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"time"
+)
+
+// ErrPermissionDenied is an error when the user can see the note
+// but its role doesn't allow the operation.
+var ErrPermissionDenied = errors.New("note: permission denied")
+
+// Role is the permission a share grant gives on a note.
+type Role string
+
+const (
+	// RoleViewer can get the note.
+	RoleViewer Role = "viewer"
+	// RoleEditor can get and update the note.
+	RoleEditor Role = "editor"
+	// RoleOwner can do everything the owner of the note can,
+	// including deleting and sharing it.
+	RoleOwner Role = "owner"
+)
+
+// Valid reports whether r is one of the roles.
+func (r Role) Valid() bool {
+	switch r {
+	case RoleViewer, RoleEditor, RoleOwner:
+		return true
+	}
+	return false
+}
+
+// CanRead reports whether r allows getting the note.
+func (r Role) CanRead() bool {
+	return r.Valid()
+}
+
+// CanWrite reports whether r allows updating the note.
+func (r Role) CanWrite() bool {
+	return r == RoleEditor || r == RoleOwner
+}
+
+// CanManage reports whether r allows deleting and sharing the note.
+func (r Role) CanManage() bool {
+	return r == RoleOwner
+}
+
+// Grant shares a note with a user.
+type Grant struct {
+	NoteID      uuid.UUID `json:"note_id"`
+	UserID      string    `json:"user_id"`
+	Role        Role      `json:"role"`
+	CreatedTime time.Time `json:"created_time"`
+}
+
+// Grants looks up the share grants. The lookups must reflect the
+// revoked grants as soon as they are revoked.
+type Grants interface {
+	// Role returns the role granted to the user on the note with
+	// an id. It returns an empty role when there's no such grant.
+	Role(noteID uuid.UUID, userID string) Role
+	// SharedWith returns the ids of the notes shared with the user.
+	SharedWith(userID string) []uuid.UUID
+}
+
+// RoleOf returns the role of the owner in ctx on n. The owner of the
+// note and a context without owner have the owner role. Other users
+// have the role of their grant, if any, or an empty role otherwise.
+// The grants can be nil when the notes are not shared.
+func RoleOf(ctx context.Context, n *Note, grants Grants) Role {
+	ownerID, ok := OwnerFromContext(ctx)
+	if !ok || n.OwnerID == ownerID {
+		return RoleOwner
+	}
+
+	if grants == nil {
+		return ""
+	}
+	return grants.Role(n.ID, ownerID)
+}
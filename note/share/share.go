@@ -0,0 +1,84 @@
+package share
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"noteapp/note"
+)
+
+var (
+	// ErrNotFound is an error when the grant doesn't exist.
+	ErrNotFound = errors.New("share: grant not found")
+	// ErrInvalidRole is an error when the role of the grant is unknown.
+	ErrInvalidRole = errors.New("share: invalid role")
+	// ErrInvalidUser is an error when the user of the grant is empty
+	// or it is the owner of the note.
+	ErrInvalidUser = errors.New("share: invalid user")
+)
+
+// Service manages the share grants of the notes. The notes are looked
+// up with the note service so the grants of a note can only be seen by
+// the users who can get the note, and only changed by the ones whose
+// role can manage it.
+type Service struct {
+	notes note.Service
+	store *Store
+}
+
+// NewService returns a service managing the grants in store.
+// The notes must be the note service using the same store
+// as its grants, see the service.WithGrants.
+func NewService(notes note.Service, store *Store) *Service {
+	return &Service{notes: notes, store: store}
+}
+
+// Share grants the role on the note with an id to the user. Sharing
+// the note again with the same user replaces the role.
+func (s *Service) Share(ctx context.Context, noteID uuid.UUID, userID string, role note.Role) (*note.Grant, error) {
+	n, err := s.manage(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	if userID == n.OwnerID {
+		return nil, fmt.Errorf("share: '%s' owns the note: %w", userID, ErrInvalidUser)
+	}
+
+	return s.store.Put(&note.Grant{NoteID: noteID, UserID: userID, Role: role})
+}
+
+// Grants returns the grants of the note with an id.
+func (s *Service) Grants(ctx context.Context, noteID uuid.UUID) ([]*note.Grant, error) {
+	if _, err := s.notes.Get(ctx, noteID); err != nil {
+		return nil, err
+	}
+	return s.store.List(noteID), nil
+}
+
+// Revoke revokes the grant of the user on the note with an id. The
+// users can revoke their own grants whatever their role is. The
+// revoked user loses the access to the note right away.
+func (s *Service) Revoke(ctx context.Context, noteID uuid.UUID, userID string) error {
+	if ownerID, ok := note.OwnerFromContext(ctx); !ok || ownerID != userID {
+		if _, err := s.manage(ctx, noteID); err != nil {
+			return err
+		}
+	}
+	return s.store.Delete(noteID, userID)
+}
+
+// manage returns the note with an id when the role of the
+// owner in ctx can manage it.
+func (s *Service) manage(ctx context.Context, noteID uuid.UUID) (*note.Note, error) {
+	n, err := s.notes.Get(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	if role := note.RoleOf(ctx, n, s.store); !role.CanManage() {
+		return nil, fmt.Errorf("share: note '%s' can't be shared by the %s: %w", noteID, role, note.ErrPermissionDenied)
+	}
+	return n, nil
+}
@@ -0,0 +1,185 @@
+package share
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/suite"
+	"noteapp/note"
+	"noteapp/note/service"
+	"noteapp/note/store/memory"
+	"noteapp/pkg/ptrconv"
+	"sort"
+	"testing"
+)
+
+var dummyCtx = context.TODO()
+
+var (
+	aliceCtx = note.WithOwner(dummyCtx, "alice")
+	bobCtx   = note.WithOwner(dummyCtx, "bob")
+	carolCtx = note.WithOwner(dummyCtx, "carol")
+)
+
+func TestService(t *testing.T) {
+	suite.Run(t, new(ServiceTestSuite))
+}
+
+type ServiceTestSuite struct {
+	suite.Suite
+	store *Store
+	notes note.Service
+	svc   *Service
+	note  *note.Note
+}
+
+func (s *ServiceTestSuite) SetupTest() {
+	file, err := afero.NewMemMapFs().Create("shares.json")
+	s.Require().NoError(err)
+
+	s.store, err = NewStore(file)
+	s.Require().NoError(err)
+
+	s.notes = service.New(memory.New(), service.WithGrants(s.store))
+	s.svc = NewService(s.notes, s.store)
+
+	s.note, err = s.notes.Create(aliceCtx, &note.Note{Title: ptrconv.StringPointer("Shared"), Content: ptrconv.StringPointer("Lorem Ipsum")})
+	s.Require().NoError(err)
+}
+
+func (s *ServiceTestSuite) TestShare() {
+	s.Run("Sharing should give the access of the role", func() {
+		_, err := s.svc.Share(aliceCtx, s.note.ID, "bob", note.RoleViewer)
+		s.Require().NoError(err)
+
+		_, err = s.notes.Get(bobCtx, s.note.ID)
+		s.NoError(err)
+
+		_, err = s.notes.Update(bobCtx, new(note.Note).SetID(s.note.ID).SetTitle("Edited"))
+		s.ErrorIs(err, note.ErrPermissionDenied)
+
+		_, err = s.svc.Share(aliceCtx, s.note.ID, "bob", note.RoleEditor)
+		s.Require().NoError(err)
+
+		_, err = s.notes.Update(bobCtx, new(note.Note).SetID(s.note.ID).SetTitle("Edited"))
+		s.NoError(err)
+		s.ErrorIs(s.notes.Delete(bobCtx, s.note.ID), note.ErrPermissionDenied)
+	})
+
+	s.Run("Only the roles managing the note can share it", func() {
+		_, err := s.svc.Share(bobCtx, s.note.ID, "carol", note.RoleViewer)
+		s.ErrorIs(err, note.ErrPermissionDenied)
+
+		_, err = s.svc.Share(carolCtx, s.note.ID, "carol", note.RoleOwner)
+		s.ErrorIs(err, note.ErrNotFound)
+
+		_, err = s.svc.Share(aliceCtx, s.note.ID, "bob", note.RoleOwner)
+		s.Require().NoError(err)
+
+		_, err = s.svc.Share(bobCtx, s.note.ID, "carol", note.RoleViewer)
+		s.NoError(err)
+	})
+
+	s.Run("Sharing with the owner should return an error", func() {
+		_, err := s.svc.Share(aliceCtx, s.note.ID, "alice", note.RoleViewer)
+		s.ErrorIs(err, ErrInvalidUser)
+	})
+}
+
+func (s *ServiceTestSuite) TestGrants() {
+	_, err := s.svc.Share(aliceCtx, s.note.ID, "bob", note.RoleViewer)
+	s.Require().NoError(err)
+
+	grants, err := s.svc.Grants(bobCtx, s.note.ID)
+	s.Require().NoError(err)
+	s.Require().Len(grants, 1)
+	s.Equal("bob", grants[0].UserID)
+
+	_, err = s.svc.Grants(carolCtx, s.note.ID)
+	s.ErrorIs(err, note.ErrNotFound)
+}
+
+func (s *ServiceTestSuite) TestRevoke() {
+	_, err := s.svc.Share(aliceCtx, s.note.ID, "bob", note.RoleViewer)
+	s.Require().NoError(err)
+	_, err = s.svc.Share(aliceCtx, s.note.ID, "carol", note.RoleViewer)
+	s.Require().NoError(err)
+
+	s.Run("Revoking should take effect right away", func() {
+		s.Require().NoError(s.svc.Revoke(aliceCtx, s.note.ID, "bob"))
+
+		_, err := s.notes.Get(bobCtx, s.note.ID)
+		s.ErrorIs(err, note.ErrNotFound)
+
+		iter, err := s.notes.Fetch(bobCtx, &note.Pagination{SharedWithMe: true})
+		s.Require().NoError(err)
+		s.Zero(iter.TotalCount())
+	})
+
+	s.Run("A viewer can only revoke its own grant", func() {
+		_, err := s.svc.Share(aliceCtx, s.note.ID, "bob", note.RoleViewer)
+		s.Require().NoError(err)
+
+		s.ErrorIs(s.svc.Revoke(carolCtx, s.note.ID, "bob"), note.ErrPermissionDenied)
+		s.NoError(s.svc.Revoke(carolCtx, s.note.ID, "carol"))
+	})
+
+	s.Run("Revoking a missing grant should return an error", func() {
+		s.ErrorIs(s.svc.Revoke(aliceCtx, s.note.ID, "dave"), ErrNotFound)
+		s.ErrorIs(s.svc.Revoke(aliceCtx, uuid.New(), "bob"), note.ErrNotFound)
+	})
+}
+
+func (s *ServiceTestSuite) TestFetchSharedWithMe() {
+	other, err := s.notes.Create(aliceCtx, &note.Note{Title: ptrconv.StringPointer("A private note")})
+	s.Require().NoError(err)
+	_, err = s.notes.Create(bobCtx, &note.Note{Title: ptrconv.StringPointer("Bob's note")})
+	s.Require().NoError(err)
+
+	_, err = s.svc.Share(aliceCtx, s.note.ID, "bob", note.RoleViewer)
+	s.Require().NoError(err)
+
+	iter, err := s.notes.Fetch(bobCtx, &note.Pagination{SharedWithMe: true})
+	s.Require().NoError(err)
+	s.Equal(uint64(1), iter.TotalCount())
+
+	got, err := note.Collect(note.Values(iter))
+	s.Require().NoError(err)
+	s.Require().Len(got, 1)
+	s.Equal(s.note.ID, got[0].ID)
+	s.NotEqual(other.ID, got[0].ID)
+
+	iter, err = s.notes.Fetch(bobCtx, &note.Pagination{})
+	s.Require().NoError(err)
+	s.Equal(uint64(1), iter.TotalCount())
+	s.NoError(iter.Close())
+}
+
+func (s *ServiceTestSuite) TestFetchSharedWithMePages() {
+	ids := []uuid.UUID{s.note.ID}
+	for i := 0; i < 2; i++ {
+		n, err := s.notes.Create(aliceCtx, &note.Note{Title: ptrconv.StringPointer("Shared")})
+		s.Require().NoError(err)
+		ids = append(ids, n.ID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+
+	for _, id := range ids {
+		_, err := s.svc.Share(aliceCtx, id, "bob", note.RoleViewer)
+		s.Require().NoError(err)
+	}
+
+	var got []uuid.UUID
+	for page := uint64(1); page <= 3; page++ {
+		iter, err := s.notes.Fetch(bobCtx, &note.Pagination{SharedWithMe: true, Size: 2, Page: page})
+		s.Require().NoError(err)
+		s.Equal(uint64(len(ids)), iter.TotalCount())
+
+		notes, err := note.Collect(note.Values(iter))
+		s.Require().NoError(err)
+		for _, n := range notes {
+			got = append(got, n.ID)
+		}
+	}
+	s.Equal(ids, got)
+}
@@ -0,0 +1,241 @@
+package share
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"noteapp/note"
+	"noteapp/pkg/logging"
+	"sort"
+	"sync"
+	"time"
+)
+
+var _ note.Grants = (*Store)(nil)
+
+// File is the file where the store keeps its data.
+type File interface {
+	io.ReadWriteSeeker
+	io.Closer
+	Sync() error
+	Truncate(size int64) error
+}
+
+// fileData is the JSON document of the store file.
+type fileData struct {
+	Grants []*note.Grant `json:"grants"`
+}
+
+// Store keeps the share grants of the notes. Every change is written
+// to the file before it returns. This is safe for concurrent use.
+type Store struct {
+	file File
+
+	mu     sync.RWMutex
+	grants []*note.Grant
+}
+
+// NewStore reads the store data from file and returns the store.
+// An empty file is an empty store.
+func NewStore(file File) (*Store, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	b, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var data fileData
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &data); err != nil {
+			return nil, fmt.Errorf("share: reading store: %w", err)
+		}
+	}
+
+	return &Store{file: file, grants: data.Grants}, nil
+}
+
+// Put adds the grant g or replaces the role of the existing grant
+// of the same note and user.
+func (s *Store) Put(g *note.Grant) (*note.Grant, error) {
+	if g.NoteID == uuid.Nil {
+		return nil, note.ErrNilID
+	}
+
+	if g.UserID == "" {
+		return nil, ErrInvalidUser
+	}
+
+	if !g.Role.Valid() {
+		return nil, ErrInvalidRole
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing := s.find(g.NoteID, g.UserID); existing != nil {
+		prev := existing.Role
+		existing.Role = g.Role
+		if err := s.write(); err != nil {
+			existing.Role = prev
+			return nil, err
+		}
+		return copyGrant(existing), nil
+	}
+
+	created := copyGrant(g)
+	created.CreatedTime = time.Now().UTC()
+
+	s.grants = append(s.grants, created)
+	if err := s.write(); err != nil {
+		s.grants = s.grants[:len(s.grants)-1]
+		return nil, err
+	}
+	return copyGrant(created), nil
+}
+
+// Delete deletes the grant of the user on the note with an id.
+func (s *Store) Delete(noteID uuid.UUID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.find(noteID, userID) == nil {
+		return ErrNotFound
+	}
+
+	return s.replace(func(g *note.Grant) bool {
+		return g.NoteID != noteID || g.UserID != userID
+	})
+}
+
+// List returns the grants of the note with an id in the order
+// they have been created.
+func (s *Store) List(noteID uuid.UUID) []*note.Grant {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	grants := []*note.Grant{}
+	for _, g := range s.grants {
+		if g.NoteID == noteID {
+			grants = append(grants, copyGrant(g))
+		}
+	}
+	return grants
+}
+
+// Role implements the note.Grants.
+func (s *Store) Role(noteID uuid.UUID, userID string) note.Role {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if g := s.find(noteID, userID); g != nil {
+		return g.Role
+	}
+	return ""
+}
+
+// SharedWith implements the note.Grants. The ids are sorted.
+func (s *Store) SharedWith(userID string) []uuid.UUID {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var ids []uuid.UUID
+	for _, g := range s.grants {
+		if g.UserID == userID {
+			ids = append(ids, g.NoteID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+	return ids
+}
+
+// Handle deletes the grants of the deleted notes. It lets the store
+// subscribe to the note.EventDeleted events of the event bus.
+func (s *Store) Handle(ctx context.Context, e note.Event) {
+	if e.Type != note.EventDeleted || e.Note == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.replace(func(g *note.Grant) bool { return g.NoteID != e.Note.ID }); err != nil {
+		logging.Logger(ctx).Error("share: ", err)
+	}
+}
+
+func (s *Store) find(noteID uuid.UUID, userID string) *note.Grant {
+	for _, g := range s.grants {
+		if g.NoteID == noteID && g.UserID == userID {
+			return g
+		}
+	}
+	return nil
+}
+
+// replace replaces the grants with the ones where keep returns
+// true. Nothing is written when all the grants are kept.
+func (s *Store) replace(keep func(g *note.Grant) bool) error {
+	grants := make([]*note.Grant, 0, len(s.grants))
+	for _, g := range s.grants {
+		if keep(g) {
+			grants = append(grants, g)
+		}
+	}
+
+	if len(grants) == len(s.grants) {
+		return nil
+	}
+
+	prev := s.grants
+	s.grants = grants
+	if err := s.write(); err != nil {
+		s.grants = prev
+		return err
+	}
+	return nil
+}
+
+// Close syncs and closes the file of the store. The store
+// must not be used after it is closed.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.file.Sync(); err != nil {
+		_ = s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+// write rewrites the whole file with the current data.
+func (s *Store) write() error {
+	b, err := json.Marshal(fileData{Grants: s.grants})
+	if err != nil {
+		return err
+	}
+
+	if err := s.file.Truncate(0); err != nil {
+		return err
+	}
+
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if _, err := s.file.Write(b); err != nil {
+		return err
+	}
+
+	return s.file.Sync()
+}
+
+func copyGrant(g *note.Grant) *note.Grant {
+	cpy := *g
+	return &cpy
+}
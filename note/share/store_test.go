@@ -0,0 +1,111 @@
+package share
+
+import (
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/suite"
+	"noteapp/note"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	suite.Run(t, new(StoreTestSuite))
+}
+
+type StoreTestSuite struct {
+	suite.Suite
+	file  afero.File
+	store *Store
+}
+
+func (s *StoreTestSuite) SetupTest() {
+	var err error
+	s.file, err = afero.NewMemMapFs().Create("shares.json")
+	s.Require().NoError(err)
+
+	s.store, err = NewStore(s.file)
+	s.Require().NoError(err)
+}
+
+func (s *StoreTestSuite) put(noteID uuid.UUID, userID string, role note.Role) *note.Grant {
+	g, err := s.store.Put(&note.Grant{NoteID: noteID, UserID: userID, Role: role})
+	s.Require().NoError(err)
+	return g
+}
+
+func (s *StoreTestSuite) TestPut() {
+	noteID := uuid.New()
+
+	g := s.put(noteID, "bob", note.RoleViewer)
+	s.False(g.CreatedTime.IsZero())
+	s.Equal(note.RoleViewer, s.store.Role(noteID, "bob"))
+
+	s.put(noteID, "bob", note.RoleEditor)
+	s.Equal(note.RoleEditor, s.store.Role(noteID, "bob"))
+	s.Len(s.store.List(noteID), 1)
+
+	reopened, err := NewStore(s.file)
+	s.Require().NoError(err)
+	s.Equal(note.RoleEditor, reopened.Role(noteID, "bob"))
+
+	invalid := []struct {
+		name  string
+		grant *note.Grant
+		err   error
+	}{
+		{name: "Missing note id", grant: &note.Grant{UserID: "bob", Role: note.RoleViewer}, err: note.ErrNilID},
+		{name: "Missing user", grant: &note.Grant{NoteID: noteID, Role: note.RoleViewer}, err: ErrInvalidUser},
+		{name: "Unknown role", grant: &note.Grant{NoteID: noteID, UserID: "bob", Role: "admin"}, err: ErrInvalidRole},
+	}
+
+	for _, tt := range invalid {
+		s.Run(tt.name, func() {
+			_, err := s.store.Put(tt.grant)
+			s.ErrorIs(err, tt.err)
+		})
+	}
+}
+
+func (s *StoreTestSuite) TestDelete() {
+	noteID := uuid.New()
+	s.put(noteID, "bob", note.RoleViewer)
+	s.put(noteID, "carol", note.RoleViewer)
+
+	s.Require().NoError(s.store.Delete(noteID, "bob"))
+	s.Empty(s.store.Role(noteID, "bob"))
+	s.Equal(note.RoleViewer, s.store.Role(noteID, "carol"))
+	s.ErrorIs(s.store.Delete(noteID, "bob"), ErrNotFound)
+}
+
+func (s *StoreTestSuite) TestSharedWith() {
+	first, second := uuid.New(), uuid.New()
+	s.put(first, "bob", note.RoleViewer)
+	s.put(second, "bob", note.RoleOwner)
+	s.put(second, "carol", note.RoleViewer)
+
+	s.ElementsMatch([]uuid.UUID{first, second}, s.store.SharedWith("bob"))
+	s.Equal([]uuid.UUID{second}, s.store.SharedWith("carol"))
+	s.Empty(s.store.SharedWith("dave"))
+}
+
+func (s *StoreTestSuite) TestHandle() {
+	deleted, kept := uuid.New(), uuid.New()
+	s.put(deleted, "bob", note.RoleViewer)
+	s.put(kept, "bob", note.RoleViewer)
+
+	s.store.Handle(dummyCtx, note.Event{Type: note.EventUpdated, Note: new(note.Note).SetID(deleted)})
+	s.Len(s.store.List(deleted), 1)
+
+	s.store.Handle(dummyCtx, note.Event{Type: note.EventDeleted, Note: new(note.Note).SetID(deleted)})
+	s.Empty(s.store.List(deleted))
+	s.Equal([]uuid.UUID{kept}, s.store.SharedWith("bob"))
+}
+
+func (s *StoreTestSuite) TestClose() {
+	noteID := uuid.New()
+	s.put(noteID, "bob", note.RoleViewer)
+	s.Require().NoError(s.store.Close())
+
+	_, err := s.store.Put(&note.Grant{NoteID: noteID, UserID: "carol", Role: note.RoleViewer})
+	s.Error(err)
+}
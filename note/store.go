@@ -36,8 +36,21 @@ type Store interface {
 	// Fetch fetches the notes in the store using the pagination setting
 	// p. It takes context in order to let the caller stop the execution in any form.
 	// I returns the fetch result containing the current pagination settings, the
-	// note data and the number of pages of the current fetch pagination.
+	// note data and the number of pages of the current fetch pagination. When the
+	// OwnerID of p is set only the notes of the owner are paginated and counted.
 	Fetch(ctx context.Context, p *Pagination) (Iterator, error)
+
+	// Usage returns the number and the total size, see Note.Size, of
+	// the notes of the owner with an id. It takes ctx context in order
+	// to let the caller stop the execution in any form. It doesn't read
+	// the notes, so it can be called for every write.
+	Usage(ctx context.Context, ownerID string) (Usage, error)
+
+	// Export returns an iterator of all the notes in the store sorted
+	// by ID. It takes context in order to let the caller stop the execution
+	// in any form. The iterator reads from a consistent snapshot of the store
+	// so writes made during the iteration won't be visible.
+	Export(ctx context.Context) (Iterator, error)
 }
 
 // SortBy describe the type of sorts supported by the pagination.
@@ -66,6 +79,13 @@ type Pagination struct {
 	// Ascend indicates that the pagination is ascend.
 	// Default is true.
 	Ascend bool `json:"ascend,omitempty"`
+	// OwnerID limits the pagination to the notes of the owner.
+	// All the notes are paginated when it is empty.
+	OwnerID string `json:"owner_id,omitempty"`
+	// SharedWithMe paginates the notes shared with the user of the
+	// request instead of its own notes, sorted by id whatever the
+	// SortBy. It is only used by the service.
+	SharedWithMe bool `json:"shared_with_me,omitempty"`
 }
 
 // Check checks the value of each pagination field and set default
@@ -3,13 +3,14 @@ package file
 import (
 	"context"
 	"github.com/google/uuid"
-	"github.com/sirupsen/logrus"
 	"io"
 	"noteapp/note"
 	"noteapp/note/noteutil"
 	"noteapp/note/proto/protoutil"
-	"sort"
+	"noteapp/note/store/index"
+	"noteapp/pkg/logging"
 	"sync"
+	"time"
 )
 
 var _ note.Store = (*Store)(nil)
@@ -24,6 +25,7 @@ func newStore(file File) *Store {
 	return &Store{
 		file:  file,
 		notes: make(map[uuid.UUID]*note.Note),
+		index: index.New(),
 	}
 }
 
@@ -37,9 +39,23 @@ type Store struct {
 	mu    sync.RWMutex
 	notes map[uuid.UUID]*note.Note
 
+	// index keeps the notes sorted for the Fetch
+	// and for writing the notes to the file.
+	index *index.Index
+
+	// size is the size of the notes written to the file, the
+	// inserted notes are appended from there.
+	size int64
+
+	// lastSyncDuration is how long the last sync
+	// of the file took.
+	lastSyncDuration time.Duration
+
 	// once use to initialize the store only
 	// once.
 	once sync.Once
+	// initErr is the error of the initialization.
+	initErr error
 }
 
 // Fetch fetches the notes in the store using the pagination setting
@@ -48,7 +64,7 @@ type Store struct {
 // note data and the number of pages of the current fetch pagination.
 func (s *Store) Fetch(ctx context.Context, p *note.Pagination) (note.Iterator, error) {
 
-	if err := s.lazyInit(); err != nil {
+	if err := s.lazyInit(ctx); err != nil {
 		return nil, err
 	}
 
@@ -70,44 +86,20 @@ func (s *Store) Fetch(ctx context.Context, p *note.Pagination) (note.Iterator, e
 		default:
 		}
 
-		start := (p.Page - 1) * p.Size
-		stop := start + p.Size
-
 		s.mu.RLock()
-		defer s.mu.RUnlock()
-
-		if int(start) > len(s.notes) {
-			iterChan <- nil
-			return
-		}
-
-		// Get all the notes in array.
-		var notes []*note.Note
-		for _, n := range s.notes {
-			notes = append(notes, n)
-		}
-
-		// Sort by ID
-		switch p.SortBy {
-		case note.SortByID:
-			sort.Sort(note.SortByIDSorter(notes))
-		case note.SortByTitle:
-			sort.Sort(note.SortByTitleSorter(notes))
-		case note.SortByCreatedTime:
-			sort.Sort(note.SortByCreatedDateSorter(notes))
-		default:
-			sort.Sort(note.SortByIDSorter(notes))
-		}
+		snapshot := s.index.Snapshot()
+		s.mu.RUnlock()
 
-		if noteSize := uint64(len(notes)); stop > noteSize {
-			stop = noteSize
+		offset, limit := int((p.Page-1)*p.Size), int(p.Size)
+		cursor, totalCount := snapshot.Range(p.SortBy, offset, limit), snapshot.Len()
+		if p.OwnerID != "" {
+			cursor, totalCount = snapshot.OwnerRange(p.OwnerID, p.SortBy, offset, limit), snapshot.OwnerLen(p.OwnerID)
 		}
 
 		iter := &iterator{
-			s:          s,
-			notes:      notes[start:stop],
-			totalCount: len(notes),
-			totalPage:  len(notes) / int(p.Size),
+			cursor:     cursor,
+			totalCount: totalCount,
+			totalPage:  totalCount / int(p.Size),
 		}
 		iterChan <- iter
 	}()
@@ -120,44 +112,108 @@ func (s *Store) Fetch(ctx context.Context, p *note.Pagination) (note.Iterator, e
 	}
 }
 
-func (s *Store) lazyInit() (err error) {
-	s.once.Do(func() {
-		_, err = s.file.Seek(0, io.SeekStart)
-		if err != nil {
-			return
-		}
+// Usage returns the number and the total size of the notes of the
+// owner with an id. They are counted by the index of the store.
+func (s *Store) Usage(ctx context.Context, ownerID string) (note.Usage, error) {
+	if err := s.lazyInit(ctx); err != nil {
+		return note.Usage{}, err
+	}
 
-		info, serr := s.file.Stat()
-		if serr != nil {
-			err = serr
-			return
-		}
-		logrus.Debug("size:", info.Size())
+	select {
+	case <-ctx.Done():
+		return note.Usage{}, ctx.Err()
+	default:
+	}
 
-		// Read all first the messages from the
-		// existing file.
-		notes, rerr := protoutil.ReadAllProtoMessages(s.file)
-		if rerr != nil {
-			err = rerr
-			return
-		}
+	s.mu.RLock()
+	snapshot := s.index.Snapshot()
+	s.mu.RUnlock()
 
-		notesWithKey := make(map[uuid.UUID]*note.Note)
+	return snapshot.OwnerUsage(ownerID), nil
+}
 
-		for _, n := range notes {
-			logrus.Debug("note:", n.ID)
-			notesWithKey[n.ID] = n
-		}
+// Export returns an iterator of all the notes in the store sorted
+// by ID. The iterator reads from a snapshot of the store so writes made
+// during the iteration won't be visible.
+func (s *Store) Export(ctx context.Context) (note.Iterator, error) {
+	if err := s.lazyInit(ctx); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	snapshot := s.index.Snapshot()
+	s.mu.RUnlock()
+
+	iter := &iterator{
+		cursor:     snapshot.Range(note.SortByID, 0, snapshot.Len()),
+		totalCount: snapshot.Len(),
+	}
+
+	if snapshot.Len() > 0 {
+		iter.totalPage = 1
+	}
+
+	return iter, nil
+}
 
-		s.notes = notesWithKey
+// Init reads the notes from the file when the store is not
+// initialized yet. The store initializes itself on its first call
+// so it's only needed to check it. It returns the error of the
+// initialization, if any, on every call.
+func (s *Store) Init(ctx context.Context) error {
+	return s.lazyInit(ctx)
+}
 
+func (s *Store) lazyInit(ctx context.Context) error {
+	s.once.Do(func() {
+		s.initErr = s.init(ctx)
 	})
-	return
+	return s.initErr
 }
 
-// Insert inserts an n note to the store.
+// init reads the notes from the file into the store.
+func (s *Store) init(ctx context.Context) error {
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+	logger := logging.Logger(ctx)
+	logger.WithField("size", info.Size()).Debug("reading the notes from the file")
+
+	// Read all first the messages from the
+	// existing file.
+	notes, err := protoutil.ReadAllProtoMessages(s.file)
+	if err != nil {
+		return err
+	}
+
+	notesWithKey := make(map[uuid.UUID]*note.Note)
+
+	for _, n := range notes {
+		logger.WithField("note_id", n.ID).Debug("read the note from the file")
+		notesWithKey[n.ID] = n
+	}
+
+	s.notes = notesWithKey
+	s.index = index.New(notes...)
+	s.size = info.Size()
+	return nil
+}
+
+// Insert inserts an n note to the store. The note is appended to
+// the file, while the updates and the deletes rewrite the file.
 func (s *Store) Insert(ctx context.Context, n *note.Note) error {
-	if err := s.lazyInit(); err != nil {
+	if err := s.lazyInit(ctx); err != nil {
 		return err
 	}
 
@@ -193,14 +249,14 @@ func (s *Store) Insert(ctx context.Context, n *note.Note) error {
 			return
 		}
 
-		s.notes[n.ID] = noteutil.Copy(n)
-
-		err := s.writeAllNotesToFile()
-		if err != nil {
+		cpyNote := noteutil.Copy(n)
+		if err := s.appendNoteToFile(cpyNote); err != nil {
 			errChan <- err
 			return
 		}
 
+		s.notes[n.ID] = cpyNote
+		s.index.Insert(cpyNote)
 		doneChan <- struct{}{}
 	}()
 
@@ -214,7 +270,7 @@ func (s *Store) Insert(ctx context.Context, n *note.Note) error {
 
 // Update updates an existing n note to the store.
 func (s *Store) Update(ctx context.Context, n *note.Note) (updated *note.Note, err error) {
-	if err := s.lazyInit(); err != nil {
+	if err := s.lazyInit(ctx); err != nil {
 		return nil, err
 	}
 
@@ -245,16 +301,22 @@ func (s *Store) Update(ctx context.Context, n *note.Note) (updated *note.Note, e
 			return
 		}
 
-		err := noteutil.Merge(existingNote, n)
+		// The existing note may be referenced by a fetch
+		// snapshot so merge the changes into a new copy.
+		updatedNote := noteutil.Copy(existingNote)
+
+		err := noteutil.Merge(updatedNote, n)
 		if err != nil {
 			errChan <- err
 			return
 		}
 
 		// Workaround 💪😅
-		existingNote.UpdatedTime = n.UpdatedTime
+		updatedNote.UpdatedTime = n.UpdatedTime
 
-		s.notes[n.ID] = existingNote
+		s.notes[n.ID] = updatedNote
+		s.index.Delete(existingNote)
+		s.index.Insert(updatedNote)
 
 		err = s.writeAllNotesToFile()
 		if err != nil {
@@ -262,7 +324,7 @@ func (s *Store) Update(ctx context.Context, n *note.Note) (updated *note.Note, e
 			return
 		}
 
-		noteChan <- noteutil.Copy(existingNote)
+		noteChan <- noteutil.Copy(updatedNote)
 	}()
 
 	select {
@@ -276,7 +338,7 @@ func (s *Store) Update(ctx context.Context, n *note.Note) (updated *note.Note, e
 
 // Delete deletes an existing note with id from the store.
 func (s *Store) Delete(ctx context.Context, id uuid.UUID) error {
-	if err := s.lazyInit(); err != nil {
+	if err := s.lazyInit(ctx); err != nil {
 		return err
 	}
 
@@ -301,7 +363,10 @@ func (s *Store) Delete(ctx context.Context, id uuid.UUID) error {
 		s.mu.Lock()
 		defer s.mu.Unlock()
 
-		delete(s.notes, id)
+		if existingNote, found := s.notes[id]; found {
+			delete(s.notes, id)
+			s.index.Delete(existingNote)
+		}
 
 		err := s.writeAllNotesToFile()
 		if err != nil {
@@ -320,9 +385,9 @@ func (s *Store) Delete(ctx context.Context, id uuid.UUID) error {
 	}
 }
 
-// Get gets the existing note with id from the store.
+// Get gets a copy of the existing note with id from the store.
 func (s *Store) Get(ctx context.Context, id uuid.UUID) (*note.Note, error) {
-	if err := s.lazyInit(); err != nil {
+	if err := s.lazyInit(ctx); err != nil {
 		return nil, err
 	}
 
@@ -351,7 +416,9 @@ func (s *Store) Get(ctx context.Context, id uuid.UUID) (*note.Note, error) {
 			return
 		}
 
-		noteChan <- n
+		// The stored note is shared with the index, so
+		// hand out a copy the caller can mutate.
+		noteChan <- noteutil.Copy(n)
 	}()
 
 	select {
@@ -362,19 +429,39 @@ func (s *Store) Get(ctx context.Context, id uuid.UUID) (*note.Note, error) {
 	}
 }
 
-func convertMapValueToSlice(notes map[uuid.UUID]*note.Note) []*note.Note {
+func collectSortedByID(snapshot index.Snapshot) []*note.Note {
+	noteSlice := make([]*note.Note, 0, snapshot.Len())
 
-	var noteSlice []*note.Note
-
-	for _, n := range notes {
+	cursor := snapshot.Range(note.SortByID, 0, snapshot.Len())
+	for n, ok := cursor.Next(); ok; n, ok = cursor.Next() {
 		noteSlice = append(noteSlice, n)
 	}
 
-	sort.Sort(note.Notes(noteSlice))
-
 	return noteSlice
 }
 
+// appendNoteToFile appends the note n to the notes of the file, so
+// that inserting a note, e.g. while importing many of them, doesn't
+// rewrite the whole file. A partly appended note is cut off the file.
+func (s *Store) appendNoteToFile(n *note.Note) error {
+	if _, err := s.file.Seek(s.size, io.SeekStart); err != nil {
+		return err
+	}
+
+	err := protoutil.WriteProtoMessage(s.file, protoutil.NoteToProto(n))
+	if err == nil {
+		err = s.sync()
+	}
+
+	if err != nil {
+		_ = s.file.Truncate(s.size)
+		return err
+	}
+
+	s.size, err = s.file.Seek(0, io.SeekCurrent)
+	return err
+}
+
 func (s *Store) writeAllNotesToFile() error {
 
 	// Erase existing file content
@@ -391,7 +478,7 @@ func (s *Store) writeAllNotesToFile() error {
 		s.file,
 		protoutil.ConvertToProtoMessage(
 			protoutil.ConvertNotesToProtos(
-				convertMapValueToSlice(s.notes),
+				collectSortedByID(s.index.Snapshot()),
 			),
 		)...,
 	)
@@ -399,10 +486,64 @@ func (s *Store) writeAllNotesToFile() error {
 		return err
 	}
 
-	err = s.file.Sync()
-	if err != nil {
+	if err := s.sync(); err != nil {
 		return err
 	}
 
-	return nil
+	s.size, err = s.file.Seek(0, io.SeekCurrent)
+	return err
+}
+
+// sync syncs the file and records how long it took.
+func (s *Store) sync() error {
+	begin := time.Now()
+	err := s.file.Sync()
+	s.lastSyncDuration = time.Since(begin)
+	return err
+}
+
+// Close waits for the writes in progress then syncs and closes
+// the file of the store. The store must not be used after it is
+// closed.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.file.Sync(); err != nil {
+		_ = s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+// Stats is a snapshot of the statistics of the store.
+type Stats struct {
+	// Notes is the number of notes in the store.
+	Notes int
+	// FileSize is the size of the file in bytes.
+	FileSize int64
+	// LastSyncDuration is how long the last sync of the file
+	// took. It's zero until the store writes to the file.
+	LastSyncDuration time.Duration
+}
+
+// Stats returns the current statistics of the store.
+func (s *Store) Stats() (Stats, error) {
+	if err := s.lazyInit(context.Background()); err != nil {
+		return Stats{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	info, err := s.file.Stat()
+	if err != nil {
+		return Stats{}, err
+	}
+
+	return Stats{
+		Notes:            len(s.notes),
+		FileSize:         info.Size(),
+		LastSyncDuration: s.lastSyncDuration,
+	}, nil
 }
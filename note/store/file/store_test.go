@@ -3,6 +3,7 @@ package file
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"github.com/google/uuid"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/require"
@@ -133,6 +134,51 @@ func (s *FileStoreTestSuite) TestInsert() {
 		got := gotNotes[0]
 		s.Equal(n, got)
 	})
+
+	s.Run("Inserting a note should append it to the notes of the file", func() {
+		s.SetupTest()
+		existing := noteFactory()
+		s.writeNotesToFile(existing)
+
+		s.Require().NoError(s.store.Insert(dummyCtx, n))
+		updated, err := s.store.Update(dummyCtx, noteutil.Copy(existing).SetTitle("Updated"))
+		s.Require().NoError(err)
+		inserted := noteFactory()
+		s.Require().NoError(s.store.Insert(dummyCtx, inserted))
+
+		s.ElementsMatch([]*note.Note{updated, n, inserted}, s.readAllNotesFromFile())
+	})
+
+	s.Run("Failing to insert a note should leave the file as is", func() {
+		s.SetupTest()
+		file := &failingFile{File: s.file}
+		s.store = newStore(file)
+		s.Require().NoError(s.store.Insert(dummyCtx, n))
+
+		file.err = errors.New("sync failed")
+		failed := noteFactory()
+		s.ErrorIs(s.store.Insert(dummyCtx, failed), file.err)
+		_, err := s.store.Get(dummyCtx, failed.ID)
+		s.ErrorIs(err, note.ErrNotFound)
+
+		file.err = nil
+		inserted := noteFactory()
+		s.Require().NoError(s.store.Insert(dummyCtx, inserted))
+		s.Equal([]*note.Note{n, inserted}, s.readAllNotesFromFile())
+	})
+}
+
+// failingFile is a file whose syncs return err when it is set.
+type failingFile struct {
+	File
+	err error
+}
+
+func (f *failingFile) Sync() error {
+	if f.err != nil {
+		return f.err
+	}
+	return f.File.Sync()
 }
 
 func (s *FileStoreTestSuite) TestUpdate() {
@@ -207,6 +253,71 @@ func (s *FileStoreTestSuite) TestFetch() {
 	s.TestSuite.TestFetch()
 }
 
+func (s *FileStoreTestSuite) TestInit() {
+	s.Require().NoError(s.store.Init(dummyCtx))
+
+	file, err := afero.NewMemMapFs().OpenFile("./corrupted_note.pb", os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666)
+	s.Require().NoError(err)
+	_, err = file.Write([]byte{0xff, 0xff, 0xff})
+	s.Require().NoError(err)
+
+	store := newStore(file)
+	s.Error(store.Init(dummyCtx))
+	// The store stays uninitialized after the first failure.
+	s.Error(store.Init(dummyCtx))
+	_, err = store.Get(dummyCtx, uuid.New())
+	s.Error(err)
+	s.NotErrorIs(err, note.ErrNotFound)
+}
+
+func (s *FileStoreTestSuite) TestClose() {
+	s.Require().NoError(s.store.Insert(dummyCtx, noteFactory()))
+	s.Require().NoError(s.store.Close())
+
+	s.Error(s.store.Insert(dummyCtx, noteFactory()))
+}
+
+func (s *FileStoreTestSuite) TestStats() {
+	stats, err := s.store.Stats()
+	s.Require().NoError(err)
+	s.Equal(Stats{}, stats)
+
+	s.Require().NoError(s.store.Insert(dummyCtx, noteFactory()))
+	s.Require().NoError(s.store.Insert(dummyCtx, noteFactory()))
+
+	info, err := s.file.Stat()
+	s.Require().NoError(err)
+
+	stats, err = s.store.Stats()
+	s.Require().NoError(err)
+	s.Equal(2, stats.Notes)
+	s.Equal(info.Size(), stats.FileSize)
+	s.NotZero(stats.FileSize)
+}
+
+func BenchmarkFetch(b *testing.B) {
+	storetest.BenchmarkFetch(b, func(b *testing.B, notes []*note.Note) note.Store {
+		file, err := afero.NewMemMapFs().OpenFile("./bench_note.pb", os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		err = protoutil.WriteAllProtoMessages(
+			file,
+			protoutil.ConvertToProtoMessage(protoutil.ConvertNotesToProtos(notes))...,
+		)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		store := newStore(file)
+		if err := store.lazyInit(context.TODO()); err != nil {
+			b.Fatal(err)
+		}
+		return store
+	})
+}
+
 func (s *FileStoreTestSuite) writeNotesToFile(notes ...*note.Note) {
 	err := protoutil.WriteAllProtoMessages(
 		s.file,
@@ -0,0 +1,348 @@
+package index
+
+import (
+	"bytes"
+	"hash/fnv"
+	"noteapp/note"
+)
+
+// Index keeps the notes sorted by every note.SortBy so that a store
+// can serve a page without copying and sorting all of its notes.
+//
+// The sorted trees are persistent: every write creates new nodes along
+// the modified path and leaves the previous version untouched. This makes
+// taking a Snapshot a constant time operation and lets an iterator read
+// from it lazily while the store keeps on accepting writes.
+//
+// Besides the trees of all the notes, every sort has a tree ordered by
+// the owner first so that the notes of an owner are next to each other
+// and a page of them can be found in logarithmic time as well. The nodes
+// also keep the total size of their subtree so that the storage used by
+// an owner is counted in logarithmic time.
+//
+// Index is not safe for concurrent use. The store is responsible for
+// guarding the writes. The notes given to the index must not be mutated
+// afterwards, the store should replace them with a new copy instead.
+type Index struct {
+	trees
+}
+
+// trees are the roots of the sorted trees.
+type trees struct {
+	byID          *node
+	byTitle       *node
+	byCreatedTime *node
+
+	byOwnerID          *node
+	byOwnerTitle       *node
+	byOwnerCreatedTime *node
+}
+
+// New returns an index containing notes.
+func New(notes ...*note.Note) *Index {
+	idx := new(Index)
+	for _, n := range notes {
+		idx.Insert(n)
+	}
+	return idx
+}
+
+// Insert adds n to the index.
+func (idx *Index) Insert(n *note.Note) {
+	nd := &node{note: n, priority: priority(n), size: 1, bytes: n.Size()}
+	idx.byID = insert(idx.byID, nd, lessByID)
+	idx.byTitle = insert(idx.byTitle, nd, lessByTitle)
+	idx.byCreatedTime = insert(idx.byCreatedTime, nd, lessByCreatedTime)
+	idx.byOwnerID = insert(idx.byOwnerID, nd, lessByOwnerID)
+	idx.byOwnerTitle = insert(idx.byOwnerTitle, nd, lessByOwnerTitle)
+	idx.byOwnerCreatedTime = insert(idx.byOwnerCreatedTime, nd, lessByOwnerCreatedTime)
+}
+
+// Delete removes n from the index. The n must be the same note,
+// or hold the same sort keys, that was given during the Insert.
+func (idx *Index) Delete(n *note.Note) {
+	idx.byID = remove(idx.byID, n, lessByID)
+	idx.byTitle = remove(idx.byTitle, n, lessByTitle)
+	idx.byCreatedTime = remove(idx.byCreatedTime, n, lessByCreatedTime)
+	idx.byOwnerID = remove(idx.byOwnerID, n, lessByOwnerID)
+	idx.byOwnerTitle = remove(idx.byOwnerTitle, n, lessByOwnerTitle)
+	idx.byOwnerCreatedTime = remove(idx.byOwnerCreatedTime, n, lessByOwnerCreatedTime)
+}
+
+// Len returns the number of notes in the index.
+func (idx *Index) Len() int {
+	return idx.byID.len()
+}
+
+// Snapshot returns the current version of the index. The snapshot
+// will not see any write that happens after it was taken.
+func (idx *Index) Snapshot() Snapshot {
+	return Snapshot{idx.trees}
+}
+
+// Snapshot is a read-only version of the index. It is safe
+// for concurrent use.
+type Snapshot struct {
+	trees
+}
+
+// Len returns the number of notes in the snapshot.
+func (s Snapshot) Len() int {
+	return s.byID.len()
+}
+
+// Range returns a cursor that walks at most limit notes sorted by
+// sortBy, starting from the note at the offset position. An unknown
+// sortBy will use the note.SortByID.
+func (s Snapshot) Range(sortBy note.SortBy, offset, limit int) *Cursor {
+	root := s.byID
+	switch sortBy {
+	case note.SortByTitle:
+		root = s.byTitle
+	case note.SortByCreatedTime:
+		root = s.byCreatedTime
+	}
+
+	return newCursor(root, offset, limit)
+}
+
+// OwnerLen returns the number of notes of the owner in the snapshot.
+func (s Snapshot) OwnerLen(ownerID string) int {
+	return rank(s.byOwnerID, func(n *note.Note) bool { return n.OwnerID <= ownerID }) -
+		rank(s.byOwnerID, func(n *note.Note) bool { return n.OwnerID < ownerID })
+}
+
+// OwnerUsage returns the number and the total size of the
+// notes of the owner in the snapshot.
+func (s Snapshot) OwnerUsage(ownerID string) note.Usage {
+	count, bytes := rankBytes(s.byOwnerID, func(n *note.Note) bool { return n.OwnerID <= ownerID })
+	beforeCount, beforeBytes := rankBytes(s.byOwnerID, func(n *note.Note) bool { return n.OwnerID < ownerID })
+	return note.Usage{Notes: count - beforeCount, Bytes: bytes - beforeBytes}
+}
+
+// OwnerRange is like the Range but only walks the notes of the owner.
+func (s Snapshot) OwnerRange(ownerID string, sortBy note.SortBy, offset, limit int) *Cursor {
+	root := s.byOwnerID
+	switch sortBy {
+	case note.SortByTitle:
+		root = s.byOwnerTitle
+	case note.SortByCreatedTime:
+		root = s.byOwnerCreatedTime
+	}
+
+	start := rank(root, func(n *note.Note) bool { return n.OwnerID < ownerID })
+	if remaining := s.OwnerLen(ownerID) - offset; remaining < limit {
+		limit = remaining
+	}
+
+	return newCursor(root, start+offset, limit)
+}
+
+// newCursor returns a cursor that walks at most limit notes of
+// the tree, starting from the note at the offset position.
+func newCursor(root *node, offset, limit int) *Cursor {
+	c := &Cursor{remaining: limit}
+
+	// Walk down to the note at offset position and keep the path
+	// of the nodes that will come next in order.
+	nd := root
+	for nd != nil {
+		leftSize := nd.left.len()
+		switch {
+		case offset < leftSize:
+			c.stack = append(c.stack, nd)
+			nd = nd.left
+		case offset == leftSize:
+			c.stack = append(c.stack, nd)
+			nd = nil
+		default:
+			offset -= leftSize + 1
+			nd = nd.right
+		}
+	}
+
+	return c
+}
+
+// Cursor walks a range of the sorted notes in the snapshot.
+type Cursor struct {
+	stack     []*node
+	remaining int
+}
+
+// Next returns the next note in the range. It returns false
+// if there are no more notes.
+func (c *Cursor) Next() (*note.Note, bool) {
+	if c.remaining <= 0 || len(c.stack) == 0 {
+		return nil, false
+	}
+
+	nd := c.stack[len(c.stack)-1]
+	c.stack = c.stack[:len(c.stack)-1]
+	for child := nd.right; child != nil; child = child.left {
+		c.stack = append(c.stack, child)
+	}
+
+	c.remaining--
+	return nd.note, true
+}
+
+type lessFunc func(a, b *note.Note) bool
+
+func lessByID(a, b *note.Note) bool {
+	return bytes.Compare(a.ID[:], b.ID[:]) < 0
+}
+
+func lessByTitle(a, b *note.Note) bool {
+	if a.GetTitle() != b.GetTitle() {
+		return a.GetTitle() < b.GetTitle()
+	}
+	return lessByID(a, b)
+}
+
+func lessByCreatedTime(a, b *note.Note) bool {
+	if !a.GetCreatedTime().Equal(b.GetCreatedTime()) {
+		return a.GetCreatedTime().Before(b.GetCreatedTime())
+	}
+	return lessByID(a, b)
+}
+
+func lessByOwnerID(a, b *note.Note) bool {
+	if a.OwnerID != b.OwnerID {
+		return a.OwnerID < b.OwnerID
+	}
+	return lessByID(a, b)
+}
+
+func lessByOwnerTitle(a, b *note.Note) bool {
+	if a.OwnerID != b.OwnerID {
+		return a.OwnerID < b.OwnerID
+	}
+	return lessByTitle(a, b)
+}
+
+func lessByOwnerCreatedTime(a, b *note.Note) bool {
+	if a.OwnerID != b.OwnerID {
+		return a.OwnerID < b.OwnerID
+	}
+	return lessByCreatedTime(a, b)
+}
+
+// node is a node of an immutable treap ordered by the less function
+// and augmented with the size and the total note size of its subtree.
+type node struct {
+	note        *note.Note
+	priority    uint64
+	size        int
+	bytes       int64
+	left, right *node
+}
+
+func (nd *node) len() int {
+	if nd == nil {
+		return 0
+	}
+	return nd.size
+}
+
+func (nd *node) totalBytes() int64 {
+	if nd == nil {
+		return 0
+	}
+	return nd.bytes
+}
+
+func (nd *node) withChildren(left, right *node) *node {
+	return &node{
+		note:     nd.note,
+		priority: nd.priority,
+		size:     left.len() + right.len() + 1,
+		bytes:    left.totalBytes() + right.totalBytes() + nd.note.Size(),
+		left:     left,
+		right:    right,
+	}
+}
+
+// priority derives the treap priority from the note id so that
+// the shape of the tree does not depend on the insertion order.
+func priority(n *note.Note) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write(n.ID[:])
+	return h.Sum64()
+}
+
+func insert(root, nd *node, less lessFunc) *node {
+	left, right := split(root, func(n *note.Note) bool { return less(n, nd.note) })
+	return merge(merge(left, nd.withChildren(nil, nil)), right)
+}
+
+func remove(root *node, n *note.Note, less lessFunc) *node {
+	left, right := split(root, func(x *note.Note) bool { return less(x, n) })
+	_, right = split(right, func(x *note.Note) bool { return !less(n, x) })
+	return merge(left, right)
+}
+
+// rank returns the number of the nodes where before is true. The
+// before must hold for a prefix of the ordered nodes.
+func rank(nd *node, before func(n *note.Note) bool) int {
+	var count int
+	for nd != nil {
+		if before(nd.note) {
+			count += nd.left.len() + 1
+			nd = nd.right
+		} else {
+			nd = nd.left
+		}
+	}
+	return count
+}
+
+// rankBytes is like the rank but also returns the
+// total note size of the nodes where before is true.
+func rankBytes(nd *node, before func(n *note.Note) bool) (int, int64) {
+	var count int
+	var bytes int64
+	for nd != nil {
+		if before(nd.note) {
+			count += nd.left.len() + 1
+			bytes += nd.left.totalBytes() + nd.note.Size()
+			nd = nd.right
+		} else {
+			nd = nd.left
+		}
+	}
+	return count, bytes
+}
+
+// split splits the tree into the nodes where goLeft is true and the
+// rest. The goLeft must hold for a prefix of the ordered nodes.
+func split(nd *node, goLeft func(n *note.Note) bool) (left, right *node) {
+	if nd == nil {
+		return nil, nil
+	}
+
+	if goLeft(nd.note) {
+		l, r := split(nd.right, goLeft)
+		return nd.withChildren(nd.left, l), r
+	}
+
+	l, r := split(nd.left, goLeft)
+	return l, nd.withChildren(r, nd.right)
+}
+
+// merge joins two trees where all nodes of a are ordered before b.
+func merge(a, b *node) *node {
+	if a == nil {
+		return b
+	}
+
+	if b == nil {
+		return a
+	}
+
+	if a.priority > b.priority {
+		return a.withChildren(a.left, merge(a.right, b))
+	}
+
+	return b.withChildren(merge(a, b.left), b.right)
+}
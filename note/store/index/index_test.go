@@ -0,0 +1,158 @@
+package index
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"noteapp/note"
+	"sort"
+	"testing"
+	"time"
+)
+
+func Test(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+type TestSuite struct {
+	suite.Suite
+}
+
+func (s *TestSuite) TestRange() {
+	notes := noteFactory(50)
+	idx := New(notes...)
+
+	table := []struct {
+		sortBy note.SortBy
+		sorter func([]*note.Note) sort.Interface
+	}{
+		{note.SortByID, func(n []*note.Note) sort.Interface { return note.SortByIDSorter(n) }},
+		{note.SortByTitle, func(n []*note.Note) sort.Interface { return note.SortByTitleSorter(n) }},
+		{note.SortByCreatedTime, func(n []*note.Note) sort.Interface { return note.SortByCreatedDateSorter(n) }},
+	}
+
+	for _, row := range table {
+		s.Run(string(row.sortBy), func() {
+			want := append([]*note.Note(nil), notes...)
+			sort.Sort(row.sorter(want))
+
+			s.Equal(want, drain(idx.Snapshot().Range(row.sortBy, 0, len(want))))
+			s.Equal(want[10:20], drain(idx.Snapshot().Range(row.sortBy, 10, 10)))
+			s.Equal(want[45:], drain(idx.Snapshot().Range(row.sortBy, 45, 10)))
+			s.Empty(drain(idx.Snapshot().Range(row.sortBy, 50, 10)))
+		})
+	}
+}
+
+func (s *TestSuite) TestOwnerRange() {
+	notes := noteFactory(30)
+	owners := []string{"alice", "bob", ""}
+	for i, n := range notes {
+		n.SetOwnerID(owners[i%len(owners)])
+	}
+	idx := New(notes...)
+
+	for _, owner := range owners {
+		s.Run(owner, func() {
+			var want []*note.Note
+			for _, n := range notes {
+				if n.OwnerID == owner {
+					want = append(want, n)
+				}
+			}
+			sort.Sort(note.SortByTitleSorter(want))
+
+			s.Equal(len(want), idx.Snapshot().OwnerLen(owner))
+			s.Equal(want, drain(idx.Snapshot().OwnerRange(owner, note.SortByTitle, 0, 30)))
+			s.Equal(want[2:5], drain(idx.Snapshot().OwnerRange(owner, note.SortByTitle, 2, 3)))
+			s.Empty(drain(idx.Snapshot().OwnerRange(owner, note.SortByTitle, 10, 10)))
+		})
+	}
+
+	s.Zero(idx.Snapshot().OwnerLen("carol"))
+	s.Empty(drain(idx.Snapshot().OwnerRange("carol", note.SortByID, 0, 10)))
+
+	for _, n := range notes[:3] {
+		idx.Delete(n)
+	}
+	s.Equal(9, idx.Snapshot().OwnerLen("alice"))
+}
+
+func (s *TestSuite) TestOwnerUsage() {
+	notes := noteFactory(30)
+	owners := []string{"alice", "bob", ""}
+	for i, n := range notes {
+		n.SetOwnerID(owners[i%len(owners)])
+	}
+	idx := New(notes...)
+
+	usage := func(owner string) note.Usage {
+		var want note.Usage
+		for _, n := range notes {
+			if n.OwnerID == owner {
+				want.Notes++
+				want.Bytes += n.Size()
+			}
+		}
+		return want
+	}
+
+	for _, owner := range owners {
+		s.Equal(usage(owner), idx.Snapshot().OwnerUsage(owner), owner)
+	}
+	s.Zero(idx.Snapshot().OwnerUsage("carol"))
+
+	idx.Delete(notes[0])
+	notes = notes[1:]
+	s.Equal(usage("alice"), idx.Snapshot().OwnerUsage("alice"))
+}
+
+func (s *TestSuite) TestDelete() {
+	notes := noteFactory(20)
+	idx := New(notes...)
+
+	for _, n := range notes[:10] {
+		idx.Delete(n)
+	}
+
+	s.Equal(10, idx.Len())
+	want := append([]*note.Note(nil), notes[10:]...)
+	sort.Sort(note.SortByTitleSorter(want))
+	s.Equal(want, drain(idx.Snapshot().Range(note.SortByTitle, 0, 20)))
+}
+
+func (s *TestSuite) TestSnapshotIsNotAffectedByWrites() {
+	notes := noteFactory(10)
+	idx := New(notes...)
+	snapshot := idx.Snapshot()
+
+	idx.Delete(notes[0])
+	idx.Insert(noteFactory(1)[0])
+	idx.Insert(noteFactory(1)[0])
+
+	s.Equal(11, idx.Len())
+	s.Equal(10, snapshot.Len())
+
+	want := append([]*note.Note(nil), notes...)
+	sort.Sort(note.SortByIDSorter(want))
+	s.Equal(want, drain(snapshot.Range(note.SortByID, 0, 10)))
+}
+
+func drain(c *Cursor) (notes []*note.Note) {
+	for n, ok := c.Next(); ok; n, ok = c.Next() {
+		notes = append(notes, n)
+	}
+	return
+}
+
+func noteFactory(size int) (notes []*note.Note) {
+	createdTime := time.Now().UTC()
+	for i := 0; i < size; i++ {
+		n := new(note.Note).
+			SetID(uuid.New()).
+			SetTitle(fmt.Sprintf("Title-%03d", (i*7)%size)).
+			SetCreatedTime(createdTime.Add(time.Duration((i*13)%size) * time.Second))
+		notes = append(notes, n)
+	}
+	return
+}
@@ -0,0 +1,72 @@
+package instrumenting
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kit/kit/metrics"
+	"github.com/google/uuid"
+	"noteapp/note"
+	"time"
+)
+
+var _ note.Store = (*Store)(nil)
+
+// New returns a store which counts the calls of the next store and
+// observes their latency in seconds. Both are labeled with the
+// "method" and whether it returned an "error".
+func New(next note.Store, requestCount metrics.Counter, requestLatency metrics.Histogram) *Store {
+	return &Store{
+		next:           next,
+		requestCount:   requestCount,
+		requestLatency: requestLatency,
+	}
+}
+
+// Store implements the note.Store interface by instrumenting
+// another store.
+type Store struct {
+	next           note.Store
+	requestCount   metrics.Counter
+	requestLatency metrics.Histogram
+}
+
+func (s *Store) observe(method string, begin time.Time, err error) {
+	labels := []string{"method", method, "error", fmt.Sprint(err != nil)}
+	s.requestCount.With(labels...).Add(1)
+	s.requestLatency.With(labels...).Observe(time.Since(begin).Seconds())
+}
+
+func (s *Store) Insert(ctx context.Context, n *note.Note) (err error) {
+	defer func(begin time.Time) { s.observe("Insert", begin, err) }(time.Now())
+	return s.next.Insert(ctx, n)
+}
+
+func (s *Store) Update(ctx context.Context, n *note.Note) (updated *note.Note, err error) {
+	defer func(begin time.Time) { s.observe("Update", begin, err) }(time.Now())
+	return s.next.Update(ctx, n)
+}
+
+func (s *Store) Delete(ctx context.Context, id uuid.UUID) (err error) {
+	defer func(begin time.Time) { s.observe("Delete", begin, err) }(time.Now())
+	return s.next.Delete(ctx, id)
+}
+
+func (s *Store) Get(ctx context.Context, id uuid.UUID) (n *note.Note, err error) {
+	defer func(begin time.Time) { s.observe("Get", begin, err) }(time.Now())
+	return s.next.Get(ctx, id)
+}
+
+func (s *Store) Fetch(ctx context.Context, p *note.Pagination) (iter note.Iterator, err error) {
+	defer func(begin time.Time) { s.observe("Fetch", begin, err) }(time.Now())
+	return s.next.Fetch(ctx, p)
+}
+
+func (s *Store) Export(ctx context.Context) (iter note.Iterator, err error) {
+	defer func(begin time.Time) { s.observe("Export", begin, err) }(time.Now())
+	return s.next.Export(ctx)
+}
+
+func (s *Store) Usage(ctx context.Context, ownerID string) (usage note.Usage, err error) {
+	defer func(begin time.Time) { s.observe("Usage", begin, err) }(time.Now())
+	return s.next.Usage(ctx, ownerID)
+}
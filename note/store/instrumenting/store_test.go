@@ -0,0 +1,53 @@
+package instrumenting
+
+import (
+	"context"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+	"noteapp/note"
+	"noteapp/note/store/memory"
+	"noteapp/note/store/storetest"
+	"testing"
+)
+
+func Test(t *testing.T) {
+	suite.Run(t, new(InstrumentingStoreTestSuite))
+}
+
+type InstrumentingStoreTestSuite struct {
+	storetest.TestSuite
+	count   *prometheus.CounterVec
+	latency *prometheus.HistogramVec
+	store   *Store
+}
+
+func (s *InstrumentingStoreTestSuite) SetupTest() {
+	s.count = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "count"}, []string{"method", "error"})
+	s.latency = prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "latency"}, []string{"method", "error"})
+	s.store = New(memory.New(), kitprometheus.NewCounter(s.count), kitprometheus.NewHistogram(s.latency))
+	s.SetStore(s.store)
+}
+
+func (s *InstrumentingStoreTestSuite) TestFetch() {
+	s.TestSuite.TestFetch()
+}
+
+func (s *InstrumentingStoreTestSuite) TestMetrics() {
+	ctx := context.TODO()
+	n := new(note.Note).SetTitle("Instrumented")
+	n.ID = uuid.New()
+
+	s.Require().NoError(s.store.Insert(ctx, n))
+	_, err := s.store.Get(ctx, n.ID)
+	s.Require().NoError(err)
+	_, err = s.store.Get(ctx, uuid.New())
+	s.Require().ErrorIs(err, note.ErrNotFound)
+
+	s.Equal(1.0, testutil.ToFloat64(s.count.WithLabelValues("Insert", "false")))
+	s.Equal(1.0, testutil.ToFloat64(s.count.WithLabelValues("Get", "false")))
+	s.Equal(1.0, testutil.ToFloat64(s.count.WithLabelValues("Get", "true")))
+	s.Equal(3, testutil.CollectAndCount(s.latency))
+}
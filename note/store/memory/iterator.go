@@ -1,18 +1,16 @@
 package memory
 
 import (
-	"github.com/sirupsen/logrus"
 	"noteapp/note"
 	"noteapp/note/noteutil"
+	"noteapp/note/store/index"
 )
 
 var _ note.Iterator = (*iterator)(nil)
 
 type iterator struct {
-	s *Store
-
-	notes      []*note.Note
-	curIndex   int
+	cursor     *index.Cursor
+	current    *note.Note
 	totalCount int
 	totalPage  int
 }
@@ -29,11 +27,13 @@ func (i *iterator) Close() error {
 
 // Next implements note.Iterator
 func (i *iterator) Next() bool {
-	if i.curIndex >= len(i.notes) {
+	n, ok := i.cursor.Next()
+	if !ok {
+		i.current = nil
 		return false
 	}
 
-	i.curIndex++
+	i.current = n
 	return true
 }
 
@@ -43,13 +43,9 @@ func (i *iterator) Error() error {
 }
 
 func (i *iterator) Note() *note.Note {
-	// The note pointer contents may be overwritten by a note update;
-	// to avoid data-races we acquire the read lock first and clone the link
-	i.s.mu.RLock()
-	defer i.s.mu.RUnlock()
-	logrus.Debug(len(i.notes), i.curIndex)
-	n := i.notes[i.curIndex-1]
-	return noteutil.Copy(n)
+	// The indexed notes are never mutated by the store but
+	// the caller may do, so hand out a copy.
+	return noteutil.Copy(i.current)
 }
 
 func (i *iterator) TotalCount() uint64 {
@@ -3,10 +3,10 @@ package memory
 import (
 	"context"
 	"github.com/google/uuid"
-	"github.com/sirupsen/logrus"
 	"noteapp/note"
 	"noteapp/note/noteutil"
-	"sort"
+	"noteapp/note/store/index"
+	"noteapp/pkg/logging"
 	"sync"
 )
 
@@ -17,6 +17,9 @@ var _ note.Store = (*Store)(nil)
 type Store struct {
 	mu   sync.RWMutex
 	data map[uuid.UUID]*note.Note
+
+	// index keeps the notes sorted for the Fetch.
+	index *index.Index
 }
 
 // Fetch fetches the notes in the store using the pagination setting
@@ -43,43 +46,20 @@ func (s *Store) Fetch(ctx context.Context, p *note.Pagination) (note.Iterator, e
 		default:
 		}
 
-		start := (p.Page - 1) * p.Size
-		stop := start + p.Size
-
 		s.mu.RLock()
-		defer s.mu.RUnlock()
-		if int(start) > len(s.data) {
-			iterChan <- nil
-			return
-		}
-
-		// Get the all the notes in array.
-		var notes []*note.Note
-		for _, n := range s.data {
-			notes = append(notes, n)
-		}
+		snapshot := s.index.Snapshot()
+		s.mu.RUnlock()
 
-		// Sort by ID
-		switch p.SortBy {
-		case note.SortByID:
-			sort.Sort(note.SortByIDSorter(notes))
-		case note.SortByTitle:
-			sort.Sort(note.SortByTitleSorter(notes))
-		case note.SortByCreatedTime:
-			sort.Sort(note.SortByCreatedDateSorter(notes))
-		default:
-			sort.Sort(note.SortByIDSorter(notes))
-		}
-
-		if noteSize := uint64(len(notes)); stop > noteSize {
-			stop = noteSize
+		offset, limit := int((p.Page-1)*p.Size), int(p.Size)
+		cursor, totalCount := snapshot.Range(p.SortBy, offset, limit), snapshot.Len()
+		if p.OwnerID != "" {
+			cursor, totalCount = snapshot.OwnerRange(p.OwnerID, p.SortBy, offset, limit), snapshot.OwnerLen(p.OwnerID)
 		}
 
 		iter := &iterator{
-			s:          s,
-			notes:      notes[start:stop],
-			totalCount: len(notes),
-			totalPage:  len(notes) / int(p.Size),
+			cursor:     cursor,
+			totalCount: totalCount,
+			totalPage:  totalCount / int(p.Size),
 		}
 		iterChan <- iter
 	}()
@@ -92,10 +72,53 @@ func (s *Store) Fetch(ctx context.Context, p *note.Pagination) (note.Iterator, e
 	}
 }
 
+// Usage returns the number and the total size of the notes of the
+// owner with an id. They are counted by the index of the store.
+func (s *Store) Usage(ctx context.Context, ownerID string) (note.Usage, error) {
+	select {
+	case <-ctx.Done():
+		return note.Usage{}, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	snapshot := s.index.Snapshot()
+	s.mu.RUnlock()
+
+	return snapshot.OwnerUsage(ownerID), nil
+}
+
+// Export returns an iterator of all the notes in the store sorted
+// by ID. The iterator reads from a snapshot of the store so writes made
+// during the iteration won't be visible.
+func (s *Store) Export(ctx context.Context) (note.Iterator, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	snapshot := s.index.Snapshot()
+	s.mu.RUnlock()
+
+	iter := &iterator{
+		cursor:     snapshot.Range(note.SortByID, 0, snapshot.Len()),
+		totalCount: snapshot.Len(),
+	}
+
+	if snapshot.Len() > 0 {
+		iter.totalPage = 1
+	}
+
+	return iter, nil
+}
+
 // New return a new instance of store.
 func New() *Store {
 	return &Store{
-		data: make(map[uuid.UUID]*note.Note),
+		data:  make(map[uuid.UUID]*note.Note),
+		index: index.New(),
 	}
 }
 
@@ -139,6 +162,7 @@ func (s *Store) Insert(ctx context.Context, n *note.Note) error {
 
 		cpyNote := noteutil.Copy(n)
 		s.data[n.ID] = cpyNote
+		s.index.Insert(cpyNote)
 		doneChan <- struct{}{}
 	}()
 
@@ -183,20 +207,27 @@ func (s *Store) Update(ctx context.Context, n *note.Note) (*note.Note, error) {
 			return
 		}
 
+		// The existing note may be referenced by a fetch
+		// snapshot so merge the changes into a new copy.
+		updated := noteutil.Copy(exist)
+
 		// I think there's a bug with copier
 		// because the UpdateTime is not copied
 		// to the toValue
-		err := noteutil.Merge(exist, n)
+		err := noteutil.Merge(updated, n)
 		if err != nil {
 			errChan <- err
 			return
 		}
 
 		// Workaround 💪😅
-		exist.UpdatedTime = n.UpdatedTime
+		updated.UpdatedTime = n.UpdatedTime
 
-		logrus.Debug(exist.UpdatedTime)
-		noteChan <- noteutil.Copy(exist)
+		logging.Logger(ctx).WithField("updated_time", updated.UpdatedTime).Debug("note updated")
+		s.data[n.ID] = updated
+		s.index.Delete(exist)
+		s.index.Insert(updated)
+		noteChan <- noteutil.Copy(updated)
 	}()
 
 	select {
@@ -232,7 +263,10 @@ func (s *Store) Delete(ctx context.Context, id uuid.UUID) error {
 
 		s.mu.Lock()
 		defer s.mu.Unlock()
-		delete(s.data, id)
+		if exist, found := s.data[id]; found {
+			delete(s.data, id)
+			s.index.Delete(exist)
+		}
 
 		doneChan <- struct{}{}
 	}()
@@ -247,8 +281,8 @@ func (s *Store) Delete(ctx context.Context, id uuid.UUID) error {
 
 // Get gets the existing note with id from the store. It takes ctx
 // context in order to let the caller stop the execution in any form.
-// It will return either a note or an error if encountered. If there's
-// an error it can be a ErrNotFound or ErrCancelled.
+// It will return either a copy of the note or an error if encountered.
+// If there's an error it can be a ErrNotFound or ErrCancelled.
 func (s *Store) Get(ctx context.Context, id uuid.UUID) (*note.Note, error) {
 
 	var (
@@ -277,7 +311,9 @@ func (s *Store) Get(ctx context.Context, id uuid.UUID) (*note.Note, error) {
 			return
 		}
 
-		noteChan <- n
+		// The stored note is shared with the index, so
+		// hand out a copy the caller can mutate.
+		noteChan <- noteutil.Copy(n)
 	}()
 
 	select {
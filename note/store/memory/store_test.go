@@ -1,7 +1,9 @@
 package memory
 
 import (
+	"context"
 	"github.com/stretchr/testify/suite"
+	"noteapp/note"
 	"noteapp/note/store/storetest"
 	"testing"
 )
@@ -21,3 +23,15 @@ func (m *MemoryStoreTestSuite) SetupTest() {
 func (m *MemoryStoreTestSuite) TestFetch() {
 	m.TestSuite.TestFetch()
 }
+
+func BenchmarkFetch(b *testing.B) {
+	storetest.BenchmarkFetch(b, func(b *testing.B, notes []*note.Note) note.Store {
+		store := New()
+		for _, n := range notes {
+			if err := store.Insert(context.TODO(), n); err != nil {
+				b.Fatal(err)
+			}
+		}
+		return store
+	})
+}
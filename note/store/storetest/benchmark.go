@@ -0,0 +1,76 @@
+package storetest
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"noteapp/note"
+	"noteapp/pkg/ptrconv"
+	"testing"
+	"time"
+)
+
+// StoreFactory takes the initial notes and returns a store
+// containing them.
+type StoreFactory func(b *testing.B, notes []*note.Note) note.Store
+
+// BenchmarkFetch benchmarks the store Fetch using a fixed page size
+// against a growing number of notes. A store with sorted indexes should
+// report roughly the same cost for each of the note count.
+func BenchmarkFetch(b *testing.B, factory StoreFactory) {
+	const pageSize = 25
+
+	sorts := []note.SortBy{
+		note.SortByID,
+		note.SortByTitle,
+		note.SortByCreatedTime,
+	}
+
+	for _, count := range []int{1000, 10000, 100000} {
+		store := factory(b, benchmarkNotes(count))
+
+		for _, sortBy := range sorts {
+			pagination := &note.Pagination{
+				Size:   pageSize,
+				Page:   uint64(count/pageSize) / 2,
+				SortBy: sortBy,
+			}
+
+			b.Run(fmt.Sprintf("notes=%d/sort=%s", count, sortBy), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					iter, err := store.Fetch(dummyCtx, pagination)
+					if err != nil {
+						b.Fatal(err)
+					}
+
+					var got int
+					for iter.Next() {
+						_ = iter.Note()
+						got++
+					}
+
+					if got != pageSize {
+						b.Fatalf("expecting %d notes but got %d", pageSize, got)
+					}
+
+					_ = iter.Close()
+				}
+			})
+		}
+	}
+}
+
+func benchmarkNotes(count int) []*note.Note {
+	createdTime := time.Now().UTC()
+	notes := make([]*note.Note, 0, count)
+	for i := 0; i < count; i++ {
+		notes = append(notes, &note.Note{
+			ID:          uuid.New(),
+			Title:       ptrconv.StringPointer(fmt.Sprintf("Benchmark-%d", i)),
+			Content:     ptrconv.StringPointer(fmt.Sprintf("Lorem Ipsum-%d", i)),
+			CreatedTime: ptrconv.TimePointer(createdTime.Add(time.Duration(i) * time.Second)),
+			IsFavorite:  ptrconv.BoolPointer(false),
+		})
+	}
+	return notes
+}
@@ -86,6 +86,17 @@ func (s *TestSuite) TestGet() {
 		s.Equal(copyNote, got)
 	})
 
+	s.Run("Changing the returned note should not change the stored note", func() {
+		want := s.setupFunc()
+		got, err := s.store.Get(dummyCtx, want.ID)
+		s.Require().NoError(err)
+		got.SetTitle("Changed")
+
+		got, err = s.store.Get(dummyCtx, want.ID)
+		s.Require().NoError(err)
+		s.Equal(want, got)
+	})
+
 	s.Run("Getting an non-existing note should return an notes.ErrNotFound", func() {
 		got, err := s.store.Get(dummyCtx, uuid.New())
 		s.Error(err)
@@ -243,6 +254,30 @@ func (s *TestSuite) TestFetch() {
 		}
 	})
 
+	s.Run("Fetching the notes of an owner", func() {
+		owner, other := uuid.NewString(), uuid.NewString()
+
+		var want []*note.Note
+		for i := 0; i < 6; i++ {
+			n := noteutil.Copy(dummyNote).SetID(uuid.New())
+			if i%2 == 0 {
+				n.SetOwnerID(owner)
+				want = append(want, n)
+			} else {
+				n.SetOwnerID(other)
+			}
+			s.Require().NoError(s.store.Insert(dummyCtx, n))
+		}
+		sort.Sort(note.SortByIDSorter(want))
+
+		iter := fetch(&note.Pagination{Size: 2, Page: 1, SortBy: note.SortByID, OwnerID: owner})
+		s.Equal(uint64(len(want)), iter.TotalCount())
+		s.Equal(want[:2], drainIterator(iter))
+
+		iter = fetch(&note.Pagination{Size: 2, Page: 2, SortBy: note.SortByID, OwnerID: owner})
+		s.Equal(want[2:], drainIterator(iter))
+	})
+
 	s.Run("Calling context cancel should return an notes.ErrCancelled", func() {
 		ctx, cancel := context.WithCancel(dummyCtx)
 		cancel()
@@ -259,6 +294,93 @@ func (s *TestSuite) TestFetch() {
 	})
 }
 
+// TestExport tests the store export method.
+func (s *TestSuite) TestExport() {
+	s.Run("Exporting should return all the notes sorted by ID", func() {
+		var want []*note.Note
+		for i := 0; i < 5; i++ {
+			want = append(want, s.setupFunc())
+		}
+		sort.Sort(note.SortByIDSorter(want))
+
+		iter, err := s.store.Export(dummyCtx)
+		s.Require().NoError(err)
+
+		var got []*note.Note
+		for iter.Next() {
+			got = append(got, iter.Note())
+		}
+		s.NoError(iter.Error())
+		s.NoError(iter.Close())
+
+		s.Equal(uint64(len(got)), iter.TotalCount())
+		s.Subset(got, want)
+		s.True(sort.IsSorted(note.SortByIDSorter(got)))
+	})
+
+	s.Run("Writes during the export should not be visible", func() {
+		first := s.setupFunc()
+
+		iter, err := s.store.Export(dummyCtx)
+		s.Require().NoError(err)
+		total := iter.TotalCount()
+
+		s.setupFunc()
+		s.Require().NoError(s.store.Delete(dummyCtx, first.ID))
+
+		var got []*note.Note
+		for iter.Next() {
+			got = append(got, iter.Note())
+		}
+		s.Require().NoError(iter.Close())
+
+		s.Equal(total, uint64(len(got)))
+		s.Contains(got, first)
+	})
+
+	s.Run("Calling context cancel should return an notes.ErrCancelled", func() {
+		ctx, cancel := context.WithCancel(dummyCtx)
+		cancel()
+
+		_, err := s.store.Export(ctx)
+		s.Error(err)
+		s.Equal(note.ErrCancelled, err)
+	})
+}
+
+// TestUsage test the store usage method.
+func (s *TestSuite) TestUsage() {
+	s.Run("Usage should follow the writes of the owner", func() {
+		ownerID := uuid.New().String()
+
+		first := noteFactory(0).SetOwnerID(ownerID)
+		second := noteFactory(1).SetOwnerID(ownerID)
+		s.Require().NoError(s.store.Insert(dummyCtx, first))
+		s.Require().NoError(s.store.Insert(dummyCtx, second))
+		s.setupFunc()
+
+		usage, err := s.store.Usage(dummyCtx, ownerID)
+		s.Require().NoError(err)
+		s.Equal(note.Usage{Notes: 2, Bytes: first.Size() + second.Size()}, usage)
+
+		updated, err := s.store.Update(dummyCtx, new(note.Note).SetID(first.ID).SetContent("Lorem"))
+		s.Require().NoError(err)
+		s.Require().NoError(s.store.Delete(dummyCtx, second.ID))
+
+		usage, err = s.store.Usage(dummyCtx, ownerID)
+		s.Require().NoError(err)
+		s.Equal(note.Usage{Notes: 1, Bytes: updated.Size()}, usage)
+	})
+
+	s.Run("Calling context cancel should return an notes.ErrCancelled", func() {
+		ctx, cancel := context.WithCancel(dummyCtx)
+		cancel()
+
+		_, err := s.store.Usage(ctx, "owner")
+		s.Equal(note.ErrCancelled, err)
+	})
+}
+
 func (s *TestSuite) setupFunc() *note.Note {
 	n := noteutil.Copy(dummyNote)
 	n.ID = uuid.New()
@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"noteapp/note"
+)
+
+var _ note.Store = (*Store)(nil)
+
+// noteIDAttribute is the span attribute of the id of the note.
+const noteIDAttribute = attribute.Key("note.id")
+
+// New returns a store which starts a span for every call of the
+// next store named after its method, e.g. "note.Store/Insert". The
+// spans record the id of the note, when known, and the returned error.
+func New(next note.Store, tracer trace.Tracer) *Store {
+	return &Store{next: next, tracer: tracer}
+}
+
+// Store implements the note.Store interface by tracing
+// another store.
+type Store struct {
+	next   note.Store
+	tracer trace.Tracer
+}
+
+func (s *Store) start(ctx context.Context, method string, id uuid.UUID) (context.Context, trace.Span) {
+	ctx, span := s.tracer.Start(ctx, "note.Store/"+method, trace.WithSpanKind(trace.SpanKindClient))
+	if id != uuid.Nil {
+		span.SetAttributes(noteIDAttribute.String(id.String()))
+	}
+	return ctx, span
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s *Store) Insert(ctx context.Context, n *note.Note) (err error) {
+	ctx, span := s.start(ctx, "Insert", noteID(n))
+	defer func() { endSpan(span, err) }()
+	return s.next.Insert(ctx, n)
+}
+
+func (s *Store) Update(ctx context.Context, n *note.Note) (updated *note.Note, err error) {
+	ctx, span := s.start(ctx, "Update", noteID(n))
+	defer func() { endSpan(span, err) }()
+	return s.next.Update(ctx, n)
+}
+
+func (s *Store) Delete(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := s.start(ctx, "Delete", id)
+	defer func() { endSpan(span, err) }()
+	return s.next.Delete(ctx, id)
+}
+
+func (s *Store) Get(ctx context.Context, id uuid.UUID) (n *note.Note, err error) {
+	ctx, span := s.start(ctx, "Get", id)
+	defer func() { endSpan(span, err) }()
+	return s.next.Get(ctx, id)
+}
+
+func (s *Store) Fetch(ctx context.Context, p *note.Pagination) (iter note.Iterator, err error) {
+	ctx, span := s.start(ctx, "Fetch", uuid.Nil)
+	defer func() { endSpan(span, err) }()
+	return s.next.Fetch(ctx, p)
+}
+
+func (s *Store) Export(ctx context.Context) (iter note.Iterator, err error) {
+	ctx, span := s.start(ctx, "Export", uuid.Nil)
+	defer func() { endSpan(span, err) }()
+	return s.next.Export(ctx)
+}
+
+func (s *Store) Usage(ctx context.Context, ownerID string) (usage note.Usage, err error) {
+	ctx, span := s.start(ctx, "Usage", uuid.Nil)
+	defer func() { endSpan(span, err) }()
+	return s.next.Usage(ctx, ownerID)
+}
+
+func noteID(n *note.Note) uuid.UUID {
+	if n == nil {
+		return uuid.Nil
+	}
+	return n.ID
+}
@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"noteapp/note"
+	"noteapp/note/store/memory"
+	"noteapp/note/store/storetest"
+	"testing"
+)
+
+func Test(t *testing.T) {
+	suite.Run(t, new(TracingStoreTestSuite))
+}
+
+type TracingStoreTestSuite struct {
+	storetest.TestSuite
+	exporter *tracetest.InMemoryExporter
+	store    *Store
+}
+
+func (s *TracingStoreTestSuite) SetupTest() {
+	s.exporter = tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(s.exporter))
+	s.store = New(memory.New(), provider.Tracer("test"))
+	s.SetStore(s.store)
+}
+
+func (s *TracingStoreTestSuite) TestFetch() {
+	s.TestSuite.TestFetch()
+}
+
+func (s *TracingStoreTestSuite) TestSpans() {
+	ctx := context.TODO()
+	n := new(note.Note).SetTitle("Traced")
+	n.ID = uuid.New()
+
+	s.Require().NoError(s.store.Insert(ctx, n))
+	missing := uuid.New()
+	_, err := s.store.Get(ctx, missing)
+	s.Require().ErrorIs(err, note.ErrNotFound)
+
+	spans := s.exporter.GetSpans()
+	s.Require().Len(spans, 2)
+
+	s.Equal("note.Store/Insert", spans[0].Name)
+	s.Contains(spans[0].Attributes, noteIDAttribute.String(n.ID.String()))
+	s.Equal(codes.Unset, spans[0].Status.Code)
+
+	s.Equal("note.Store/Get", spans[1].Name)
+	s.Contains(spans[1].Attributes, noteIDAttribute.String(missing.String()))
+	s.Equal(codes.Error, spans[1].Status.Code)
+	s.Require().Len(spans[1].Events, 1)
+	s.Equal("exception", spans[1].Events[0].Name)
+}
@@ -0,0 +1,28 @@
+package note
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError is a validation failure of a single field of a note.
+type FieldError struct {
+	// Field is the JSON name of the field.
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is an error when a note doesn't pass the validation
+// rules. It contains a failure per invalid field.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Error returns all the field failures in a single line.
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		messages = append(messages, fmt.Sprintf("%s %s", f.Field, f.Message))
+	}
+	return "note: invalid note: " + strings.Join(messages, "; ")
+}
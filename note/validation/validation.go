@@ -0,0 +1,158 @@
+package validation
+
+import (
+	"fmt"
+	"noteapp/note"
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// FieldTitle is the field name of the note title.
+	FieldTitle = "title"
+	// FieldContent is the field name of the note content.
+	FieldContent = "content"
+	// FieldCreatedTime is the field name of the note created time.
+	FieldCreatedTime = "created_time"
+	// FieldUpdatedTime is the field name of the note updated time.
+	FieldUpdatedTime = "updated_time"
+	// FieldOwnerID is the field name of the note owner id.
+	FieldOwnerID = "owner_id"
+)
+
+// Rules is the configuration of the validation rules.
+type Rules struct {
+	// MaxTitleLength is the maximum number of characters of the
+	// title. Zero means there is no limit.
+	MaxTitleLength int
+	// MaxContentLength is the maximum number of characters of the
+	// content. Zero means there is no limit.
+	MaxContentLength int
+	// RequireTitle rejects the notes without a title.
+	RequireTitle bool
+	// AllowedTitleCharacters is a regular expression character class,
+	// without the brackets, of the characters allowed in the title,
+	// e.g. `\p{L}\p{N} `. When it is empty all the characters except
+	// the control characters are allowed.
+	AllowedTitleCharacters string
+	// AllowedContentCharacters is the same as AllowedTitleCharacters
+	// for the content. When it is empty all the characters except the
+	// control characters other than tabs and line breaks are allowed.
+	AllowedContentCharacters string
+	// RejectServerOwnedFields rejects the notes setting the fields
+	// that only the server sets, such as the timestamps and the owner.
+	RejectServerOwnedFields bool
+}
+
+// Validator checks the notes against the rules.
+type Validator struct {
+	rules          Rules
+	titlePattern   *regexp.Regexp
+	contentPattern *regexp.Regexp
+}
+
+// New compiles the rules and returns a validator.
+func New(rules Rules) (*Validator, error) {
+	v := &Validator{rules: rules}
+
+	var err error
+	if v.titlePattern, err = compileCharacters(rules.AllowedTitleCharacters); err != nil {
+		return nil, fmt.Errorf("validation: allowed title characters: %w", err)
+	}
+
+	if v.contentPattern, err = compileCharacters(rules.AllowedContentCharacters); err != nil {
+		return nil, fmt.Errorf("validation: allowed content characters: %w", err)
+	}
+
+	return v, nil
+}
+
+func compileCharacters(class string) (*regexp.Regexp, error) {
+	if class == "" {
+		return nil, nil
+	}
+	return regexp.Compile(`^[` + class + `]*$`)
+}
+
+// ValidateCreate checks the note n to be created. It returns a
+// *note.ValidationError with all the invalid fields.
+func (v *Validator) ValidateCreate(n *note.Note) error {
+	return v.validate(n, true)
+}
+
+// ValidateUpdate checks the note n with the changes to an existing
+// note. A field that is not set is left unchanged so it is not checked.
+func (v *Validator) ValidateUpdate(n *note.Note) error {
+	return v.validate(n, false)
+}
+
+func (v *Validator) validate(n *note.Note, create bool) error {
+	var fields []note.FieldError
+	add := func(field, format string, args ...interface{}) {
+		fields = append(fields, note.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch {
+	case n.Title == nil && create && v.rules.RequireTitle,
+		n.Title != nil && v.rules.RequireTitle && n.GetTitle() == "":
+		add(FieldTitle, "must not be empty")
+	case n.Title != nil:
+		v.validateText(n.GetTitle(), v.rules.MaxTitleLength, v.titlePattern, isTitleControl, func(format string, args ...interface{}) {
+			add(FieldTitle, format, args...)
+		})
+	}
+
+	if n.Content != nil {
+		v.validateText(n.GetContent(), v.rules.MaxContentLength, v.contentPattern, isContentControl, func(format string, args ...interface{}) {
+			add(FieldContent, format, args...)
+		})
+	}
+
+	if v.rules.RejectServerOwnedFields {
+		if n.CreatedTime != nil {
+			add(FieldCreatedTime, "is set by the server")
+		}
+
+		if n.UpdatedTime != nil {
+			add(FieldUpdatedTime, "is set by the server")
+		}
+
+		if n.OwnerID != "" {
+			add(FieldOwnerID, "is set by the server")
+		}
+	}
+
+	if len(fields) > 0 {
+		return &note.ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+func (v *Validator) validateText(s string, max int, pattern *regexp.Regexp, isControl func(rune) bool, fail func(format string, args ...interface{})) {
+	if max > 0 && utf8.RuneCountInString(s) > max {
+		fail("must be at most %d characters", max)
+	}
+
+	if pattern != nil {
+		if !pattern.MatchString(s) {
+			fail("contains characters that are not allowed")
+		}
+		return
+	}
+
+	for _, r := range s {
+		if isControl(r) {
+			fail("contains control characters")
+			return
+		}
+	}
+}
+
+func isTitleControl(r rune) bool {
+	return unicode.IsControl(r)
+}
+
+func isContentControl(r rune) bool {
+	return unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t'
+}
@@ -0,0 +1,140 @@
+package validation
+
+import (
+	"github.com/stretchr/testify/suite"
+	"noteapp/note"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+type TestSuite struct {
+	suite.Suite
+}
+
+func (s *TestSuite) TestNew() {
+	_, err := New(Rules{AllowedTitleCharacters: `\p{Foo}`})
+	s.Error(err)
+
+	_, err = New(Rules{AllowedContentCharacters: `a-`})
+	s.NoError(err)
+}
+
+func (s *TestSuite) TestValidate() {
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		rules  Rules
+		note   *note.Note
+		update bool
+		want   []note.FieldError
+	}{
+		{
+			name: "Valid note should pass",
+			rules: Rules{
+				MaxTitleLength:   10,
+				MaxContentLength: 20,
+				RequireTitle:     true,
+			},
+			note: new(note.Note).SetTitle("Title").SetContent("Line one\n\tLine two"),
+		},
+		{
+			name:  "Missing title should fail when required",
+			rules: Rules{RequireTitle: true},
+			note:  new(note.Note).SetContent("Content"),
+			want:  []note.FieldError{{Field: FieldTitle, Message: "must not be empty"}},
+		},
+		{
+			name:   "Missing title should pass on update",
+			rules:  Rules{RequireTitle: true},
+			note:   new(note.Note).SetContent("Content"),
+			update: true,
+		},
+		{
+			name:   "Empty title should fail on update when required",
+			rules:  Rules{RequireTitle: true},
+			note:   new(note.Note).SetTitle(""),
+			update: true,
+			want:   []note.FieldError{{Field: FieldTitle, Message: "must not be empty"}},
+		},
+		{
+			name:  "Length should be counted in characters",
+			rules: Rules{MaxTitleLength: 3, MaxContentLength: 3},
+			note:  new(note.Note).SetTitle("界界界").SetContent("éééé"),
+			want:  []note.FieldError{{Field: FieldContent, Message: "must be at most 3 characters"}},
+		},
+		{
+			name: "Control characters should fail by default",
+			note: new(note.Note).SetTitle("Line\nbreak").SetContent("Bell\a"),
+			want: []note.FieldError{
+				{Field: FieldTitle, Message: "contains control characters"},
+				{Field: FieldContent, Message: "contains control characters"},
+			},
+		},
+		{
+			name:  "Characters outside the allowed ones should fail",
+			rules: Rules{AllowedTitleCharacters: `\p{L}\p{N} `, AllowedContentCharacters: `a-z`},
+			note:  new(note.Note).SetTitle("Title 1").SetContent("abc!"),
+			want:  []note.FieldError{{Field: FieldContent, Message: "contains characters that are not allowed"}},
+		},
+		{
+			name:  "Server owned fields should fail when rejected",
+			rules: Rules{RejectServerOwnedFields: true},
+			note:  new(note.Note).SetCreatedTime(now).SetUpdatedTime(now).SetOwnerID("alice"),
+			want: []note.FieldError{
+				{Field: FieldCreatedTime, Message: "is set by the server"},
+				{Field: FieldUpdatedTime, Message: "is set by the server"},
+				{Field: FieldOwnerID, Message: "is set by the server"},
+			},
+		},
+		{
+			name: "Server owned fields should pass by default",
+			note: new(note.Note).SetCreatedTime(now).SetUpdatedTime(now),
+		},
+		{
+			name:  "Every invalid field should be reported",
+			rules: Rules{MaxTitleLength: 1, MaxContentLength: 1},
+			note:  new(note.Note).SetTitle("Title\x00").SetContent(strings.Repeat("a", 2)),
+			want: []note.FieldError{
+				{Field: FieldTitle, Message: "must be at most 1 characters"},
+				{Field: FieldTitle, Message: "contains control characters"},
+				{Field: FieldContent, Message: "must be at most 1 characters"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			v, err := New(tt.rules)
+			s.Require().NoError(err)
+
+			validate := v.ValidateCreate
+			if tt.update {
+				validate = v.ValidateUpdate
+			}
+
+			err = validate(tt.note)
+			if tt.want == nil {
+				s.NoError(err)
+				return
+			}
+
+			var validationErr *note.ValidationError
+			s.Require().ErrorAs(err, &validationErr)
+			s.Equal(tt.want, validationErr.Fields)
+		})
+	}
+}
+
+func (s *TestSuite) TestValidationError() {
+	err := &note.ValidationError{Fields: []note.FieldError{
+		{Field: FieldTitle, Message: "must not be empty"},
+		{Field: FieldContent, Message: "must be at most 1 characters"},
+	}}
+	s.Equal("note: invalid note: title must not be empty; content must be at most 1 characters", err.Error())
+}
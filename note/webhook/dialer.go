@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is an error when a delivery would be sent to an
+// internal address which is not in the allowed networks.
+var ErrForbiddenAddress = errors.New("webhook: forbidden address")
+
+// internalNetworks are the loopback, private, link-local and other
+// special purpose networks the deliveries are not sent to, so that the
+// subscriptions can't reach the services behind the server, e.g. the
+// cloud metadata service at 169.254.169.254.
+var internalNetworks = mustParseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"64:ff9b::/96",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// newClient returns the client of the deliveries which refuses to
+// connect to the internal addresses, but the ones in the allowed
+// networks. The address is checked once resolved, right before the
+// connection, so that a host resolving to an internal address, at
+// first or after a redirect or a DNS rebinding, is refused as well.
+func newClient(allowed []*net.IPNet) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: defaultTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return checkAddress(address, allowed)
+		},
+	}
+
+	return &http.Client{
+		Timeout: defaultTimeout,
+		Transport: &http.Transport{
+			// No proxy, the proxy would connect to the address instead.
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: defaultTimeout,
+		},
+	}
+}
+
+// checkAddress returns an error when the "ip:port" address is an
+// internal address which is not in the allowed networks.
+func checkAddress(address string, allowed []*net.IPNet) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("webhook: address '%s': %w", address, ErrForbiddenAddress)
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("webhook: address '%s': %w", address, ErrForbiddenAddress)
+	}
+
+	if contains(allowed, ip) || !contains(internalNetworks, ip) {
+		return nil
+	}
+	return fmt.Errorf("webhook: address '%s': %w", address, ErrForbiddenAddress)
+}
+
+func contains(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,387 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-kit/kit/metrics"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net"
+	"net/http"
+	"noteapp/note"
+	"noteapp/pkg/logging"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// queueSize is the number of events whose deliveries can wait
+	// for the dispatcher to add them to the store. The deliveries
+	// of the events over it are dropped.
+	queueSize = 1024
+
+	// subscriptionConcurrency is the number of deliveries sent at
+	// once to a subscription, so that a slow receiver doesn't hold
+	// up the deliveries to the others.
+	subscriptionConcurrency = 4
+
+	defaultInitialBackoff = 5 * time.Second
+	defaultMaxBackoff     = time.Hour
+	defaultMaxAttempts    = 10
+	defaultTimeout        = 10 * time.Second
+)
+
+// Option configures the optional parts of the dispatcher.
+type Option func(d *Dispatcher)
+
+// WithHTTPClient sets the client used to send the deliveries. It
+// replaces the client refusing to connect to the internal addresses.
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// WithAllowedNetworks sets the internal networks the deliveries can
+// be sent to, e.g. the network of the receivers on the same host. The
+// deliveries to the loopback, private and link-local addresses are
+// refused without it.
+func WithAllowedNetworks(networks ...*net.IPNet) Option {
+	return func(d *Dispatcher) {
+		d.allowed = networks
+	}
+}
+
+// WithBackoff sets the delay before the second attempt, the maximum
+// delay between the attempts and the number of attempts of a delivery.
+// The delay doubles after every failed attempt.
+func WithBackoff(initial, max time.Duration, attempts int) Option {
+	return func(d *Dispatcher) {
+		d.initialBackoff = initial
+		d.maxBackoff = max
+		d.maxAttempts = attempts
+	}
+}
+
+// WithGrants sets the share grants of the notes so that the
+// subscriptions also receive the events of the notes shared with
+// their owner. Only the events of their owner's notes are
+// delivered without it.
+func WithGrants(grants note.Grants) Option {
+	return func(d *Dispatcher) {
+		d.grants = grants
+	}
+}
+
+// WithDroppedCounter sets the counter of the deliveries dropped
+// because the queue or the pending deliveries of their subscription
+// are full.
+func WithDroppedCounter(dropped metrics.Counter) Option {
+	return func(d *Dispatcher) {
+		d.dropped = dropped
+	}
+}
+
+// Dispatcher queues the note events for the matching subscriptions
+// and delivers them. The deliveries are added to the store by Run so
+// that the publishers don't wait for the file to be written. A delivery
+// is sent at least once: a delivery that has been sent but not recorded
+// before a restart is sent again.
+type Dispatcher struct {
+	store   *Store
+	client  *http.Client
+	allowed []*net.IPNet
+	grants  note.Grants
+	dropped metrics.Counter
+
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	maxAttempts    int
+
+	queue chan []*Delivery
+	// sent wakes up Run when a delivery has been sent.
+	sent chan struct{}
+
+	mu sync.Mutex
+	// sending are the ids of the deliveries being sent.
+	sending map[uuid.UUID]bool
+	// active is the number of the deliveries being
+	// sent by the id of their subscription.
+	active map[uuid.UUID]int
+}
+
+// NewDispatcher takes the store of the subscriptions and the queue
+// and options and returns a dispatcher.
+func NewDispatcher(store *Store, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		store:          store,
+		initialBackoff: defaultInitialBackoff,
+		maxBackoff:     defaultMaxBackoff,
+		maxAttempts:    defaultMaxAttempts,
+		queue:          make(chan []*Delivery, queueSize),
+		sent:           make(chan struct{}, 1),
+		sending:        make(map[uuid.UUID]bool),
+		active:         make(map[uuid.UUID]int),
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	if d.client == nil {
+		d.client = newClient(d.allowed)
+	}
+	return d
+}
+
+// Handle queues the event e for every subscription accepting it
+// whose owner can read the note of the event. It never blocks, the
+// deliveries are dropped when the queue is full.
+func (d *Dispatcher) Handle(ctx context.Context, e note.Event) {
+	t, now := e.Type, time.Now().UTC()
+	payload := Payload{ID: uuid.New(), Type: t, Version: e.Version, Time: e.Time, Note: e.Note}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		logging.Logger(ctx).Error("webhook: ", err)
+		return
+	}
+
+	var deliveries []*Delivery
+	for _, sub := range d.store.activeSubscriptions() {
+		if !sub.Accepts(t) || !d.canRead(sub, e.Note) {
+			continue
+		}
+
+		deliveries = append(deliveries, &Delivery{
+			ID:             uuid.New(),
+			SubscriptionID: sub.ID,
+			EventID:        payload.ID,
+			Event:          t,
+			Payload:        b,
+			Status:         StatusPending,
+			NextAttempt:    now,
+			CreatedTime:    now,
+		})
+	}
+
+	if len(deliveries) == 0 {
+		return
+	}
+
+	select {
+	case d.queue <- deliveries:
+	default:
+		logging.Logger(ctx).Errorf("webhook: queue full, dropped %d deliveries of the event %d", len(deliveries), e.ID)
+		d.drop(len(deliveries))
+	}
+}
+
+func (d *Dispatcher) drop(n int) {
+	if d.dropped != nil && n > 0 {
+		d.dropped.Add(float64(n))
+	}
+}
+
+// canRead reports whether the owner of sub can read the note n.
+func (d *Dispatcher) canRead(sub *Subscription, n *note.Note) bool {
+	if n == nil {
+		return false
+	}
+
+	ctx := context.Background()
+	if sub.OwnerID != "" {
+		ctx = note.WithOwner(ctx, sub.OwnerID)
+	}
+	return note.RoleOf(ctx, n, d.grants).CanRead()
+}
+
+// Run adds the queued deliveries to the store and sends them until ctx
+// is done. The deliveries queued by then are added to the store before
+// it returns, so that they are sent after a restart.
+func (d *Dispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		next := d.sendDue(ctx, &wg)
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+
+		var wait <-chan time.Time
+		if !next.IsZero() {
+			timer.Reset(time.Until(next))
+			wait = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			d.enqueue(nil)
+			return
+		case deliveries := <-d.queue:
+			d.enqueue(deliveries)
+		case <-d.sent:
+		case <-wait:
+		}
+	}
+}
+
+// enqueue adds the deliveries with the ones waiting in the queue to
+// the store, so that the file is written once for all of them.
+func (d *Dispatcher) enqueue(deliveries []*Delivery) {
+	for more := true; more; {
+		select {
+		case queued := <-d.queue:
+			deliveries = append(deliveries, queued...)
+		default:
+			more = false
+		}
+	}
+
+	if len(deliveries) == 0 {
+		return
+	}
+
+	dropped, err := d.store.enqueue(deliveries)
+	if err != nil {
+		logrus.Error("webhook: ", err)
+		return
+	}
+
+	if dropped > 0 {
+		logrus.Errorf("webhook: too many pending deliveries, dropped %d deliveries", dropped)
+		d.drop(dropped)
+	}
+}
+
+// sendDue starts sending the due deliveries which are not being sent
+// yet, at most subscriptionConcurrency at once per subscription, and
+// returns the time of the next attempt. It doesn't wait for them, Run
+// is woken up once one of them has been sent.
+func (d *Dispatcher) sendDue(ctx context.Context, wg *sync.WaitGroup) time.Time {
+	deliveries, subs, next := d.store.due(time.Now())
+
+	for i := range deliveries {
+		delivery, sub := deliveries[i], subs[i]
+		if !d.acquire(delivery, sub) {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.deliver(ctx, delivery, sub)
+			d.release(delivery, sub)
+		}()
+	}
+	return next
+}
+
+// acquire marks the delivery as being sent unless it is already
+// sent or its subscription has no more room for it.
+func (d *Dispatcher) acquire(delivery *Delivery, sub *Subscription) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.sending[delivery.ID] || d.active[sub.ID] >= subscriptionConcurrency {
+		return false
+	}
+
+	d.sending[delivery.ID] = true
+	d.active[sub.ID]++
+	return true
+}
+
+func (d *Dispatcher) release(delivery *Delivery, sub *Subscription) {
+	d.mu.Lock()
+	delete(d.sending, delivery.ID)
+	if d.active[sub.ID]--; d.active[sub.ID] == 0 {
+		delete(d.active, sub.ID)
+	}
+	d.mu.Unlock()
+
+	select {
+	case d.sent <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *Delivery, sub *Subscription) {
+	attempt := d.send(ctx, delivery, sub)
+	if ctx.Err() != nil {
+		// The dispatcher is stopping, the delivery stays
+		// pending to be sent again by the next run.
+		return
+	}
+
+	status, next := StatusSucceeded, time.Time{}
+	if attempt.Error != "" {
+		status = StatusPending
+		if attempts := len(delivery.Attempts) + 1; attempts >= d.maxAttempts {
+			status = StatusFailed
+		} else {
+			next = attempt.Time.Add(d.backoff(attempts))
+		}
+	}
+
+	if err := d.store.record(delivery.ID, attempt, status, next); err != nil {
+		logrus.Error("webhook: ", err)
+	}
+}
+
+// backoff returns the delay after the number of failed attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.initialBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.maxBackoff {
+			return d.maxBackoff
+		}
+	}
+	return delay
+}
+
+// send sends the delivery once. A response other than 2xx is a failure.
+func (d *Dispatcher) send(ctx context.Context, delivery *Delivery, sub *Subscription) (attempt Attempt) {
+	attempt.Time = time.Now().UTC()
+	defer func() { attempt.Duration = time.Since(attempt.Time) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, string(delivery.Event))
+	req.Header.Set(HeaderDelivery, delivery.ID.String())
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// Drain the response so that the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("unexpected status code %d", resp.StatusCode)
+	}
+	return attempt
+}
@@ -0,0 +1,399 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"noteapp/note"
+	"sync"
+	"testing"
+	"time"
+)
+
+var dummyCtx = context.TODO()
+
+// loopback is the network of the test receivers.
+var loopback = mustParseCIDRs("127.0.0.0/8")[0]
+
+// receiver is a webhook endpoint that records the deliveries
+// and fails the first failures of them.
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+// grants is the share grants of the users keyed by the note id.
+type grants map[uuid.UUID]map[string]note.Role
+
+func (g grants) Role(noteID uuid.UUID, userID string) note.Role {
+	return g[noteID][userID]
+}
+
+func (g grants) SharedWith(userID string) []uuid.UUID {
+	var ids []uuid.UUID
+	for id, roles := range g {
+		if _, ok := roles[userID]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func TestDispatcher(t *testing.T) {
+	suite.Run(t, new(DispatcherTestSuite))
+}
+
+type DispatcherTestSuite struct {
+	suite.Suite
+	file     afero.File
+	store    *Store
+	receiver *receiver
+	server   *httptest.Server
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func (s *DispatcherTestSuite) SetupTest() {
+	var err error
+	s.file, err = afero.NewMemMapFs().Create("webhooks.json")
+	s.Require().NoError(err)
+
+	s.store, err = NewStore(s.file)
+	s.Require().NoError(err)
+
+	s.receiver = new(receiver)
+	s.server = httptest.NewServer(s.receiver)
+}
+
+func (s *DispatcherTestSuite) TearDownTest() {
+	s.stop()
+	s.server.Close()
+}
+
+func (s *DispatcherTestSuite) start(store *Store, attempts int, opts ...Option) *Dispatcher {
+	opts = append(opts, WithAllowedNetworks(loopback), WithBackoff(10*time.Millisecond, 20*time.Millisecond, attempts))
+	d := NewDispatcher(store, opts...)
+
+	var ctx context.Context
+	ctx, s.cancel = context.WithCancel(dummyCtx)
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		d.Run(ctx)
+	}()
+	return d
+}
+
+func (s *DispatcherTestSuite) stop() {
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+		s.cancel = nil
+	}
+}
+
+func (s *DispatcherTestSuite) subscribe(events ...note.EventType) *Subscription {
+	sub, err := s.store.CreateSubscription(dummyCtx, &Subscription{URL: s.server.URL, Events: events, Secret: "secret"})
+	s.Require().NoError(err)
+	return sub
+}
+
+func (s *DispatcherTestSuite) waitForStatus(id uuid.UUID, status Status) []*Delivery {
+	var deliveries []*Delivery
+	s.Require().Eventually(func() bool {
+		var err error
+		deliveries, err = s.store.Deliveries(dummyCtx, id)
+		s.Require().NoError(err)
+		return len(deliveries) > 0 && deliveries[0].Status == status
+	}, 5*time.Second, 5*time.Millisecond)
+	return deliveries
+}
+
+func (s *DispatcherTestSuite) TestDeliver() {
+	n := new(note.Note)
+	n.SetID(uuid.New()).SetTitle("Webhook").SetContent("Test")
+
+	s.Run("Delivery should be signed", func() {
+		s.SetupTest()
+		defer s.TearDownTest()
+		sub := s.subscribe()
+		d := s.start(s.store, 3)
+
+		d.Handle(dummyCtx, note.Event{ID: 1, Type: note.EventCreated, Version: 1, Note: n})
+		deliveries := s.waitForStatus(sub.ID, StatusSucceeded)
+		s.Require().Len(deliveries, 1)
+		s.Equal(http.StatusNoContent, deliveries[0].Attempts[0].StatusCode)
+
+		s.Require().Equal(1, s.receiver.count())
+		req, body := s.receiver.requests[0], s.receiver.bodies[0]
+		s.Equal("created", req.Header.Get(HeaderEvent))
+		s.Equal(deliveries[0].ID.String(), req.Header.Get(HeaderDelivery))
+		s.True(Verify("secret", req.Header.Get(HeaderTimestamp), body, req.Header.Get(HeaderSignature)))
+
+		var payload Payload
+		s.Require().NoError(json.Unmarshal(body, &payload))
+		s.Equal(note.EventCreated, payload.Type)
+		s.Equal(uint64(1), payload.Version)
+		s.Equal(deliveries[0].EventID, payload.ID)
+		s.Equal(n, payload.Note)
+	})
+
+	s.Run("Events should be filtered per subscription", func() {
+		s.SetupTest()
+		defer s.TearDownTest()
+		deleted := s.subscribe(note.EventDeleted)
+		all := s.subscribe()
+		d := s.start(s.store, 3)
+
+		d.Handle(dummyCtx, note.Event{ID: 1, Type: note.EventUpdated, Version: 2, Note: n})
+		s.waitForStatus(all.ID, StatusSucceeded)
+
+		deliveries, err := s.store.Deliveries(dummyCtx, deleted.ID)
+		s.Require().NoError(err)
+		s.Empty(deliveries)
+		s.Equal(1, s.receiver.count())
+	})
+
+	s.Run("Failed delivery should be retried", func() {
+		s.SetupTest()
+		defer s.TearDownTest()
+		s.receiver.failures = 2
+		sub := s.subscribe()
+		d := s.start(s.store, 5)
+
+		d.Handle(dummyCtx, note.Event{ID: 1, Type: note.EventCreated, Version: 1, Note: n})
+		deliveries := s.waitForStatus(sub.ID, StatusSucceeded)
+
+		attempts := deliveries[0].Attempts
+		s.Require().Len(attempts, 3)
+		s.Equal(http.StatusServiceUnavailable, attempts[0].StatusCode)
+		s.NotEmpty(attempts[0].Error)
+		s.Equal(http.StatusNoContent, attempts[2].StatusCode)
+		s.Empty(attempts[2].Error)
+
+		// The delay doubles between the attempts.
+		s.GreaterOrEqual(int64(attempts[1].Time.Sub(attempts[0].Time)), int64(10*time.Millisecond))
+		s.GreaterOrEqual(int64(attempts[2].Time.Sub(attempts[1].Time)), int64(20*time.Millisecond))
+
+		// Every attempt has the same delivery id and payload.
+		for i := range s.receiver.requests {
+			s.Equal(deliveries[0].ID.String(), s.receiver.requests[i].Header.Get(HeaderDelivery))
+			s.Equal(s.receiver.bodies[0], s.receiver.bodies[i])
+		}
+	})
+
+	s.Run("Delivery should fail after the last attempt", func() {
+		s.SetupTest()
+		defer s.TearDownTest()
+		s.receiver.failures = 10
+		sub := s.subscribe()
+		d := s.start(s.store, 3)
+
+		d.Handle(dummyCtx, note.Event{ID: 1, Type: note.EventCreated, Version: 1, Note: n})
+		deliveries := s.waitForStatus(sub.ID, StatusFailed)
+		s.Len(deliveries[0].Attempts, 3)
+		s.Equal(3, s.receiver.count())
+	})
+
+	s.Run("Queued deliveries should be sent after a restart", func() {
+		s.SetupTest()
+		defer s.TearDownTest()
+		sub := s.subscribe()
+
+		// Queue and stop the dispatcher before sending.
+		d := NewDispatcher(s.store)
+		d.Handle(dummyCtx, note.Event{ID: 1, Type: note.EventDeleted, Version: 3, Note: n})
+		ctx, cancel := context.WithCancel(dummyCtx)
+		cancel()
+		d.Run(ctx)
+		s.Equal(0, s.receiver.count())
+
+		store, err := NewStore(s.file)
+		s.Require().NoError(err)
+		s.store = store
+		s.start(s.store, 3)
+
+		deliveries := s.waitForStatus(sub.ID, StatusSucceeded)
+		s.Equal(note.EventDeleted, deliveries[0].Event)
+		s.Equal(1, s.receiver.count())
+	})
+}
+
+func (s *DispatcherTestSuite) TestInternalAddresses() {
+	n := new(note.Note)
+	n.SetID(uuid.New()).SetTitle("Webhook")
+
+	s.Run("Deliveries to the internal addresses should be refused", func() {
+		s.SetupTest()
+		defer s.TearDownTest()
+		sub := s.subscribe()
+
+		d := NewDispatcher(s.store, WithBackoff(10*time.Millisecond, 20*time.Millisecond, 1))
+		ctx, cancel := context.WithCancel(dummyCtx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			d.Run(ctx)
+		}()
+		defer func() {
+			cancel()
+			<-done
+		}()
+
+		d.Handle(dummyCtx, note.Event{ID: 1, Type: note.EventCreated, Version: 1, Note: n})
+		deliveries := s.waitForStatus(sub.ID, StatusFailed)
+		s.Contains(deliveries[0].Attempts[0].Error, ErrForbiddenAddress.Error())
+		s.Equal(0, s.receiver.count())
+	})
+
+	s.Run("Deliveries to the allowed networks should be sent", func() {
+		s.SetupTest()
+		defer s.TearDownTest()
+		sub := s.subscribe()
+		d := s.start(s.store, 1)
+
+		d.Handle(dummyCtx, note.Event{ID: 1, Type: note.EventCreated, Version: 1, Note: n})
+		s.waitForStatus(sub.ID, StatusSucceeded)
+		s.Equal(1, s.receiver.count())
+	})
+}
+
+func TestCheckAddress(t *testing.T) {
+	allowed := mustParseCIDRs("10.1.0.0/16")
+
+	tests := []struct {
+		address   string
+		forbidden bool
+	}{
+		{address: "93.184.216.34:443"},
+		{address: "[2606:2800:220:1:248:1893:25c8:1946]:443"},
+		{address: "10.1.2.3:80"},
+		{address: "127.0.0.1:80", forbidden: true},
+		{address: "[::1]:80", forbidden: true},
+		{address: "[::ffff:127.0.0.1]:80", forbidden: true},
+		{address: "169.254.169.254:80", forbidden: true},
+		{address: "10.2.0.1:80", forbidden: true},
+		{address: "172.16.0.1:80", forbidden: true},
+		{address: "192.168.1.1:80", forbidden: true},
+		{address: "0.0.0.0:80", forbidden: true},
+		{address: "[fd00::1]:80", forbidden: true},
+		{address: "[fe80::1]:80", forbidden: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.address, func(t *testing.T) {
+			err := checkAddress(test.address, allowed)
+			if test.forbidden {
+				require.ErrorIs(t, err, ErrForbiddenAddress)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func (s *DispatcherTestSuite) TestBackpressure() {
+	n := new(note.Note)
+	n.SetID(uuid.New()).SetTitle("Webhook")
+
+	s.Run("Handle should drop the deliveries when the queue is full", func() {
+		s.SetupTest()
+		defer s.TearDownTest()
+		s.subscribe()
+		dropped := generic.NewCounter("dropped")
+
+		// The dispatcher isn't running so the queue isn't read.
+		d := NewDispatcher(s.store, WithDroppedCounter(dropped))
+		for i := 0; i < queueSize+2; i++ {
+			d.Handle(dummyCtx, note.Event{ID: uint64(i + 1), Type: note.EventCreated, Version: 1, Note: n})
+		}
+		s.Equal(float64(2), dropped.Value())
+	})
+
+	s.Run("Slow subscription should not hold up the others", func() {
+		s.SetupTest()
+		defer s.TearDownTest()
+
+		release := make(chan struct{})
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer slow.Close()
+		defer close(release)
+
+		_, err := s.store.CreateSubscription(dummyCtx, &Subscription{URL: slow.URL, Secret: "secret"})
+		s.Require().NoError(err)
+		fast := s.subscribe()
+		d := s.start(s.store, 3)
+
+		d.Handle(dummyCtx, note.Event{ID: 1, Type: note.EventCreated, Version: 1, Note: n})
+		s.waitForStatus(fast.ID, StatusSucceeded)
+
+		d.Handle(dummyCtx, note.Event{ID: 2, Type: note.EventUpdated, Version: 2, Note: n})
+		s.Require().Eventually(func() bool {
+			deliveries, err := s.store.Deliveries(dummyCtx, fast.ID)
+			s.Require().NoError(err)
+			return len(deliveries) == 2 && deliveries[0].Status == StatusSucceeded
+		}, 5*time.Second, 5*time.Millisecond)
+	})
+}
+
+func (s *DispatcherTestSuite) TestOwnership() {
+	s.Run("Events should be delivered to the owners who can read the note", func() {
+		s.SetupTest()
+		defer s.TearDownTest()
+
+		subscribe := func(ownerID string) *Subscription {
+			sub, err := s.store.CreateSubscription(note.WithOwner(dummyCtx, ownerID), &Subscription{URL: s.server.URL, Secret: "secret"})
+			s.Require().NoError(err)
+			return sub
+		}
+		owner, viewer, other := subscribe("alice"), subscribe("bob"), subscribe("carol")
+
+		n := new(note.Note)
+		n.SetID(uuid.New()).SetTitle("Private").SetOwnerID("alice")
+
+		d := s.start(s.store, 3, WithGrants(grants{n.ID: {"bob": note.RoleViewer}}))
+		d.Handle(dummyCtx, note.Event{ID: 1, Type: note.EventCreated, Version: 1, Note: n})
+
+		s.waitForStatus(owner.ID, StatusSucceeded)
+		s.waitForStatus(viewer.ID, StatusSucceeded)
+
+		deliveries, err := s.store.Deliveries(dummyCtx, other.ID)
+		s.Require().NoError(err)
+		s.Empty(deliveries)
+		s.Equal(2, s.receiver.count())
+	})
+}
@@ -0,0 +1,539 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"noteapp/note"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// deliveryLogSize is the number of finished deliveries kept
+	// in the delivery log of a subscription.
+	deliveryLogSize = 100
+
+	// maxPendingDeliveries is the number of pending deliveries of
+	// a subscription. The deliveries over it are dropped so that
+	// a receiver which is down doesn't grow the queue forever.
+	maxPendingDeliveries = 1000
+
+	// minCompactEntries is the number of entries appended to the
+	// file before it is compacted, unless the store has more
+	// subscriptions and deliveries than that.
+	minCompactEntries = 1000
+)
+
+// File is the file where the store keeps its data.
+type File interface {
+	io.ReadWriteSeeker
+	io.Closer
+	Sync() error
+	Truncate(size int64) error
+}
+
+// entry is a line of the store file. The file starts with a snapshot
+// of the store followed by the changes made since, so that a change is
+// written without rewriting the whole file. An entry is a snapshot
+// when none of the change fields is set.
+type entry struct {
+	// Subscriptions and Deliveries are the whole store data
+	// of a snapshot.
+	Subscriptions []*Subscription `json:"subscriptions,omitempty"`
+	Deliveries    []*Delivery     `json:"deliveries,omitempty"`
+
+	// Subscription is a created subscription.
+	Subscription *Subscription `json:"subscription,omitempty"`
+	// DeletedSubscription is the id of a deleted subscription.
+	DeletedSubscription *uuid.UUID `json:"deleted_subscription,omitempty"`
+	// Delivery is a queued delivery or a delivery with a
+	// new attempt, replacing the one with the same id.
+	Delivery *Delivery `json:"delivery,omitempty"`
+}
+
+// apply makes the change of the entry e to the data st.
+func (e entry) apply(st *data) {
+	switch {
+	case e.Subscription != nil:
+		st.subscriptions = append(st.subscriptions, copySubscription(e.Subscription))
+	case e.DeletedSubscription != nil:
+		st.deleteSubscription(*e.DeletedSubscription)
+	case e.Delivery != nil:
+		st.putDelivery(copyDelivery(e.Delivery))
+	default:
+		st.subscriptions, st.deliveries = e.Subscriptions, e.Deliveries
+	}
+}
+
+// data is the subscriptions and the deliveries of the store. The
+// changes replace the deliveries instead of updating them, so that
+// a copy of the slices doesn't share the changes.
+type data struct {
+	subscriptions []*Subscription
+	deliveries    []*Delivery
+}
+
+func (st *data) clone() data {
+	return data{
+		subscriptions: append([]*Subscription(nil), st.subscriptions...),
+		deliveries:    append([]*Delivery(nil), st.deliveries...),
+	}
+}
+
+func (st *data) deleteSubscription(id uuid.UUID) {
+	subs := make([]*Subscription, 0, len(st.subscriptions))
+	for _, sub := range st.subscriptions {
+		if sub.ID != id {
+			subs = append(subs, sub)
+		}
+	}
+
+	deliveries := make([]*Delivery, 0, len(st.deliveries))
+	for _, d := range st.deliveries {
+		if d.SubscriptionID != id {
+			deliveries = append(deliveries, d)
+		}
+	}
+	st.subscriptions, st.deliveries = subs, deliveries
+}
+
+// putDelivery adds the delivery d or replaces the one with its id.
+// The delivery log is trimmed when d is finished.
+func (st *data) putDelivery(d *Delivery) {
+	found := false
+	for i := range st.deliveries {
+		if st.deliveries[i].ID == d.ID {
+			st.deliveries[i], found = d, true
+			break
+		}
+	}
+
+	if !found {
+		st.deliveries = append(st.deliveries, d)
+	}
+
+	if d.Status != StatusPending {
+		st.trimLog(d.SubscriptionID)
+	}
+}
+
+// trimLog drops the oldest finished deliveries of the
+// subscription over the delivery log size.
+func (st *data) trimLog(id uuid.UUID) {
+	var finished int
+	for _, d := range st.deliveries {
+		if d.SubscriptionID == id && d.Status != StatusPending {
+			finished++
+		}
+	}
+
+	if finished <= deliveryLogSize {
+		return
+	}
+
+	drop := finished - deliveryLogSize
+	deliveries := make([]*Delivery, 0, len(st.deliveries)-drop)
+	for _, d := range st.deliveries {
+		if drop > 0 && d.SubscriptionID == id && d.Status != StatusPending {
+			drop--
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+	st.deliveries = deliveries
+}
+
+// Store keeps the subscriptions and the deliveries. The pending
+// deliveries are the queue of the dispatcher and the finished ones
+// are the delivery log. Every change is appended to the file before
+// it returns so that the queue survives a restart, and the file is
+// compacted once the changes outnumber the data. This is safe for
+// concurrent use.
+type Store struct {
+	file File
+
+	mu   sync.Mutex
+	data data
+	// appended is the number of changes written
+	// after the snapshot of the file.
+	appended int
+	// torn is true when the file may end with a partially
+	// written entry, it is rewritten by the next change.
+	torn bool
+
+	// subs is the []*Subscription read by the dispatcher without
+	// waiting for the writes of the file. Its subscriptions are
+	// not modified.
+	subs atomic.Value
+}
+
+// NewStore reads the store data from file and returns the store.
+// An empty file is an empty store.
+func NewStore(file File) (*Store, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	b, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{file: file}
+	lines := bytes.Split(bytes.TrimSpace(b), []byte("\n"))
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+
+		var e entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			if i == len(lines)-1 && i > 0 {
+				// The last change has not been written
+				// completely before a crash.
+				s.torn = true
+				break
+			}
+			return nil, fmt.Errorf("webhook: reading store: %w", err)
+		}
+
+		e.apply(&s.data)
+		if i > 0 {
+			s.appended++
+		}
+	}
+
+	s.publish()
+	return s, nil
+}
+
+// CreateSubscription validates sub and adds it with a new id. The
+// subscription belongs to the owner in ctx, see note.WithOwner.
+func (s *Store) CreateSubscription(ctx context.Context, sub *Subscription) (*Subscription, error) {
+	if err := sub.Validate(); err != nil {
+		return nil, err
+	}
+
+	created := copySubscription(sub)
+	created.ID = uuid.New()
+	created.OwnerID, _ = note.OwnerFromContext(ctx)
+	created.CreatedTime = time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.commit(entry{Subscription: created}); err != nil {
+		return nil, err
+	}
+	s.publish()
+	return copySubscription(created), nil
+}
+
+// Subscriptions returns the subscriptions of the owner in ctx in
+// the order they have been created. A context without owner gets
+// all the subscriptions.
+func (s *Store) Subscriptions(ctx context.Context) []*Subscription {
+	all := s.activeSubscriptions()
+
+	subs := make([]*Subscription, 0, len(all))
+	for _, sub := range all {
+		if sub.visibleTo(ctx) {
+			subs = append(subs, copySubscription(sub))
+		}
+	}
+	return subs
+}
+
+// activeSubscriptions returns all the subscriptions without
+// copying them, they must not be modified.
+func (s *Store) activeSubscriptions() []*Subscription {
+	subs, _ := s.subs.Load().([]*Subscription)
+	return subs
+}
+
+// publish makes the current subscriptions visible
+// to activeSubscriptions. It is called with the lock.
+func (s *Store) publish() {
+	subs := make([]*Subscription, len(s.data.subscriptions))
+	for i, sub := range s.data.subscriptions {
+		subs[i] = copySubscription(sub)
+	}
+	s.subs.Store(subs)
+}
+
+// Subscription returns the subscription with an id. The
+// subscriptions of other owners than the one in ctx are
+// reported as not found.
+func (s *Store) Subscription(ctx context.Context, id uuid.UUID) (*Subscription, error) {
+	sub := findVisibleSubscription(ctx, s.activeSubscriptions(), id)
+	if sub == nil {
+		return nil, ErrNotFound
+	}
+	return copySubscription(sub), nil
+}
+
+// DeleteSubscription deletes the subscription with an id along
+// with its pending deliveries and its delivery log. The
+// subscriptions of other owners than the one in ctx are
+// reported as not found.
+func (s *Store) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if findVisibleSubscription(ctx, s.data.subscriptions, id) == nil {
+		return ErrNotFound
+	}
+
+	if err := s.commit(entry{DeletedSubscription: &id}); err != nil {
+		return err
+	}
+	s.publish()
+	return nil
+}
+
+// Deliveries returns the deliveries of the subscription with an
+// id, the latest first. The subscriptions of other owners than
+// the one in ctx are reported as not found.
+func (s *Store) Deliveries(ctx context.Context, id uuid.UUID) ([]*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if findVisibleSubscription(ctx, s.data.subscriptions, id) == nil {
+		return nil, ErrNotFound
+	}
+
+	deliveries := []*Delivery{}
+	for i := len(s.data.deliveries) - 1; i >= 0; i-- {
+		if d := s.data.deliveries[i]; d.SubscriptionID == id {
+			deliveries = append(deliveries, copyDelivery(d))
+		}
+	}
+	return deliveries, nil
+}
+
+// enqueue adds the pending deliveries and returns the number of the
+// ones dropped because their subscription has too many pending.
+func (s *Store) enqueue(deliveries []*Delivery) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := make(map[uuid.UUID]int)
+	for _, d := range s.data.deliveries {
+		if d.Status == StatusPending {
+			pending[d.SubscriptionID]++
+		}
+	}
+
+	entries := make([]entry, 0, len(deliveries))
+	for _, d := range deliveries {
+		if pending[d.SubscriptionID] >= maxPendingDeliveries {
+			continue
+		}
+		pending[d.SubscriptionID]++
+		entries = append(entries, entry{Delivery: d})
+	}
+
+	dropped := len(deliveries) - len(entries)
+	if len(entries) == 0 {
+		return dropped, nil
+	}
+	return dropped, s.commit(entries...)
+}
+
+// due returns the pending deliveries whose next attempt is not after
+// now with their subscriptions, and the time of the earliest attempt
+// after now. The time is zero when there is no such delivery.
+func (s *Store) due(now time.Time) ([]*Delivery, []*Subscription, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		deliveries []*Delivery
+		subs       []*Subscription
+		next       time.Time
+	)
+
+	for _, d := range s.data.deliveries {
+		if d.Status != StatusPending {
+			continue
+		}
+
+		if d.NextAttempt.After(now) {
+			if next.IsZero() || d.NextAttempt.Before(next) {
+				next = d.NextAttempt
+			}
+			continue
+		}
+
+		if sub := findSubscription(s.data.subscriptions, d.SubscriptionID); sub != nil {
+			deliveries = append(deliveries, copyDelivery(d))
+			subs = append(subs, copySubscription(sub))
+		}
+	}
+	return deliveries, subs, next
+}
+
+// record adds the attempt to the delivery with an id and updates its
+// status. The next attempt is only used for a pending delivery. A
+// delivery that has been deleted meanwhile is ignored.
+func (s *Store) record(id uuid.UUID, attempt Attempt, status Status, next time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found *Delivery
+	for _, d := range s.data.deliveries {
+		if d.ID == id {
+			found = d
+			break
+		}
+	}
+
+	if found == nil {
+		return nil
+	}
+
+	updated := copyDelivery(found)
+	updated.Attempts = append(updated.Attempts, attempt)
+	updated.Status = status
+	if status == StatusPending {
+		updated.NextAttempt = next
+	}
+	return s.commit(entry{Delivery: updated})
+}
+
+// commit writes the changes of the entries and then makes them. The
+// entries are appended to the file, unless it is due for compaction
+// or may end with a torn entry, then it is rewritten with the changes.
+// Nothing is changed when the file can't be written.
+func (s *Store) commit(entries ...entry) error {
+	limit := minCompactEntries
+	if size := len(s.data.subscriptions) + len(s.data.deliveries); size > limit {
+		limit = size
+	}
+
+	if !s.torn && s.appended+len(entries) <= limit {
+		if err := s.append(entries); err != nil {
+			s.torn = true
+			return err
+		}
+
+		for _, e := range entries {
+			e.apply(&s.data)
+		}
+		s.appended += len(entries)
+		return nil
+	}
+
+	st := s.data.clone()
+	for _, e := range entries {
+		e.apply(&st)
+	}
+
+	if err := s.write(st); err != nil {
+		s.torn = true
+		return err
+	}
+	s.data, s.appended, s.torn = st, 0, false
+	return nil
+}
+
+func findSubscription(subs []*Subscription, id uuid.UUID) *Subscription {
+	for _, sub := range subs {
+		if sub.ID == id {
+			return sub
+		}
+	}
+	return nil
+}
+
+// findVisibleSubscription returns the subscription with an id
+// of subs when the owner in ctx can see it.
+func findVisibleSubscription(ctx context.Context, subs []*Subscription, id uuid.UUID) *Subscription {
+	if sub := findSubscription(subs, id); sub != nil && sub.visibleTo(ctx) {
+		return sub
+	}
+	return nil
+}
+
+// Close compacts, syncs and closes the file of the store.
+// The store must not be used after it is closed.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.appended > 0 || s.torn {
+		if err := s.write(s.data); err != nil {
+			_ = s.file.Close()
+			return err
+		}
+	}
+
+	if err := s.file.Sync(); err != nil {
+		_ = s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+// append writes the entries at the end of the file.
+func (s *Store) append(entries []entry) error {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+
+	if _, err := s.file.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+
+	if _, err := s.file.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	return s.file.Sync()
+}
+
+// write rewrites the whole file with a snapshot of st.
+func (s *Store) write(st data) error {
+	b, err := json.Marshal(entry{Subscriptions: st.subscriptions, Deliveries: st.deliveries})
+	if err != nil {
+		return err
+	}
+
+	if err := s.file.Truncate(0); err != nil {
+		return err
+	}
+
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if _, err := s.file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+
+	return s.file.Sync()
+}
+
+func copySubscription(sub *Subscription) *Subscription {
+	cpy := *sub
+	cpy.Events = append([]note.EventType(nil), sub.Events...)
+	return &cpy
+}
+
+func copyDelivery(d *Delivery) *Delivery {
+	cpy := *d
+	cpy.Payload = append(json.RawMessage(nil), d.Payload...)
+	cpy.Attempts = append([]Attempt(nil), d.Attempts...)
+	return &cpy
+}
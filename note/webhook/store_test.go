@@ -0,0 +1,332 @@
+package webhook
+
+import (
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/suite"
+	"noteapp/note"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	suite.Run(t, new(StoreTestSuite))
+}
+
+type StoreTestSuite struct {
+	suite.Suite
+	file  afero.File
+	store *Store
+}
+
+func (s *StoreTestSuite) SetupTest() {
+	var err error
+	s.file, err = afero.NewMemMapFs().Create("webhooks.json")
+	s.Require().NoError(err)
+
+	s.store, err = NewStore(s.file)
+	s.Require().NoError(err)
+}
+
+// reopen reads the store from its file again.
+func (s *StoreTestSuite) reopen() *Store {
+	store, err := NewStore(s.file)
+	s.Require().NoError(err)
+	return store
+}
+
+// enqueue adds the deliveries to the store.
+func (s *StoreTestSuite) enqueue(deliveries []*Delivery) {
+	dropped, err := s.store.enqueue(deliveries)
+	s.Require().NoError(err)
+	s.Require().Zero(dropped)
+}
+
+func (s *StoreTestSuite) createSubscription() *Subscription {
+	sub, err := s.store.CreateSubscription(dummyCtx, &Subscription{
+		URL:    "https://example.com/hook",
+		Events: []note.EventType{note.EventCreated},
+		Secret: "secret",
+	})
+	s.Require().NoError(err)
+	return sub
+}
+
+func (s *StoreTestSuite) TestCreateSubscription() {
+	s.Run("Creating a subscription", func() {
+		s.SetupTest()
+		sub := s.createSubscription()
+		s.NotEqual(uuid.Nil, sub.ID)
+		s.False(sub.CreatedTime.IsZero())
+
+		got, err := s.reopen().Subscription(dummyCtx, sub.ID)
+		s.Require().NoError(err)
+		s.Equal(sub.ID, got.ID)
+		s.Equal(sub.URL, got.URL)
+		s.Equal(sub.Events, got.Events)
+		s.Equal(sub.Secret, got.Secret)
+	})
+
+	tests := []struct {
+		name    string
+		sub     *Subscription
+		wantErr error
+	}{
+		{
+			name:    "Relative url should return an error",
+			sub:     &Subscription{URL: "/hook", Secret: "secret"},
+			wantErr: ErrInvalidURL,
+		},
+		{
+			name:    "Url with other scheme should return an error",
+			sub:     &Subscription{URL: "ftp://example.com/hook", Secret: "secret"},
+			wantErr: ErrInvalidURL,
+		},
+		{
+			name:    "Unknown event should return an error",
+			sub:     &Subscription{URL: "https://example.com/hook", Events: []note.EventType{"moved"}, Secret: "secret"},
+			wantErr: ErrInvalidEvent,
+		},
+		{
+			name:    "Empty secret should return an error",
+			sub:     &Subscription{URL: "https://example.com/hook"},
+			wantErr: ErrEmptySecret,
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			s.SetupTest()
+			_, err := s.store.CreateSubscription(dummyCtx, tt.sub)
+			s.ErrorIs(err, tt.wantErr)
+			s.Empty(s.store.Subscriptions(dummyCtx))
+		})
+	}
+}
+
+func (s *StoreTestSuite) TestDeleteSubscription() {
+	s.Run("Deleting a subscription with its deliveries", func() {
+		s.SetupTest()
+		sub := s.createSubscription()
+		other := s.createSubscription()
+
+		s.enqueue([]*Delivery{
+			{ID: uuid.New(), SubscriptionID: sub.ID, Status: StatusPending},
+			{ID: uuid.New(), SubscriptionID: other.ID, Status: StatusPending},
+		})
+
+		s.Require().NoError(s.store.DeleteSubscription(dummyCtx, sub.ID))
+
+		store := s.reopen()
+		_, err := store.Subscription(dummyCtx, sub.ID)
+		s.ErrorIs(err, ErrNotFound)
+
+		deliveries, _, _ := store.due(time.Now())
+		s.Require().Len(deliveries, 1)
+		s.Equal(other.ID, deliveries[0].SubscriptionID)
+	})
+
+	s.Run("Deleting a subscription that doesn't exist should return an error", func() {
+		s.SetupTest()
+		s.ErrorIs(s.store.DeleteSubscription(dummyCtx, uuid.New()), ErrNotFound)
+	})
+}
+
+func (s *StoreTestSuite) TestQueue() {
+	s.Run("Due deliveries should be the pending ones that are due", func() {
+		s.SetupTest()
+		sub := s.createSubscription()
+		now := time.Now()
+
+		due := &Delivery{ID: uuid.New(), SubscriptionID: sub.ID, Status: StatusPending, NextAttempt: now}
+		later := &Delivery{ID: uuid.New(), SubscriptionID: sub.ID, Status: StatusPending, NextAttempt: now.Add(time.Minute)}
+		done := &Delivery{ID: uuid.New(), SubscriptionID: sub.ID, Status: StatusSucceeded, NextAttempt: now}
+		s.enqueue([]*Delivery{due, later, done})
+
+		deliveries, subs, next := s.store.due(now)
+		s.Require().Len(deliveries, 1)
+		s.Equal(due.ID, deliveries[0].ID)
+		s.Equal(sub.ID, subs[0].ID)
+		s.True(later.NextAttempt.Equal(next))
+	})
+
+	s.Run("Recording an attempt", func() {
+		s.SetupTest()
+		sub := s.createSubscription()
+		d := &Delivery{ID: uuid.New(), SubscriptionID: sub.ID, Status: StatusPending}
+		s.enqueue([]*Delivery{d})
+
+		next := time.Now().Add(time.Minute).UTC()
+		s.Require().NoError(s.store.record(d.ID, Attempt{StatusCode: 500, Error: "failed"}, StatusPending, next))
+		s.Require().NoError(s.store.record(d.ID, Attempt{StatusCode: 200}, StatusSucceeded, time.Time{}))
+
+		deliveries, err := s.reopen().Deliveries(dummyCtx, sub.ID)
+		s.Require().NoError(err)
+		s.Require().Len(deliveries, 1)
+		s.Equal(StatusSucceeded, deliveries[0].Status)
+		s.Equal([]Attempt{{StatusCode: 500, Error: "failed"}, {StatusCode: 200}}, deliveries[0].Attempts)
+		s.True(next.Equal(deliveries[0].NextAttempt))
+	})
+
+	s.Run("Delivery log should keep the latest finished deliveries", func() {
+		s.SetupTest()
+		sub := s.createSubscription()
+
+		var ids []uuid.UUID
+		for i := 0; i < deliveryLogSize+5; i++ {
+			d := &Delivery{ID: uuid.New(), SubscriptionID: sub.ID, Status: StatusPending}
+			s.enqueue([]*Delivery{d})
+			s.Require().NoError(s.store.record(d.ID, Attempt{StatusCode: 200}, StatusSucceeded, time.Time{}))
+			ids = append(ids, d.ID)
+		}
+
+		deliveries, err := s.store.Deliveries(dummyCtx, sub.ID)
+		s.Require().NoError(err)
+		s.Require().Len(deliveries, deliveryLogSize)
+		s.Equal(ids[len(ids)-1], deliveries[0].ID)
+		s.Equal(ids[5], deliveries[len(deliveries)-1].ID)
+	})
+
+	s.Run("Pending deliveries should be limited per subscription", func() {
+		s.SetupTest()
+		sub, other := s.createSubscription(), s.createSubscription()
+
+		deliveries := make([]*Delivery, maxPendingDeliveries+1)
+		for i := range deliveries {
+			deliveries[i] = &Delivery{ID: uuid.New(), SubscriptionID: sub.ID, Status: StatusPending}
+		}
+		deliveries = append(deliveries, &Delivery{ID: uuid.New(), SubscriptionID: other.ID, Status: StatusPending})
+
+		dropped, err := s.store.enqueue(deliveries)
+		s.Require().NoError(err)
+		s.Equal(1, dropped)
+
+		got, err := s.reopen().Deliveries(dummyCtx, sub.ID)
+		s.Require().NoError(err)
+		s.Len(got, maxPendingDeliveries)
+	})
+
+	s.Run("Deliveries of a subscription that doesn't exist should return an error", func() {
+		s.SetupTest()
+		_, err := s.store.Deliveries(dummyCtx, uuid.New())
+		s.ErrorIs(err, ErrNotFound)
+	})
+}
+
+func (s *StoreTestSuite) TestFile() {
+	size := func() int64 {
+		info, err := s.file.Stat()
+		s.Require().NoError(err)
+		return info.Size()
+	}
+
+	s.Run("Changes should be appended to the file", func() {
+		s.SetupTest()
+		sub := s.createSubscription()
+		d := &Delivery{ID: uuid.New(), SubscriptionID: sub.ID, Status: StatusPending}
+		s.enqueue([]*Delivery{d})
+
+		before := size()
+		s.Require().NoError(s.store.record(d.ID, Attempt{StatusCode: 500, Error: "failed"}, StatusPending, time.Now()))
+		s.Require().NoError(s.store.record(d.ID, Attempt{StatusCode: 500, Error: "failed"}, StatusPending, time.Now()))
+		grown := size() - before
+		s.Require().NoError(s.store.record(d.ID, Attempt{StatusCode: 200}, StatusSucceeded, time.Time{}))
+		s.Less(size()-before-grown, 2*grown, "Expecting a record to append a single delivery")
+
+		deliveries, err := s.reopen().Deliveries(dummyCtx, sub.ID)
+		s.Require().NoError(err)
+		s.Require().Len(deliveries, 1)
+		s.Equal(StatusSucceeded, deliveries[0].Status)
+		s.Len(deliveries[0].Attempts, 3)
+	})
+
+	s.Run("File should be compacted after many changes", func() {
+		s.SetupTest()
+		subs := []*Subscription{s.createSubscription(), s.createSubscription()}
+		for i := 0; i < minCompactEntries; i++ {
+			s.enqueue([]*Delivery{{ID: uuid.New(), SubscriptionID: subs[i%2].ID, Status: StatusPending}})
+		}
+		s.Less(s.store.appended, minCompactEntries)
+
+		deliveries, err := s.reopen().Deliveries(dummyCtx, subs[0].ID)
+		s.Require().NoError(err)
+		s.Len(deliveries, minCompactEntries/2)
+	})
+
+	s.Run("Partially written last change should be ignored", func() {
+		s.SetupTest()
+		sub := s.createSubscription()
+		_, err := s.file.Write([]byte(`{"delivery":{"id":`))
+		s.Require().NoError(err)
+
+		store := s.reopen()
+		_, err = store.Subscription(dummyCtx, sub.ID)
+		s.Require().NoError(err)
+
+		// The next change rewrites the file without the partial change.
+		s.store = store
+		other := s.createSubscription()
+		s.Len(s.reopen().Subscriptions(dummyCtx), 2)
+		s.Equal(0, s.store.appended)
+		_, err = s.reopen().Subscription(dummyCtx, other.ID)
+		s.NoError(err)
+	})
+
+	s.Run("File with a single document should be read", func() {
+		s.SetupTest()
+		id := uuid.New()
+		_, err := s.file.Write([]byte(`{"subscriptions":[{"id":"` + id.String() + `","url":"https://example.com/hook","secret":"secret"}],"deliveries":[]}`))
+		s.Require().NoError(err)
+
+		got, err := s.reopen().Subscription(dummyCtx, id)
+		s.Require().NoError(err)
+		s.Equal("https://example.com/hook", got.URL)
+	})
+}
+
+func (s *StoreTestSuite) TestOwnership() {
+	alice, bob := note.WithOwner(dummyCtx, "alice"), note.WithOwner(dummyCtx, "bob")
+
+	sub, err := s.store.CreateSubscription(alice, &Subscription{URL: "https://example.com/hook", Secret: "secret"})
+	s.Require().NoError(err)
+	s.Equal("alice", sub.OwnerID)
+
+	s.Run("Subscriptions should be listed to their owner only", func() {
+		s.Len(s.store.Subscriptions(alice), 1)
+		s.Empty(s.store.Subscriptions(bob))
+		s.Len(s.store.Subscriptions(dummyCtx), 1)
+	})
+
+	s.Run("Subscriptions of another owner should not be found", func() {
+		_, err := s.store.Subscription(bob, sub.ID)
+		s.ErrorIs(err, ErrNotFound)
+
+		_, err = s.store.Deliveries(bob, sub.ID)
+		s.ErrorIs(err, ErrNotFound)
+
+		s.ErrorIs(s.store.DeleteSubscription(bob, sub.ID), ErrNotFound)
+
+		got, err := s.store.Subscription(alice, sub.ID)
+		s.Require().NoError(err)
+		s.Equal(sub, got)
+	})
+}
+
+func (s *StoreTestSuite) TestSign() {
+	body := []byte(`{"id":"1"}`)
+	signature := Sign("secret", "1600000000", body)
+
+	s.True(Verify("secret", "1600000000", body, signature))
+	s.False(Verify("other", "1600000000", body, signature))
+	s.False(Verify("secret", "1600000001", body, signature))
+	s.False(Verify("secret", "1600000000", []byte(`{"id":"2"}`), signature))
+	s.False(Verify("secret", "1600000000", body, ""))
+}
+
+func (s *StoreTestSuite) TestClose() {
+	s.createSubscription()
+	s.Require().NoError(s.store.Close())
+
+	_, err := s.store.CreateSubscription(dummyCtx, &Subscription{URL: "https://example.com/other"})
+	s.Error(err)
+}
@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"net/url"
+	"noteapp/note"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderSignature is the header of the HMAC-SHA256 signature
+	// of the delivery in the form of "sha256=<hex>".
+	HeaderSignature = "X-Noteapp-Signature"
+	// HeaderTimestamp is the header of the unix time when the
+	// delivery has been signed.
+	HeaderTimestamp = "X-Noteapp-Timestamp"
+	// HeaderEvent is the header of the event type of the delivery.
+	HeaderEvent = "X-Noteapp-Event"
+	// HeaderDelivery is the header of the delivery id. It is the
+	// same for all the attempts of a delivery.
+	HeaderDelivery = "X-Noteapp-Delivery"
+)
+
+var (
+	// ErrNotFound is an error when the subscription doesn't exist.
+	ErrNotFound = errors.New("webhook: subscription not found")
+	// ErrInvalidURL is an error when the subscription url is
+	// not an absolute http or https url.
+	ErrInvalidURL = errors.New("webhook: invalid url")
+	// ErrInvalidEvent is an error when the subscription
+	// filters an unknown event type.
+	ErrInvalidEvent = errors.New("webhook: invalid event")
+	// ErrEmptySecret is an error when the subscription
+	// doesn't have a secret to sign the deliveries with.
+	ErrEmptySecret = errors.New("webhook: empty secret")
+)
+
+// Subscription is a receiver of the note events.
+type Subscription struct {
+	ID  uuid.UUID `json:"id"`
+	URL string    `json:"url"`
+	// OwnerID is the id of the user who has created the subscription.
+	// The subscription only receives the events of the notes the owner
+	// can read. It is empty when the subscription has been created
+	// without owner, then it receives the events of all the notes.
+	OwnerID string `json:"owner_id,omitempty"`
+	// Events is the filter of the event types to deliver.
+	// An empty filter delivers all the events.
+	Events []note.EventType `json:"events"`
+	// Secret is the key of the delivery signatures.
+	Secret      string    `json:"secret"`
+	CreatedTime time.Time `json:"created_time"`
+}
+
+// Validate checks the url, the event filter and the secret of the subscription.
+func (s *Subscription) Validate() error {
+	if s.Secret == "" {
+		return ErrEmptySecret
+	}
+
+	u, err := url.Parse(s.URL)
+	if err != nil || !u.IsAbs() || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("webhook: url '%s': %w", s.URL, ErrInvalidURL)
+	}
+
+	for _, t := range s.Events {
+		switch t {
+		case note.EventCreated, note.EventUpdated, note.EventDeleted:
+		default:
+			return fmt.Errorf("webhook: event '%s': %w", t, ErrInvalidEvent)
+		}
+	}
+	return nil
+}
+
+// visibleTo reports whether the owner in ctx can see the
+// subscription. A context without owner sees all of them.
+func (s *Subscription) visibleTo(ctx context.Context) bool {
+	ownerID, ok := note.OwnerFromContext(ctx)
+	return !ok || s.OwnerID == ownerID
+}
+
+// Accepts returns true when the event type t passes the event filter.
+func (s *Subscription) Accepts(t note.EventType) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+
+	for _, event := range s.Events {
+		if event == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Status is the state of a delivery.
+type Status string
+
+const (
+	// StatusPending is when the delivery is waiting for its next attempt.
+	StatusPending Status = "pending"
+	// StatusSucceeded is when the receiver has accepted the delivery.
+	StatusSucceeded Status = "succeeded"
+	// StatusFailed is when all the attempts of the delivery have failed.
+	StatusFailed Status = "failed"
+)
+
+// Payload is the body of a delivery.
+type Payload struct {
+	// ID is the id of the event. It is the same for the
+	// deliveries of the event to all the subscriptions.
+	ID   uuid.UUID      `json:"id"`
+	Type note.EventType `json:"type"`
+	// Version is the number of changes made to the note so far.
+	Version uint64     `json:"version"`
+	Time    time.Time  `json:"time"`
+	Note    *note.Note `json:"note"`
+}
+
+// Attempt is the outcome of sending a delivery once.
+type Attempt struct {
+	Time       time.Time     `json:"time"`
+	Duration   time.Duration `json:"duration"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Delivery is an event queued for, or delivered to, a subscription.
+type Delivery struct {
+	ID             uuid.UUID       `json:"id"`
+	SubscriptionID uuid.UUID       `json:"subscription_id"`
+	EventID        uuid.UUID       `json:"event_id"`
+	Event          note.EventType  `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         Status          `json:"status"`
+	Attempts       []Attempt       `json:"attempts"`
+	// NextAttempt is the time of the next attempt
+	// of a pending delivery.
+	NextAttempt time.Time `json:"next_attempt"`
+	CreatedTime time.Time `json:"created_time"`
+}
+
+// Sign returns the signature of the body signed at the unix
+// timestamp with the secret. The timestamp is part of the signed
+// message so that a receiver can reject replayed deliveries.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify returns true when the signature is the signature of the
+// body signed at the unix timestamp with the secret.
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
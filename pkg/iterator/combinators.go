@@ -0,0 +1,216 @@
+package iterator
+
+// Filter returns an iterator that only loads the values of it
+// where keep returns true.
+func Filter(it ValueIterator, keep func(v interface{}) bool) ValueIterator {
+	return &filterIterator{ValueIterator: it, keep: keep}
+}
+
+type filterIterator struct {
+	ValueIterator
+	keep func(v interface{}) bool
+}
+
+func (f *filterIterator) Next() bool {
+	for f.ValueIterator.Next() {
+		if f.keep(f.ValueIterator.Value()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Map returns an iterator that loads the values of it converted by fn.
+// The iteration will stop on the first error returned by fn and the
+// error will be reported by the Error method.
+func Map(it ValueIterator, fn func(v interface{}) (interface{}, error)) ValueIterator {
+	return &mapIterator{ValueIterator: it, fn: fn}
+}
+
+type mapIterator struct {
+	ValueIterator
+	fn    func(v interface{}) (interface{}, error)
+	value interface{}
+	err   error
+}
+
+func (m *mapIterator) Next() bool {
+	if m.err != nil || !m.ValueIterator.Next() {
+		m.value = nil
+		return false
+	}
+
+	m.value, m.err = m.fn(m.ValueIterator.Value())
+	if m.err != nil {
+		m.value = nil
+		return false
+	}
+
+	return true
+}
+
+func (m *mapIterator) Value() interface{} {
+	return m.value
+}
+
+func (m *mapIterator) Error() error {
+	if m.err != nil {
+		return m.err
+	}
+	return m.ValueIterator.Error()
+}
+
+// Take returns an iterator that loads at most n values of it.
+func Take(it ValueIterator, n int) ValueIterator {
+	return &takeIterator{ValueIterator: it, remaining: n}
+}
+
+type takeIterator struct {
+	ValueIterator
+	remaining int
+}
+
+func (t *takeIterator) Next() bool {
+	if t.remaining <= 0 {
+		return false
+	}
+	t.remaining--
+	return t.ValueIterator.Next()
+}
+
+// Skip returns an iterator that discards the first n values of it.
+func Skip(it ValueIterator, n int) ValueIterator {
+	return &skipIterator{ValueIterator: it, skip: n}
+}
+
+type skipIterator struct {
+	ValueIterator
+	skip int
+}
+
+func (s *skipIterator) Next() bool {
+	for ; s.skip > 0; s.skip-- {
+		if !s.ValueIterator.Next() {
+			s.skip = 0
+			return false
+		}
+	}
+	return s.ValueIterator.Next()
+}
+
+// Chunk returns an iterator that loads the values of it in groups of
+// size. Each value is a []interface{} and the last group may contain
+// less than size values. A size less than 1 is treated as 1.
+func Chunk(it ValueIterator, size int) ValueIterator {
+	if size < 1 {
+		size = 1
+	}
+	return &chunkIterator{ValueIterator: it, size: size}
+}
+
+type chunkIterator struct {
+	ValueIterator
+	size  int
+	chunk []interface{}
+}
+
+func (c *chunkIterator) Next() bool {
+	c.chunk = nil
+	for len(c.chunk) < c.size && c.ValueIterator.Next() {
+		c.chunk = append(c.chunk, c.ValueIterator.Value())
+	}
+
+	// Don't hand out a partial chunk when the iteration stopped
+	// because of an error.
+	if c.ValueIterator.Error() != nil {
+		c.chunk = nil
+	}
+
+	return len(c.chunk) > 0
+}
+
+func (c *chunkIterator) Value() interface{} {
+	if c.chunk == nil {
+		return nil
+	}
+	return c.chunk
+}
+
+// Merge returns an iterator that loads the values of all the iterators
+// in the order defined by less. Each of the iterators must already be
+// sorted by less. Closing the returned iterator closes all of them.
+func Merge(less func(a, b interface{}) bool, its ...ValueIterator) ValueIterator {
+	return &mergeIterator{its: its, less: less, current: -1}
+}
+
+type mergeIterator struct {
+	its     []ValueIterator
+	less    func(a, b interface{}) bool
+	heads   []bool
+	started bool
+	current int
+	err     error
+}
+
+func (m *mergeIterator) Next() bool {
+	if m.err != nil {
+		return false
+	}
+
+	if !m.started {
+		m.started = true
+		m.heads = make([]bool, len(m.its))
+		for i := range m.its {
+			if !m.advance(i) {
+				return false
+			}
+		}
+	} else if m.current >= 0 && !m.advance(m.current) {
+		return false
+	}
+
+	m.current = -1
+	for i, ok := range m.heads {
+		if !ok {
+			continue
+		}
+		if m.current < 0 || m.less(m.its[i].Value(), m.its[m.current].Value()) {
+			m.current = i
+		}
+	}
+
+	return m.current >= 0
+}
+
+// advance loads the next value of the i-th iterator. It returns
+// false when the iterator failed.
+func (m *mergeIterator) advance(i int) bool {
+	m.heads[i] = m.its[i].Next()
+	if err := m.its[i].Error(); err != nil {
+		m.err = err
+		m.current = -1
+		return false
+	}
+	return true
+}
+
+func (m *mergeIterator) Value() interface{} {
+	if m.current < 0 || m.err != nil {
+		return nil
+	}
+	return m.its[m.current].Value()
+}
+
+func (m *mergeIterator) Error() error {
+	return m.err
+}
+
+func (m *mergeIterator) Close() error {
+	var err error
+	for _, it := range m.its {
+		if cerr := it.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,171 @@
+package iterator
+
+import (
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+var errDummy = errors.New("dummy error")
+
+func Test(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+type TestSuite struct {
+	suite.Suite
+}
+
+func (s *TestSuite) TestFilter() {
+	it := Filter(FromSlice(1, 2, 3, 4, 5, 6), func(v interface{}) bool {
+		return v.(int)%2 == 0
+	})
+	s.Equal([]interface{}{2, 4, 6}, s.collect(it))
+}
+
+func (s *TestSuite) TestMap() {
+	s.Run("Mapping the values", func() {
+		it := Map(FromSlice(1, 2, 3), func(v interface{}) (interface{}, error) {
+			return v.(int) * 10, nil
+		})
+		s.Equal([]interface{}{10, 20, 30}, s.collect(it))
+	})
+
+	s.Run("Mapping function error should stop the iteration", func() {
+		it := Map(FromSlice(1, 2, 3), func(v interface{}) (interface{}, error) {
+			if v.(int) == 2 {
+				return nil, errDummy
+			}
+			return v, nil
+		})
+		s.True(it.Next())
+		s.False(it.Next())
+		s.False(it.Next())
+		s.Equal(errDummy, it.Error())
+	})
+}
+
+func (s *TestSuite) TestTakeAndSkip() {
+	s.Equal([]interface{}{1, 2}, s.collect(Take(FromSlice(1, 2, 3), 2)))
+	s.Equal([]interface{}{1, 2, 3}, s.collect(Take(FromSlice(1, 2, 3), 5)))
+	s.Equal([]interface{}{3}, s.collect(Skip(FromSlice(1, 2, 3), 2)))
+	s.Empty(s.collect(Skip(FromSlice(1, 2, 3), 5)))
+	s.Equal([]interface{}{3, 4}, s.collect(Take(Skip(FromSlice(1, 2, 3, 4, 5), 2), 2)))
+}
+
+func (s *TestSuite) TestChunk() {
+	s.Run("Chunking the values", func() {
+		got := s.collect(Chunk(FromSlice(1, 2, 3, 4, 5), 2))
+		s.Equal([]interface{}{
+			[]interface{}{1, 2},
+			[]interface{}{3, 4},
+			[]interface{}{5},
+		}, got)
+	})
+
+	s.Run("Error in the source should not return a partial chunk", func() {
+		it := Chunk(&failingIterator{ValueIterator: FromSlice(1, 2, 3), failAt: 3}, 2)
+		s.True(it.Next())
+		s.False(it.Next())
+		s.Equal(errDummy, it.Error())
+	})
+}
+
+func (s *TestSuite) TestMerge() {
+	less := func(a, b interface{}) bool { return a.(int) < b.(int) }
+
+	s.Run("Merging sorted iterators", func() {
+		it := Merge(less, FromSlice(1, 4, 7), FromSlice(2, 5), FromSlice(), FromSlice(3, 6, 8, 9))
+		s.Equal([]interface{}{1, 2, 3, 4, 5, 6, 7, 8, 9}, s.collect(it))
+	})
+
+	s.Run("Error in any of the iterators should stop the merge", func() {
+		it := Merge(less, FromSlice(1, 3, 5), &failingIterator{ValueIterator: FromSlice(2, 4, 6), failAt: 2})
+		_, err := Collect(it)
+		s.Equal(errDummy, err)
+	})
+
+	s.Run("Closing should close all the iterators", func() {
+		first, second := &closeRecorder{ValueIterator: FromSlice(1)}, &closeRecorder{ValueIterator: FromSlice(2), err: errDummy}
+		err := Merge(less, first, second).Close()
+		s.Equal(errDummy, err)
+		s.True(first.closed)
+		s.True(second.closed)
+	})
+}
+
+func (s *TestSuite) TestCollect() {
+	s.Run("Collect should close the iterator", func() {
+		it := &closeRecorder{ValueIterator: FromSlice(1, 2)}
+		got, err := Collect(Filter(it, func(interface{}) bool { return true }))
+		s.NoError(err)
+		s.Equal([]interface{}{1, 2}, got)
+		s.True(it.closed)
+	})
+
+	s.Run("Collect should return the close error", func() {
+		it := &closeRecorder{ValueIterator: FromSlice(1, 2), err: errDummy}
+		got, err := Collect(Take(it, 1))
+		s.Equal(errDummy, err)
+		s.Nil(got)
+		s.True(it.closed)
+	})
+
+	s.Run("Collect should return the iteration error", func() {
+		it := &closeRecorder{ValueIterator: &failingIterator{ValueIterator: FromSlice(1, 2), failAt: 2}}
+		_, err := Collect(Skip(it, 1))
+		s.Equal(errDummy, err)
+		s.True(it.closed)
+	})
+}
+
+func (s *TestSuite) TestForEach() {
+	var got []interface{}
+	err := ForEach(FromSlice(1, 2, 3), func(v interface{}) error {
+		if v.(int) == 3 {
+			return errDummy
+		}
+		got = append(got, v)
+		return nil
+	})
+	s.Equal(errDummy, err)
+	s.Equal([]interface{}{1, 2}, got)
+}
+
+func (s *TestSuite) collect(it ValueIterator) []interface{} {
+	values, err := Collect(it)
+	s.Require().NoError(err)
+	return values
+}
+
+// failingIterator fails when loading the failAt-th value.
+type failingIterator struct {
+	ValueIterator
+	failAt int
+	count  int
+	err    error
+}
+
+func (f *failingIterator) Next() bool {
+	f.count++
+	if f.count >= f.failAt {
+		f.err = errDummy
+		return false
+	}
+	return f.ValueIterator.Next()
+}
+
+func (f *failingIterator) Error() error {
+	return f.err
+}
+
+type closeRecorder struct {
+	ValueIterator
+	closed bool
+	err    error
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return c.err
+}
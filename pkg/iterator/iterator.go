@@ -10,3 +10,11 @@ type Iterator interface {
 	// Error returns the last error encountered by the iterator.
 	Error() error
 }
+
+// ValueIterator is an Iterator that exposes its loaded value.
+// It is the common type the combinators work on.
+type ValueIterator interface {
+	Iterator
+	// Value returns the current loaded value.
+	Value() interface{}
+}
@@ -0,0 +1,78 @@
+package iterator
+
+// FromSlice returns an iterator that loads each of the values in order.
+func FromSlice(values ...interface{}) ValueIterator {
+	return &sliceIterator{values: values, index: -1}
+}
+
+type sliceIterator struct {
+	values []interface{}
+	index  int
+}
+
+func (s *sliceIterator) Close() error {
+	s.index = len(s.values)
+	return nil
+}
+
+func (s *sliceIterator) Next() bool {
+	if s.index+1 >= len(s.values) {
+		s.index = len(s.values)
+		return false
+	}
+	s.index++
+	return true
+}
+
+func (s *sliceIterator) Error() error {
+	return nil
+}
+
+func (s *sliceIterator) Value() interface{} {
+	if s.index < 0 || s.index >= len(s.values) {
+		return nil
+	}
+	return s.values[s.index]
+}
+
+// Collect loads all the values of it into a slice then closes it.
+// It returns the error encountered by the iterator, or by the Close
+// when there's none.
+func Collect(it ValueIterator) (values []interface{}, err error) {
+	defer func() {
+		cerr := it.Close()
+		if cerr != nil && err == nil {
+			values, err = nil, cerr
+		}
+	}()
+
+	for it.Next() {
+		values = append(values, it.Value())
+	}
+
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// ForEach calls fn for each of the values of it then closes it. The
+// iteration will stop when fn returns an error and that error will
+// be returned.
+func ForEach(it ValueIterator, fn func(v interface{}) error) (err error) {
+	defer func() {
+		cerr := it.Close()
+		if cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	for it.Next() {
+		if err := fn(it.Value()); err != nil {
+			return err
+		}
+	}
+
+	return it.Error()
+}
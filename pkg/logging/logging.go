@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"sync"
+)
+
+const (
+	// FormatJSON logs a JSON object per line.
+	FormatJSON = "json"
+	// FormatText logs the key=value pairs per line.
+	FormatText = "text"
+)
+
+// Configure sets the level, e.g. "debug" or "info", and the format,
+// either "json" or "text", of the logger.
+func Configure(logger *logrus.Logger, level, format string) error {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case FormatJSON:
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	case FormatText:
+		logger.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	default:
+		return fmt.Errorf("logging: unknown format %q, want %q or %q", format, FormatJSON, FormatText)
+	}
+
+	logger.SetLevel(lvl)
+	return nil
+}
+
+type loggerKey struct{}
+
+// scope is the logger of a context with the fields added to it.
+type scope struct {
+	logger logrus.FieldLogger
+
+	mu     sync.RWMutex
+	fields logrus.Fields
+}
+
+// NewContext returns a copy of ctx with the logger. The fields
+// added with AddFields to the returned context, or to the contexts
+// derived from it, are on all the lines logged with its logger.
+func NewContext(ctx context.Context, logger logrus.FieldLogger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, &scope{logger: logger, fields: logrus.Fields{}})
+}
+
+// AddFields adds the fields to the logger of ctx. It does
+// nothing when ctx has no logger.
+func AddFields(ctx context.Context, fields logrus.Fields) {
+	s, ok := ctx.Value(loggerKey{}).(*scope)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range fields {
+		s.fields[k] = v
+	}
+}
+
+// FromContext returns the logger of ctx with its fields and
+// whether ctx has a logger.
+func FromContext(ctx context.Context) (logrus.FieldLogger, bool) {
+	s, ok := ctx.Value(loggerKey{}).(*scope)
+	if !ok {
+		return nil, false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.logger.WithFields(s.fields), true
+}
+
+// Logger returns the logger of ctx with its fields, or the
+// standard logger when ctx has none.
+func Logger(ctx context.Context) logrus.FieldLogger {
+	if logger, ok := FromContext(ctx); ok {
+		return logger
+	}
+	return logrus.StandardLogger()
+}
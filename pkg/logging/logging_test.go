@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfigure(t *testing.T) {
+	logger := logrus.New()
+
+	require.NoError(t, Configure(logger, "debug", FormatJSON))
+	require.Equal(t, logrus.DebugLevel, logger.GetLevel())
+	require.IsType(t, &logrus.JSONFormatter{}, logger.Formatter)
+
+	require.NoError(t, Configure(logger, "warn", FormatText))
+	require.Equal(t, logrus.WarnLevel, logger.GetLevel())
+	require.IsType(t, &logrus.TextFormatter{}, logger.Formatter)
+
+	require.Error(t, Configure(logger, "loud", FormatJSON))
+	require.Error(t, Configure(logger, "info", "xml"))
+}
+
+func TestContext(t *testing.T) {
+	_, ok := FromContext(context.TODO())
+	require.False(t, ok)
+	require.Equal(t, logrus.StandardLogger(), Logger(context.TODO()))
+
+	// Adding fields to a context without logger is a no-op.
+	AddFields(context.TODO(), logrus.Fields{"note_id": "1"})
+
+	logger, hook := logtest.NewNullLogger()
+	ctx := NewContext(context.TODO(), logger.WithField("request_id", "request-1"))
+
+	// The fields added to a derived context are seen
+	// by the loggers of its parents too.
+	child, cancel := context.WithCancel(ctx)
+	defer cancel()
+	AddFields(child, logrus.Fields{"note_id": "1"})
+
+	Logger(ctx).Info("done")
+	require.Equal(t, logrus.Fields{"request_id": "request-1", "note_id": "1"}, hook.LastEntry().Data)
+}
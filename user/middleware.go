@@ -0,0 +1,94 @@
+package user
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"noteapp/api"
+	"noteapp/api/middleware"
+	"noteapp/api/problem"
+	"noteapp/auth"
+	"noteapp/note"
+	"noteapp/pkg/logging"
+)
+
+// NewMiddleware returns a user middleware with its name.
+func NewMiddleware(r *Registry) api.NamedMiddleware {
+	return api.NewNamedMiddleware("User", Middleware(r))
+}
+
+// Middleware returns an http handler middleware which registers the
+// user of the authenticated requests and makes it the note owner of
+// the request context, and adds the user id to the logger of the
+// request. It must come after the authentication. The anonymous
+// requests are left as they are.
+func Middleware(r *Registry) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx, err := withOwner(req.Context(), r)
+			if err != nil {
+				logging.Logger(req.Context()).Error(err)
+				p := problem.New(http.StatusInternalServerError, "internal_error", "Unexpected error")
+				p.RequestID = middleware.RequestIDFromContext(req.Context())
+				_ = problem.Write(w, p)
+				return
+			}
+			if ownerID, ok := note.OwnerFromContext(ctx); ok {
+				logging.AddFields(ctx, logrus.Fields{"user_id": ownerID})
+			}
+			h.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor which sets
+// the note owner of the unary calls, the same way as the http middleware.
+func UnaryServerInterceptor(r *Registry) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ownerCtx, err := withOwner(ctx, r)
+		if err != nil {
+			logging.Logger(ctx).Error(err)
+			return nil, status.Error(codes.Internal, "Unexpected error")
+		}
+		return handler(ownerCtx, req)
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor which sets
+// the note owner of the streaming calls, the same way as the http middleware.
+func StreamServerInterceptor(r *Registry) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := withOwner(ss.Context(), r)
+		if err != nil {
+			logging.Logger(ss.Context()).Error(err)
+			return status.Error(codes.Internal, "Unexpected error")
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func withOwner(ctx context.Context, r *Registry) (context.Context, error) {
+	p, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return ctx, nil
+	}
+
+	u, err := r.Register(p.Subject)
+	if err != nil {
+		return nil, err
+	}
+	return note.WithOwner(ctx, u.ID), nil
+}
+
+// serverStream replaces the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,70 @@
+package user
+
+import (
+	"context"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"net/http"
+	"net/http/httptest"
+	"noteapp/auth"
+	"noteapp/note"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	suite.Run(t, new(MiddlewareTestSuite))
+}
+
+type MiddlewareTestSuite struct {
+	suite.Suite
+	registry *Registry
+}
+
+func (s *MiddlewareTestSuite) SetupTest() {
+	file, err := afero.NewMemMapFs().Create("users.json")
+	s.Require().NoError(err)
+
+	s.registry, err = NewRegistry(file)
+	s.Require().NoError(err)
+}
+
+func (s *MiddlewareTestSuite) TestMiddleware() {
+	var (
+		owner    string
+		hasOwner bool
+	)
+	handler := Middleware(s.registry)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		owner, hasOwner = note.OwnerFromContext(r.Context())
+	}))
+
+	s.Run("Authenticated request", func() {
+		req := httptest.NewRequest(http.MethodGet, "/v1/notes", nil)
+		req = req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{Subject: "alice"}))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		s.True(hasOwner)
+		s.Equal("alice", owner)
+
+		_, err := s.registry.Get("alice")
+		s.NoError(err)
+	})
+
+	s.Run("Anonymous request", func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/meta", nil))
+		s.False(hasOwner)
+	})
+}
+
+func (s *MiddlewareTestSuite) TestUnaryServerInterceptor() {
+	interceptor := UnaryServerInterceptor(s.registry)
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		owner, _ := note.OwnerFromContext(ctx)
+		return owner, nil
+	}
+
+	ctx := auth.WithPrincipal(context.TODO(), &auth.Principal{Subject: "bob"})
+	got, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	s.Require().NoError(err)
+	s.Equal("bob", got)
+}
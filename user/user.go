@@ -0,0 +1,174 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is an error when the user doesn't exist.
+	ErrNotFound = errors.New("user: not found")
+	// ErrEmptyID is an error when the user id is empty.
+	ErrEmptyID = errors.New("user: empty id")
+)
+
+// User is a user of the server. The id of the user is the subject
+// of its credentials and the owner id of its notes.
+type User struct {
+	ID          string    `json:"id"`
+	CreatedTime time.Time `json:"created_time"`
+}
+
+// File is the file where the registry keeps its data.
+type File interface {
+	io.ReadWriteSeeker
+	io.Closer
+	Sync() error
+	Truncate(size int64) error
+}
+
+// fileData is the JSON document of the registry file.
+type fileData struct {
+	Users []*User `json:"users"`
+}
+
+// Registry keeps the users seen by the server. Every new user is
+// written to the file before it returns. This is safe for concurrent use.
+type Registry struct {
+	file File
+
+	mu    sync.RWMutex
+	users map[string]*User
+}
+
+// NewRegistry reads the registry data from file and returns the
+// registry. An empty file is an empty registry.
+func NewRegistry(file File) (*Registry, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	b, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var data fileData
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &data); err != nil {
+			return nil, fmt.Errorf("user: reading registry: %w", err)
+		}
+	}
+
+	users := make(map[string]*User, len(data.Users))
+	for _, u := range data.Users {
+		users[u.ID] = u
+	}
+	return &Registry{file: file, users: users}, nil
+}
+
+// Register returns the user with an id. The user is added first
+// when it is seen for the first time.
+func (r *Registry) Register(id string) (*User, error) {
+	if id == "" {
+		return nil, ErrEmptyID
+	}
+
+	r.mu.RLock()
+	u, ok := r.users[id]
+	r.mu.RUnlock()
+	if ok {
+		return copyUser(u), nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if u, ok := r.users[id]; ok {
+		return copyUser(u), nil
+	}
+
+	u = &User{ID: id, CreatedTime: time.Now().UTC()}
+	r.users[id] = u
+	if err := r.write(); err != nil {
+		delete(r.users, id)
+		return nil, err
+	}
+	return copyUser(u), nil
+}
+
+// Get returns the user with an id.
+func (r *Registry) Get(id string) (*User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyUser(u), nil
+}
+
+// List returns all the users sorted by id.
+func (r *Registry) List() []*User {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]*User, 0, len(r.users))
+	for _, u := range r.users {
+		users = append(users, copyUser(u))
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+// Close syncs and closes the file of the registry. The registry
+// must not be used after it is closed.
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.file.Sync(); err != nil {
+		_ = r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+// write rewrites the whole file with the current data.
+func (r *Registry) write() error {
+	users := make([]*User, 0, len(r.users))
+	for _, u := range r.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	b, err := json.Marshal(fileData{Users: users})
+	if err != nil {
+		return err
+	}
+
+	if err := r.file.Truncate(0); err != nil {
+		return err
+	}
+
+	if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if _, err := r.file.Write(b); err != nil {
+		return err
+	}
+
+	return r.file.Sync()
+}
+
+func copyUser(u *User) *User {
+	cpy := *u
+	return &cpy
+}
@@ -0,0 +1,80 @@
+package user
+
+import (
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	suite.Run(t, new(RegistryTestSuite))
+}
+
+type RegistryTestSuite struct {
+	suite.Suite
+	file     afero.File
+	registry *Registry
+}
+
+func (s *RegistryTestSuite) SetupTest() {
+	var err error
+	s.file, err = afero.NewMemMapFs().Create("users.json")
+	s.Require().NoError(err)
+
+	s.registry, err = NewRegistry(s.file)
+	s.Require().NoError(err)
+}
+
+func (s *RegistryTestSuite) TestRegister() {
+	alice, err := s.registry.Register("alice")
+	s.Require().NoError(err)
+	s.Equal("alice", alice.ID)
+	s.False(alice.CreatedTime.IsZero())
+
+	again, err := s.registry.Register("alice")
+	s.Require().NoError(err)
+	s.Equal(alice, again)
+
+	_, err = s.registry.Register("bob")
+	s.Require().NoError(err)
+
+	_, err = s.registry.Register("")
+	s.ErrorIs(err, ErrEmptyID)
+
+	reopened, err := NewRegistry(s.file)
+	s.Require().NoError(err)
+
+	got, err := reopened.Get("alice")
+	s.Require().NoError(err)
+	s.True(alice.CreatedTime.Equal(got.CreatedTime))
+	s.Len(reopened.List(), 2)
+}
+
+func (s *RegistryTestSuite) TestGet() {
+	_, err := s.registry.Get("alice")
+	s.ErrorIs(err, ErrNotFound)
+}
+
+func (s *RegistryTestSuite) TestList() {
+	s.Empty(s.registry.List())
+
+	for _, id := range []string{"carol", "alice", "bob"} {
+		_, err := s.registry.Register(id)
+		s.Require().NoError(err)
+	}
+
+	var ids []string
+	for _, u := range s.registry.List() {
+		ids = append(ids, u.ID)
+	}
+	s.Equal([]string{"alice", "bob", "carol"}, ids)
+}
+
+func (s *RegistryTestSuite) TestClose() {
+	_, err := s.registry.Register("alice")
+	s.Require().NoError(err)
+	s.Require().NoError(s.registry.Close())
+
+	_, err = s.registry.Register("bob")
+	s.Error(err)
+}